@@ -37,7 +37,7 @@ func main() {
 	}
 
 	userRepo := gormrepo.NewUserRepository(db)
-	demoUser, err := ensureDemoUser(ctx, userRepo)
+	demoUser, err := ensureDemoUser(ctx, cfg, userRepo)
 	if err != nil {
 		log.Fatalf("failed to prepare demo user: %v", err)
 	}
@@ -49,7 +49,7 @@ func main() {
 	log.Printf("demo data ready for %s / %s\n", seedEmail, seedPassword)
 }
 
-func ensureDemoUser(ctx context.Context, userRepo *gormrepo.UserRepository) (*entity.User, error) {
+func ensureDemoUser(ctx context.Context, cfg config.Config, userRepo *gormrepo.UserRepository) (*entity.User, error) {
 	existing, err := userRepo.GetByEmail(ctx, seedEmail)
 	switch {
 	case err == nil:
@@ -58,7 +58,7 @@ func ensureDemoUser(ctx context.Context, userRepo *gormrepo.UserRepository) (*en
 	case !errors.Is(err, gorm.ErrRecordNotFound):
 		return nil, fmt.Errorf("lookup user: %w", err)
 	default:
-		authUC := usecase.NewAuthUsecase(userRepo)
+		authUC := usecase.NewAuthUsecase(userRepo, cfg)
 		user, signupErr := authUC.Signup(ctx, usecase.SignupParams{
 			Email:       seedEmail,
 			Password:    seedPassword,
@@ -68,6 +68,12 @@ func ensureDemoUser(ctx context.Context, userRepo *gormrepo.UserRepository) (*en
 		if signupErr != nil {
 			return nil, signupErr
 		}
+		// EMAIL_VERIFICATION=required でもログインできるよう、デモユーザーは確認済みにしておく。
+		verifiedAt := time.Now()
+		user.EmailVerifiedAt = &verifiedAt
+		if err := userRepo.Update(ctx, user); err != nil {
+			return nil, fmt.Errorf("verify user: %w", err)
+		}
 		log.Printf("created seed user %s\n", seedEmail)
 		return user, nil
 	}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chronome/internal/usecase"
+)
+
+const idleSweepInterval = 5 * time.Minute
+
+// runIdleSweeper は heartbeat の途絶えた実行中エントリを定期的に自動停止する。ctx の終了で止まる。
+func runIdleSweeper(ctx context.Context, idleUC *usecase.IdleUsecase) {
+	ticker := time.NewTicker(idleSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stopped, err := idleUC.StopForgotten(ctx)
+			if err != nil {
+				log.Printf("idle sweep failed: %v", err)
+				continue
+			}
+			if stopped > 0 {
+				log.Printf("idle sweep stopped %d forgotten entries", stopped)
+			}
+		}
+	}
+}
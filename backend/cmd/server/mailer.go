@@ -0,0 +1,23 @@
+package main
+
+import (
+	"log"
+
+	"chronome/internal/adapter/infra/config"
+	"chronome/internal/adapter/infra/mail"
+	"chronome/internal/usecase/provider"
+)
+
+// newMailer は MAIL_DRIVER に応じた送信経路を返す。SMTP 以外はファイルかログへ書き出すだけ。
+func newMailer(cfg config.Config) provider.Mailer {
+	if cfg.MailDriver == "smtp" {
+		if cfg.SMTPHost == "" {
+			log.Fatal("SMTP_HOST must be provided when MAIL_DRIVER=smtp")
+		}
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+	if cfg.Environment == "production" {
+		log.Printf("MAIL_DRIVER=%s does not deliver mail; set MAIL_DRIVER=smtp in production", cfg.MailDriver)
+	}
+	return mail.NewLogMailer(cfg.MailDir, cfg.MailFrom, log.Default())
+}
@@ -12,6 +12,8 @@ import (
 	"chronome/internal/adapter/db/gormrepo"
 	"chronome/internal/adapter/http/handler"
 	"chronome/internal/adapter/infra/config"
+	"chronome/internal/adapter/infra/mail"
+	"chronome/internal/adapter/infra/oidc"
 	sess "chronome/internal/adapter/infra/session"
 	infTime "chronome/internal/adapter/infra/time"
 	"chronome/internal/usecase"
@@ -25,16 +27,30 @@ func main() {
 		log.Fatal("SESSION_SECRET must be provided and at least 32 characters long in production")
 	}
 
+	if !cfg.EmailVerificationMode().Valid() {
+		log.Fatalf("EMAIL_VERIFICATION must be one of optional, limited or required: %q", cfg.EmailVerificationModeValue)
+	}
+
 	db, err := openDatabaseWithRetry(cfg)
 	if err != nil {
 		log.Fatalf("database startup failed: %v", err)
 	}
 
 	// セッションは HTTP 層の関心事なので、ユースケースには渡さず handler 側で扱う。
-	sessionStore, err := sess.NewSignedCookieStore(cfg.SessionSecret)
+	// 一覧と失効は DB に置き、どのインスタンスでログアウトしても全インスタンスで無効になるようにする。
+	// SESSION_SECRET を入れ替える間は、以前の値を SESSION_PREVIOUS_SECRETS に残して発行済みの Cookie を検証できるようにする。
+	sessionStore, err := sess.NewSignedCookieStore(cfg.SessionSecret, sess.NewGormRegistry(db), cfg.SessionPreviousSecrets...)
 	if err != nil {
 		log.Fatalf("failed to initialize session store: %v", err)
 	}
+	tokenSigner, err := sess.NewHMACTokenSigner(cfg.SessionSecret, cfg.SessionPreviousSecrets...)
+	if err != nil {
+		log.Fatalf("failed to initialize token signer: %v", err)
+	}
+	rateLimitStore, err := newRateLimitStore(cfg, db)
+	if err != nil {
+		log.Fatalf("failed to initialize rate limiter: %v", err)
+	}
 
 	// リポジトリ
 	userRepo := gormrepo.NewUserRepository(db)
@@ -42,17 +58,93 @@ func main() {
 	entryRepo := gormrepo.NewEntryRepository(db)
 	tagRepo := gormrepo.NewTagRepository(db)
 	allocationRepo := gormrepo.NewAllocationRepository(db)
+	allocationTemplateRepo := gormrepo.NewAllocationTemplateRepository(db)
+	favoriteRepo := gormrepo.NewFavoriteRepository(db)
+	pomodoroRepo := gormrepo.NewPomodoroRepository(db)
+	goalRepo := gormrepo.NewGoalRepository(db)
+	scheduleRepo := gormrepo.NewScheduleRepository(db)
+	refreshTokenRepo := gormrepo.NewRefreshTokenRepository(db)
+	personalTokenRepo := gormrepo.NewPersonalTokenRepository(db)
+	passwordResetRepo := gormrepo.NewPasswordResetRepository(db)
+	emailVerificationRepo := gormrepo.NewEmailVerificationRepository(db)
+	recoveryCodeRepo := gormrepo.NewRecoveryCodeRepository(db)
+	loginChallengeRepo := gormrepo.NewLoginChallengeRepository(db)
+	externalIdentityRepo := gormrepo.NewExternalIdentityRepository(db)
+	oidcLoginRequestRepo := gormrepo.NewOIDCLoginRequestRepository(db)
+
+	// メールは送信先の応答待ちでアドレスの有無が推測されないよう、常にバックグラウンドで送る。
+	mailer := mail.NewAsyncMailer(newMailer(cfg), log.Default())
 
 	// ユースケース
 	// ユースケースは repository interface に依存し、DB 実装の詳細を知らない。
-	authUC := usecase.NewAuthUsecase(userRepo)
+	authUC := usecase.NewAuthUsecase(userRepo, cfg)
+	tokenUC := usecase.NewTokenUsecase(refreshTokenRepo, userRepo, cfg, infTime.SystemClock{})
+	personalTokenUC := usecase.NewPersonalTokenUsecase(personalTokenRepo, infTime.SystemClock{})
+	passwordResetUC := usecase.NewPasswordResetUsecase(userRepo, passwordResetRepo, mailer, cfg, infTime.SystemClock{})
+	emailVerificationUC := usecase.NewEmailVerificationUsecase(userRepo, emailVerificationRepo, mailer, cfg, infTime.SystemClock{})
+	accountUC := usecase.NewAccountUsecase(userRepo, emailVerificationUC, mailer)
+	twoFactorUC := usecase.NewTwoFactorUsecase(userRepo, recoveryCodeRepo, loginChallengeRepo, infTime.SystemClock{})
+	// OIDC_ISSUER を設定した場合だけ ID プロバイダーでのログインを有効にする。ディスカバリーは初回のログイン時に行う。
+	var oidcUC *usecase.OIDCUsecase
+	if cfg.OIDCEnabled() {
+		idp, err := oidc.NewClient(oidc.Options{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+		})
+		if err != nil {
+			log.Fatalf("failed to initialize oidc client: %v", err)
+		}
+		oidcUC = usecase.NewOIDCUsecase(userRepo, externalIdentityRepo, oidcLoginRequestRepo, idp, cfg, infTime.SystemClock{})
+	}
 	projectUC := usecase.NewProjectUsecase(projectRepo, cfg)
 	tagUC := usecase.NewTagUsecase(tagRepo, cfg)
 	entryUC := usecase.NewEntryUsecase(entryRepo, tagRepo, infTime.SystemClock{})
-	reportUC := usecase.NewReportUsecase(entryRepo, projectRepo)
-	allocationUC := usecase.NewAllocationUsecase(allocationRepo, infTime.SystemClock{})
+	reportUC := usecase.NewReportUsecase(entryRepo, projectRepo, scheduleRepo)
+	allocationUC := usecase.NewAllocationUsecase(allocationRepo, entryRepo, projectRepo, tagRepo, infTime.SystemClock{})
+	allocationTemplateUC := usecase.NewAllocationTemplateUsecase(allocationTemplateRepo, allocationUC, entryRepo, projectRepo, tagRepo, infTime.SystemClock{})
+	favoriteUC := usecase.NewFavoriteUsecase(favoriteRepo, entryRepo, tagRepo, infTime.SystemClock{})
+	idleUC := usecase.NewIdleUsecase(entryRepo, cfg, infTime.SystemClock{})
+	pomodoroUC := usecase.NewPomodoroUsecase(pomodoroRepo, entryRepo, infTime.SystemClock{})
+	goalUC := usecase.NewGoalUsecase(goalRepo, entryRepo, projectRepo, tagRepo, infTime.SystemClock{})
+	scheduleUC := usecase.NewScheduleUsecase(scheduleRepo)
 
-	apiHandler := handler.NewAPIHandler(cfg, sessionStore, authUC, projectUC, tagUC, entryUC, reportUC, allocationUC)
+	apiHandler := handler.NewAPIHandler(handler.APIHandlerDeps{
+		Config:    cfg,
+		Sessions:  sessionStore,
+		Signer:    tokenSigner,
+		Limits:    rateLimitStore,
+		Auth:      authUC,
+		Tokens:    tokenUC,
+		Personal:  personalTokenUC,
+		Resets:    passwordResetUC,
+		Accounts:  accountUC,
+		Verifier:  emailVerificationUC,
+		TwoFactor: twoFactorUC,
+		OIDC:      oidcUC,
+		Projects:  projectUC,
+		Tags:      tagUC,
+		Entries:   entryUC,
+		Reports:   reportUC,
+		Allocs:    allocationUC,
+		Templates: allocationTemplateUC,
+		Favorites: favoriteUC,
+		Idle:      idleUC,
+		Pomodoros: pomodoroUC,
+		Goals:     goalUC,
+		Schedules: scheduleUC,
+	})
+
+	// AUTO_STOP_AFTER が設定されている場合だけ、放置された実行中エントリの自動停止を回す。
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	defer stopSweep()
+	if cfg.AutoStopAfter() > 0 {
+		go runIdleSweeper(sweepCtx, idleUC)
+	}
+	// ポモドーロのフェーズは参照がなくても境界で切り替え、work のエントリが伸び続けないようにする。
+	go runPomodoroSweeper(sweepCtx, pomodoroUC)
 
 	// HTTP サーバーは chi ルーターを入口にし、各 request を handler -> usecase へ流す。
 	srv := &http.Server{
@@ -70,6 +162,7 @@ func main() {
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 	<-shutdown
+	stopSweep()
 
 	// SIGINT/SIGTERM 受信時は処理中の request を短時間待ってから終了する。
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -77,5 +170,6 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("graceful shutdown failed: %v", err)
 	}
+	mailer.Wait()
 	log.Println("server stopped")
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chronome/internal/usecase"
+)
+
+const pomodoroSweepInterval = time.Minute
+
+// runPomodoroSweeper は誰も参照していないポモドーロセッションも定期的にフェーズ境界まで進める。ctx の終了で止まる。
+func runPomodoroSweeper(ctx context.Context, pomodoroUC *usecase.PomodoroUsecase) {
+	ticker := time.NewTicker(pomodoroSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := pomodoroUC.AdvanceAll(ctx); err != nil {
+				log.Printf("pomodoro sweep failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"chronome/internal/adapter/infra/config"
+	"chronome/internal/adapter/infra/ratelimit"
+)
+
+// newRateLimitStore は RATE_LIMIT_BACKEND に応じてカウンタの保存先を選ぶ。
+// 複数インスタンスで動かす場合、memory だとインスタンスごとに別々に数えるため制限が緩くなる。
+func newRateLimitStore(cfg config.Config, db *gorm.DB) (ratelimit.Store, error) {
+	switch cfg.RateLimitBackend {
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "db":
+		return ratelimit.NewGormStore(db), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit backend: %s", cfg.RateLimitBackend)
+	}
+}
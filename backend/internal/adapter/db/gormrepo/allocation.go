@@ -2,10 +2,13 @@ package gormrepo
 
 import (
 	"context"
+	"errors"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
 )
 
 // AllocationRepository は分配履歴を保存する。
@@ -19,12 +22,113 @@ func NewAllocationRepository(db *gorm.DB) *AllocationRepository {
 
 func (r *AllocationRepository) Create(ctx context.Context, request *entity.AllocationRequest, allocations []entity.TaskAllocation) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(request).Error; err != nil {
+		return createAllocation(tx, request, allocations)
+	})
+}
+
+func (r *AllocationRepository) CreateWithEntries(ctx context.Context, request *entity.AllocationRequest, allocations []entity.TaskAllocation, changes repository.EntryChanges) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := createAllocation(tx, request, allocations); err != nil {
 			return err
 		}
-		if len(allocations) == 0 {
+		return applyEntryChanges(tx, request.UserID, changes)
+	})
+}
+
+func createAllocation(tx *gorm.DB, request *entity.AllocationRequest, allocations []entity.TaskAllocation) error {
+	if err := tx.Create(request).Error; err != nil {
+		return err
+	}
+	if len(allocations) == 0 {
+		return nil
+	}
+	return tx.Create(&allocations).Error
+}
+
+func (r *AllocationRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]entity.AllocationRequest, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entity.AllocationRequest{}).Where("user_id = ?", userID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var requests []entity.AllocationRequest
+	if err := query.Order("created_at desc").Order("id").Limit(limit).Offset(offset).Find(&requests).Error; err != nil {
+		return nil, 0, err
+	}
+	return requests, total, nil
+}
+
+func (r *AllocationRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationRequest, []entity.TaskAllocation, error) {
+	var request entity.AllocationRequest
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, repository.ErrNotFound
+		}
+		return nil, nil, err
+	}
+	var allocations []entity.TaskAllocation
+	if err := r.db.WithContext(ctx).Where("request_id = ?", request.ID).Order("id").Find(&allocations).Error; err != nil {
+		return nil, nil, err
+	}
+	return &request, allocations, nil
+}
+
+func (r *AllocationRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 所有者の一致を確認してから子行を消し、他ユーザーの分配結果には触れない。
+		result := tx.Where("user_id = ? AND id = ?", userID, id).Delete(&entity.AllocationRequest{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
 			return nil
 		}
-		return tx.Create(&allocations).Error
+		return tx.Where("request_id = ?", id).Delete(&entity.TaskAllocation{}).Error
 	})
 }
+
+func (r *AllocationRepository) CreateBatch(ctx context.Context, batch *entity.AllocationBatch, requests []entity.AllocationRequest, allocations [][]entity.TaskAllocation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		for i := range requests {
+			if err := tx.Create(&requests[i]).Error; err != nil {
+				return err
+			}
+			if len(allocations[i]) == 0 {
+				continue
+			}
+			if err := tx.Create(&allocations[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *AllocationRepository) GetBatch(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationBatch, []entity.AllocationRequest, []entity.TaskAllocation, error) {
+	var batch entity.AllocationBatch
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).First(&batch).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil, repository.ErrNotFound
+		}
+		return nil, nil, nil, err
+	}
+	var requests []entity.AllocationRequest
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND batch_id = ?", userID, batch.ID).Order("batch_index").Find(&requests).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	if len(requests) == 0 {
+		return &batch, requests, nil, nil
+	}
+	requestIDs := make([]uuid.UUID, 0, len(requests))
+	for _, request := range requests {
+		requestIDs = append(requestIDs, request.ID)
+	}
+	var allocations []entity.TaskAllocation
+	if err := r.db.WithContext(ctx).Where("request_id IN ?", requestIDs).Order("id").Find(&allocations).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	return &batch, requests, allocations, nil
+}
@@ -0,0 +1,92 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+)
+
+// AllocationTemplateRepository は GORM で repository.AllocationTemplateRepository を実装する。
+type AllocationTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewAllocationTemplateRepository(db *gorm.DB) *AllocationTemplateRepository {
+	return &AllocationTemplateRepository{db: db}
+}
+
+func (r *AllocationTemplateRepository) Create(ctx context.Context, template *entity.AllocationTemplate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(template).Error
+	})
+}
+
+func (r *AllocationTemplateRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.AllocationTemplate, error) {
+	var templates []entity.AllocationTemplate
+	err := r.db.WithContext(ctx).
+		Preload("Tasks", orderTemplateTasks).
+		Where("user_id = ?", userID).
+		Order("created_at asc").
+		Find(&templates).Error
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *AllocationTemplateRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationTemplate, error) {
+	var template entity.AllocationTemplate
+	err := r.db.WithContext(ctx).
+		Preload("Tasks", orderTemplateTasks).
+		Where("user_id = ? AND id = ?", userID, id).
+		First(&template).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *AllocationTemplateRepository) Update(ctx context.Context, template *entity.AllocationTemplate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// タスクは差分を取らずに置き換え、Position を入力順に振り直す。
+		if err := tx.Omit("Tasks").Save(template).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ?", template.ID).Delete(&entity.AllocationTemplateTask{}).Error; err != nil {
+			return err
+		}
+		if len(template.Tasks) == 0 {
+			return nil
+		}
+		for i := range template.Tasks {
+			template.Tasks[i].ID = 0
+			template.Tasks[i].TemplateID = template.ID
+		}
+		return tx.Create(&template.Tasks).Error
+	})
+}
+
+func (r *AllocationTemplateRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND id = ?", userID, id).Delete(&entity.AllocationTemplate{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return tx.Where("template_id = ?", id).Delete(&entity.AllocationTemplateTask{}).Error
+	})
+}
+
+func orderTemplateTasks(db *gorm.DB) *gorm.DB {
+	return db.Order("position asc")
+}
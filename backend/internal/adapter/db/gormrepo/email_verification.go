@@ -0,0 +1,58 @@
+package gormrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"chronome/internal/domain/entity"
+)
+
+// EmailVerificationRepository は GORM で repository.EmailVerificationRepository を実装する。
+type EmailVerificationRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+func (r *EmailVerificationRepository) Create(ctx context.Context, token *entity.EmailVerificationToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *EmailVerificationRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.EmailVerificationToken, error) {
+	var token entity.EmailVerificationToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *EmailVerificationRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	// used_at IS NULL を条件に含め、同じトークンでの同時確認は片方だけ成功させる。
+	result := r.db.WithContext(ctx).Model(&entity.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *EmailVerificationRepository) InvalidateByUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.EmailVerificationToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", at).Error
+}
+
+func (r *EmailVerificationRepository) ListCreatedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]entity.EmailVerificationToken, error) {
+	var tokens []entity.EmailVerificationToken
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
@@ -2,6 +2,8 @@ package gormrepo
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -35,13 +37,20 @@ func (r *EntryRepository) ListByUser(ctx context.Context, userID uuid.UUID, filt
 	if filter.ProjectID != nil {
 		query = query.Where("project_id = ?", filter.ProjectID)
 	}
+	if filter.RunningOnly {
+		query = query.Where("ended_at IS NULL")
+	}
 	if filter.TagID != nil {
 		// タグ絞り込みは many-to-many の中間テーブル entry_tags を JOIN する。
 		query = query.Joins("JOIN entry_tags ON entry_tags.entry_id = entries.id").
 			Where("entry_tags.tag_id = ?", *filter.TagID)
 	}
+	query = query.Order("started_at desc")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
 	var entries []entity.Entry
-	if err := query.Order("started_at desc").Find(&entries).Error; err != nil {
+	if err := query.Find(&entries).Error; err != nil {
 		return nil, err
 	}
 	return entries, nil
@@ -51,6 +60,9 @@ func (r *EntryRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid
 	var entry entity.Entry
 	err := r.db.WithContext(ctx).Preload("Tags").Where("user_id = ? AND id = ?", userID, id).First(&entry).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
 		return nil, err
 	}
 	return &entry, nil
@@ -65,7 +77,66 @@ func (r *EntryRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.
 }
 
 func (r *EntryRepository) ReplaceTags(ctx context.Context, entry *entity.Entry, tagIDs []uuid.UUID) error {
-	db := r.db.WithContext(ctx)
+	return replaceEntryTags(r.db.WithContext(ctx), entry, tagIDs)
+}
+
+func (r *EntryRepository) ApplyChanges(ctx context.Context, userID uuid.UUID, changes repository.EntryChanges) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return applyEntryChanges(tx, userID, changes)
+	})
+}
+
+// applyEntryChanges は tx の中で changes を適用する。他のリポジトリの保存と同じトランザクションにまとめる場合にも使う。
+func applyEntryChanges(tx *gorm.DB, userID uuid.UUID, changes repository.EntryChanges) error {
+	for _, id := range changes.Delete {
+		result := tx.Where("user_id = ? AND id = ?", userID, id).Delete(&entity.Entry{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repository.ErrNotFound
+		}
+	}
+	for i := range changes.Stop {
+		// ended_at が空の行だけを更新し、同時に停止したリクエストの片方だけを成功させる。
+		if err := updateEntry(tx.Where("ended_at IS NULL"), userID, &changes.Stop[i]); err != nil {
+			return err
+		}
+	}
+	for i := range changes.Update {
+		if err := updateEntry(tx, userID, &changes.Update[i]); err != nil {
+			return err
+		}
+	}
+	for i := range changes.Create {
+		entry := &changes.Create[i]
+		if entry.UserID != userID {
+			return repository.ErrNotFound
+		}
+		if err := tx.Omit("Tags").Create(entry).Error; err != nil {
+			return err
+		}
+		if len(entry.Tags) > 0 {
+			if err := replaceEntryTags(tx, entry, tagIDsOf(entry.Tags)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func updateEntry(tx *gorm.DB, userID uuid.UUID, entry *entity.Entry) error {
+	result := tx.Model(entry).Where("user_id = ?", userID).Select("*").Omit("Tags", "CreatedAt").Updates(entry)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return replaceEntryTags(tx.Session(&gorm.Session{NewDB: true}), entry, tagIDsOf(entry.Tags))
+}
+
+func replaceEntryTags(db *gorm.DB, entry *entity.Entry, tagIDs []uuid.UUID) error {
 	assoc := db.Model(entry).Association("Tags")
 	if len(tagIDs) == 0 {
 		// 空配列は「タグをすべて外す」という明示的な更新として扱う。
@@ -77,3 +148,24 @@ func (r *EntryRepository) ReplaceTags(ctx context.Context, entry *entity.Entry,
 	}
 	return assoc.Replace(tags)
 }
+
+func tagIDsOf(tags []entity.Tag) []uuid.UUID {
+	ids := make([]uuid.UUID, len(tags))
+	for i, tag := range tags {
+		ids[i] = tag.ID
+	}
+	return ids
+}
+
+func (r *EntryRepository) ListIdleRunning(ctx context.Context, inactiveBefore time.Time) ([]entity.Entry, error) {
+	// heartbeat を一度も受けていないエントリは、heartbeat を送らないクライアントのタイマーなので対象にしない。
+	var entries []entity.Entry
+	err := r.db.WithContext(ctx).Preload("Tags").
+		Where("ended_at IS NULL AND last_activity_at IS NOT NULL AND last_activity_at < ?", inactiveBefore).
+		Order("started_at asc").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,67 @@
+package gormrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"chronome/internal/domain/entity"
+)
+
+// ExternalIdentityRepository は GORM で repository.ExternalIdentityRepository を実装する。
+type ExternalIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewExternalIdentityRepository(db *gorm.DB) *ExternalIdentityRepository {
+	return &ExternalIdentityRepository{db: db}
+}
+
+func (r *ExternalIdentityRepository) Create(ctx context.Context, identity *entity.ExternalIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *ExternalIdentityRepository) GetBySubject(ctx context.Context, issuer, subject string) (*entity.ExternalIdentity, error) {
+	var identity entity.ExternalIdentity
+	if err := r.db.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *ExternalIdentityRepository) TouchLastLogin(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.ExternalIdentity{}).Where("id = ?", id).Update("last_login_at", at).Error
+}
+
+// OIDCLoginRequestRepository は GORM で repository.OIDCLoginRequestRepository を実装する。
+type OIDCLoginRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCLoginRequestRepository(db *gorm.DB) *OIDCLoginRequestRepository {
+	return &OIDCLoginRequestRepository{db: db}
+}
+
+func (r *OIDCLoginRequestRepository) Create(ctx context.Context, request *entity.OIDCLoginRequest) error {
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *OIDCLoginRequestRepository) GetByStateHash(ctx context.Context, stateHash string) (*entity.OIDCLoginRequest, error) {
+	var request entity.OIDCLoginRequest
+	if err := r.db.WithContext(ctx).Where("state_hash = ?", stateHash).First(&request).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *OIDCLoginRequestRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.OIDCLoginRequest{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,61 @@
+package gormrepo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"chronome/internal/domain/entity"
+)
+
+// FavoriteRepository は GORM で repository.FavoriteRepository を実装する。
+type FavoriteRepository struct {
+	db *gorm.DB
+}
+
+func NewFavoriteRepository(db *gorm.DB) *FavoriteRepository {
+	return &FavoriteRepository{db: db}
+}
+
+func (r *FavoriteRepository) Create(ctx context.Context, favorite *entity.Favorite) error {
+	// タグの関連付けは ReplaceTags に任せ、ここでは本体だけを保存する。
+	return r.db.WithContext(ctx).Omit("Tags").Create(favorite).Error
+}
+
+func (r *FavoriteRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.Favorite, error) {
+	var favorites []entity.Favorite
+	if err := r.db.WithContext(ctx).Preload("Tags").Where("user_id = ?", userID).Order("created_at desc").Find(&favorites).Error; err != nil {
+		return nil, err
+	}
+	return favorites, nil
+}
+
+func (r *FavoriteRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Favorite, error) {
+	var favorite entity.Favorite
+	err := r.db.WithContext(ctx).Preload("Tags").Where("user_id = ? AND id = ?", userID, id).First(&favorite).Error
+	if err != nil {
+		return nil, err
+	}
+	return &favorite, nil
+}
+
+func (r *FavoriteRepository) Update(ctx context.Context, favorite *entity.Favorite) error {
+	return r.db.WithContext(ctx).Omit("Tags").Save(favorite).Error
+}
+
+func (r *FavoriteRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).Delete(&entity.Favorite{}).Error
+}
+
+func (r *FavoriteRepository) ReplaceTags(ctx context.Context, favorite *entity.Favorite, tagIDs []uuid.UUID) error {
+	assoc := r.db.WithContext(ctx).Model(favorite).Association("Tags")
+	if len(tagIDs) == 0 {
+		return assoc.Clear()
+	}
+	tags := make([]entity.Tag, len(tagIDs))
+	for i, id := range tagIDs {
+		tags[i] = entity.Tag{ID: id}
+	}
+	return assoc.Replace(tags)
+}
@@ -0,0 +1,47 @@
+package gormrepo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"chronome/internal/domain/entity"
+)
+
+// GoalRepository は GORM で repository.GoalRepository を実装する。
+type GoalRepository struct {
+	db *gorm.DB
+}
+
+func NewGoalRepository(db *gorm.DB) *GoalRepository {
+	return &GoalRepository{db: db}
+}
+
+func (r *GoalRepository) Create(ctx context.Context, goal *entity.Goal) error {
+	return r.db.WithContext(ctx).Create(goal).Error
+}
+
+func (r *GoalRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.Goal, error) {
+	var goals []entity.Goal
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at asc").Find(&goals).Error; err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (r *GoalRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Goal, error) {
+	var goal entity.Goal
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).First(&goal).Error; err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+func (r *GoalRepository) Update(ctx context.Context, goal *entity.Goal) error {
+	return r.db.WithContext(ctx).Save(goal).Error
+}
+
+func (r *GoalRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).Delete(&entity.Goal{}).Error
+}
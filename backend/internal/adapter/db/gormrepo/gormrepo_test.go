@@ -2,6 +2,7 @@ package gormrepo
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
@@ -28,7 +29,25 @@ func newTestDB(t *testing.T) *gorm.DB {
 		&entity.Tag{},
 		&entity.EntryTag{},
 		&entity.AllocationRequest{},
+		&entity.AllocationBatch{},
 		&entity.TaskAllocation{},
+		&entity.AllocationTemplate{},
+		&entity.AllocationTemplateTask{},
+		&entity.Favorite{},
+		&entity.FavoriteTag{},
+		&entity.PomodoroSession{},
+		&entity.Goal{},
+		&entity.WorkSchedule{},
+		&entity.Holiday{},
+		&entity.TimeOff{},
+		&entity.RefreshToken{},
+		&entity.PersonalAccessToken{},
+		&entity.PasswordResetToken{},
+		&entity.EmailVerificationToken{},
+		&entity.RecoveryCode{},
+		&entity.LoginChallenge{},
+		&entity.ExternalIdentity{},
+		&entity.OIDCLoginRequest{},
 	))
 	require.NoError(t, db.Exec("PRAGMA foreign_keys = ON").Error)
 	sqlDB, err := db.DB()
@@ -151,6 +170,84 @@ func TestEntryRepository_TagAssociationsAndFiltering(t *testing.T) {
 	require.Equal(t, tagB.ID, result[0].Tags[0].ID)
 }
 
+func TestEntryRepository_ApplyChangesIsAtomic(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewEntryRepository(db)
+	tagRepo := NewTagRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	tag := &entity.Tag{ID: uuid.New(), UserID: userID, Name: "Focus", Color: "#111111"}
+	require.NoError(t, tagRepo.Create(ctx, tag))
+	original := &entity.Entry{ID: uuid.New(), UserID: userID, Title: "Original", StartedAt: start, Ratio: 1}
+	require.NoError(t, repo.Create(ctx, original))
+
+	// 2 件目の作成が主キーの重複で失敗すると、先に適用した削除と 1 件目の作成も取り消される。
+	first := entity.Entry{ID: uuid.New(), UserID: userID, Title: "First", StartedAt: start, Ratio: 1, Tags: []entity.Tag{*tag}}
+	duplicate := entity.Entry{ID: first.ID, UserID: userID, Title: "Duplicate", StartedAt: start, Ratio: 1}
+	err := repo.ApplyChanges(ctx, userID, repository.EntryChanges{Delete: []uuid.UUID{original.ID}, Create: []entity.Entry{first, duplicate}})
+	require.Error(t, err)
+	entries, err := repo.ListByUser(ctx, userID, repository.EntryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, original.ID, entries[0].ID)
+
+	// 他ユーザーのエントリは削除・更新できない。
+	err = repo.ApplyChanges(ctx, uuid.New(), repository.EntryChanges{Delete: []uuid.UUID{original.ID}})
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	updated := *original
+	updated.Title = "Updated"
+	updated.Tags = []entity.Tag{*tag}
+	require.NoError(t, repo.ApplyChanges(ctx, userID, repository.EntryChanges{Update: []entity.Entry{updated}, Create: []entity.Entry{first}}))
+	entries, err = repo.ListByUser(ctx, userID, repository.EntryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.Len(t, entry.Tags, 1)
+		require.Equal(t, tag.ID, entry.Tags[0].ID)
+	}
+	loaded, err := repo.GetByID(ctx, userID, original.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Updated", loaded.Title)
+}
+
+func TestEntryRepository_ApplyChangesStopsOnlyRunningEntries(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewEntryRepository(db)
+	tagRepo := NewTagRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	tag := &entity.Tag{ID: uuid.New(), UserID: userID, Name: "Focus", Color: "#111111"}
+	require.NoError(t, tagRepo.Create(ctx, tag))
+	running := &entity.Entry{ID: uuid.New(), UserID: userID, Title: "Running", StartedAt: start, Ratio: 1}
+	require.NoError(t, repo.Create(ctx, running))
+
+	stopped := *running
+	stopped.EndedAt = &end
+	stopped.Tags = []entity.Tag{*tag}
+	next := entity.Entry{ID: uuid.New(), UserID: userID, Title: "Next", StartedAt: end, Ratio: 1}
+	require.NoError(t, repo.ApplyChanges(ctx, userID, repository.EntryChanges{Stop: []entity.Entry{stopped}, Create: []entity.Entry{next}}))
+	loaded, err := repo.GetByID(ctx, userID, running.ID)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.EndedAt)
+	require.Len(t, loaded.Tags, 1)
+	_, err = repo.GetByID(ctx, uuid.New(), running.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	// 停止済みのエントリをもう一度止めようとすると、後続の作成ごと取り消す。
+	again := entity.Entry{ID: uuid.New(), UserID: userID, Title: "Again", StartedAt: end, Ratio: 1}
+	err = repo.ApplyChanges(ctx, userID, repository.EntryChanges{Stop: []entity.Entry{stopped}, Create: []entity.Entry{again}})
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	entries, err := repo.ListByUser(ctx, userID, repository.EntryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
+
 func TestTagRepository_CreateAndList(t *testing.T) {
 	db := newTestDB(t)
 	repo := NewTagRepository(db)
@@ -200,6 +297,42 @@ func TestUserRepository_NormalizesOnCreate(t *testing.T) {
 	require.Equal(t, "user@example.com", byID.Email)
 }
 
+func TestUserRepository_UpdatePersistsRounding(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	user := &entity.User{ID: uuid.New(), Email: "rounding@example.com", PasswordHash: "secret"}
+	require.NoError(t, repo.Create(ctx, user))
+	found, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.False(t, found.Rounding.IsSet())
+
+	found.Rounding = entity.RoundingRule{Mode: entity.RoundingUp, IncrementMinutes: 15}
+	require.NoError(t, repo.Update(ctx, found))
+
+	reloaded, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, entity.RoundingRule{Mode: entity.RoundingUp, IncrementMinutes: 15}, reloaded.Rounding)
+}
+
+func TestUserRepository_RevokeAccessTokensSetsCutoff(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	user := &entity.User{ID: uuid.New(), Email: "revoke@example.com", PasswordHash: "secret"}
+	require.NoError(t, repo.Create(ctx, user))
+	at := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RevokeAccessTokens(ctx, user.ID, at))
+
+	reloaded, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, reloaded.TokensNotBefore)
+	require.True(t, at.Equal(*reloaded.TokensNotBefore))
+	require.Equal(t, "revoke@example.com", reloaded.Email)
+}
+
 func TestAllocationRepository_Create(t *testing.T) {
 	db := newTestDB(t)
 	repo := NewAllocationRepository(db)
@@ -242,3 +375,631 @@ func TestAllocationRepository_Create_RollsBackOnFailure(t *testing.T) {
 	require.NoError(t, db.Model(&entity.TaskAllocation{}).Count(&allocationCount).Error)
 	require.Equal(t, int64(0), allocationCount)
 }
+
+func TestAllocationRepository_CreateWithEntriesRollsBackTogether(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewAllocationRepository(db)
+	entryRepo := NewEntryRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	original := &entity.Entry{ID: uuid.New(), UserID: userID, Title: "Support", StartedAt: start, EndedAt: &end, DurationSec: 3600, Ratio: 1}
+	require.NoError(t, entryRepo.Create(ctx, original))
+	projectID := uuid.New()
+	updated := *original
+	updated.ProjectID = &projectID
+	missing := entity.Entry{ID: uuid.New(), UserID: userID, Title: "Missing", StartedAt: start, Ratio: 1}
+
+	// 存在しないエントリの更新で失敗すると、先に保存した履歴と元エントリの更新も取り消される。
+	request := &entity.AllocationRequest{ID: uuid.New(), UserID: userID, TotalMinutes: 60, CreatedAt: time.Now().UTC()}
+	err := repo.CreateWithEntries(ctx, request, nil, repository.EntryChanges{Update: []entity.Entry{updated, missing}})
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	var requestCount int64
+	require.NoError(t, db.Model(&entity.AllocationRequest{}).Count(&requestCount).Error)
+	require.Zero(t, requestCount)
+	loaded, err := entryRepo.GetByID(ctx, userID, original.ID)
+	require.NoError(t, err)
+	require.Nil(t, loaded.ProjectID)
+
+	request.ID = uuid.New()
+	require.NoError(t, repo.CreateWithEntries(ctx, request, nil, repository.EntryChanges{Update: []entity.Entry{updated}}))
+	require.NoError(t, db.Model(&entity.AllocationRequest{}).Count(&requestCount).Error)
+	require.Equal(t, int64(1), requestCount)
+	loaded, err = entryRepo.GetByID(ctx, userID, original.ID)
+	require.NoError(t, err)
+	require.Equal(t, projectID, *loaded.ProjectID)
+	require.Equal(t, "Support", loaded.Title)
+}
+
+func TestAllocationRepository_ScopedToUser(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewAllocationRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	otherID := uuid.New()
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		request := &entity.AllocationRequest{ID: uuid.New(), UserID: userID, TotalMinutes: 60, CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		allocations := []entity.TaskAllocation{
+			{RequestID: request.ID, TaskID: "task-a", Ratio: 1, AllocatedMinutes: 60, CreatedAt: base, UpdatedAt: base},
+		}
+		require.NoError(t, repo.Create(ctx, request, allocations))
+		ids = append(ids, request.ID)
+	}
+	other := &entity.AllocationRequest{ID: uuid.New(), UserID: otherID, TotalMinutes: 30, CreatedAt: base}
+	require.NoError(t, repo.Create(ctx, other, nil))
+
+	page, total, err := repo.ListByUser(ctx, userID, 2, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.Len(t, page, 2)
+	require.Equal(t, ids[2], page[0].ID)
+	require.Equal(t, ids[1], page[1].ID)
+
+	_, _, err = repo.GetByID(ctx, otherID, ids[0])
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	request, allocations, err := repo.GetByID(ctx, userID, ids[0])
+	require.NoError(t, err)
+	require.Equal(t, 60, request.TotalMinutes)
+	require.Len(t, allocations, 1)
+
+	require.NoError(t, repo.Delete(ctx, otherID, ids[0]))
+	_, _, err = repo.GetByID(ctx, userID, ids[0])
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Delete(ctx, userID, ids[0]))
+	_, _, err = repo.GetByID(ctx, userID, ids[0])
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	var allocationCount int64
+	require.NoError(t, db.Model(&entity.TaskAllocation{}).Where("request_id = ?", ids[0]).Count(&allocationCount).Error)
+	require.Equal(t, int64(0), allocationCount)
+}
+
+func TestAllocationRepository_BatchScopedAndOrdered(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewAllocationRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	batch := &entity.AllocationBatch{ID: uuid.New(), UserID: userID, TotalMinutes: 90, CreatedAt: now}
+	var requests []entity.AllocationRequest
+	var rows [][]entity.TaskAllocation
+	for i, total := range []int{30, 60} {
+		request := entity.AllocationRequest{ID: uuid.New(), UserID: userID, TotalMinutes: total, BatchID: &batch.ID, BatchIndex: i, CreatedAt: now}
+		requests = append(requests, request)
+		rows = append(rows, []entity.TaskAllocation{
+			{RequestID: request.ID, TaskID: "a", Ratio: 1, AllocatedMinutes: total, CreatedAt: now, UpdatedAt: now},
+		})
+	}
+	require.NoError(t, repo.CreateBatch(ctx, batch, requests, rows))
+
+	_, _, _, err := repo.GetBatch(ctx, uuid.New(), batch.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	loaded, children, allocations, err := repo.GetBatch(ctx, userID, batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, 90, loaded.TotalMinutes)
+	require.Len(t, children, 2)
+	require.Equal(t, 30, children[0].TotalMinutes)
+	require.Equal(t, 60, children[1].TotalMinutes)
+	require.Len(t, allocations, 2)
+
+	// 子リクエストは通常の履歴一覧にも現れる。
+	_, total, err := repo.ListByUser(ctx, userID, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+}
+
+func TestAllocationTemplateRepository_ReplacesTasksInOrder(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewAllocationTemplateRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	projectA, projectB, tagID := uuid.New(), uuid.New(), uuid.New()
+	template := &entity.AllocationTemplate{ID: uuid.New(), UserID: userID, Name: "Daily", Strategy: "largest_remainder", Tasks: []entity.AllocationTemplateTask{
+		{Position: 0, ProjectID: &projectB, Ratio: 1},
+		{Position: 1, ProjectID: &projectA, Ratio: 3},
+	}}
+	require.NoError(t, repo.Create(ctx, template))
+
+	_, err := repo.GetByID(ctx, uuid.New(), template.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	loaded, err := repo.GetByID(ctx, userID, template.ID)
+	require.NoError(t, err)
+	require.Len(t, loaded.Tasks, 2)
+	require.Equal(t, projectB, *loaded.Tasks[0].ProjectID)
+
+	loaded.Name = "Weekly"
+	loaded.Tasks = []entity.AllocationTemplateTask{{Position: 0, TagID: &tagID, Ratio: 1}}
+	require.NoError(t, repo.Update(ctx, loaded))
+	list, err := repo.ListByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "Weekly", list[0].Name)
+	require.Len(t, list[0].Tasks, 1)
+	require.Equal(t, tagID, *list[0].Tasks[0].TagID)
+
+	require.NoError(t, repo.Delete(ctx, userID, template.ID))
+	var remaining int64
+	require.NoError(t, db.Model(&entity.AllocationTemplateTask{}).Count(&remaining).Error)
+	require.Zero(t, remaining)
+}
+
+func TestFavoriteRepository_CRUDWithTags(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewFavoriteRepository(db)
+	tagRepo := NewTagRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	tag := &entity.Tag{ID: uuid.New(), UserID: userID, Name: "Focus", Color: "#111111"}
+	require.NoError(t, tagRepo.Create(ctx, tag))
+
+	favorite := &entity.Favorite{ID: uuid.New(), UserID: userID, Title: "Deep work", Ratio: 1}
+	require.NoError(t, repo.Create(ctx, favorite))
+	require.NoError(t, repo.ReplaceTags(ctx, favorite, []uuid.UUID{tag.ID}))
+
+	list, err := repo.ListByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Len(t, list[0].Tags, 1)
+	require.Equal(t, tag.ID, list[0].Tags[0].ID)
+
+	_, err = repo.GetByID(ctx, uuid.New(), favorite.ID)
+	require.Error(t, err, "other users should not see the favorite")
+
+	require.NoError(t, repo.Delete(ctx, userID, favorite.ID))
+	_, err = repo.GetByID(ctx, userID, favorite.ID)
+	require.Error(t, err)
+}
+
+func TestEntryRepository_ListLimit(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewEntryRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	for i := 0; i < 3; i++ {
+		entry := &entity.Entry{ID: uuid.New(), UserID: userID, Title: "E", StartedAt: time.Now().Add(-time.Duration(i) * time.Hour), Ratio: 1}
+		require.NoError(t, repo.Create(ctx, entry))
+	}
+
+	result, err := repo.ListByUser(ctx, userID, repository.EntryFilter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	require.True(t, result[0].StartedAt.After(result[1].StartedAt))
+}
+
+func TestEntryRepository_ListIdleRunning(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewEntryRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	recent := now.Add(-10 * time.Minute)
+	stale := now.Add(-10 * time.Hour)
+	ended := now.Add(-9 * time.Hour)
+
+	entries := []entity.Entry{
+		{ID: uuid.New(), UserID: uuid.New(), Title: "Stale heartbeat", StartedAt: now.Add(-12 * time.Hour), LastActivityAt: &stale, Ratio: 1},
+		{ID: uuid.New(), UserID: uuid.New(), Title: "Fresh heartbeat", StartedAt: now.Add(-12 * time.Hour), LastActivityAt: &recent, Ratio: 1},
+		{ID: uuid.New(), UserID: uuid.New(), Title: "No heartbeat", StartedAt: now.Add(-11 * time.Hour), Ratio: 1},
+		{ID: uuid.New(), UserID: uuid.New(), Title: "Stopped", StartedAt: now.Add(-12 * time.Hour), EndedAt: &ended, Ratio: 1},
+	}
+	for i := range entries {
+		require.NoError(t, repo.Create(ctx, &entries[i]))
+	}
+
+	result, err := repo.ListIdleRunning(ctx, now.Add(-8*time.Hour))
+	require.NoError(t, err)
+	// heartbeat を送らないクライアントのタイマーは対象にしない。
+	require.Len(t, result, 1)
+	require.Equal(t, "Stale heartbeat", result[0].Title)
+}
+
+func TestPomodoroRepository_GetActiveIgnoresEndedSessions(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPomodoroRepository(db)
+	entries := NewEntryRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	now := time.Now().UTC()
+	ended := now.Add(-time.Hour)
+
+	finished := &entity.PomodoroSession{ID: uuid.New(), UserID: userID, Title: "Morning", WorkSec: 1500, ShortBreakSec: 300, LongBreakSec: 900, Cycles: 4, PhaseStartedAt: now.Add(-3 * time.Hour), StartedAt: now.Add(-3 * time.Hour), EndedAt: &ended}
+	require.NoError(t, repo.Create(ctx, finished, finished.NewPhaseEntry()))
+
+	_, err := repo.GetActive(ctx, userID)
+	require.Error(t, err)
+
+	active := &entity.PomodoroSession{ID: uuid.New(), UserID: userID, Title: "Afternoon", WorkSec: 1500, ShortBreakSec: 300, LongBreakSec: 900, Cycles: 4, PhaseStartedAt: now, StartedAt: now}
+	first := active.NewPhaseEntry()
+	active.CurrentEntryID = &first.ID
+	require.NoError(t, repo.Create(ctx, active, first))
+	_, err = entries.GetByID(ctx, userID, first.ID)
+	require.NoError(t, err)
+
+	got, err := repo.GetActive(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, active.ID, got.ID)
+	listed, err := repo.ListActive(ctx)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	require.Equal(t, active.ID, listed[0].ID)
+
+	_, err = repo.GetByID(ctx, uuid.New(), active.ID)
+	require.Error(t, err)
+}
+
+func TestPomodoroRepository_AdvanceOnlyFromTheLoadedPhase(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPomodoroRepository(db)
+	entries := NewEntryRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	session := &entity.PomodoroSession{ID: uuid.New(), UserID: userID, Title: "Focus", WorkSec: 1500, ShortBreakSec: 300, LongBreakSec: 900, Cycles: 4, PhaseStartedAt: start, StartedAt: start}
+	work := session.NewPhaseEntry()
+	session.CurrentEntryID = &work.ID
+	require.NoError(t, repo.Create(ctx, session, work))
+
+	// 同じフェーズから進めようとした 2 つのリクエストのうち、先に保存した方だけが次のエントリを作る。
+	advance := func(title string) error {
+		loaded, err := repo.GetByID(ctx, userID, session.ID)
+		require.NoError(t, err)
+		ended := *work
+		end := start.Add(25 * time.Minute)
+		ended.EndedAt = &end
+		loaded.PhaseIndex++
+		loaded.PhaseStartedAt = end
+		loaded.CompletedPomodoros++
+		next := loaded.NewPhaseEntry()
+		next.Title = title
+		loaded.CurrentEntryID = &next.ID
+		return repo.Advance(ctx, loaded, 0, repository.EntryChanges{Update: []entity.Entry{ended}, Create: []entity.Entry{*next}})
+	}
+	require.NoError(t, advance("First"))
+	require.ErrorIs(t, advance("Second"), repository.ErrConflict)
+
+	stored, err := entries.ListByUser(ctx, userID, repository.EntryFilter{})
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+	for _, entry := range stored {
+		require.NotEqual(t, "Second", entry.Title)
+	}
+	reloaded, err := repo.GetByID(ctx, userID, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, reloaded.PhaseIndex)
+	require.Equal(t, 1, reloaded.CompletedPomodoros)
+
+	// 停止済みのセッションは進めない。
+	end := start.Add(time.Hour)
+	reloaded.EndedAt = &end
+	require.NoError(t, repo.Advance(ctx, reloaded, 1, repository.EntryChanges{}))
+	require.ErrorIs(t, repo.Advance(ctx, reloaded, 1, repository.EntryChanges{}), repository.ErrConflict)
+}
+
+func TestGoalRepository_PersistsWorkingDays(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewGoalRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	goal := &entity.Goal{
+		ID: uuid.New(), UserID: userID, Name: "Weekday focus", Scope: entity.GoalScopeUser,
+		Period: entity.GoalPeriodDaily, TargetSec: 3600, WorkingDays: entity.NewWeekdayMask(time.Monday, time.Wednesday),
+	}
+	require.NoError(t, repo.Create(ctx, goal))
+
+	loaded, err := repo.GetByID(ctx, userID, goal.ID)
+	require.NoError(t, err)
+	require.Equal(t, []time.Weekday{time.Monday, time.Wednesday}, loaded.WorkingDays.Weekdays())
+
+	_, err = repo.GetByID(ctx, uuid.New(), goal.ID)
+	require.Error(t, err)
+
+	require.NoError(t, repo.Delete(ctx, userID, goal.ID))
+	goals, err := repo.ListByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Empty(t, goals)
+}
+
+func TestScheduleRepository_UpsertHolidaysAndDateRange(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewScheduleRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	require.NoError(t, repo.UpsertHolidays(ctx, []entity.Holiday{
+		{ID: uuid.New(), UserID: userID, Date: "2024-05-03", Name: "Constitution Day"},
+		{ID: uuid.New(), UserID: userID, Date: "2024-05-06", Name: "Substitute"},
+	}))
+	// 再取り込みでは同じ日付の祝日が上書きされ、重複しない。
+	require.NoError(t, repo.UpsertHolidays(ctx, []entity.Holiday{
+		{ID: uuid.New(), UserID: userID, Date: "2024-05-03", Name: "Kenpo Kinenbi", UID: "uid-1"},
+	}))
+	require.NoError(t, repo.UpsertHolidays(ctx, []entity.Holiday{
+		{ID: uuid.New(), UserID: uuid.New(), Date: "2024-05-03", Name: "Other user"},
+	}))
+
+	holidays, err := repo.ListHolidays(ctx, userID, "", "")
+	require.NoError(t, err)
+	require.Len(t, holidays, 2)
+	require.Equal(t, "Kenpo Kinenbi", holidays[0].Name)
+	require.Equal(t, "uid-1", holidays[0].UID)
+
+	holidays, err = repo.ListHolidays(ctx, userID, "2024-05-04", "2024-05-31")
+	require.NoError(t, err)
+	require.Len(t, holidays, 1)
+	require.Equal(t, "2024-05-06", holidays[0].Date)
+
+	_, err = repo.GetSchedule(ctx, userID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	schedule := &entity.WorkSchedule{UserID: userID, MondaySec: 8 * 3600}
+	require.NoError(t, repo.SaveSchedule(ctx, schedule))
+	schedule.MondaySec = 6 * 3600
+	require.NoError(t, repo.SaveSchedule(ctx, schedule))
+	loaded, err := repo.GetSchedule(ctx, userID)
+	require.NoError(t, err)
+	require.EqualValues(t, 6*3600, loaded.SecondsFor(time.Monday))
+}
+
+func TestRefreshTokenRepository_MarkUsedOnceAndRevokeFamily(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	familyID := uuid.New()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	first := &entity.RefreshToken{ID: uuid.New(), UserID: userID, FamilyID: familyID, TokenHash: "hash-1", ExpiresAt: now.Add(time.Hour)}
+	second := &entity.RefreshToken{ID: uuid.New(), UserID: userID, FamilyID: familyID, TokenHash: "hash-2", ExpiresAt: now.Add(time.Hour)}
+	other := &entity.RefreshToken{ID: uuid.New(), UserID: userID, FamilyID: uuid.New(), TokenHash: "hash-3", ExpiresAt: now.Add(time.Hour)}
+	for _, token := range []*entity.RefreshToken{first, second, other} {
+		require.NoError(t, repo.Create(ctx, token))
+	}
+
+	marked, err := repo.MarkUsed(ctx, first.ID, now)
+	require.NoError(t, err)
+	require.True(t, marked)
+	marked, err = repo.MarkUsed(ctx, first.ID, now)
+	require.NoError(t, err)
+	require.False(t, marked)
+
+	require.NoError(t, repo.RevokeFamily(ctx, familyID, now))
+	loaded, err := repo.GetByHash(ctx, "hash-2")
+	require.NoError(t, err)
+	require.NotNil(t, loaded.RevokedAt)
+	require.False(t, loaded.Active(now))
+	untouched, err := repo.GetByHash(ctx, "hash-3")
+	require.NoError(t, err)
+	require.True(t, untouched.Active(now))
+}
+
+func TestPersonalTokenRepository_DeleteScopedToOwner(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPersonalTokenRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	token := &entity.PersonalAccessToken{ID: uuid.New(), UserID: userID, Name: "cli", TokenHash: "hash", Prefix: "chrpat_abcde", Scopes: "entries:read"}
+	require.NoError(t, repo.Create(ctx, token))
+
+	at := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.TouchLastUsed(ctx, token.ID, at))
+	loaded, err := repo.GetByHash(ctx, "hash")
+	require.NoError(t, err)
+	require.True(t, at.Equal(*loaded.LastUsedAt))
+
+	deleted, err := repo.Delete(ctx, uuid.New(), token.ID)
+	require.NoError(t, err)
+	require.False(t, deleted)
+	deleted, err = repo.Delete(ctx, userID, token.ID)
+	require.NoError(t, err)
+	require.True(t, deleted)
+	tokens, err := repo.ListByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+}
+
+func TestPasswordResetRepository_InvalidateByUser(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPasswordResetRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	mine := &entity.PasswordResetToken{ID: uuid.New(), UserID: userID, TokenHash: "mine", ExpiresAt: now.Add(time.Hour)}
+	other := &entity.PasswordResetToken{ID: uuid.New(), UserID: uuid.New(), TokenHash: "other", ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, repo.Create(ctx, mine))
+	require.NoError(t, repo.Create(ctx, other))
+
+	require.NoError(t, repo.InvalidateByUser(ctx, userID, now))
+	loaded, err := repo.GetByHash(ctx, "mine")
+	require.NoError(t, err)
+	require.False(t, loaded.Usable(now))
+	marked, err := repo.MarkUsed(ctx, mine.ID, now)
+	require.NoError(t, err)
+	require.False(t, marked)
+
+	untouched, err := repo.GetByHash(ctx, "other")
+	require.NoError(t, err)
+	require.True(t, untouched.Usable(now))
+}
+
+func TestEmailVerificationRepository_ListCreatedSinceNewestFirst(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewEmailVerificationRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	for i, age := range []time.Duration{25 * time.Hour, 2 * time.Hour, 10 * time.Minute} {
+		require.NoError(t, repo.Create(ctx, &entity.EmailVerificationToken{
+			ID: uuid.New(), UserID: userID, Email: "user@example.com", TokenHash: "mine-" + strconv.Itoa(i),
+			ExpiresAt: now.Add(time.Hour), CreatedAt: now.Add(-age),
+		}))
+	}
+	require.NoError(t, repo.Create(ctx, &entity.EmailVerificationToken{
+		ID: uuid.New(), UserID: uuid.New(), Email: "other@example.com", TokenHash: "other",
+		ExpiresAt: now.Add(time.Hour), CreatedAt: now,
+	}))
+
+	tokens, err := repo.ListCreatedSince(ctx, userID, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	require.Equal(t, "mine-2", tokens[0].TokenHash)
+	require.Equal(t, "mine-1", tokens[1].TokenHash)
+}
+
+func TestRecoveryCodeRepository_ReplaceAndUseOnce(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRecoveryCodeRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.Replace(ctx, userID, []entity.RecoveryCode{
+		{ID: uuid.New(), UserID: userID, CodeHash: "old"},
+	}))
+	require.NoError(t, repo.Replace(ctx, userID, []entity.RecoveryCode{
+		{ID: uuid.New(), UserID: userID, CodeHash: "new-1"},
+		{ID: uuid.New(), UserID: userID, CodeHash: "new-2"},
+	}))
+
+	used, err := repo.Use(ctx, userID, "old", now)
+	require.NoError(t, err)
+	require.False(t, used)
+	used, err = repo.Use(ctx, uuid.New(), "new-1", now)
+	require.NoError(t, err)
+	require.False(t, used)
+	used, err = repo.Use(ctx, userID, "new-1", now)
+	require.NoError(t, err)
+	require.True(t, used)
+	used, err = repo.Use(ctx, userID, "new-1", now)
+	require.NoError(t, err)
+	require.False(t, used)
+
+	remaining, err := repo.CountUnused(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), remaining)
+}
+
+func TestLoginChallengeRepository_RecordFailureCountsAttempts(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewLoginChallengeRepository(db)
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	challenge := &entity.LoginChallenge{ID: uuid.New(), UserID: uuid.New(), TokenHash: "challenge", ExpiresAt: now.Add(time.Minute)}
+	require.NoError(t, repo.Create(ctx, challenge))
+
+	require.NoError(t, repo.RecordFailure(ctx, challenge.ID))
+	require.NoError(t, repo.RecordFailure(ctx, challenge.ID))
+	loaded, err := repo.GetByHash(ctx, "challenge")
+	require.NoError(t, err)
+	require.Equal(t, 2, loaded.Attempts)
+	require.True(t, loaded.Usable(now, 3))
+	require.False(t, loaded.Usable(now, 2))
+
+	marked, err := repo.MarkUsed(ctx, challenge.ID, now)
+	require.NoError(t, err)
+	require.True(t, marked)
+	marked, err = repo.MarkUsed(ctx, challenge.ID, now)
+	require.NoError(t, err)
+	require.False(t, marked)
+}
+
+func TestExternalIdentityRepository_SubjectIsUniquePerIssuer(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewExternalIdentityRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+	require.NoError(t, NewUserRepository(db).Create(ctx, &entity.User{ID: userID, Email: "alice@example.com", PasswordHash: "secret"}))
+	identity := &entity.ExternalIdentity{ID: uuid.New(), UserID: userID, Issuer: "https://idp.example", Subject: "sub-1"}
+	require.NoError(t, repo.Create(ctx, identity))
+	require.NoError(t, repo.Create(ctx, &entity.ExternalIdentity{ID: uuid.New(), UserID: userID, Issuer: "https://other.example", Subject: "sub-1"}))
+	require.Error(t, repo.Create(ctx, &entity.ExternalIdentity{ID: uuid.New(), UserID: uuid.New(), Issuer: "https://idp.example", Subject: "sub-1"}))
+
+	at := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.TouchLastLogin(ctx, identity.ID, at))
+	loaded, err := repo.GetBySubject(ctx, "https://idp.example", "sub-1")
+	require.NoError(t, err)
+	require.Equal(t, identity.ID, loaded.ID)
+	require.True(t, at.Equal(loaded.LastLoginAt))
+
+	// ユーザーを削除すると紐付けも消える。
+	require.NoError(t, NewUserRepository(db).Delete(ctx, userID))
+	_, err = repo.GetBySubject(ctx, "https://idp.example", "sub-1")
+	require.Error(t, err)
+}
+
+func TestOIDCLoginRequestRepository_MarkUsedOnce(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewOIDCLoginRequestRepository(db)
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	request := &entity.OIDCLoginRequest{ID: uuid.New(), StateHash: "state", Nonce: "nonce", CodeVerifier: "verifier", RedirectPath: "/", ExpiresAt: now.Add(time.Minute)}
+	require.NoError(t, repo.Create(ctx, request))
+
+	loaded, err := repo.GetByStateHash(ctx, "state")
+	require.NoError(t, err)
+	require.True(t, loaded.Usable(now))
+
+	marked, err := repo.MarkUsed(ctx, request.ID, now)
+	require.NoError(t, err)
+	require.True(t, marked)
+	marked, err = repo.MarkUsed(ctx, request.ID, now)
+	require.NoError(t, err)
+	require.False(t, marked)
+	loaded, err = repo.GetByStateHash(ctx, "state")
+	require.NoError(t, err)
+	require.False(t, loaded.Usable(now))
+}
+
+func TestUserRepository_DeleteRemovesOwnedDataOnly(t *testing.T) {
+	db := newTestDB(t)
+	users := NewUserRepository(db)
+	ctx := context.Background()
+
+	seed := func(email string) uuid.UUID {
+		user := &entity.User{ID: uuid.New(), Email: email, PasswordHash: "secret"}
+		require.NoError(t, users.Create(ctx, user))
+		project := &entity.Project{ID: uuid.New(), UserID: user.ID, Name: "Work", Color: "#111111"}
+		require.NoError(t, NewProjectRepository(db).Create(ctx, project))
+		tag := &entity.Tag{ID: uuid.New(), UserID: user.ID, Name: "Focus", Color: "#111111"}
+		require.NoError(t, NewTagRepository(db).Create(ctx, tag))
+		entry := &entity.Entry{ID: uuid.New(), UserID: user.ID, ProjectID: &project.ID, Title: "Task", StartedAt: time.Now().Add(-time.Hour), Ratio: 1}
+		require.NoError(t, NewEntryRepository(db).Create(ctx, entry))
+		require.NoError(t, NewEntryRepository(db).ReplaceTags(ctx, entry, []uuid.UUID{tag.ID}))
+		favorite := &entity.Favorite{ID: uuid.New(), UserID: user.ID, Title: "Deep work", Ratio: 1}
+		require.NoError(t, NewFavoriteRepository(db).Create(ctx, favorite))
+		require.NoError(t, NewFavoriteRepository(db).ReplaceTags(ctx, favorite, []uuid.UUID{tag.ID}))
+		request := &entity.AllocationRequest{ID: uuid.New(), UserID: user.ID, TotalMinutes: 60}
+		require.NoError(t, NewAllocationRepository(db).Create(ctx, request, []entity.TaskAllocation{
+			{RequestID: request.ID, TaskID: "task-a", Ratio: 1, AllocatedMinutes: 60},
+		}))
+		require.NoError(t, NewRefreshTokenRepository(db).Create(ctx, &entity.RefreshToken{
+			ID: uuid.New(), UserID: user.ID, FamilyID: uuid.New(), TokenHash: uuid.NewString(), ExpiresAt: time.Now().Add(time.Hour),
+		}))
+		return user.ID
+	}
+	deleted := seed("gone@example.com")
+	kept := seed("kept@example.com")
+
+	require.NoError(t, users.Delete(ctx, deleted))
+
+	_, err := users.GetByID(ctx, deleted)
+	require.Error(t, err)
+	for _, model := range []any{&entity.Project{}, &entity.Tag{}, &entity.Entry{}, &entity.Favorite{}, &entity.AllocationRequest{}, &entity.RefreshToken{}} {
+		var count int64
+		require.NoError(t, db.Model(model).Where("user_id = ?", deleted).Count(&count).Error)
+		require.Zero(t, count, "%T", model)
+		require.NoError(t, db.Model(model).Where("user_id = ?", kept).Count(&count).Error)
+		require.Equal(t, int64(1), count, "%T", model)
+	}
+	var links int64
+	require.NoError(t, db.Model(&entity.EntryTag{}).Count(&links).Error)
+	require.Equal(t, int64(1), links)
+	require.NoError(t, db.Model(&entity.FavoriteTag{}).Count(&links).Error)
+	require.Equal(t, int64(1), links)
+	require.NoError(t, db.Model(&entity.TaskAllocation{}).Count(&links).Error)
+	require.Equal(t, int64(1), links)
+}
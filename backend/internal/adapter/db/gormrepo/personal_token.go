@@ -0,0 +1,52 @@
+package gormrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"chronome/internal/domain/entity"
+)
+
+// PersonalTokenRepository は GORM で repository.PersonalTokenRepository を実装する。
+type PersonalTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonalTokenRepository(db *gorm.DB) *PersonalTokenRepository {
+	return &PersonalTokenRepository{db: db}
+}
+
+func (r *PersonalTokenRepository) Create(ctx context.Context, token *entity.PersonalAccessToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *PersonalTokenRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.PersonalAccessToken, error) {
+	var tokens []entity.PersonalAccessToken
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at asc").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *PersonalTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.PersonalAccessToken, error) {
+	var token entity.PersonalAccessToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *PersonalTokenRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).Delete(&entity.PersonalAccessToken{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *PersonalTokenRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.PersonalAccessToken{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,71 @@
+package gormrepo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+)
+
+// PomodoroRepository は GORM で repository.PomodoroRepository を実装する。
+type PomodoroRepository struct {
+	db *gorm.DB
+}
+
+func NewPomodoroRepository(db *gorm.DB) *PomodoroRepository {
+	return &PomodoroRepository{db: db}
+}
+
+func (r *PomodoroRepository) Create(ctx context.Context, session *entity.PomodoroSession, entry *entity.Entry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Tags").Create(entry).Error
+	})
+}
+
+func (r *PomodoroRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.PomodoroSession, error) {
+	var session entity.PomodoroSession
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *PomodoroRepository) GetActive(ctx context.Context, userID uuid.UUID) (*entity.PomodoroSession, error) {
+	var session entity.PomodoroSession
+	err := r.db.WithContext(ctx).Where("user_id = ? AND ended_at IS NULL", userID).Order("started_at desc").First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *PomodoroRepository) ListActive(ctx context.Context) ([]entity.PomodoroSession, error) {
+	var sessions []entity.PomodoroSession
+	if err := r.db.WithContext(ctx).Where("ended_at IS NULL").Order("started_at asc").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *PomodoroRepository) Advance(ctx context.Context, session *entity.PomodoroSession, fromIndex int, changes repository.EntryChanges) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 読み込んだ時点のフェーズのまま進行中の場合だけ更新し、同時に進めたリクエストの片方だけを成功させる。
+		result := tx.Model(session).
+			Where("user_id = ? AND phase_index = ? AND ended_at IS NULL", session.UserID, fromIndex).
+			Select("*").Omit("CreatedAt").
+			Updates(session)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repository.ErrConflict
+		}
+		return applyEntryChanges(tx, session.UserID, changes)
+	})
+}
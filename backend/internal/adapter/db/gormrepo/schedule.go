@@ -0,0 +1,87 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+)
+
+// ScheduleRepository は GORM で repository.ScheduleRepository を実装する。
+type ScheduleRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduleRepository(db *gorm.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+func (r *ScheduleRepository) GetSchedule(ctx context.Context, userID uuid.UUID) (*entity.WorkSchedule, error) {
+	var schedule entity.WorkSchedule
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *ScheduleRepository) SaveSchedule(ctx context.Context, schedule *entity.WorkSchedule) error {
+	return r.db.WithContext(ctx).Save(schedule).Error
+}
+
+func (r *ScheduleRepository) ListHolidays(ctx context.Context, userID uuid.UUID, from, to string) ([]entity.Holiday, error) {
+	var holidays []entity.Holiday
+	if err := dateRange(r.db.WithContext(ctx).Where("user_id = ?", userID), from, to).Order("date asc").Find(&holidays).Error; err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
+
+func (r *ScheduleRepository) UpsertHolidays(ctx context.Context, holidays []entity.Holiday) error {
+	if len(holidays) == 0 {
+		return nil
+	}
+	// 同じ日付の祝日は再取り込み時に名前と UID を更新する。
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "uid", "updated_at"}),
+	}).Create(&holidays).Error
+}
+
+func (r *ScheduleRepository) DeleteHoliday(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).Delete(&entity.Holiday{}).Error
+}
+
+func (r *ScheduleRepository) ListTimeOff(ctx context.Context, userID uuid.UUID, from, to string) ([]entity.TimeOff, error) {
+	var records []entity.TimeOff
+	if err := dateRange(r.db.WithContext(ctx).Where("user_id = ?", userID), from, to).Order("date asc").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *ScheduleRepository) CreateTimeOff(ctx context.Context, timeOff *entity.TimeOff) error {
+	return r.db.WithContext(ctx).Create(timeOff).Error
+}
+
+func (r *ScheduleRepository) DeleteTimeOff(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).Delete(&entity.TimeOff{}).Error
+}
+
+// dateRange は YYYY-MM-DD 文字列の date 列を両端含みで絞り込む。文字列比較でも日付順になる。
+func dateRange(query *gorm.DB, from, to string) *gorm.DB {
+	if from != "" {
+		query = query.Where("date >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("date <= ?", to)
+	}
+	return query
+}
@@ -3,6 +3,7 @@ package gormrepo
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -33,6 +34,15 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entity.
 	return &user, nil
 }
 
+func (r *UserRepository) Update(ctx context.Context, user *entity.User) error {
+	user.Normalize()
+	return r.db.WithContext(ctx).Save(user).Error
+}
+
+func (r *UserRepository) RevokeAccessTokens(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ?", id).Update("tokens_not_before", at).Error
+}
+
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
 	var user entity.User
 	if err := r.db.WithContext(ctx).First(&user, "id = ?", id).Error; err != nil {
@@ -40,3 +50,54 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Use
 	}
 	return &user, nil
 }
+
+func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 関連テーブルは親の ID で引くため、親より先に消す。
+		children := []struct {
+			model  any
+			column string
+			parent any
+		}{
+			{&entity.EntryTag{}, "entry_id", &entity.Entry{}},
+			{&entity.EntryTag{}, "tag_id", &entity.Tag{}},
+			{&entity.FavoriteTag{}, "favorite_id", &entity.Favorite{}},
+			{&entity.FavoriteTag{}, "tag_id", &entity.Tag{}},
+			{&entity.TaskAllocation{}, "request_id", &entity.AllocationRequest{}},
+			{&entity.AllocationTemplateTask{}, "template_id", &entity.AllocationTemplate{}},
+		}
+		for _, child := range children {
+			owned := tx.Model(child.parent).Select("id").Where("user_id = ?", id)
+			if err := tx.Where(child.column+" IN (?)", owned).Delete(child.model).Error; err != nil {
+				return err
+			}
+		}
+		owners := []any{
+			&entity.Entry{},
+			&entity.Favorite{},
+			&entity.Tag{},
+			&entity.Project{},
+			&entity.AllocationRequest{},
+			&entity.AllocationBatch{},
+			&entity.AllocationTemplate{},
+			&entity.PomodoroSession{},
+			&entity.Goal{},
+			&entity.WorkSchedule{},
+			&entity.Holiday{},
+			&entity.TimeOff{},
+			&entity.RefreshToken{},
+			&entity.PersonalAccessToken{},
+			&entity.PasswordResetToken{},
+			&entity.EmailVerificationToken{},
+			&entity.RecoveryCode{},
+			&entity.LoginChallenge{},
+			&entity.ExternalIdentity{},
+		}
+		for _, model := range owners {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&entity.User{}, "id = ?", id).Error
+	})
+}
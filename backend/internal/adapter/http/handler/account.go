@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/usecase"
+	"chronome/internal/usecase/dto"
+)
+
+func (h *APIHandler) updateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.ProfileUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	user, err := h.accounts.UpdateProfile(r.Context(), userID, payload)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"user": mapUser(user)})
+}
+
+// changePassword はパスワードを変更し、他の端末の session とリフレッシュトークンをすべて失効させる。
+// cookie で呼ばれた場合は呼び出し元の session も作り直し、変更後もログイン状態を保つ。
+func (h *APIHandler) changePassword(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.PasswordChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if err := h.accounts.ChangePassword(r.Context(), userID, payload); err != nil {
+		respondAccountError(w, err)
+		return
+	}
+	if err := h.sessions.RevokeUser(r.Context(), userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "session error")
+		return
+	}
+	if err := h.tokens.RevokeAll(r.Context(), userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "token error")
+		return
+	}
+	if !middleware.AuthenticatedByBearer(r.Context()) {
+		if err := h.startSession(w, r, userID); err != nil {
+			respondError(w, http.StatusInternalServerError, "session error")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) requestEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.EmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if err := h.accounts.RequestEmailChange(r.Context(), userID, payload); err != nil {
+		respondAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *APIHandler) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.AccountDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if err := h.accounts.Delete(r.Context(), userID, payload); err != nil {
+		respondAccountError(w, err)
+		return
+	}
+	if err := h.sessions.RevokeUser(r.Context(), userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "session error")
+		return
+	}
+	if err := h.endSession(w, r); err != nil {
+		respondError(w, http.StatusInternalServerError, "session error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func respondAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrIncorrectPassword):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, usecase.ErrEmailTaken),
+		errors.Is(err, usecase.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, usecase.ErrTwoFactorNotSetUp),
+		errors.Is(err, usecase.ErrTwoFactorNotEnabled):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, usecase.ErrInvalidEmailVerificationToken), errors.Is(err, usecase.ErrInvalidTwoFactorCode):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		var valErr dto.ValidationError
+		if errors.As(err, &valErr) {
+			respondError(w, http.StatusBadRequest, valErr.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "account error")
+	}
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/usecase"
+	"chronome/internal/usecase/dto"
+)
+
+func (h *APIHandler) listAllocations(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	page, ok := positiveQueryInt(r, "page")
+	if !ok {
+		respondError(w, http.StatusBadRequest, "invalid page")
+		return
+	}
+	perPage, ok := positiveQueryInt(r, "per_page")
+	if !ok {
+		respondError(w, http.StatusBadRequest, "invalid per_page")
+		return
+	}
+	result, err := h.allocs.List(r.Context(), userID, page, perPage)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// previewAllocation は保存せずに分配結果を返す。制約を満たせない場合も 200 で診断を返す。
+func (h *APIHandler) previewAllocation(w http.ResponseWriter, r *http.Request) {
+	var payload dto.AllocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	result, err := h.allocs.Preview(payload)
+	if err != nil {
+		respondAllocationError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+func (h *APIHandler) allocateFromTracked(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.AllocationFromTrackedRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	user, err := h.auth.GetProfile(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	// date と時間帯はユーザーのタイムゾーンで解釈する。
+	loc, err := h.resolveLocation(r, user)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid time_zone")
+		return
+	}
+	result, err := h.allocs.AllocateFromTracked(r.Context(), userID, payload, loc)
+	if err != nil {
+		respondAllocationError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, result)
+}
+
+func (h *APIHandler) createAllocationBatch(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.AllocationBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	result, err := h.allocs.AllocateBatch(r.Context(), userID, payload)
+	if err != nil {
+		respondAllocationError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, result)
+}
+
+func (h *APIHandler) getAllocationBatch(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	bid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	result, err := h.allocs.GetBatch(r.Context(), userID, bid)
+	if err != nil {
+		if errors.Is(err, usecase.ErrAllocationBatchNotFound) {
+			respondError(w, http.StatusNotFound, "allocation batch not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+func (h *APIHandler) getAllocation(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	aid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	// 他ユーザーの分配結果も存在しないものとして扱い、ID の存在を漏らさない。
+	result, err := h.allocs.Get(r.Context(), userID, aid)
+	if err != nil {
+		if errors.Is(err, usecase.ErrAllocationNotFound) {
+			respondError(w, http.StatusNotFound, "allocation not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+func (h *APIHandler) deleteAllocation(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	aid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.allocs.Delete(r.Context(), userID, aid); err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) applyAllocation(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	aid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var payload dto.AllocationApplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	entries, err := h.allocs.Apply(r.Context(), userID, aid, payload)
+	if err != nil {
+		if errors.Is(err, usecase.ErrAllocationNotFound) {
+			respondError(w, http.StatusNotFound, "allocation not found")
+			return
+		}
+		respondAllocationError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"request_id": aid,
+		"entries":    entries,
+	})
+}
+
+func (h *APIHandler) splitEntry(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	eid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var payload dto.EntrySplitRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	entries, err := h.allocs.SplitEntry(r.Context(), userID, eid, payload)
+	if err != nil {
+		if errors.Is(err, usecase.ErrEntryNotFound) {
+			respondError(w, http.StatusNotFound, "entry not found")
+			return
+		}
+		respondAllocationError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]any{"entries": entries})
+}
+
+// positiveQueryInt は任意の正の整数クエリを読む。未指定は 0 を返す。
+func positiveQueryInt(r *http.Request, key string) (int, bool) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return 0, true
+	}
+	parsed, err := strconv.Atoi(v)
+	if err != nil || parsed <= 0 {
+		return 0, false
+	}
+	return parsed, true
+}
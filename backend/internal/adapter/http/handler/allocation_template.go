@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/usecase"
+	"chronome/internal/usecase/dto"
+)
+
+func (h *APIHandler) listAllocationTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	templates, err := h.templates.List(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"templates": templates})
+}
+
+func (h *APIHandler) getAllocationTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	tid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	template, err := h.templates.Get(r.Context(), userID, tid)
+	if err != nil {
+		respondTemplateError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, template)
+}
+
+func (h *APIHandler) createAllocationTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.AllocationTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	template, err := h.templates.Create(r.Context(), userID, payload)
+	if err != nil {
+		respondTemplateError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, template)
+}
+
+func (h *APIHandler) updateAllocationTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	tid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var payload dto.AllocationTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	template, err := h.templates.Update(r.Context(), userID, tid, payload)
+	if err != nil {
+		respondTemplateError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, template)
+}
+
+func (h *APIHandler) deleteAllocationTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	tid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.templates.Delete(r.Context(), userID, tid); err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) runAllocationTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	tid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var payload dto.AllocationTemplateRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	user, err := h.auth.GetProfile(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	// date の日付境界はレポートと同じくユーザーのタイムゾーンで決める。
+	loc, err := h.resolveLocation(r, user)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid time_zone")
+		return
+	}
+	result, err := h.templates.Run(r.Context(), userID, tid, payload, loc)
+	if err != nil {
+		respondTemplateError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, result)
+}
+
+// respondTemplateError は存在しないテンプレートを 404、参照切れや制約違反を 422 として返す。
+func respondTemplateError(w http.ResponseWriter, err error) {
+	if errors.Is(err, usecase.ErrAllocationTemplateNotFound) {
+		respondError(w, http.StatusNotFound, "allocation template not found")
+		return
+	}
+	respondAllocationError(w, err)
+}
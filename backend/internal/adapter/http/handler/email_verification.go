@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+// verifyEmail はメールのリンクから呼ばれるため認証を要求しない。トークン自体が本人確認になる。
+func (h *APIHandler) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	var payload dto.EmailVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	user, err := h.verifier.Confirm(r.Context(), payload)
+	if err != nil {
+		respondAccountError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"user": mapUser(user)})
+}
+
+// resendVerification は EMAIL_VERIFICATION=required でログインできないユーザーも使えるよう、認証なしでメールアドレスを受け取る。
+// 未登録・確認済みのアドレスや、ユーザーごとの送信の上限に達した場合でも同じく 202 を返す。429 は IP ごとの Throttle からだけ返る。
+func (h *APIHandler) resendVerification(w http.ResponseWriter, r *http.Request) {
+	var payload dto.EmailVerifyResendRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if err := h.verifier.Resend(r.Context(), payload); err != nil {
+		respondAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// requireVerifiedEmail は limited モードでだけ未確認ユーザーの状態変更を拒否するミドルウェアを返す。
+func (h *APIHandler) requireVerifiedEmail() func(http.Handler) http.Handler {
+	if h.cfg.EmailVerificationMode() != provider.EmailVerificationLimited {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return middleware.RequireVerifiedEmail(func(ctx context.Context, userID uuid.UUID) bool {
+		user, err := h.auth.GetProfile(ctx, userID)
+		return err == nil && user.EmailVerified()
+	})
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/usecase/dto"
+)
+
+func (h *APIHandler) listFavorites(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	favorites, err := h.favs.List(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"favorites": favorites})
+}
+
+func (h *APIHandler) createFavorite(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.FavoriteCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	favorite, err := h.favs.Create(r.Context(), userID, payload)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, favorite)
+}
+
+func (h *APIHandler) updateFavorite(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	fid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var payload dto.FavoriteUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	favorite, err := h.favs.Update(r.Context(), userID, fid, payload)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, favorite)
+}
+
+func (h *APIHandler) deleteFavorite(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	fid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.favs.Delete(r.Context(), userID, fid); err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) startFavorite(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	fid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	entry, err := h.favs.Start(r.Context(), userID, fid)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, entry)
+}
+
+func (h *APIHandler) recentCombinations(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	// limit は走査する直近エントリ件数で、未指定や上限超過は usecase 側で丸める。
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			respondError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = parsed
+	}
+	combinations, err := h.favs.Recent(r.Context(), userID, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"combinations": combinations})
+}
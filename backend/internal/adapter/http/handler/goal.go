@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/usecase/dto"
+)
+
+func (h *APIHandler) listGoals(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	goals, err := h.goals.List(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"goals": goals})
+}
+
+func (h *APIHandler) createGoal(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.GoalCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	goal, err := h.goals.Create(r.Context(), userID, payload)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, goal)
+}
+
+func (h *APIHandler) updateGoal(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	gid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var payload dto.GoalUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	goal, err := h.goals.Update(r.Context(), userID, gid, payload)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, goal)
+}
+
+func (h *APIHandler) deleteGoal(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	gid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.goals.Delete(r.Context(), userID, gid); err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) goalProgress(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	gid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	user, err := h.auth.GetProfile(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	// 期間の境界と稼働日の判定はレポートと同じくユーザーのタイムゾーンで行う。
+	loc, err := h.resolveLocation(r, user)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid time_zone")
+		return
+	}
+	at := time.Now().In(loc)
+	if v := r.URL.Query().Get("date"); v != "" {
+		parsed, parseErr := time.ParseInLocation("2006-01-02", v, loc)
+		if parseErr != nil {
+			respondError(w, http.StatusBadRequest, "invalid date")
+			return
+		}
+		at = parsed
+	}
+	progress, err := h.goals.Progress(r.Context(), userID, gid, at)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "goal not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, progress)
+}
@@ -15,6 +15,7 @@ import (
 
 	"chronome/internal/adapter/http/middleware"
 	"chronome/internal/adapter/infra/config"
+	"chronome/internal/adapter/infra/ratelimit"
 	sess "chronome/internal/adapter/infra/session"
 	"chronome/internal/domain/entity"
 	"chronome/internal/domain/repository"
@@ -29,27 +30,86 @@ const (
 
 // APIHandler は HTTP エンドポイントをユースケースに接続する。
 type APIHandler struct {
-	auth     *usecase.AuthUsecase
-	projects *usecase.ProjectUsecase
-	tags     *usecase.TagUsecase
-	entries  *usecase.EntryUsecase
-	reports  *usecase.ReportUsecase
-	allocs   *usecase.AllocationUsecase
-	sessions sess.Store
-	cfg      config.Config
+	auth      *usecase.AuthUsecase
+	tokens    *usecase.TokenUsecase
+	personal  *usecase.PersonalTokenUsecase
+	resets    *usecase.PasswordResetUsecase
+	accounts  *usecase.AccountUsecase
+	verifier  *usecase.EmailVerificationUsecase
+	twoFactor *usecase.TwoFactorUsecase
+	// oidc は OIDC_ISSUER を設定していなければ nil で、OpenID Connect のルートを登録しない。
+	oidc      *usecase.OIDCUsecase
+	projects  *usecase.ProjectUsecase
+	tags      *usecase.TagUsecase
+	entries   *usecase.EntryUsecase
+	reports   *usecase.ReportUsecase
+	allocs    *usecase.AllocationUsecase
+	templates *usecase.AllocationTemplateUsecase
+	favs      *usecase.FavoriteUsecase
+	idle      *usecase.IdleUsecase
+	pomodoros *usecase.PomodoroUsecase
+	goals     *usecase.GoalUsecase
+	schedules *usecase.ScheduleUsecase
+	sessions  sess.Store
+	signer    sess.TokenSigner
+	limiter   *ratelimit.Limiter
+	cfg       config.Config
+}
+
+// APIHandlerDeps は APIHandler が使う設定・session・usecase をまとめる。
+// OIDC は OIDC_ISSUER を設定していなければ nil のままにする。
+type APIHandlerDeps struct {
+	Config    config.Config
+	Sessions  sess.Store
+	Signer    sess.TokenSigner
+	Limits    ratelimit.Store
+	Auth      *usecase.AuthUsecase
+	Tokens    *usecase.TokenUsecase
+	Personal  *usecase.PersonalTokenUsecase
+	Resets    *usecase.PasswordResetUsecase
+	Accounts  *usecase.AccountUsecase
+	Verifier  *usecase.EmailVerificationUsecase
+	TwoFactor *usecase.TwoFactorUsecase
+	OIDC      *usecase.OIDCUsecase
+	Projects  *usecase.ProjectUsecase
+	Tags      *usecase.TagUsecase
+	Entries   *usecase.EntryUsecase
+	Reports   *usecase.ReportUsecase
+	Allocs    *usecase.AllocationUsecase
+	Templates *usecase.AllocationTemplateUsecase
+	Favorites *usecase.FavoriteUsecase
+	Idle      *usecase.IdleUsecase
+	Pomodoros *usecase.PomodoroUsecase
+	Goals     *usecase.GoalUsecase
+	Schedules *usecase.ScheduleUsecase
 }
 
 // NewAPIHandler は usecase と session store を束ねた APIHandler を生成する。
-func NewAPIHandler(cfg config.Config, sessions sess.Store, auth *usecase.AuthUsecase, projects *usecase.ProjectUsecase, tags *usecase.TagUsecase, entries *usecase.EntryUsecase, reports *usecase.ReportUsecase, allocs *usecase.AllocationUsecase) *APIHandler {
+func NewAPIHandler(deps APIHandlerDeps) *APIHandler {
 	return &APIHandler{
-		auth:     auth,
-		projects: projects,
-		tags:     tags,
-		entries:  entries,
-		reports:  reports,
-		allocs:   allocs,
-		sessions: sessions,
-		cfg:      cfg,
+		auth:      deps.Auth,
+		tokens:    deps.Tokens,
+		personal:  deps.Personal,
+		resets:    deps.Resets,
+		accounts:  deps.Accounts,
+		verifier:  deps.Verifier,
+		twoFactor: deps.TwoFactor,
+		oidc:      deps.OIDC,
+		projects:  deps.Projects,
+		tags:      deps.Tags,
+		entries:   deps.Entries,
+		reports:   deps.Reports,
+		allocs:    deps.Allocs,
+		templates: deps.Templates,
+		favs:      deps.Favorites,
+		idle:      deps.Idle,
+		pomodoros: deps.Pomodoros,
+		goals:     deps.Goals,
+		schedules: deps.Schedules,
+		sessions:  deps.Sessions,
+		signer:    deps.Signer,
+		limiter:   ratelimit.NewLimiter(deps.Limits),
+		cfg:       deps.Config,
 	}
 }
 
@@ -59,50 +119,157 @@ func (h *APIHandler) Router() *chi.Mux {
 	// フロントエンドから cookie を送るため、CORS は credentials 前提で許可する。
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{h.cfg.AllowedOrigin},
-		AllowedMethods:   []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", middleware.CSRFHeaderName},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
-	r.Use(middleware.WithSession(h.sessions))
+	r.Use(middleware.WithSession(h.sessions, h.signer, h.tokens, h.personal))
+	r.Use(h.renewSession)
 
 	r.Get("/healthz", h.healthz)
 
+	// EMAIL_VERIFICATION=limited の場合だけ、未確認ユーザーのデータ変更を止める。
+	verified := h.requireVerifiedEmail()
+	// ログイン系は失敗による締め出しを共有し、bcrypt を総当たりに使わせない。
+	loginThrottle := middleware.Throttle(h.limiter, h.throttleOptions("login", h.cfg.LoginRateLimit, h.cfg.LoginRateWindow, true))
+	signupThrottle := middleware.Throttle(h.limiter, h.throttleOptions("signup", h.cfg.SignupRateLimit, h.cfg.SignupRateWindow, false))
+	// 再設定メールの依頼は送信先ごとにも数え、同じアドレスへのメールの連打を防ぐ。
+	forgotOptions := h.throttleOptions("password-forgot", h.cfg.PasswordResetRateLimit, h.cfg.PasswordResetRateWindow, false)
+	forgotOptions.PerAccount = ratelimit.Policy{Requests: h.cfg.PasswordResetEmailRateLimit, Window: h.cfg.PasswordResetRateWindow}
+	forgotThrottle := middleware.Throttle(h.limiter, forgotOptions)
+	// 確認メールの再送はユーザーごとの上限に加えて IP ごとにも数える。
+	resendThrottle := middleware.Throttle(h.limiter, h.throttleOptions("verify-resend", h.cfg.EmailVerificationRateLimit, h.cfg.EmailVerificationRateWindow, false))
+
 	r.Route("/api", func(api chi.Router) {
-		// 認証系は signup/login だけ未認証で、プロフィール取得と logout は session を必須にする。
+		// 認証系は signup/login/token とパスワード再設定、メールアドレス確認、二段階ログインの 2 段目、OIDC ログインだけ未認証で、それ以外は session を必須にする。
 		api.Route("/auth", func(auth chi.Router) {
-			auth.Post("/signup", h.signup)
-			auth.Post("/login", h.login)
+			auth.With(signupThrottle).Post("/signup", h.signup)
+			auth.With(loginThrottle).Post("/login", h.login)
+			auth.With(loginThrottle).Post("/login/2fa", h.loginTwoFactor)
+			auth.With(loginThrottle).Post("/token", h.issueToken)
+			if h.oidc != nil {
+				auth.With(loginThrottle).Get("/oidc/login", h.oidcLogin)
+				auth.With(loginThrottle).Get("/oidc/callback", h.oidcCallback)
+			}
+			auth.Post("/token/revoke", h.revokeToken)
+			auth.With(forgotThrottle).Post("/password/forgot", h.forgotPassword)
+			auth.Post("/password/reset", h.resetPassword)
+			auth.Post("/email/verify", h.verifyEmail)
+			auth.With(resendThrottle).Post("/email/verify/resend", h.resendVerification)
 			auth.With(middleware.RequireAuth).Get("/me", h.me)
+			auth.With(middleware.RequireAuth, middleware.RequireCSRF(h.cfg.AllowedOrigin)).Patch("/me", h.updateProfile)
+			auth.With(middleware.RequireAuth, middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/me", h.deleteAccount)
+			auth.With(middleware.RequireAuth, middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/password", h.changePassword)
+			auth.With(middleware.RequireAuth, middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/email", h.requestEmailChange)
 			auth.With(middleware.RequireAuth, middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/logout", h.logout)
+			auth.With(middleware.RequireAuth).Route("/sessions", func(sr chi.Router) {
+				sr.Get("/", h.listSessions)
+				sr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/{id}", h.revokeSession)
+			})
+			auth.With(middleware.RequireAuth).Route("/2fa", func(tf chi.Router) {
+				tf.Get("/", h.twoFactorStatus)
+				tf.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/setup", h.setupTwoFactor)
+				tf.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/enable", h.enableTwoFactor)
+				tf.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/", h.disableTwoFactor)
+				tf.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/recovery-codes", h.regenerateRecoveryCodes)
+			})
+			// パーソナルアクセストークンの管理は RequireAuth 配下なので、トークン自身では操作できない。
+			auth.With(middleware.RequireAuth, verified).Route("/tokens", func(tr chi.Router) {
+				tr.Get("/", h.listPersonalTokens)
+				tr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/", h.createPersonalToken)
+				tr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/{id}", h.deletePersonalToken)
+			})
 		})
 
 		// 参照系は CSRF 不要、状態変更系は CSRF を必須にする。
-		api.With(middleware.RequireAuth).Route("/projects", func(pr chi.Router) {
+		// RequireScopedAuth のグループだけがパーソナルアクセストークンを受け付け、ほかは RequireAuth で拒否する。
+		api.With(middleware.RequireScopedAuth(entity.ScopeProjectsRead, entity.ScopeProjectsWrite), verified).Route("/projects", func(pr chi.Router) {
 			pr.Get("/", h.listProjects)
 			pr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/", h.createProject)
 			pr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Patch("/{id}", h.updateProject)
 			pr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/{id}", h.deleteProject)
 		})
-		api.With(middleware.RequireAuth).Route("/tags", func(tr chi.Router) {
+		api.With(middleware.RequireScopedAuth(entity.ScopeProjectsRead, entity.ScopeProjectsWrite), verified).Route("/tags", func(tr chi.Router) {
 			tr.Get("/", h.listTags)
 			tr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/", h.createTag)
 			tr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Patch("/{id}", h.updateTag)
 			tr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/{id}", h.deleteTag)
 		})
 
-		api.With(middleware.RequireAuth).Route("/entries", func(er chi.Router) {
+		api.With(middleware.RequireScopedAuth(entity.ScopeEntriesRead, entity.ScopeEntriesWrite), verified).Route("/entries", func(er chi.Router) {
 			er.Get("/", h.listEntries)
+			er.Get("/running", h.runningEntries)
 			er.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/", h.createEntry)
 			er.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Patch("/{id}", h.updateEntry)
 			er.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/{id}", h.deleteEntry)
+			er.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/{id}/heartbeat", h.heartbeatEntry)
+			er.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/{id}/idle", h.resolveIdleEntry)
+			er.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/{id}/split", h.splitEntry)
+		})
+
+		api.With(middleware.RequireScopedAuth(entity.ScopeEntriesRead, entity.ScopeEntriesWrite), verified).Route("/favorites", func(fr chi.Router) {
+			fr.Get("/", h.listFavorites)
+			fr.Get("/recent", h.recentCombinations)
+			fr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/", h.createFavorite)
+			fr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Patch("/{id}", h.updateFavorite)
+			fr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/{id}", h.deleteFavorite)
+			fr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/{id}/start", h.startFavorite)
+		})
+
+		api.With(middleware.RequireScopedAuth(entity.ScopeEntriesRead, entity.ScopeEntriesWrite), verified).Route("/pomodoro", func(pr chi.Router) {
+			pr.Get("/current", h.currentPomodoro)
+			pr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/", h.startPomodoro)
+			pr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/{id}/stop", h.stopPomodoro)
+		})
+
+		api.With(middleware.RequireAuth, verified).Route("/goals", func(gr chi.Router) {
+			gr.Get("/", h.listGoals)
+			gr.Get("/{id}/progress", h.goalProgress)
+			gr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/", h.createGoal)
+			gr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Patch("/{id}", h.updateGoal)
+			gr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/{id}", h.deleteGoal)
+		})
+
+		api.With(middleware.RequireAuth, verified).Route("/schedule", func(sr chi.Router) {
+			sr.Get("/", h.getSchedule)
+			sr.Get("/holidays", h.listHolidays)
+			sr.Get("/time-off", h.listTimeOff)
+			sr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Put("/", h.updateSchedule)
+			sr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/holidays/import", h.importHolidays)
+			sr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/holidays/{id}", h.deleteHoliday)
+			sr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/time-off", h.createTimeOff)
+			sr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/time-off/{id}", h.deleteTimeOff)
 		})
 
-		api.With(middleware.RequireAuth).Route("/allocations", func(ar chi.Router) {
+		// 実績からの分配と適用はエントリも作成・更新するので、entries:write も要求する。
+		writesEntries := middleware.RequireTokenScope(entity.ScopeEntriesWrite)
+		api.With(middleware.RequireScopedAuth(entity.ScopeAllocationsRead, entity.ScopeAllocationsWrite), verified).Route("/allocations", func(ar chi.Router) {
+			ar.Get("/", h.listAllocations)
+			ar.Get("/{id}", h.getAllocation)
+			ar.Get("/batches/{id}", h.getAllocationBatch)
 			ar.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/", h.createAllocation)
+			ar.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/preview", h.previewAllocation)
+			ar.With(middleware.RequireCSRF(h.cfg.AllowedOrigin), writesEntries).Post("/from-tracked", h.allocateFromTracked)
+			ar.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/batch", h.createAllocationBatch)
+			ar.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/{id}", h.deleteAllocation)
+			ar.With(middleware.RequireCSRF(h.cfg.AllowedOrigin), writesEntries).Post("/{id}/apply", h.applyAllocation)
+		})
+
+		api.With(middleware.RequireScopedAuth(entity.ScopeAllocationsRead, entity.ScopeAllocationsWrite), verified).Route("/allocation-templates", func(tr chi.Router) {
+			tr.Get("/", h.listAllocationTemplates)
+			tr.Get("/{id}", h.getAllocationTemplate)
+			tr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/", h.createAllocationTemplate)
+			tr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Put("/{id}", h.updateAllocationTemplate)
+			tr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Delete("/{id}", h.deleteAllocationTemplate)
+			tr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Post("/{id}/run", h.runAllocationTemplate)
+		})
+
+		api.With(middleware.RequireAuth, verified).Route("/settings", func(sr chi.Router) {
+			sr.With(middleware.RequireCSRF(h.cfg.AllowedOrigin)).Put("/rounding", h.updateRounding)
 		})
 
-		api.With(middleware.RequireAuth).Route("/reports", func(rr chi.Router) {
+		api.With(middleware.RequireScopedAuth(entity.ScopeReportsRead, ""), verified).Route("/reports", func(rr chi.Router) {
 			// レポート系は参照専用のため CSRF は不要にしている。
 			rr.Get("/daily", h.dailyReport)
 			rr.Get("/weekly", h.weeklyReport)
@@ -134,6 +301,8 @@ func (h *APIHandler) signup(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	// 確認メールは再送できるので、送信準備に失敗しても登録自体は成功として返す。
+	_ = h.verifier.Send(r.Context(), user)
 	respondJSON(w, http.StatusCreated, map[string]any{"user": mapUser(user)})
 }
 
@@ -148,16 +317,46 @@ func (h *APIHandler) login(w http.ResponseWriter, r *http.Request) {
 	}
 	// 認証成功後に session cookie と CSRF cookie を発行し、以降の状態変更 request を検証する。
 	user, err := h.auth.Login(r.Context(), payload.Email, payload.Password)
+	if errors.Is(err, usecase.ErrEmailNotVerified) {
+		respondError(w, http.StatusForbidden, err.Error())
+		return
+	}
 	if err != nil {
 		respondError(w, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
-	sessionID, err := h.sessions.Create(user.ID, h.cfg.SessionTTL())
-	if err != nil {
+	if user.TwoFactorEnabled() {
+		h.respondLoginChallenge(w, r, user)
+		return
+	}
+	if err := h.startSession(w, r, user.ID); err != nil {
 		respondError(w, http.StatusInternalServerError, "session error")
 		return
 	}
+	respondJSON(w, http.StatusOK, map[string]any{"user": mapUser(user)})
+}
+
+// startSession は session cookie と CSRF cookie を発行する。セッション一覧に出すため、端末情報も記録する。
+func (h *APIHandler) startSession(w http.ResponseWriter, r *http.Request, userID uuid.UUID) error {
+	client := sess.Client{
+		UserAgent: r.UserAgent(),
+		IPAddress: middleware.ClientIP(r, h.cfg.RateLimitIPHeader, h.cfg.RateLimitProxyHops),
+	}
+	sessionID, err := h.sessions.Create(r.Context(), userID, h.cfg.SessionTTL(), client)
+	if err != nil {
+		return err
+	}
 	expiresAt := time.Now().UTC().Add(h.cfg.SessionTTL())
+	h.setSessionCookie(w, sessionID, expiresAt)
+	csrfToken, err := generateCSRFToken()
+	if err != nil {
+		return err
+	}
+	h.setCSRFCookie(w, csrfToken, expiresAt)
+	return nil
+}
+
+func (h *APIHandler) setSessionCookie(w http.ResponseWriter, sessionID string, expiresAt time.Time) {
 	// session cookie は HttpOnly にして JavaScript から読ませず、CSRF token は別 cookie で扱う。
 	http.SetCookie(w, &http.Cookie{
 		Name:     middleware.SessionCookieName,
@@ -169,18 +368,43 @@ func (h *APIHandler) login(w http.ResponseWriter, r *http.Request) {
 		MaxAge:   int(h.cfg.SessionTTL().Seconds()),
 		Expires:  expiresAt,
 	})
-	csrfToken, err := generateCSRFToken()
-	if err != nil {
+}
+
+// renewSession は有効期間の半分を過ぎた session cookie を延長して発行し直す（スライディング期限）。
+// 使い続けている間はログアウトされず、SESSION_TTL の間使わなければ期限が切れる。
+// CSRF cookie も同じ値のまま期限をそろえる。延長に失敗しても今のリクエストは通す。
+func (h *APIHandler) renewSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := middleware.SessionIDFromContext(r.Context()); ok {
+			if cookie, err := r.Cookie(middleware.SessionCookieName); err == nil {
+				sessionID, expiresAt, err := h.sessions.Renew(r.Context(), cookie.Value, h.cfg.SessionTTL())
+				if err == nil && sessionID != "" {
+					h.setSessionCookie(w, sessionID, expiresAt)
+					if csrf, err := r.Cookie(middleware.CSRFCookieName); err == nil && csrf.Value != "" {
+						h.setCSRFCookie(w, csrf.Value, expiresAt)
+					}
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *APIHandler) logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.endSession(w, r); err != nil {
 		respondError(w, http.StatusInternalServerError, "session error")
 		return
 	}
-	h.setCSRFCookie(w, csrfToken, expiresAt)
-	respondJSON(w, http.StatusOK, map[string]any{"user": mapUser(user)})
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *APIHandler) logout(w http.ResponseWriter, r *http.Request) {
+// endSession は request の session を失効させ、session cookie と CSRF cookie を消す。
+// 失効を記録できなかった場合は Cookie を残したままエラーを返す。
+func (h *APIHandler) endSession(w http.ResponseWriter, r *http.Request) error {
 	if cookie, err := r.Cookie(middleware.SessionCookieName); err == nil {
-		h.sessions.Delete(cookie.Value)
+		if err := h.sessions.Delete(r.Context(), cookie.Value); err != nil {
+			return err
+		}
 		cookie.Value = ""
 		cookie.Path = "/"
 		cookie.HttpOnly = true
@@ -191,7 +415,7 @@ func (h *APIHandler) logout(w http.ResponseWriter, r *http.Request) {
 		http.SetCookie(w, cookie)
 	}
 	h.clearCSRFCookie(w)
-	w.WriteHeader(http.StatusNoContent)
+	return nil
 }
 
 func (h *APIHandler) me(w http.ResponseWriter, r *http.Request) {
@@ -388,35 +612,39 @@ func (h *APIHandler) deleteEntry(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *APIHandler) createAllocation(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
 	var payload dto.AllocationRequest
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		respondError(w, http.StatusBadRequest, "invalid payload")
 		return
 	}
-	result, err := h.allocs.Allocate(r.Context(), payload)
+	result, err := h.allocs.Allocate(r.Context(), userID, payload)
 	if err != nil {
-		// 割当 API は入力制約違反を 422 として返し、JSON 形式の誤りとは区別する。
-		var valErr dto.ValidationError
-		var constraintErr usecase.AllocationConstraintError
-		switch {
-		case errors.As(err, &valErr):
-			respondError(w, http.StatusUnprocessableEntity, valErr.Error())
-			return
-		case errors.As(err, &constraintErr):
-			respondError(w, http.StatusUnprocessableEntity, constraintErr.Error())
-			return
-		default:
-			respondError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		respondAllocationError(w, err)
+		return
 	}
 	respondJSON(w, http.StatusCreated, map[string]any{
 		"request_id":    result.RequestID,
 		"total_minutes": result.TotalMinutes,
+		"strategy":      result.Strategy,
 		"allocations":   result.Allocations,
 	})
 }
 
+func respondAllocationError(w http.ResponseWriter, err error) {
+	// 割当 API は入力制約違反を 422 として返し、JSON 形式の誤りとは区別する。
+	var valErr dto.ValidationError
+	var constraintErr usecase.AllocationConstraintError
+	switch {
+	case errors.As(err, &valErr):
+		respondError(w, http.StatusUnprocessableEntity, valErr.Error())
+	case errors.As(err, &constraintErr):
+		respondError(w, http.StatusUnprocessableEntity, constraintErr.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
 func (h *APIHandler) dailyReport(w http.ResponseWriter, r *http.Request) {
 	userID, _ := middleware.UserIDFromContext(r.Context())
 	user, err := h.auth.GetProfile(r.Context(), userID)
@@ -448,6 +676,7 @@ func (h *APIHandler) dailyReport(w http.ResponseWriter, r *http.Request) {
 		Start:    start,
 		End:      start.AddDate(0, 0, 1),
 		Location: loc,
+		Rounding: user.Rounding,
 	})
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
@@ -486,6 +715,7 @@ func (h *APIHandler) weeklyReport(w http.ResponseWriter, r *http.Request) {
 		Start:    start,
 		End:      start.AddDate(0, 0, 7),
 		Location: loc,
+		Rounding: user.Rounding,
 	})
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
@@ -526,6 +756,7 @@ func (h *APIHandler) monthlyReport(w http.ResponseWriter, r *http.Request) {
 		Start:    start,
 		End:      start.AddDate(0, 1, 0),
 		Location: loc,
+		Rounding: user.Rounding,
 	})
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
@@ -593,7 +824,11 @@ func mapUser(user *entity.User) map[string]any {
 		"email":        user.Email,
 		"display_name": user.DisplayName,
 		"time_zone":    user.TimeZone,
+		"rounding":     user.Rounding,
 		"created_at":   user.CreatedAt,
+		// 未確認なら null を返し、frontend が確認を促す表示に使う。
+		"email_verified_at":  user.EmailVerifiedAt,
+		"two_factor_enabled": user.TwoFactorEnabled(),
 	}
 }
 
@@ -3,9 +3,13 @@ package handler
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,10 +19,13 @@ import (
 
 	"chronome/internal/adapter/http/middleware"
 	"chronome/internal/adapter/infra/config"
+	"chronome/internal/adapter/infra/oidc"
+	"chronome/internal/adapter/infra/ratelimit"
 	sess "chronome/internal/adapter/infra/session"
 	"chronome/internal/domain/entity"
 	"chronome/internal/domain/repository"
 	"chronome/internal/usecase"
+	"chronome/internal/usecase/provider"
 	"chronome/test/fakes"
 )
 
@@ -132,7 +139,7 @@ func TestAPIHandler_WeeklyReportSuccess(t *testing.T) {
 func TestAPIHandler_LoginSetsSecureCookie(t *testing.T) {
 	entryRepo := &fakes.FakeEntryRepository{}
 	projectRepo := &fakes.FakeProjectRepository{}
-	store, err := sess.NewSignedCookieStore("another-secret")
+	store, err := sess.NewSignedCookieStore("another-secret", sess.NewMemoryRegistry())
 	require.NoError(t, err)
 
 	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
@@ -147,7 +154,6 @@ func TestAPIHandler_LoginSetsSecureCookie(t *testing.T) {
 			return user, nil
 		},
 	}
-	auth := usecase.NewAuthUsecase(userRepo)
 	cfg := config.Config{
 		AllowedOrigin:          "http://localhost:5173",
 		SessionTTLValue:        time.Hour,
@@ -155,10 +161,48 @@ func TestAPIHandler_LoginSetsSecureCookie(t *testing.T) {
 		SessionCookieSecure:    true,
 		DefaultProjectColorHex: "#3B82F6",
 	}
+	auth := usecase.NewAuthUsecase(userRepo, cfg)
 	tagUC := usecase.NewTagUsecase(&fakes.FakeTagRepository{}, cfg)
 	entryUC := usecase.NewEntryUsecase(entryRepo, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
-	allocationUC := usecase.NewAllocationUsecase(&fakes.FakeAllocationRepository{}, fakes.FixedTimeProvider{})
-	handler := NewAPIHandler(cfg, store, auth, usecase.NewProjectUsecase(projectRepo, cfg), tagUC, entryUC, usecase.NewReportUsecase(entryRepo, projectRepo), allocationUC)
+	allocationUC := usecase.NewAllocationUsecase(&fakes.FakeAllocationRepository{}, entryRepo, projectRepo, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+	templateUC := usecase.NewAllocationTemplateUsecase(&fakes.FakeAllocationTemplateRepository{}, allocationUC, entryRepo, projectRepo, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+	favoriteUC := usecase.NewFavoriteUsecase(&fakes.FakeFavoriteRepository{}, entryRepo, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+	idleUC := usecase.NewIdleUsecase(entryRepo, cfg, fakes.FixedTimeProvider{})
+	pomodoroUC := usecase.NewPomodoroUsecase(&fakes.FakePomodoroRepository{}, entryRepo, fakes.FixedTimeProvider{})
+	goalUC := usecase.NewGoalUsecase(&fakes.FakeGoalRepository{}, entryRepo, projectRepo, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+	scheduleUC := usecase.NewScheduleUsecase(&fakes.FakeScheduleRepository{})
+	signer, err := sess.NewHMACTokenSigner(cfg.SessionSecret)
+	require.NoError(t, err)
+	tokenUC := usecase.NewTokenUsecase(&fakes.FakeRefreshTokenRepository{}, userRepo, cfg, fakes.FixedTimeProvider{})
+	personalUC := usecase.NewPersonalTokenUsecase(&fakes.FakePersonalTokenRepository{}, fakes.FixedTimeProvider{})
+	resetUC := usecase.NewPasswordResetUsecase(userRepo, &fakes.FakePasswordResetRepository{}, &fakes.RecordingMailer{}, cfg, fakes.FixedTimeProvider{})
+	verifier := usecase.NewEmailVerificationUsecase(userRepo, &fakes.FakeEmailVerificationRepository{}, &fakes.RecordingMailer{}, cfg, fakes.FixedTimeProvider{})
+	accountUC := usecase.NewAccountUsecase(userRepo, verifier, &fakes.RecordingMailer{})
+	twoFactorUC := usecase.NewTwoFactorUsecase(userRepo, &fakes.FakeRecoveryCodeRepository{}, &fakes.FakeLoginChallengeRepository{}, fakes.FixedTimeProvider{})
+	handler := NewAPIHandler(APIHandlerDeps{
+		Config:    cfg,
+		Sessions:  store,
+		Signer:    signer,
+		Limits:    ratelimit.NewMemoryStore(),
+		Auth:      auth,
+		Tokens:    tokenUC,
+		Personal:  personalUC,
+		Resets:    resetUC,
+		Accounts:  accountUC,
+		Verifier:  verifier,
+		TwoFactor: twoFactorUC,
+		Projects:  usecase.NewProjectUsecase(projectRepo, cfg),
+		Tags:      tagUC,
+		Entries:   entryUC,
+		Reports:   usecase.NewReportUsecase(entryRepo, projectRepo, &fakes.FakeScheduleRepository{}),
+		Allocs:    allocationUC,
+		Templates: templateUC,
+		Favorites: favoriteUC,
+		Idle:      idleUC,
+		Pomodoros: pomodoroUC,
+		Goals:     goalUC,
+		Schedules: scheduleUC,
+	})
 
 	body := bytes.NewBufferString(`{"email":"user@example.com","password":"s3cret"}`)
 	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", body)
@@ -214,6 +258,7 @@ func TestAPIHandler_CreateAllocationSuccess(t *testing.T) {
 	require.Equal(t, http.StatusCreated, rec.Code)
 	require.NotNil(t, receivedRequest)
 	require.Equal(t, 60, receivedRequest.TotalMinutes)
+	require.Equal(t, userID, receivedRequest.UserID)
 	require.Len(t, receivedAllocations, 2)
 	allocations := map[string]int{}
 	for _, allocation := range receivedAllocations {
@@ -265,6 +310,42 @@ func TestAPIHandler_CreateAllocationConstraintError(t *testing.T) {
 	require.False(t, called)
 }
 
+func TestAPIHandler_PreviewAllocationReturnsDiagnosisWithoutSaving(t *testing.T) {
+	called := false
+	allocationRepo := &fakes.FakeAllocationRepository{
+		CreateFn: func(context.Context, *entity.AllocationRequest, []entity.TaskAllocation) error {
+			called = true
+			return nil
+		},
+	}
+	h, store, cfg := newAPIHandlerForTests(t, nil, nil, nil, allocationRepo)
+	userID := uuid.New()
+	body := bytes.NewBufferString(`{"total_minutes":10,"tasks":[{"task_id":"task-a","ratio":1,"min_minutes":20}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/allocations/preview", body)
+	addSessionCookie(t, store, cfg, req, userID)
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.False(t, called)
+	var resp struct {
+		Feasible  bool `json:"feasible"`
+		Diagnosis struct {
+			MinTotalMinutes int `json:"min_total_minutes"`
+			Binding         []struct {
+				TaskID string `json:"task_id"`
+				Bound  string `json:"bound"`
+			} `json:"binding"`
+		} `json:"diagnosis"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.False(t, resp.Feasible)
+	require.Equal(t, 20, resp.Diagnosis.MinTotalMinutes)
+	require.Len(t, resp.Diagnosis.Binding, 1)
+	require.Equal(t, "min", resp.Diagnosis.Binding[0].Bound)
+}
+
 func TestAPIHandler_CreateAllocationRepositoryError(t *testing.T) {
 	called := false
 	allocationRepo := &fakes.FakeAllocationRepository{
@@ -286,29 +367,1197 @@ func TestAPIHandler_CreateAllocationRepositoryError(t *testing.T) {
 	require.True(t, called)
 }
 
-// ヘルパー ------------------------------------------------------------------
+func TestAPIHandler_GetAllocationScopedToOwner(t *testing.T) {
+	ownerID := uuid.New()
+	requestID := uuid.New()
+	allocationRepo := &fakes.FakeAllocationRepository{
+		GetByIDFn: func(_ context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationRequest, []entity.TaskAllocation, error) {
+			if id != requestID {
+				return nil, nil, errors.New("connection refused")
+			}
+			if userID != ownerID {
+				return nil, nil, repository.ErrNotFound
+			}
+			return &entity.AllocationRequest{ID: requestID, UserID: ownerID, TotalMinutes: 60},
+				[]entity.TaskAllocation{{RequestID: requestID, TaskID: "task-a", Ratio: 1, AllocatedMinutes: 60}}, nil
+		},
+	}
+	h, store, cfg := newAPIHandlerForTests(t, nil, nil, nil, allocationRepo)
 
-func newAPIHandlerForTests(t *testing.T, projectRepo *fakes.FakeProjectRepository, entryRepo *fakes.FakeEntryRepository, tagRepo *fakes.FakeTagRepository, allocationRepo *fakes.FakeAllocationRepository) (*APIHandler, sess.Store, config.Config) {
-	t.Helper()
-	if projectRepo == nil {
-		projectRepo = &fakes.FakeProjectRepository{}
+	req := httptest.NewRequest(http.MethodGet, "/api/allocations/"+requestID.String(), nil)
+	addSessionCookie(t, store, cfg, req, ownerID)
+	rec := httptest.NewRecorder()
+	h.Router().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result usecase.AllocationResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.Equal(t, requestID, result.RequestID)
+	require.Len(t, result.Allocations, 1)
+	require.Equal(t, 60, result.Allocations[0].AllocatedMinutes)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/allocations/"+requestID.String(), nil)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec = httptest.NewRecorder()
+	h.Router().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// DB の障害は 404 にしない。
+	req = httptest.NewRequest(http.MethodGet, "/api/allocations/"+uuid.NewString(), nil)
+	addSessionCookie(t, store, cfg, req, ownerID)
+	rec = httptest.NewRecorder()
+	h.Router().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestAPIHandler_ApplyAllocationNotFound(t *testing.T) {
+	h, store, cfg := newAPIHandlerForTests(t, nil, nil, nil, &fakes.FakeAllocationRepository{})
+	body := bytes.NewBufferString(`{"window_start":"2024-05-01T09:00:00Z","window_end":"2024-05-01T12:00:00Z","mappings":[{"task_id":"a","project_id":"` + uuid.NewString() + `"}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/allocations/"+uuid.NewString()+"/apply", body)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAPIHandler_SplitEntryConstraintError(t *testing.T) {
+	start := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+	entryRepo := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			return &entity.Entry{ID: id, UserID: userID, Title: "Work", StartedAt: start, EndedAt: &end, Ratio: 1}, nil
+		},
+	}
+	h, store, cfg := newAPIHandlerForTests(t, nil, entryRepo, nil, nil)
+	body := bytes.NewBufferString(`{"parts":[{"ratio":1,"min_minutes":20},{"ratio":1,"min_minutes":20}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/entries/"+uuid.NewString()+"/split", body)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestAPIHandler_UpdateRoundingRejectsUnknownMode(t *testing.T) {
+	h, store, cfg := newAPIHandlerForTests(t, nil, nil, nil, nil)
+	body := bytes.NewBufferString(`{"mode":"ceil","increment_minutes":15}`)
+	req := httptest.NewRequest(http.MethodPut, "/api/settings/rounding", body)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+	h.Router().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	body = bytes.NewBufferString(`{"mode":"up","increment_minutes":15}`)
+	req = httptest.NewRequest(http.MethodPut, "/api/settings/rounding", body)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec = httptest.NewRecorder()
+	h.Router().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"rounding":{"mode":"up","increment_minutes":15}`)
+}
+
+func TestAPIHandler_RunAllocationTemplateNotFound(t *testing.T) {
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{
+		templates: &fakes.FakeAllocationTemplateRepository{
+			GetByIDFn: func(context.Context, uuid.UUID, uuid.UUID) (*entity.AllocationTemplate, error) {
+				return nil, repository.ErrNotFound
+			},
+		},
+	})
+	body := bytes.NewBufferString(`{"total_minutes":60}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/allocation-templates/"+uuid.NewString()+"/run", body)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAPIHandler_CreateAllocationTemplateRejectsForeignProject(t *testing.T) {
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{
+		projects: &fakes.FakeProjectRepository{
+			GetByIDFn: func(context.Context, uuid.UUID, uuid.UUID) (*entity.Project, error) {
+				return nil, errors.New("record not found")
+			},
+		},
+	})
+	body := bytes.NewBufferString(`{"name":"Daily","tasks":[{"project_id":"` + uuid.NewString() + `","ratio":1}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/allocation-templates/", body)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), "tasks[0].project_id")
+}
+
+func TestAPIHandler_AllocateFromTrackedRejectsUnknownSource(t *testing.T) {
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{})
+	body := bytes.NewBufferString(`{"source":"calendar","date":"2024-05-01","tasks":[{"task_id":"a","ratio":1}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/allocations/from-tracked", body)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), "source")
+}
+
+func TestAPIHandler_GetAllocationBatchNotFound(t *testing.T) {
+	lookupErr := repository.ErrNotFound
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{
+		allocations: &fakes.FakeAllocationRepository{
+			GetBatchFn: func(context.Context, uuid.UUID, uuid.UUID) (*entity.AllocationBatch, []entity.AllocationRequest, []entity.TaskAllocation, error) {
+				return nil, nil, nil, lookupErr
+			},
+		},
+	})
+	router := h.Router()
+	get := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/allocations/batches/"+uuid.NewString(), nil)
+		addSessionCookie(t, store, cfg, req, uuid.New())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
 	}
-	if entryRepo == nil {
-		entryRepo = &fakes.FakeEntryRepository{}
+
+	require.Equal(t, http.StatusNotFound, get())
+	// DB の障害は 404 にしない。
+	lookupErr = errors.New("connection refused")
+	require.Equal(t, http.StatusInternalServerError, get())
+}
+
+func TestAPIHandler_TokenGrantAuthenticatesWithoutCSRF(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: string(hash), TimeZone: "UTC"}
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(context.Context, string) (*entity.User, error) { return user, nil },
+		GetByIDFn:    func(context.Context, uuid.UUID) (*entity.User, error) { return user, nil },
 	}
-	if tagRepo == nil {
-		tagRepo = &fakes.FakeTagRepository{}
+	stored := make(map[string]*entity.RefreshToken)
+	refreshTokens := &fakes.FakeRefreshTokenRepository{
+		CreateFn: func(_ context.Context, token *entity.RefreshToken) error {
+			stored[token.TokenHash] = token
+			return nil
+		},
+		GetByHashFn: func(_ context.Context, hash string) (*entity.RefreshToken, error) {
+			if token, ok := stored[hash]; ok {
+				return token, nil
+			}
+			return nil, errors.New("not found")
+		},
 	}
-	if allocationRepo == nil {
-		allocationRepo = &fakes.FakeAllocationRepository{}
+	var created *entity.Project
+	projects := &fakes.FakeProjectRepository{
+		CreateFn: func(_ context.Context, project *entity.Project) error {
+			created = project
+			return nil
+		},
 	}
-	userRepo := &fakes.FakeUserRepository{
-		GetByEmailFn: func(context.Context, string) (*entity.User, error) {
+	now := time.Now().UTC()
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{users: users, refreshTokens: refreshTokens, projects: projects, clock: fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }}})
+	router := h.Router()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/token", bytes.NewBufferString(`{"grant_type":"password","email":"user@example.com","password":"s3cret"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	require.Empty(t, rec.Result().Cookies())
+	var issued struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &issued))
+	require.Equal(t, "Bearer", issued.TokenType)
+	require.Equal(t, 900, issued.ExpiresIn)
+	require.NotEmpty(t, issued.RefreshToken)
+
+	// Bearer 認証の変更系リクエストは CSRF トークンなしで通る。
+	req = httptest.NewRequest(http.MethodPost, "/api/projects", bytes.NewBufferString(`{"name":"Mobile"}`))
+	req.Header.Set("Authorization", "Bearer "+issued.AccessToken)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.NotNil(t, created)
+	require.Equal(t, user.ID, created.UserID)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/token", bytes.NewBufferString(`{"grant_type":"refresh_token","refresh_token":"`+issued.RefreshToken+`"}`))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var refreshed struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &refreshed))
+	require.NotEqual(t, issued.RefreshToken, refreshed.RefreshToken)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/token", bytes.NewBufferString(`{"grant_type":"client_credentials"}`))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAPIHandler_InvalidBearerDoesNotFallBackToCookie(t *testing.T) {
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{})
+	router := h.Router()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/projects", nil)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	req.Header.Set("Authorization", "Bearer not-a-token")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// セッション Cookie の値を Bearer として送っても通らない。
+	sessionID, err := store.Create(context.Background(), uuid.New(), cfg.SessionTTL(), sess.Client{})
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, "/api/projects", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Cookie 認証は引き続き CSRF トークンを要求する。
+	req = httptest.NewRequest(http.MethodPost, "/api/projects", bytes.NewBufferString(`{"name":"Web"}`))
+	req.AddCookie(&http.Cookie{Name: middleware.SessionCookieName, Value: sessionID})
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestAPIHandler_PersonalTokenScopesPerRouteGroup(t *testing.T) {
+	userID := uuid.New()
+	secret := entity.PersonalTokenPrefix + "scripted"
+	personalTokens := &fakes.FakePersonalTokenRepository{
+		GetByHashFn: func(context.Context, string) (*entity.PersonalAccessToken, error) {
+			return &entity.PersonalAccessToken{ID: uuid.New(), UserID: userID, Scopes: "entries:read reports:read"}, nil
+		},
+	}
+	var listedFor uuid.UUID
+	entries := &fakes.FakeEntryRepository{
+		ListFn: func(_ context.Context, uid uuid.UUID, _ repository.EntryFilter) ([]entity.Entry, error) {
+			listedFor = uid
+			return nil, nil
+		},
+	}
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{entries: entries, personalTokens: personalTokens})
+	router := h.Router()
+
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{http.MethodGet, "/api/entries", "", http.StatusOK},
+		{http.MethodPost, "/api/entries", `{"title":"x"}`, http.StatusForbidden},
+		{http.MethodGet, "/api/projects", "", http.StatusForbidden},
+		{http.MethodPost, "/api/allocations/preview", `{}`, http.StatusForbidden},
+		// スコープを宣言していないグループとトークン管理はパーソナルアクセストークンでは呼べない。
+		{http.MethodGet, "/api/goals", "", http.StatusForbidden},
+		{http.MethodGet, "/api/auth/tokens", "", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		require.Equal(t, tc.want, rec.Code, "%s %s", tc.method, tc.path)
+	}
+	require.Equal(t, userID, listedFor)
+}
+
+func TestAPIHandler_AllocationEntryWritesRequireEntriesScope(t *testing.T) {
+	userID := uuid.New()
+	scopes := "allocations:read allocations:write"
+	personalTokens := &fakes.FakePersonalTokenRepository{
+		GetByHashFn: func(context.Context, string) (*entity.PersonalAccessToken, error) {
+			return &entity.PersonalAccessToken{ID: uuid.New(), UserID: userID, Scopes: scopes}, nil
+		},
+	}
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{personalTokens: personalTokens})
+	router := h.Router()
+
+	post := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+entity.PersonalTokenPrefix+"scripted")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	fromTracked := "/api/allocations/from-tracked"
+	apply := "/api/allocations/" + uuid.NewString() + "/apply"
+
+	// allocations:write だけではエントリを書き換えるルートを呼べない。
+	require.Equal(t, http.StatusForbidden, post(fromTracked, `{}`))
+	require.Equal(t, http.StatusForbidden, post(apply, `{}`))
+	require.Equal(t, http.StatusUnprocessableEntity, post("/api/allocations/preview", `{}`))
+
+	// entries:write もあればスコープの検査を通り、入力の検証まで進む。
+	scopes = "allocations:read allocations:write entries:write"
+	require.Equal(t, http.StatusUnprocessableEntity, post(fromTracked, `{}`))
+	require.Equal(t, http.StatusUnprocessableEntity, post(apply, `{}`))
+}
+
+func TestAPIHandler_CreatePersonalTokenReturnsSecretOnce(t *testing.T) {
+	var saved *entity.PersonalAccessToken
+	personalTokens := &fakes.FakePersonalTokenRepository{
+		CreateFn: func(_ context.Context, token *entity.PersonalAccessToken) error {
+			saved = token
+			return nil
+		},
+		ListFn: func(context.Context, uuid.UUID) ([]entity.PersonalAccessToken, error) {
+			return []entity.PersonalAccessToken{*saved}, nil
+		},
+	}
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{personalTokens: personalTokens})
+	router := h.Router()
+	userID := uuid.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/tokens", bytes.NewBufferString(`{"name":"cli","scopes":["entries:write"]}`))
+	addSessionCookie(t, store, cfg, req, userID)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created struct {
+		Secret string `json:"secret"`
+		Token  struct {
+			Scopes []string `json:"scopes"`
+		} `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.True(t, strings.HasPrefix(created.Secret, entity.PersonalTokenPrefix))
+	require.Equal(t, []string{"entries:write"}, created.Token.Scopes)
+	require.Equal(t, userID, saved.UserID)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/auth/tokens", nil)
+	addSessionCookie(t, store, cfg, req, userID)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotContains(t, rec.Body.String(), created.Secret)
+	require.NotContains(t, rec.Body.String(), saved.TokenHash)
+}
+
+func TestAPIHandler_ForgotPasswordRespondsIdenticallyForUnknownEmail(t *testing.T) {
+	known := &entity.User{ID: uuid.New(), Email: "user@example.com"}
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(_ context.Context, email string) (*entity.User, error) {
+			if email == known.Email {
+				return known, nil
+			}
 			return nil, errors.New("not found")
 		},
+	}
+	mailer := &fakes.RecordingMailer{}
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{users: users, mailer: mailer})
+	router := h.Router()
+
+	var bodies []string
+	for _, email := range []string{"user@example.com", "nobody@example.com"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/password/forgot", bytes.NewBufferString(`{"email":"`+email+`"}`))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		bodies = append(bodies, rec.Body.String())
+	}
+	require.Equal(t, bodies[0], bodies[1])
+	require.Len(t, mailer.Messages(), 1)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/password/reset", bytes.NewBufferString(`{"token":"bogus","password":"new-password"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "invalid or expired reset token")
+}
+
+func TestAPIHandler_ForgotPasswordIsRateLimitedPerIPAndEmail(t *testing.T) {
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(context.Context, string) (*entity.User, error) { return nil, errors.New("not found") },
+	}
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{users: users})
+	h.cfg.PasswordResetRateLimit = 3
+	h.cfg.PasswordResetEmailRateLimit = 2
+	h.cfg.PasswordResetRateWindow = time.Hour
+	router := h.Router()
+
+	forgot := func(email, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/password/forgot", bytes.NewBufferString(`{"email":"`+email+`"}`))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	// 未登録のアドレスでも同じように数え、登録の有無を応答の違いで明かさない。
+	require.Equal(t, http.StatusAccepted, forgot("victim@example.com", "192.0.2.1:1000"))
+	require.Equal(t, http.StatusAccepted, forgot("Victim@example.com", "192.0.2.2:1000"))
+	require.Equal(t, http.StatusTooManyRequests, forgot("victim@example.com", "192.0.2.3:1000"))
+
+	require.Equal(t, http.StatusAccepted, forgot("a@example.com", "198.51.100.1:1000"))
+	require.Equal(t, http.StatusAccepted, forgot("b@example.com", "198.51.100.1:1000"))
+	require.Equal(t, http.StatusAccepted, forgot("c@example.com", "198.51.100.1:1000"))
+	require.Equal(t, http.StatusTooManyRequests, forgot("d@example.com", "198.51.100.1:1000"))
+}
+
+func TestAPIHandler_ResetPasswordRevokesSessionsAndRefreshTokens(t *testing.T) {
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com", TimeZone: "UTC"}
+	users := &fakes.FakeUserRepository{
+		GetByIDFn: func(context.Context, uuid.UUID) (*entity.User, error) {
+			copied := *user
+			return &copied, nil
+		},
+		UpdateFn: func(_ context.Context, updated *entity.User) error {
+			user = updated
+			return nil
+		},
+	}
+	resets := &fakes.FakePasswordResetRepository{
+		GetByHashFn: func(context.Context, string) (*entity.PasswordResetToken, error) {
+			return &entity.PasswordResetToken{ID: uuid.New(), UserID: user.ID, ExpiresAt: time.Unix(0, 0).Add(time.Hour)}, nil
+		},
+		MarkUsedFn: func(context.Context, uuid.UUID, time.Time) (bool, error) { return true, nil },
+	}
+	var revokedTokensFor uuid.UUID
+	refreshTokens := &fakes.FakeRefreshTokenRepository{
+		RevokeByUserFn: func(_ context.Context, userID uuid.UUID, _ time.Time) error {
+			revokedTokensFor = userID
+			return nil
+		},
+	}
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{users: users, resets: resets, refreshTokens: refreshTokens})
+	stolen, err := store.Create(context.Background(), user.ID, cfg.SessionTTL(), sess.Client{})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/password/reset", bytes.NewBufferString(`{"token":"emailed","password":"new-password"}`))
+	rec := httptest.NewRecorder()
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("new-password")))
+	_, ok := store.Get(context.Background(), stolen)
+	require.False(t, ok)
+	require.Equal(t, user.ID, revokedTokensFor)
+}
+
+func TestAPIHandler_ChangePasswordRevokesOtherSessions(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: string(hash), TimeZone: "UTC"}
+	users := &fakes.FakeUserRepository{
+		GetByIDFn: func(context.Context, uuid.UUID) (*entity.User, error) {
+			copied := *user
+			return &copied, nil
+		},
+		UpdateFn: func(_ context.Context, updated *entity.User) error {
+			user = updated
+			return nil
+		},
+	}
+	var revokedTokensFor uuid.UUID
+	refreshTokens := &fakes.FakeRefreshTokenRepository{
+		RevokeByUserFn: func(_ context.Context, userID uuid.UUID, _ time.Time) error {
+			revokedTokensFor = userID
+			return nil
+		},
+	}
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{users: users, refreshTokens: refreshTokens})
+	router := h.Router()
+	otherDevice, err := store.Create(context.Background(), user.ID, cfg.SessionTTL(), sess.Client{})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/password", bytes.NewBufferString(`{"current_password":"wrong-password","new_password":"new-password"}`))
+	addSessionCookie(t, store, cfg, req, user.ID)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	_, ok := store.Get(context.Background(), otherDevice)
+	require.True(t, ok)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/password", bytes.NewBufferString(`{"current_password":"old-password","new_password":"new-password"}`))
+	addSessionCookie(t, store, cfg, req, user.ID)
+	current, err := req.Cookie(middleware.SessionCookieName)
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("new-password")))
+	require.Equal(t, user.ID, revokedTokensFor)
+
+	_, ok = store.Get(context.Background(), otherDevice)
+	require.False(t, ok)
+	_, ok = store.Get(context.Background(), current.Value)
+	require.False(t, ok)
+	// 呼び出し元には新しい session が発行され、ログイン状態が続く。
+	var renewed *http.Cookie
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == middleware.SessionCookieName {
+			renewed = cookie
+		}
+	}
+	require.NotNil(t, renewed)
+	got, ok := store.Get(context.Background(), renewed.Value)
+	require.True(t, ok)
+	require.Equal(t, user.ID, got.UserID)
+}
+
+func TestAPIHandler_DeleteAccountRequiresPassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password-1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: string(hash), TimeZone: "UTC"}
+	var deleted []uuid.UUID
+	users := &fakes.FakeUserRepository{
+		GetByIDFn: func(context.Context, uuid.UUID) (*entity.User, error) { return user, nil },
+		DeleteFn: func(_ context.Context, id uuid.UUID) error {
+			deleted = append(deleted, id)
+			return nil
+		},
+	}
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{users: users})
+	router := h.Router()
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/auth/me", bytes.NewBufferString(`{"password":"wrong-password"}`))
+	addSessionCookie(t, store, cfg, req, user.ID)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Empty(t, deleted)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/auth/me", bytes.NewBufferString(`{"password":"password-1"}`))
+	addSessionCookie(t, store, cfg, req, user.ID)
+	current, err := req.Cookie(middleware.SessionCookieName)
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, []uuid.UUID{user.ID}, deleted)
+	_, ok := store.Get(context.Background(), current.Value)
+	require.False(t, ok)
+	require.Contains(t, rec.Header().Values("Set-Cookie")[0], middleware.SessionCookieName+"=;")
+}
+
+func TestAPIHandler_AccessTokensStopWorkingAfterPasswordChangeAndDeletion(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: string(hash), TimeZone: "UTC"}
+	deleted := false
+	users := &fakes.FakeUserRepository{
+		GetByIDFn: func(context.Context, uuid.UUID) (*entity.User, error) {
+			if deleted {
+				return nil, repository.ErrNotFound
+			}
+			copied := *user
+			return &copied, nil
+		},
+		UpdateFn: func(_ context.Context, updated *entity.User) error {
+			user = updated
+			return nil
+		},
+		RevokeAccessTokensFn: func(_ context.Context, _ uuid.UUID, at time.Time) error {
+			user.TokensNotBefore = &at
+			return nil
+		},
+		DeleteFn: func(context.Context, uuid.UUID) error {
+			deleted = true
+			return nil
+		},
+	}
+	h, _, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{users: users, clock: fakes.FixedTimeProvider{NowFunc: time.Now}})
+	router := h.Router()
+	signer, err := sess.NewHMACTokenSigner(cfg.SessionSecret)
+	require.NoError(t, err)
+	bearer := func(method, path, body, token string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	stolen, err := signer.Issue(user.ID, cfg.AccessTokenTTL())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, bearer(http.MethodGet, "/api/auth/me", "", stolen))
+	require.Equal(t, http.StatusNoContent, bearer(http.MethodPost, "/api/auth/password", `{"current_password":"old-password","new_password":"new-password"}`, stolen))
+	require.Equal(t, http.StatusUnauthorized, bearer(http.MethodGet, "/api/auth/me", "", stolen))
+
+	// 変更後に発行したトークンは使え、アカウントを削除すると期限内でも拒否される。
+	current, err := signer.Issue(user.ID, cfg.AccessTokenTTL())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, bearer(http.MethodGet, "/api/auth/me", "", current))
+	require.Equal(t, http.StatusNoContent, bearer(http.MethodDelete, "/api/auth/me", `{"password":"new-password"}`, current))
+	require.Equal(t, http.StatusUnauthorized, bearer(http.MethodGet, "/api/auth/me", "", current))
+}
+
+func TestAPIHandler_LimitedEmailVerificationAllowsReadsOnly(t *testing.T) {
+	verifiedAt := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	unverified := &entity.User{ID: uuid.New(), Email: "new@example.com", TimeZone: "UTC"}
+	verified := &entity.User{ID: uuid.New(), Email: "done@example.com", TimeZone: "UTC", EmailVerifiedAt: &verifiedAt}
+	users := &fakes.FakeUserRepository{
 		GetByIDFn: func(_ context.Context, id uuid.UUID) (*entity.User, error) {
-			return &entity.User{ID: id, TimeZone: "UTC"}, nil
+			if id == verified.ID {
+				return verified, nil
+			}
+			return unverified, nil
+		},
+	}
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{users: users, emailVerification: provider.EmailVerificationLimited})
+	router := h.Router()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/projects", nil)
+	addSessionCookie(t, store, cfg, req, unverified.ID)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/projects", bytes.NewBufferString(`{"name":"Web"}`))
+	addSessionCookie(t, store, cfg, req, unverified.ID)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), "email not verified")
+
+	req = httptest.NewRequest(http.MethodPost, "/api/projects", bytes.NewBufferString(`{"name":"Web"}`))
+	addSessionCookie(t, store, cfg, req, verified.ID)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestAPIHandler_RequiredEmailVerificationBlocksLogin(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password-1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &entity.User{ID: uuid.New(), Email: "new@example.com", PasswordHash: string(hash), TimeZone: "UTC"}
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(context.Context, string) (*entity.User, error) { return user, nil },
+	}
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{users: users, emailVerification: provider.EmailVerificationRequired})
+	router := h.Router()
+
+	// パスワードが違う場合は未確認であることを明かさない。
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString(`{"email":"new@example.com","password":"wrong-password"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString(`{"email":"new@example.com","password":"password-1"}`))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Empty(t, rec.Header().Values("Set-Cookie"))
+}
+
+func TestAPIHandler_ResendVerificationDoesNotRevealAccountState(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	user := &entity.User{ID: uuid.New(), Email: "new@example.com", TimeZone: "UTC"}
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(_ context.Context, email string) (*entity.User, error) {
+			if email == user.Email {
+				return user, nil
+			}
+			return nil, errors.New("not found")
+		},
+	}
+	verifications := &fakes.FakeEmailVerificationRepository{
+		ListCreatedSinceFn: func(context.Context, uuid.UUID, time.Time) ([]entity.EmailVerificationToken, error) {
+			return []entity.EmailVerificationToken{{UserID: user.ID, CreatedAt: now.Add(-20 * time.Second)}}, nil
+		},
+	}
+	mailer := &fakes.RecordingMailer{}
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }}
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{users: users, verifications: verifications, mailer: mailer, clock: clock})
+	router := h.Router()
+
+	resend := func(email string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/email/verify/resend", bytes.NewBufferString(`{"email":"`+email+`"}`))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	// 再送の上限に達した未確認ユーザーと未登録のアドレスで、応答を区別できないようにする。
+	limited := resend("new@example.com")
+	unknown := resend("unknown@example.com")
+	require.Equal(t, http.StatusAccepted, limited.Code)
+	require.Equal(t, unknown.Code, limited.Code)
+	require.Equal(t, unknown.Body.String(), limited.Body.String())
+	require.Empty(t, limited.Header().Get("Retry-After"))
+	require.Empty(t, mailer.Messages())
+}
+
+func TestAPIHandler_ResendVerificationIsRateLimitedPerIP(t *testing.T) {
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(context.Context, string) (*entity.User, error) { return nil, errors.New("not found") },
+	}
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{users: users})
+	h.cfg.EmailVerificationRateLimit = 2
+	h.cfg.EmailVerificationRateWindow = time.Hour
+	router := h.Router()
+
+	resend := func(email, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/email/verify/resend", bytes.NewBufferString(`{"email":"`+email+`"}`))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	// 未登録のアドレスでも同じように数える。
+	require.Equal(t, http.StatusAccepted, resend("a@example.com", "198.51.100.1:1000"))
+	require.Equal(t, http.StatusAccepted, resend("b@example.com", "198.51.100.1:1000"))
+	require.Equal(t, http.StatusTooManyRequests, resend("c@example.com", "198.51.100.1:1000"))
+	require.Equal(t, http.StatusAccepted, resend("c@example.com", "198.51.100.2:1000"))
+}
+
+func TestAPIHandler_LoginWithTwoFactorIssuesSessionOnlyAfterSecondFactor(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password-1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	enabledAt := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: string(hash), TimeZone: "UTC", TOTPSecret: "JBSWY3DPEHPK3PXP", TOTPEnabledAt: &enabledAt}
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(context.Context, string) (*entity.User, error) { return user, nil },
+		GetByIDFn:    func(context.Context, uuid.UUID) (*entity.User, error) { return user, nil },
+	}
+	var stored *entity.LoginChallenge
+	challenges := &fakes.FakeLoginChallengeRepository{
+		CreateFn: func(_ context.Context, challenge *entity.LoginChallenge) error {
+			stored = challenge
+			return nil
+		},
+		GetByHashFn: func(_ context.Context, hash string) (*entity.LoginChallenge, error) {
+			if stored == nil || stored.TokenHash != hash {
+				return nil, errors.New("not found")
+			}
+			return stored, nil
+		},
+	}
+	sum := sha256.Sum256([]byte("ABCDEFGHJK"))
+	recoveryCodes := &fakes.FakeRecoveryCodeRepository{
+		UseFn: func(_ context.Context, userID uuid.UUID, codeHash string, _ time.Time) (bool, error) {
+			return userID == user.ID && codeHash == hex.EncodeToString(sum[:]), nil
+		},
+	}
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return enabledAt.Add(time.Hour) }}
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{users: users, challenges: challenges, recoveryCodes: recoveryCodes, clock: clock})
+	router := h.Router()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString(`{"email":"user@example.com","password":"password-1"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Empty(t, rec.Header().Values("Set-Cookie"))
+	var challenge struct {
+		Required bool   `json:"two_factor_required"`
+		Token    string `json:"challenge_token"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &challenge))
+	require.True(t, challenge.Required)
+	require.NotEmpty(t, challenge.Token)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/login/2fa", bytes.NewBufferString(`{"challenge_token":"`+challenge.Token+`","recovery_code":"WRONG-CODES"}`))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Empty(t, rec.Header().Values("Set-Cookie"))
+
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/login/2fa", bytes.NewBufferString(`{"challenge_token":"`+challenge.Token+`","recovery_code":"abcde-fghjk"}`))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Values("Set-Cookie")[0], middleware.SessionCookieName+"=")
+	require.Contains(t, rec.Body.String(), `"two_factor_enabled":true`)
+
+	// パスワードグラントもチャレンジを返し、トークンは発行しない。
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/token", bytes.NewBufferString(`{"grant_type":"password","email":"user@example.com","password":"password-1"}`))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.NotContains(t, rec.Body.String(), "access_token")
+}
+
+func TestAPIHandler_LoginLocksOutAfterRepeatedFailures(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password-1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: string(hash), TimeZone: "UTC"}
+	var lookups int
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(context.Context, string) (*entity.User, error) {
+			lookups++
+			return user, nil
+		},
+	}
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{users: users})
+	h.cfg.LoginMaxFailures = 2
+	h.cfg.LoginFailureWindow = time.Hour
+	h.cfg.LoginLockout = time.Minute
+	h.cfg.LoginLockoutMax = time.Hour
+	router := h.Router()
+
+	login := func(email, password, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString(`{"email":"`+email+`","password":"`+password+`"}`))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	require.Equal(t, http.StatusUnauthorized, login("user@example.com", "wrong-1", "192.0.2.1:1000").Code)
+	require.Equal(t, http.StatusUnauthorized, login("User@Example.com", "wrong-2", "192.0.2.2:1000").Code)
+
+	// 締め出し中は正しいパスワードでも別の IP からでも照合しない。
+	rec := login("user@example.com", "password-1", "192.0.2.3:1000")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "60", rec.Header().Get("Retry-After"))
+	require.Equal(t, 2, lookups)
+
+	// 別のアカウントは締め出さない。
+	require.Equal(t, http.StatusUnauthorized, login("other@example.com", "wrong-3", "192.0.2.3:1000").Code)
+}
+
+func TestAPIHandler_SignupIsRateLimitedPerIP(t *testing.T) {
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(context.Context, string) (*entity.User, error) { return nil, errors.New("not found") },
+	}
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{users: users})
+	h.cfg.SignupRateLimit = 1
+	h.cfg.SignupRateWindow = time.Hour
+	h.cfg.RateLimitIPHeader = "X-Forwarded-For"
+	h.cfg.RateLimitProxyHops = 2
+	router := h.Router()
+
+	signup := func(forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/signup", bytes.NewBufferString(`{"email":"new@example.com","password":"password-1"}`))
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	require.Equal(t, http.StatusCreated, signup("203.0.113.9, 10.0.0.1").Code)
+	// クライアントが先頭に付け足した値では別の IP にならない。
+	rec := signup("198.51.100.7, 203.0.113.9, 10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "3600", rec.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusCreated, signup("203.0.113.10, 10.0.0.1").Code)
+}
+
+func TestAPIHandler_ListAndRevokeSessions(t *testing.T) {
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{})
+	router := h.Router()
+	userID := uuid.New()
+	phone, err := store.Create(context.Background(), userID, cfg.SessionTTL(), sess.Client{
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+		IPAddress: "198.51.100.7",
+	})
+	require.NoError(t, err)
+	_, err = store.Create(context.Background(), uuid.New(), cfg.SessionTTL(), sess.Client{})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/sessions", nil)
+	addSessionCookie(t, store, cfg, req, userID)
+	current, err := req.Cookie(middleware.SessionCookieName)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed struct {
+		Sessions []struct {
+			ID        uuid.UUID `json:"id"`
+			Device    string    `json:"device"`
+			IPAddress string    `json:"ip_address"`
+			Current   bool      `json:"current"`
+		} `json:"sessions"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	// 他ユーザーのセッションは含まず、新しい順に並ぶ。
+	require.Len(t, listed.Sessions, 2)
+	require.True(t, listed.Sessions[0].Current)
+	require.False(t, listed.Sessions[1].Current)
+	require.Equal(t, "Safari on iPhone", listed.Sessions[1].Device)
+	require.Equal(t, "198.51.100.7", listed.Sessions[1].IPAddress)
+
+	revoke := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/api/auth/sessions/"+id, nil)
+		req.AddCookie(current)
+		req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: "csrf"})
+		req.Header.Set(middleware.CSRFHeaderName, "csrf")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	require.Equal(t, http.StatusNoContent, revoke(listed.Sessions[1].ID.String()).Code)
+	_, ok := store.Get(context.Background(), phone)
+	require.False(t, ok)
+	require.Equal(t, http.StatusNotFound, revoke(listed.Sessions[1].ID.String()).Code)
+
+	// 自分のセッションを失効させると Cookie も消える。
+	rec = revoke(listed.Sessions[0].ID.String())
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	var cleared bool
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == middleware.SessionCookieName && cookie.MaxAge < 0 {
+			cleared = true
+		}
+	}
+	require.True(t, cleared)
+	_, ok = store.Get(context.Background(), current.Value)
+	require.False(t, ok)
+}
+
+// memoryOIDCLoginRequests は state のハッシュで引けるログインの保存先を fake に被せる。
+func memoryOIDCLoginRequests() *fakes.FakeOIDCLoginRequestRepository {
+	stored := make(map[string]*entity.OIDCLoginRequest)
+	return &fakes.FakeOIDCLoginRequestRepository{
+		CreateFn: func(_ context.Context, request *entity.OIDCLoginRequest) error {
+			stored[request.StateHash] = request
+			return nil
+		},
+		GetByStateHashFn: func(_ context.Context, hash string) (*entity.OIDCLoginRequest, error) {
+			if request, ok := stored[hash]; ok {
+				copied := *request
+				return &copied, nil
+			}
+			return nil, errors.New("not found")
+		},
+		MarkUsedFn: func(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+			for _, request := range stored {
+				if request.ID == id && request.UsedAt == nil {
+					request.UsedAt = &at
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	}
+}
+
+// startOIDCLogin は /oidc/login から ID プロバイダーの認可までを進め、コールバックのリクエストを返す。
+func startOIDCLogin(t *testing.T, router http.Handler, idp *fakes.FakeOIDCProvider, path string) *http.Request {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+	callback, err := idp.Authorize(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "/api/auth/oidc/callback", callback.Path)
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == oidcStateCookieName {
+			req.AddCookie(cookie)
+		}
+	}
+	return req
+}
+
+func TestAPIHandler_OIDCLoginLinksVerifiedEmailAndStartsSession(t *testing.T) {
+	now := time.Now().UTC()
+	user := &entity.User{ID: uuid.New(), Email: "alice@example.com", PasswordHash: "hash", TimeZone: "UTC", EmailVerifiedAt: &now}
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(_ context.Context, email string) (*entity.User, error) {
+			if email == user.Email {
+				return user, nil
+			}
+			return nil, errors.New("not found")
 		},
+		GetByIDFn: func(context.Context, uuid.UUID) (*entity.User, error) { return user, nil },
+	}
+	var linked []entity.ExternalIdentity
+	identities := &fakes.FakeExternalIdentityRepository{
+		CreateFn: func(_ context.Context, identity *entity.ExternalIdentity) error {
+			linked = append(linked, *identity)
+			return nil
+		},
+	}
+	idp := fakes.NewFakeOIDCProvider(t)
+	idp.SignIn(&fakes.OIDCIdentity{Subject: "sub-1", Email: "Alice@example.com", EmailVerified: true})
+	h, store, _ := newAPIHandlerWithDeps(t, handlerTestDeps{
+		users:        users,
+		identities:   identities,
+		oidcRequests: memoryOIDCLoginRequests(),
+		oidcProvider: idp,
+		clock:        fakes.FixedTimeProvider{NowFunc: time.Now},
+	})
+	router := h.Router()
+
+	req := startOIDCLogin(t, router, idp, "/api/auth/oidc/login?redirect=/reports?range=week")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "http://localhost:5173/reports?range=week", rec.Header().Get("Location"))
+	var session *http.Cookie
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == middleware.SessionCookieName {
+			session = cookie
+		}
+	}
+	require.NotNil(t, session)
+	got, ok := store.Get(context.Background(), session.Value)
+	require.True(t, ok)
+	require.Equal(t, user.ID, got.UserID)
+	require.Len(t, linked, 1)
+	require.Equal(t, idp.Issuer(), linked[0].Issuer)
+	require.Equal(t, "sub-1", linked[0].Subject)
+	require.Equal(t, user.ID, linked[0].UserID)
+
+	// 同じコールバックを再送してもログインできない。
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, "http://localhost:5173/login?oidc_error=invalid_state", rec.Header().Get("Location"))
+}
+
+func TestAPIHandler_OIDCCallbackRejectsMissingStateCookieAndUnverifiedEmail(t *testing.T) {
+	idp := fakes.NewFakeOIDCProvider(t)
+	idp.SignIn(&fakes.OIDCIdentity{Subject: "sub-1", Email: "alice@example.com", EmailVerified: false})
+	h, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{
+		identities:   &fakes.FakeExternalIdentityRepository{},
+		oidcRequests: memoryOIDCLoginRequests(),
+		oidcProvider: idp,
+		clock:        fakes.FixedTimeProvider{NowFunc: time.Now},
+	})
+	router := h.Router()
+
+	// 別のブラウザで始めたログインのコールバックは、state の cookie がないので完了できない。
+	req := startOIDCLogin(t, router, idp, "/api/auth/oidc/login")
+	withoutCookie := httptest.NewRequest(http.MethodGet, req.URL.RequestURI(), nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, withoutCookie)
+	require.Equal(t, "http://localhost:5173/login?oidc_error=invalid_state", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, "http://localhost:5173/login?oidc_error=email_not_verified", rec.Header().Get("Location"))
+	for _, cookie := range rec.Result().Cookies() {
+		require.NotEqual(t, middleware.SessionCookieName, cookie.Name)
+	}
+
+	// OIDC_ISSUER がなければルート自体を登録しない。
+	disabled, _, _ := newAPIHandlerWithDeps(t, handlerTestDeps{})
+	rec = httptest.NewRecorder()
+	disabled.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSafeRedirectPath(t *testing.T) {
+	cases := map[string]string{
+		"":                             "/",
+		"/reports?range=week":          "/reports?range=week",
+		"//evil.example":               "/",
+		"/\\evil.example":              "/",
+		"https://evil.example/":        "/",
+		"reports":                      "/",
+		"/" + strings.Repeat("a", 512): "/",
+	}
+	for input, want := range cases {
+		require.Equal(t, want, safeRedirectPath(input), input)
+	}
+}
+
+// ヘルパー ------------------------------------------------------------------
+
+func newAPIHandlerForTests(t *testing.T, projectRepo *fakes.FakeProjectRepository, entryRepo *fakes.FakeEntryRepository, tagRepo *fakes.FakeTagRepository, allocationRepo *fakes.FakeAllocationRepository) (*APIHandler, sess.Store, config.Config) {
+	t.Helper()
+	return newAPIHandlerWithDeps(t, handlerTestDeps{
+		projects:    projectRepo,
+		entries:     entryRepo,
+		tags:        tagRepo,
+		allocations: allocationRepo,
+	})
+}
+
+// handlerTestDeps は handler テストで差し替える fake repository をまとめる。nil はデフォルトの fake で埋める。
+type handlerTestDeps struct {
+	projects    *fakes.FakeProjectRepository
+	entries     *fakes.FakeEntryRepository
+	tags        *fakes.FakeTagRepository
+	allocations *fakes.FakeAllocationRepository
+	templates   *fakes.FakeAllocationTemplateRepository
+	favorites   *fakes.FakeFavoriteRepository
+	pomodoros   *fakes.FakePomodoroRepository
+	goals       *fakes.FakeGoalRepository
+	schedules   *fakes.FakeScheduleRepository
+	// users を省略すると GetByID だけ UTC のユーザーを返す既定の fake を使う。
+	users          *fakes.FakeUserRepository
+	refreshTokens  *fakes.FakeRefreshTokenRepository
+	personalTokens *fakes.FakePersonalTokenRepository
+	resets         *fakes.FakePasswordResetRepository
+	verifications  *fakes.FakeEmailVerificationRepository
+	recoveryCodes  *fakes.FakeRecoveryCodeRepository
+	challenges     *fakes.FakeLoginChallengeRepository
+	// oidcProvider を指定した場合だけ OpenID Connect のルートを有効にする。
+	oidcProvider *fakes.FakeOIDCProvider
+	identities   *fakes.FakeExternalIdentityRepository
+	oidcRequests *fakes.FakeOIDCLoginRequestRepository
+	mailer       *fakes.RecordingMailer
+	clock        fakes.FixedTimeProvider
+	// emailVerification は EMAIL_VERIFICATION の値。省略すると optional と同じ扱いになる。
+	emailVerification provider.EmailVerificationMode
+}
+
+func newAPIHandlerWithDeps(t *testing.T, deps handlerTestDeps) (*APIHandler, sess.Store, config.Config) {
+	t.Helper()
+	if deps.projects == nil {
+		deps.projects = &fakes.FakeProjectRepository{}
+	}
+	if deps.entries == nil {
+		deps.entries = &fakes.FakeEntryRepository{}
+	}
+	if deps.tags == nil {
+		deps.tags = &fakes.FakeTagRepository{}
+	}
+	if deps.allocations == nil {
+		deps.allocations = &fakes.FakeAllocationRepository{}
+	}
+	if deps.templates == nil {
+		deps.templates = &fakes.FakeAllocationTemplateRepository{}
+	}
+	if deps.favorites == nil {
+		deps.favorites = &fakes.FakeFavoriteRepository{}
+	}
+	if deps.pomodoros == nil {
+		deps.pomodoros = &fakes.FakePomodoroRepository{}
+	}
+	if deps.goals == nil {
+		deps.goals = &fakes.FakeGoalRepository{}
+	}
+	if deps.schedules == nil {
+		deps.schedules = &fakes.FakeScheduleRepository{}
+	}
+	if deps.refreshTokens == nil {
+		deps.refreshTokens = &fakes.FakeRefreshTokenRepository{}
+	}
+	if deps.personalTokens == nil {
+		deps.personalTokens = &fakes.FakePersonalTokenRepository{}
+	}
+	if deps.resets == nil {
+		deps.resets = &fakes.FakePasswordResetRepository{}
+	}
+	if deps.verifications == nil {
+		deps.verifications = &fakes.FakeEmailVerificationRepository{}
+	}
+	if deps.recoveryCodes == nil {
+		deps.recoveryCodes = &fakes.FakeRecoveryCodeRepository{}
+	}
+	if deps.challenges == nil {
+		deps.challenges = &fakes.FakeLoginChallengeRepository{}
+	}
+	if deps.mailer == nil {
+		deps.mailer = &fakes.RecordingMailer{}
+	}
+	clock := deps.clock
+	userRepo := deps.users
+	if userRepo == nil {
+		userRepo = &fakes.FakeUserRepository{
+			GetByEmailFn: func(context.Context, string) (*entity.User, error) {
+				return nil, errors.New("not found")
+			},
+			GetByIDFn: func(_ context.Context, id uuid.UUID) (*entity.User, error) {
+				return &entity.User{ID: id, TimeZone: "UTC"}, nil
+			},
+		}
 	}
 	cfg := config.Config{
 		AllowedOrigin:          "http://localhost:5173",
@@ -316,21 +1565,83 @@ func newAPIHandlerForTests(t *testing.T, projectRepo *fakes.FakeProjectRepositor
 		SessionSecret:          "test-secret",
 		SessionCookieSecure:    false,
 		DefaultProjectColorHex: "#3B82F6",
+		IdleThresholdValue:     15 * time.Minute,
+		AccessTokenTTLValue:    15 * time.Minute,
+		RefreshTokenTTLValue:   24 * time.Hour,
+
+		EmailVerificationModeValue:           string(deps.emailVerification),
+		EmailVerificationResendIntervalValue: time.Minute,
 	}
-	store, err := sess.NewSignedCookieStore(cfg.SessionSecret)
+	cfg.AppBaseURL = cfg.AllowedOrigin
+	store, err := sess.NewSignedCookieStore(cfg.SessionSecret, sess.NewMemoryRegistry())
+	require.NoError(t, err)
+	signer, err := sess.NewHMACTokenSigner(cfg.SessionSecret)
 	require.NoError(t, err)
-	auth := usecase.NewAuthUsecase(userRepo)
-	projects := usecase.NewProjectUsecase(projectRepo, cfg)
-	tags := usecase.NewTagUsecase(tagRepo, cfg)
-	entries := usecase.NewEntryUsecase(entryRepo, tagRepo, fakes.FixedTimeProvider{})
-	reports := usecase.NewReportUsecase(entryRepo, projectRepo)
-	allocationUC := usecase.NewAllocationUsecase(allocationRepo, fakes.FixedTimeProvider{})
-	return NewAPIHandler(cfg, store, auth, projects, tags, entries, reports, allocationUC), store, cfg
+	auth := usecase.NewAuthUsecase(userRepo, cfg)
+	tokenUC := usecase.NewTokenUsecase(deps.refreshTokens, userRepo, cfg, clock)
+	personalUC := usecase.NewPersonalTokenUsecase(deps.personalTokens, clock)
+	resetUC := usecase.NewPasswordResetUsecase(userRepo, deps.resets, deps.mailer, cfg, clock)
+	verifier := usecase.NewEmailVerificationUsecase(userRepo, deps.verifications, deps.mailer, cfg, clock)
+	accountUC := usecase.NewAccountUsecase(userRepo, verifier, deps.mailer)
+	twoFactorUC := usecase.NewTwoFactorUsecase(userRepo, deps.recoveryCodes, deps.challenges, clock)
+	projects := usecase.NewProjectUsecase(deps.projects, cfg)
+	tags := usecase.NewTagUsecase(deps.tags, cfg)
+	entries := usecase.NewEntryUsecase(deps.entries, deps.tags, clock)
+	reports := usecase.NewReportUsecase(deps.entries, deps.projects, deps.schedules)
+	allocationUC := usecase.NewAllocationUsecase(deps.allocations, deps.entries, deps.projects, deps.tags, clock)
+	templateUC := usecase.NewAllocationTemplateUsecase(deps.templates, allocationUC, deps.entries, deps.projects, deps.tags, clock)
+	favoriteUC := usecase.NewFavoriteUsecase(deps.favorites, deps.entries, deps.tags, clock)
+	idleUC := usecase.NewIdleUsecase(deps.entries, cfg, clock)
+	pomodoroUC := usecase.NewPomodoroUsecase(deps.pomodoros, deps.entries, clock)
+	goalUC := usecase.NewGoalUsecase(deps.goals, deps.entries, deps.projects, deps.tags, clock)
+	scheduleUC := usecase.NewScheduleUsecase(deps.schedules)
+	var oidcUC *usecase.OIDCUsecase
+	if deps.oidcProvider != nil {
+		cfg.OIDCIssuer = deps.oidcProvider.Issuer()
+		cfg.OIDCClientID = deps.oidcProvider.ClientID
+		cfg.OIDCClientSecret = deps.oidcProvider.ClientSecret
+		cfg.OIDCRedirectURL = "http://localhost:8080/api/auth/oidc/callback"
+		cfg.OIDCAllowSignupValue = true
+		idp, err := oidc.NewClient(oidc.Options{Issuer: cfg.OIDCIssuer, ClientID: cfg.OIDCClientID, ClientSecret: cfg.OIDCClientSecret, RedirectURL: cfg.OIDCRedirectURL})
+		require.NoError(t, err)
+		if deps.identities == nil {
+			deps.identities = &fakes.FakeExternalIdentityRepository{}
+		}
+		if deps.oidcRequests == nil {
+			deps.oidcRequests = &fakes.FakeOIDCLoginRequestRepository{}
+		}
+		oidcUC = usecase.NewOIDCUsecase(userRepo, deps.identities, deps.oidcRequests, idp, cfg, clock)
+	}
+	return NewAPIHandler(APIHandlerDeps{
+		Config:    cfg,
+		Sessions:  store,
+		Signer:    signer,
+		Limits:    ratelimit.NewMemoryStore(),
+		Auth:      auth,
+		Tokens:    tokenUC,
+		Personal:  personalUC,
+		Resets:    resetUC,
+		Accounts:  accountUC,
+		Verifier:  verifier,
+		TwoFactor: twoFactorUC,
+		OIDC:      oidcUC,
+		Projects:  projects,
+		Tags:      tags,
+		Entries:   entries,
+		Reports:   reports,
+		Allocs:    allocationUC,
+		Templates: templateUC,
+		Favorites: favoriteUC,
+		Idle:      idleUC,
+		Pomodoros: pomodoroUC,
+		Goals:     goalUC,
+		Schedules: scheduleUC,
+	}), store, cfg
 }
 
 func addSessionCookie(t *testing.T, store sess.Store, cfg config.Config, req *http.Request, userID uuid.UUID) {
 	t.Helper()
-	sessionID, err := store.Create(userID, cfg.SessionTTL())
+	sessionID, err := store.Create(context.Background(), userID, cfg.SessionTTL(), sess.Client{})
 	require.NoError(t, err)
 	req.AddCookie(&http.Cookie{
 		Name:  middleware.SessionCookieName,
@@ -343,3 +1654,160 @@ func addSessionCookie(t *testing.T, store sess.Store, cfg config.Config, req *ht
 	})
 	req.Header.Set(middleware.CSRFHeaderName, csrfToken)
 }
+
+func TestAPIHandler_StartFavoriteCreatesEntry(t *testing.T) {
+	favoriteID := uuid.New()
+	favoriteRepo := &fakes.FakeFavoriteRepository{
+		GetByIDFn: func(_ context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Favorite, error) {
+			return &entity.Favorite{ID: id, UserID: userID, Title: "Standup", Ratio: 1}, nil
+		},
+	}
+	var created *entity.Entry
+	entryRepo := &fakes.FakeEntryRepository{
+		CreateFn: func(_ context.Context, entry *entity.Entry) error {
+			created = entry
+			return nil
+		},
+	}
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{entries: entryRepo, favorites: favoriteRepo})
+	userID := uuid.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/favorites/"+favoriteID.String()+"/start", nil)
+	addSessionCookie(t, store, cfg, req, userID)
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.NotNil(t, created)
+	require.Equal(t, userID, created.UserID)
+	require.Equal(t, "Standup", created.Title)
+}
+
+func TestAPIHandler_RecentCombinationsRejectsInvalidLimit(t *testing.T) {
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{})
+	req := httptest.NewRequest(http.MethodGet, "/api/favorites/recent?limit=abc", nil)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAPIHandler_ResolveIdleSplitsBreak(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	lastActive := now.Add(-time.Hour)
+	entryID := uuid.New()
+	var updated *entity.Entry
+	var created []*entity.Entry
+	entryRepo := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			return &entity.Entry{ID: id, UserID: userID, Title: "Coding", StartedAt: now.Add(-3 * time.Hour), LastActivityAt: &lastActive, Ratio: 1}, nil
+		},
+		UpdateFn: func(_ context.Context, entry *entity.Entry) error {
+			updated = entry
+			return nil
+		},
+		CreateFn: func(_ context.Context, entry *entity.Entry) error {
+			created = append(created, entry)
+			return nil
+		},
+	}
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{
+		entries: entryRepo,
+		clock:   fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }},
+	})
+	body := bytes.NewBufferString(`{"action":"break","continue":true}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/entries/"+entryID.String()+"/idle", body)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, updated)
+	require.Equal(t, lastActive, *updated.EndedAt)
+	require.EqualValues(t, 2*3600, updated.DurationSec)
+	require.Len(t, created, 2)
+	require.True(t, created[0].IsBreak)
+	require.Equal(t, lastActive, created[0].StartedAt)
+	require.EqualValues(t, 3600, created[0].DurationSec)
+	require.Equal(t, "Coding", created[1].Title)
+	require.Equal(t, now, created[1].StartedAt)
+	require.Nil(t, created[1].EndedAt)
+}
+
+func TestAPIHandler_CurrentPomodoroNotFound(t *testing.T) {
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{})
+	req := httptest.NewRequest(http.MethodGet, "/api/pomodoro/current", nil)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Contains(t, rec.Body.String(), "pomodoro session not found")
+}
+
+func TestAPIHandler_GoalProgressUsesRequestedDate(t *testing.T) {
+	goalID := uuid.New()
+	var capturedFrom, capturedTo time.Time
+	goalRepo := &fakes.FakeGoalRepository{
+		GetByIDFn: func(_ context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Goal, error) {
+			return &entity.Goal{ID: id, UserID: userID, Name: "Daily", Scope: entity.GoalScopeUser, Period: entity.GoalPeriodDaily, TargetSec: 3600, WorkingDays: entity.DefaultWorkingDays}, nil
+		},
+	}
+	entryRepo := &fakes.FakeEntryRepository{
+		ListFn: func(_ context.Context, _ uuid.UUID, filter repository.EntryFilter) ([]entity.Entry, error) {
+			capturedFrom, capturedTo = *filter.From, *filter.To
+			return nil, nil
+		},
+	}
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{entries: entryRepo, goals: goalRepo})
+	req := httptest.NewRequest(http.MethodGet, "/api/goals/"+goalID.String()+"/progress?date=2024-05-11&time_zone=Asia/Tokyo", nil)
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"period_start":"2024-05-11"`)
+	require.Contains(t, rec.Body.String(), `"is_working_day":false`)
+	require.Contains(t, rec.Body.String(), `"working_days":[1,2,3,4,5]`)
+	require.Equal(t, time.Date(2024, 5, 11, 15, 0, 0, 0, time.UTC), capturedTo)
+	require.True(t, capturedFrom.Before(capturedTo))
+}
+
+func TestAPIHandler_ImportHolidaysFromICalendar(t *testing.T) {
+	var saved []entity.Holiday
+	schedules := &fakes.FakeScheduleRepository{
+		UpsertHolidaysFn: func(_ context.Context, holidays []entity.Holiday) error {
+			saved = holidays
+			return nil
+		},
+		ListHolidaysFn: func(context.Context, uuid.UUID, string, string) ([]entity.Holiday, error) {
+			return saved, nil
+		},
+	}
+	h, store, cfg := newAPIHandlerWithDeps(t, handlerTestDeps{schedules: schedules})
+	calendar := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:gw\r\nDTSTART;VALUE=DATE:20240503\r\nDTEND;VALUE=DATE:20240505\r\nSUMMARY:Golden Week\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/schedule/holidays/import", bytes.NewBufferString(calendar))
+	req.Header.Set("Content-Type", "text/calendar")
+	addSessionCookie(t, store, cfg, req, uuid.New())
+	rec := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"imported":2`)
+	require.Len(t, saved, 2)
+	require.Equal(t, "2024-05-03", saved[0].Date)
+	require.Equal(t, "2024-05-04", saved[1].Date)
+	require.Equal(t, "Golden Week", saved[1].Name)
+
+	bad := httptest.NewRequest(http.MethodPost, "/api/schedule/holidays/import", bytes.NewBufferString("not a calendar"))
+	addSessionCookie(t, store, cfg, bad, uuid.New())
+	rec = httptest.NewRecorder()
+	h.Router().ServeHTTP(rec, bad)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/usecase/dto"
+)
+
+func (h *APIHandler) runningEntries(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	entries, err := h.idle.Running(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"entries": entries})
+}
+
+func (h *APIHandler) heartbeatEntry(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	eid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	// アイドル中でも 200 を返し、クライアントは idle_since の有無で解決ダイアログを出す。
+	entry, err := h.idle.Heartbeat(r.Context(), userID, eid)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, entry)
+}
+
+func (h *APIHandler) resolveIdleEntry(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	eid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var payload dto.IdleResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	entries, err := h.idle.ResolveIdle(r.Context(), userID, eid, payload)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"entries": entries})
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"chronome/internal/usecase"
+)
+
+const (
+	// oidcStateCookieName はログインを始めたブラウザとコールバックを結び付ける cookie。他人が始めたログインを完了させられないようにする。
+	oidcStateCookieName = "chronome_oidc_state"
+	oidcCookiePath      = "/api/auth/oidc"
+)
+
+// oidcLogin は ID プロバイダーの認可エンドポイントへリダイレクトする。redirect クエリでログイン後に戻すパスを指定できる。
+func (h *APIHandler) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	start, err := h.oidc.Begin(r.Context(), safeRedirectPath(r.URL.Query().Get("redirect")))
+	if err != nil {
+		h.redirectOIDCError(w, r, "unavailable")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    start.State,
+		Path:     oidcCookiePath,
+		HttpOnly: true,
+		Secure:   h.cfg.SessionCookieSecure,
+		// ID プロバイダーからのトップレベルの遷移で送られるよう Lax にする。
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(time.Until(start.ExpiresAt).Seconds()),
+		Expires:  start.ExpiresAt,
+	})
+	http.Redirect(w, r, start.AuthURL, http.StatusFound)
+}
+
+// oidcCallback は ID プロバイダーから戻ったブラウザのログインを完了し、session を発行してフロントエンドへ戻す。
+// 失敗した場合はフロントエンドのログイン画面へ oidc_error クエリを付けて戻す。
+func (h *APIHandler) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookieName)
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookieName, Value: "", Path: oidcCookiePath, MaxAge: -1})
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		h.redirectOIDCError(w, r, "invalid_state")
+		return
+	}
+	// ユーザーが同意しなかった場合などは code の代わりに error が返る。
+	if query.Get("error") != "" || query.Get("code") == "" {
+		h.redirectOIDCError(w, r, "cancelled")
+		return
+	}
+
+	result, err := h.oidc.Complete(r.Context(), state, query.Get("code"))
+	if err != nil {
+		h.redirectOIDCError(w, r, oidcErrorCode(err))
+		return
+	}
+	user := result.User
+	// 未確認のアカウントに紐付けた場合は、パスワードを知っていた人の session とトークンを残さない。
+	if result.CredentialsReset {
+		if h.sessions.RevokeUser(r.Context(), user.ID) != nil || h.tokens.RevokeAll(r.Context(), user.ID) != nil {
+			h.redirectOIDCError(w, r, "failed")
+			return
+		}
+	}
+	if user.TwoFactorEnabled() {
+		challenge, err := h.twoFactor.Challenge(r.Context(), user)
+		if err != nil {
+			h.redirectOIDCError(w, r, "failed")
+			return
+		}
+		// チャレンジのトークンはサーバーのアクセスログに残らないよう、URL のフラグメントで渡す。
+		fragment := url.Values{"challenge_token": {challenge.Token}, "redirect": {result.RedirectPath}}
+		http.Redirect(w, r, h.frontendURL("/login")+"#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+	if err := h.startSession(w, r, user.ID); err != nil {
+		h.redirectOIDCError(w, r, "failed")
+		return
+	}
+	http.Redirect(w, r, h.frontendURL(result.RedirectPath), http.StatusFound)
+}
+
+func (h *APIHandler) redirectOIDCError(w http.ResponseWriter, r *http.Request, code string) {
+	http.Redirect(w, r, h.frontendURL("/login")+"?oidc_error="+url.QueryEscape(code), http.StatusFound)
+}
+
+// frontendURL は APP_BASE_URL にパスを付けたフロントエンドの URL を返す。
+func (h *APIHandler) frontendURL(path string) string {
+	return strings.TrimSuffix(h.cfg.AppBaseURL, "/") + path
+}
+
+func oidcErrorCode(err error) string {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidOIDCLogin):
+		return "invalid_state"
+	case errors.Is(err, usecase.ErrIdentityEmailNotVerified):
+		return "email_not_verified"
+	case errors.Is(err, usecase.ErrIdentitySignupDisabled):
+		return "signup_disabled"
+	default:
+		return "failed"
+	}
+}
+
+// safeRedirectPath はログイン後に戻すパスを、同じオリジン内の絶対パスに限る。オープンリダイレクトを防ぐため、それ以外は "/" にする。
+func safeRedirectPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	parsed, err := url.Parse(path)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" || len(path) > 512 {
+		return "/"
+	}
+	return path
+}
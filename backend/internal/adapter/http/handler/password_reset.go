@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"chronome/internal/usecase"
+	"chronome/internal/usecase/dto"
+)
+
+// forgotPassword は登録の有無にかかわらず 202 を返し、メールアドレスの存在を明かさない。
+func (h *APIHandler) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var payload dto.PasswordForgotRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if err := h.resets.Request(r.Context(), payload); err != nil {
+		var valErr dto.ValidationError
+		if errors.As(err, &valErr) {
+			respondUsecaseError(w, err)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "password reset error")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *APIHandler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload dto.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	userID, err := h.resets.Reset(r.Context(), payload)
+	if err != nil {
+		var valErr dto.ValidationError
+		if errors.As(err, &valErr) || errors.Is(err, usecase.ErrInvalidPasswordResetToken) {
+			respondUsecaseError(w, err)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "password reset error")
+		return
+	}
+	// パスワードの変更と同じく、漏れた可能性のある既存のセッションとリフレッシュトークンをすべて失効させる。
+	if err := h.sessions.RevokeUser(r.Context(), userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "session error")
+		return
+	}
+	if err := h.tokens.RevokeAll(r.Context(), userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "token error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/usecase/dto"
+)
+
+func (h *APIHandler) startPomodoro(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.PomodoroStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	status, err := h.pomodoros.Start(r.Context(), userID, payload)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, status)
+}
+
+func (h *APIHandler) currentPomodoro(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	// 参照のたびに経過済みのフェーズ境界までエントリが確定する。
+	status, err := h.pomodoros.Current(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "pomodoro session not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
+func (h *APIHandler) stopPomodoro(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	sid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	status, err := h.pomodoros.Stop(r.Context(), userID, sid)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/adapter/infra/ical"
+	"chronome/internal/usecase/dto"
+)
+
+// maxCalendarBytes は取り込む iCalendar ファイルの上限サイズ。
+const maxCalendarBytes = 1 << 20
+
+func (h *APIHandler) getSchedule(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	schedule, err := h.schedules.GetSchedule(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, schedule)
+}
+
+func (h *APIHandler) updateSchedule(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.ScheduleUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	schedule, err := h.schedules.UpdateSchedule(r.Context(), userID, payload)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, schedule)
+}
+
+func (h *APIHandler) listHolidays(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	from, to, err := dto.ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	holidays, err := h.schedules.ListHolidays(r.Context(), userID, from, to)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"holidays": holidays})
+}
+
+func (h *APIHandler) importHolidays(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	// body は text/calendar をそのまま受け取り、上限を超えるファイルは拒否する。
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCalendarBytes+1))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if len(body) > maxCalendarBytes {
+		respondError(w, http.StatusRequestEntityTooLarge, "calendar is too large")
+		return
+	}
+	events, err := ical.Parse(bytes.NewReader(body))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid calendar: "+err.Error())
+		return
+	}
+	user, err := h.auth.GetProfile(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	// 時刻付きのイベントはユーザーのタイムゾーンでの日付として取り込む。
+	loc, err := h.resolveLocation(r, user)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid time_zone")
+		return
+	}
+	var items []dto.HolidayImportItem
+	for _, event := range events {
+		for _, date := range event.Dates(loc) {
+			items = append(items, dto.HolidayImportItem{Date: date, Name: event.Summary, UID: event.UID})
+		}
+	}
+	holidays, err := h.schedules.ImportHolidays(r.Context(), userID, items)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"imported": len(holidays), "holidays": holidays})
+}
+
+func (h *APIHandler) deleteHoliday(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	hid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.schedules.DeleteHoliday(r.Context(), userID, hid); err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) listTimeOff(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	from, to, err := dto.ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	records, err := h.schedules.ListTimeOff(r.Context(), userID, from, to)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"time_off": records})
+}
+
+func (h *APIHandler) createTimeOff(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.TimeOffCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	record, err := h.schedules.CreateTimeOff(r.Context(), userID, payload)
+	if err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, record)
+}
+
+func (h *APIHandler) deleteTimeOff(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	tid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.schedules.DeleteTimeOff(r.Context(), userID, tid); err != nil {
+		respondUsecaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"chronome/internal/adapter/http/middleware"
+	sess "chronome/internal/adapter/infra/session"
+)
+
+// sessionResponse は一覧の各セッションに、リクエスト元のセッションかどうかを添える。
+type sessionResponse struct {
+	sess.Record
+	Current bool `json:"current"`
+}
+
+func (h *APIHandler) listSessions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	records, err := h.sessions.List(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "session error")
+		return
+	}
+	currentID, _ := middleware.SessionIDFromContext(r.Context())
+	sessions := make([]sessionResponse, 0, len(records))
+	for _, record := range records {
+		sessions = append(sessions, sessionResponse{Record: record, Current: record.ID == currentID})
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+}
+
+// revokeSession は指定したセッションを失効させる。リクエスト元のセッションなら Cookie も消す。
+func (h *APIHandler) revokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	sid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.sessions.Revoke(r.Context(), userID, sid); err != nil {
+		if errors.Is(err, sess.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "session error")
+		return
+	}
+	if currentID, ok := middleware.SessionIDFromContext(r.Context()); ok && currentID == sid {
+		if err := h.endSession(w, r); err != nil {
+			respondError(w, http.StatusInternalServerError, "session error")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"time"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/adapter/infra/ratelimit"
+)
+
+// throttleOptions は設定値から Throttle の制限を組み立てる。lockout が false のエンドポイントは失敗を数えない。
+func (h *APIHandler) throttleOptions(name string, requests int, window time.Duration, lockout bool) middleware.ThrottleOptions {
+	opts := middleware.ThrottleOptions{
+		Name:      name,
+		PerIP:     ratelimit.Policy{Requests: requests, Window: window},
+		IPHeader:  h.cfg.RateLimitIPHeader,
+		ProxyHops: h.cfg.RateLimitProxyHops,
+	}
+	if lockout {
+		policy := ratelimit.LockoutPolicy{
+			Window:      h.cfg.LoginFailureWindow,
+			BaseLockout: h.cfg.LoginLockout,
+			MaxLockout:  h.cfg.LoginLockoutMax,
+		}
+		opts.AccountLockout = policy
+		opts.AccountLockout.MaxFailures = h.cfg.LoginMaxFailures
+		opts.IPLockout = policy
+		opts.IPLockout.MaxFailures = h.cfg.LoginMaxFailuresPerIP
+	}
+	return opts
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"chronome/internal/usecase"
+	"chronome/internal/usecase/dto"
+)
+
+// issueToken は iOS アプリやスクリプト向けにアクセストークンとリフレッシュトークンの組を発行する。
+// grant_type=password はメール・パスワードで、grant_type=refresh_token はリフレッシュトークンのローテーションで発行する。
+// 二要素認証を有効にしたユーザーの password グラントはチャレンジを返し、grant_type=two_factor で応えると発行する。
+func (h *APIHandler) issueToken(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		GrantType    string `json:"grant_type"`
+		Email        string `json:"email"`
+		Password     string `json:"password"`
+		RefreshToken string `json:"refresh_token"`
+		dto.TwoFactorLoginRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	var grant *usecase.RefreshTokenGrant
+	switch payload.GrantType {
+	case "password":
+		user, err := h.auth.Login(r.Context(), payload.Email, payload.Password)
+		if errors.Is(err, usecase.ErrEmailNotVerified) {
+			respondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if user.TwoFactorEnabled() {
+			h.respondLoginChallenge(w, r, user)
+			return
+		}
+		grant, err = h.tokens.Issue(r.Context(), user.ID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "token error")
+			return
+		}
+	case "two_factor":
+		user, err := h.twoFactor.CompleteLogin(r.Context(), payload.TwoFactorLoginRequest)
+		if err != nil {
+			respondTwoFactorLoginError(w, err)
+			return
+		}
+		grant, err = h.tokens.Issue(r.Context(), user.ID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "token error")
+			return
+		}
+	case "refresh_token":
+		var err error
+		grant, err = h.tokens.Rotate(r.Context(), payload.RefreshToken)
+		if errors.Is(err, usecase.ErrInvalidRefreshToken) {
+			respondError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "token error")
+			return
+		}
+	default:
+		respondError(w, http.StatusBadRequest, "unsupported grant_type")
+		return
+	}
+	user, err := h.auth.GetProfile(r.Context(), grant.UserID)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+	accessToken, err := h.signer.Issue(user.ID, h.cfg.AccessTokenTTL())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "token error")
+		return
+	}
+	// トークンをキャッシュさせないよう OAuth 2.0 のトークンレスポンスと同じヘッダーを付ける。
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusOK, map[string]any{
+		"access_token":             accessToken,
+		"token_type":               "Bearer",
+		"expires_in":               int(h.cfg.AccessTokenTTL().Seconds()),
+		"refresh_token":            grant.Token,
+		"refresh_token_expires_at": grant.ExpiresAt,
+		"user":                     mapUser(user),
+	})
+}
+
+// revokeToken はリフレッシュトークンを失効させる。トークンを持っていること自体を認可とみなすため認証は不要。
+func (h *APIHandler) revokeToken(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if err := h.tokens.Revoke(r.Context(), payload.RefreshToken); err != nil {
+		respondError(w, http.StatusInternalServerError, "token error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"chronome/internal/adapter/http/middleware"
+	"chronome/internal/domain/entity"
+	"chronome/internal/usecase"
+	"chronome/internal/usecase/dto"
+)
+
+func (h *APIHandler) twoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	status, err := h.twoFactor.Status(r.Context(), userID)
+	if err != nil {
+		respondAccountError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"enabled":                  status.Enabled,
+		"recovery_codes_remaining": status.RecoveryCodesRemaining,
+	})
+}
+
+// setupTwoFactor は共有鍵を発行する。/enable でコードを確認するまでログインには影響しない。
+func (h *APIHandler) setupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.TwoFactorPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	provisioning, err := h.twoFactor.Setup(r.Context(), userID, payload)
+	if err != nil {
+		respondAccountError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusOK, map[string]any{
+		"secret":      provisioning.Secret,
+		"otpauth_uri": provisioning.URI,
+	})
+}
+
+func (h *APIHandler) enableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.TwoFactorEnableRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	codes, err := h.twoFactor.Enable(r.Context(), userID, payload)
+	if err != nil {
+		respondAccountError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+func (h *APIHandler) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.TwoFactorPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if err := h.twoFactor.Disable(r.Context(), userID, payload); err != nil {
+		respondAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	var payload dto.TwoFactorPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(r.Context(), userID, payload)
+	if err != nil {
+		respondAccountError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// loginTwoFactor は /login が返したチャレンジに二要素目で応える。session はここで初めて発行する。
+func (h *APIHandler) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var payload dto.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	user, err := h.twoFactor.CompleteLogin(r.Context(), payload)
+	if err != nil {
+		respondTwoFactorLoginError(w, err)
+		return
+	}
+	if err := h.startSession(w, r, user.ID); err != nil {
+		respondError(w, http.StatusInternalServerError, "session error")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"user": mapUser(user)})
+}
+
+// respondLoginChallenge はパスワード確認を済ませた二要素認証ユーザーに、session やトークンの代わりにチャレンジを返す。
+func (h *APIHandler) respondLoginChallenge(w http.ResponseWriter, r *http.Request, user *entity.User) {
+	challenge, err := h.twoFactor.Challenge(r.Context(), user)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "session error")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, http.StatusAccepted, map[string]any{
+		"two_factor_required": true,
+		"challenge_token":     challenge.Token,
+		"expires_at":          challenge.ExpiresAt,
+	})
+}
+
+func respondTwoFactorLoginError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidLoginChallenge), errors.Is(err, usecase.ErrInvalidTwoFactorCode):
+		respondError(w, http.StatusUnauthorized, err.Error())
+	default:
+		respondAccountError(w, err)
+	}
+}
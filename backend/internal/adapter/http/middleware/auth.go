@@ -3,24 +3,62 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
 	"chronome/internal/adapter/infra/session"
+	"chronome/internal/domain/entity"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "chronome_user_id"
+const (
+	userIDKey     contextKey = "chronome_user_id"
+	sessionIDKey  contextKey = "chronome_session_id"
+	bearerAuthKey contextKey = "chronome_bearer_auth"
+	scopesKey     contextKey = "chronome_token_scopes"
+)
+
+// AccessTokenValidator は署名と期限を検証済みのアクセストークンを、ユーザー単位の失効と突き合わせる。
+type AccessTokenValidator interface {
+	AccessTokenValid(ctx context.Context, userID uuid.UUID, issuedAt time.Time) bool
+}
+
+// PersonalTokenAuthenticator はパーソナルアクセストークンの平文を照合する。
+type PersonalTokenAuthenticator interface {
+	Authenticate(ctx context.Context, raw string) (*entity.PersonalAccessToken, error)
+}
 
-// WithSession はクッキーが有効な場合に認証ユーザーをコンテキストへ付与する。
-func WithSession(store session.Store) func(http.Handler) http.Handler {
+// WithSession は Bearer トークンまたはクッキーが有効な場合に認証ユーザーをコンテキストへ付与する。
+// Authorization: Bearer が付いたリクエストはトークンだけで判定し、無効でもクッキーへはフォールバックしない。
+// パーソナルアクセストークンで認証した場合はスコープもコンテキストへ載せる。
+// アクセストークンは削除済みのユーザーやパスワード変更前に発行したものを access で拒否する。
+func WithSession(store session.Store, tokens session.TokenSigner, access AccessTokenValidator, personal PersonalTokenAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token, ok := bearerToken(r); ok {
+				if strings.HasPrefix(token, entity.PersonalTokenPrefix) {
+					if pat, err := personal.Authenticate(r.Context(), token); err == nil {
+						ctx := context.WithValue(r.Context(), userIDKey, pat.UserID)
+						ctx = context.WithValue(ctx, bearerAuthKey, true)
+						ctx = context.WithValue(ctx, scopesKey, pat.ScopeList())
+						r = r.WithContext(ctx)
+					}
+				} else if claims, valid := tokens.Verify(token); valid && access.AccessTokenValid(r.Context(), claims.UserID, claims.IssuedAt) {
+					ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+					ctx = context.WithValue(ctx, bearerAuthKey, true)
+					r = r.WithContext(ctx)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
 			cookie, err := r.Cookie(SessionCookieName)
 			if err == nil && cookie.Value != "" {
-				if userID, ok := store.Get(cookie.Value); ok {
-					ctx := context.WithValue(r.Context(), userIDKey, userID)
+				if info, ok := store.Get(r.Context(), cookie.Value); ok {
+					ctx := context.WithValue(r.Context(), userIDKey, info.UserID)
+					ctx = context.WithValue(ctx, sessionIDKey, info.ID)
 					r = r.WithContext(ctx)
 				}
 			}
@@ -30,12 +68,17 @@ func WithSession(store session.Store) func(http.Handler) http.Handler {
 }
 
 // RequireAuth は WithSession がユーザーを付与していない場合にリクエストを止める。
+// スコープを宣言していないルートなので、パーソナルアクセストークンは受け付けない。
 func RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if _, ok := UserIDFromContext(r.Context()); !ok {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
+		if _, scoped := TokenScopesFromContext(r.Context()); scoped {
+			http.Error(w, "insufficient scope", http.StatusForbidden)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -48,5 +91,30 @@ func UserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
 	return uuid.Nil, false
 }
 
+// SessionIDFromContext は Cookie で認証したリクエストのセッション一覧の ID を取り出す。
+// Bearer 認証や、一覧を導入する前に発行した Cookie では false を返す。
+func SessionIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	if val, ok := ctx.Value(sessionIDKey).(uuid.UUID); ok && val != uuid.Nil {
+		return val, true
+	}
+	return uuid.Nil, false
+}
+
+// AuthenticatedByBearer は Bearer トークンで認証されたリクエストかを返す。
+func AuthenticatedByBearer(ctx context.Context) bool {
+	val, _ := ctx.Value(bearerAuthKey).(bool)
+	return val
+}
+
+// bearerToken は Authorization ヘッダーから Bearer トークンを取り出す。ほかのスキームは無視する。
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
 // SessionCookieName はハンドラ間で一貫させるため公開している。
 const SessionCookieName = "chronome_session"
@@ -14,11 +14,12 @@ const (
 )
 
 // RequireCSRF はダブルサブミットの CSRF トークンと任意の Origin チェックを強制する。
+// Bearer トークンはブラウザが自動送信しないため、トークン認証のリクエストは対象外にする。
 func RequireCSRF(allowedOrigin string) func(http.Handler) http.Handler {
 	allowed := normalizeOrigin(allowedOrigin)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !needsCSRFProtection(r.Method) {
+			if !needsCSRFProtection(r.Method) || AuthenticatedByBearer(r.Context()) {
 				next.ServeHTTP(w, r)
 				return
 			}
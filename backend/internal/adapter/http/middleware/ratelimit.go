@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chronome/internal/adapter/infra/ratelimit"
+)
+
+// maxThrottledBodyBytes はアカウントを特定するために読むリクエストボディの上限。
+const maxThrottledBodyBytes = 1 << 20
+
+// ThrottleOptions は Throttle の制限内容。ゼロ値の Policy / LockoutPolicy は制限しない。
+type ThrottleOptions struct {
+	// Name はリクエスト数のカウンタを分ける名前。締め出しはエンドポイントをまたいで共有する。
+	Name  string
+	PerIP ratelimit.Policy
+	// PerAccount は JSON ボディの email ごとのリクエスト数。登録の有無にかかわらず数える。
+	PerAccount ratelimit.Policy
+	// AccountLockout と IPLockout は 401 の応答を失敗として数える。アカウントは JSON ボディの email で特定する。
+	AccountLockout ratelimit.LockoutPolicy
+	IPLockout      ratelimit.LockoutPolicy
+	// IPHeader はリバースプロキシが付けるクライアント IP のヘッダー。空なら接続元アドレスを使う。
+	// クライアントが送った値を信用しないよう、末尾から ProxyHops 番目（1 なら最後）の値を使う。
+	IPHeader  string
+	ProxyHops int
+}
+
+// Throttle は IP ごと (PerAccount があればアカウントごとも) のリクエスト数を制限し、認証の失敗が続いた IP とアカウントを段階的に締め出す。
+// 制限中は 429 と Retry-After（秒）を返し、後続のハンドラ（bcrypt の照合）を呼ばない。
+func Throttle(limiter *ratelimit.Limiter, opts ThrottleOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			ipKey := "ip:" + ClientIP(r, opts.IPHeader, opts.ProxyHops)
+			keys := []string{ipKey}
+			if account := accountFromBody(r); account != "" {
+				keys = append(keys, "account:"+account)
+			}
+
+			for _, key := range keys {
+				wait, err := limiter.Locked(ctx, key)
+				if err != nil {
+					http.Error(w, "rate limit error", http.StatusInternalServerError)
+					return
+				}
+				if wait > 0 {
+					tooManyRequests(w, wait)
+					return
+				}
+			}
+			if !allowRequest(ctx, w, limiter, opts.Name+":"+ipKey, opts.PerIP) {
+				return
+			}
+			if len(keys) > 1 && !allowRequest(ctx, w, limiter, opts.Name+":"+keys[1], opts.PerAccount) {
+				return
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			// 応答は書き終えているので、以降の記録の失敗はクライアントに返さない。
+			switch {
+			case recorder.status == http.StatusUnauthorized:
+				_, _ = limiter.Fail(ctx, ipKey, opts.IPLockout)
+				if len(keys) > 1 {
+					_, _ = limiter.Fail(ctx, keys[1], opts.AccountLockout)
+				}
+			case recorder.status < http.StatusMultipleChoices && len(keys) > 1:
+				// IP 側は消さない。1 つの正しいアカウントで他アカウントへの試行をやり直せないようにする。
+				_ = limiter.Succeed(ctx, keys[1])
+			}
+		})
+	}
+}
+
+// allowRequest は key のリクエストを数え、上限を超えていれば応答を書いて false を返す。
+func allowRequest(ctx context.Context, w http.ResponseWriter, limiter *ratelimit.Limiter, key string, policy ratelimit.Policy) bool {
+	wait, err := limiter.Allow(ctx, key, policy)
+	if err != nil {
+		http.Error(w, "rate limit error", http.StatusInternalServerError)
+		return false
+	}
+	if wait > 0 {
+		tooManyRequests(w, wait)
+		return false
+	}
+	return true
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "too many requests", http.StatusTooManyRequests)
+}
+
+// ClientIP はヘッダーが指定されていればプロキシが追加したアドレスを、なければ接続元アドレスを返す。
+func ClientIP(r *http.Request, header string, hops int) string {
+	if header != "" {
+		if value := r.Header.Get(header); value != "" {
+			parts := strings.Split(value, ",")
+			index := max(len(parts)-max(hops, 1), 0)
+			return strings.TrimSpace(parts[index])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// accountFromBody は JSON ボディの email を小文字にして返し、ハンドラが読めるようボディを戻しておく。
+func accountFromBody(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxThrottledBodyBytes))
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
+
+// statusRecorder はハンドラが返したステータスコードを覚えておく。
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequireVerifiedEmail はメールアドレスが未確認のユーザーに参照系メソッドだけを許可する。
+// 認証していないリクエストはそのまま通し、認証の要否は後続の RequireAuth などに任せる。
+func RequireVerifiedEmail(verified func(ctx context.Context, userID uuid.UUID) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := UserIDFromContext(r.Context())
+			if ok && needsCSRFProtection(r.Method) && !verified(r.Context(), userID) {
+				http.Error(w, "email not verified", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"chronome/internal/usecase/provider"
 )
 
 // DefaultSessionSecret はローカル開発専用。
@@ -16,25 +19,120 @@ type Config struct {
 	DBDsn                  string
 	SessionTTLValue        time.Duration
 	SessionSecret          string
+	SessionPreviousSecrets []string
 	SessionCookieSecure    bool
 	AllowedOrigin          string
 	Environment            string
 	DefaultProjectColorHex string
+	IdleThresholdValue     time.Duration
+	AutoStopAfterValue     time.Duration
+	AccessTokenTTLValue    time.Duration
+	RefreshTokenTTLValue   time.Duration
+	// AppBaseURL はメール本文のリンクに使うフロントエンドの URL。
+	AppBaseURL                string
+	PasswordResetTTLValue     time.Duration
+	EmailVerificationTTLValue time.Duration
+	// EmailVerificationModeValue は "optional" (既定) / "limited" / "required"。
+	EmailVerificationModeValue           string
+	EmailVerificationResendIntervalValue time.Duration
+	// MailDriver は "log" (既定。MailDir があればファイル出力) か "smtp"。
+	MailDriver   string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// RateLimitBackend は "memory" (既定) か "db"。複数インスタンスで制限を共有する場合は db にする。
+	RateLimitBackend string
+	// RateLimitIPHeader はリバースプロキシが付けるクライアント IP のヘッダー名。空なら接続元アドレスを使う。
+	// ヘッダーはカンマ区切りの末尾から RateLimitProxyHops 番目をクライアントとみなす（プロキシの段数に合わせる）。
+	RateLimitIPHeader  string
+	RateLimitProxyHops int
+	// LoginRateLimit / SignupRateLimit は IP ごとに Window あたり受け付けるリクエスト数。0 なら制限しない。
+	LoginRateLimit   int
+	LoginRateWindow  time.Duration
+	SignupRateLimit  int
+	SignupRateWindow time.Duration
+	// PasswordResetRateLimit は IP ごと、PasswordResetEmailRateLimit はメールアドレスごとに
+	// PasswordResetRateWindow あたり受け付ける再設定メールの依頼数。0 なら制限しない。
+	PasswordResetRateLimit      int
+	PasswordResetEmailRateLimit int
+	PasswordResetRateWindow     time.Duration
+	// EmailVerificationRateLimit は IP ごとに EmailVerificationRateWindow あたり受け付ける確認メールの再送依頼数。0 なら制限しない。
+	EmailVerificationRateLimit  int
+	EmailVerificationRateWindow time.Duration
+	// LoginMaxFailures / LoginMaxFailuresPerIP 回ログインに失敗すると LoginLockout だけ締め出し、
+	// 以降は失敗するたびに倍にする（LoginLockoutMax が上限）。失敗回数は LoginFailureWindow ごとに数え直す。
+	LoginMaxFailures      int
+	LoginMaxFailuresPerIP int
+	LoginFailureWindow    time.Duration
+	LoginLockout          time.Duration
+	LoginLockoutMax       time.Duration
+	// OIDCIssuer を設定すると OpenID Connect でのログインを有効にする。設定はディスカバリー文書から読み込む。
+	// OIDCRedirectURL は ID プロバイダーに登録したバックエンドの /api/auth/oidc/callback の URL。
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+	// OIDCAllowSignupValue が false なら、既存ユーザーと紐付かない ID ではユーザーを作らない。
+	OIDCAllowSignupValue bool
 }
 
 // Load はローカル開発向けの妥当なデフォルトを含む設定を返す。
 func Load() Config {
 	env := getEnv("APP_ENV", "development")
 	cfg := Config{
-		Address:                getEnv("SERVER_ADDRESS", ":8080"),
-		DBDriver:               getEnv("DB_DRIVER", "sqlite"),
-		DBDsn:                  getEnv("DB_DSN", "dev.db"),
-		AllowedOrigin:          getEnv("ALLOWED_ORIGIN", "http://localhost:3000"),
-		SessionTTLValue:        12 * time.Hour,
-		SessionSecret:          getEnv("SESSION_SECRET", DefaultSessionSecret),
-		Environment:            env,
-		DefaultProjectColorHex: getEnv("DEFAULT_PROJECT_COLOR", "#3B82F6"),
+		Address:                              getEnv("SERVER_ADDRESS", ":8080"),
+		DBDriver:                             getEnv("DB_DRIVER", "sqlite"),
+		DBDsn:                                getEnv("DB_DSN", "dev.db"),
+		AllowedOrigin:                        getEnv("ALLOWED_ORIGIN", "http://localhost:3000"),
+		SessionTTLValue:                      12 * time.Hour,
+		SessionSecret:                        getEnv("SESSION_SECRET", DefaultSessionSecret),
+		SessionPreviousSecrets:               getEnvList("SESSION_PREVIOUS_SECRETS"),
+		Environment:                          env,
+		DefaultProjectColorHex:               getEnv("DEFAULT_PROJECT_COLOR", "#3B82F6"),
+		IdleThresholdValue:                   getEnvDuration("IDLE_THRESHOLD", 15*time.Minute),
+		AutoStopAfterValue:                   getEnvDuration("AUTO_STOP_AFTER", 0),
+		AccessTokenTTLValue:                  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTLValue:                 getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		PasswordResetTTLValue:                getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTLValue:            getEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		EmailVerificationModeValue:           getEnv("EMAIL_VERIFICATION", string(provider.EmailVerificationOptional)),
+		EmailVerificationResendIntervalValue: getEnvDuration("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute),
+		MailDriver:                           getEnv("MAIL_DRIVER", "log"),
+		MailFrom:                             getEnv("MAIL_FROM", "ChronoMe <no-reply@localhost>"),
+		MailDir:                              os.Getenv("MAIL_DIR"),
+		SMTPHost:                             os.Getenv("SMTP_HOST"),
+		SMTPPort:                             getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:                         os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:                         os.Getenv("SMTP_PASSWORD"),
+		RateLimitBackend:                     getEnv("RATE_LIMIT_BACKEND", "memory"),
+		RateLimitIPHeader:                    os.Getenv("RATE_LIMIT_IP_HEADER"),
+		RateLimitProxyHops:                   getEnvInt("RATE_LIMIT_PROXY_HOPS", 1),
+		LoginRateLimit:                       getEnvInt("LOGIN_RATE_LIMIT", 20),
+		LoginRateWindow:                      getEnvDuration("LOGIN_RATE_WINDOW", time.Minute),
+		SignupRateLimit:                      getEnvInt("SIGNUP_RATE_LIMIT", 5),
+		SignupRateWindow:                     getEnvDuration("SIGNUP_RATE_WINDOW", time.Hour),
+		PasswordResetRateLimit:               getEnvInt("PASSWORD_RESET_RATE_LIMIT", 10),
+		PasswordResetEmailRateLimit:          getEnvInt("PASSWORD_RESET_EMAIL_RATE_LIMIT", 3),
+		PasswordResetRateWindow:              getEnvDuration("PASSWORD_RESET_RATE_WINDOW", time.Hour),
+		EmailVerificationRateLimit:           getEnvInt("EMAIL_VERIFICATION_RATE_LIMIT", 10),
+		EmailVerificationRateWindow:          getEnvDuration("EMAIL_VERIFICATION_RATE_WINDOW", time.Hour),
+		LoginMaxFailures:                     getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginMaxFailuresPerIP:                getEnvInt("LOGIN_MAX_FAILURES_PER_IP", 20),
+		LoginFailureWindow:                   getEnvDuration("LOGIN_FAILURE_WINDOW", 24*time.Hour),
+		LoginLockout:                         getEnvDuration("LOGIN_LOCKOUT", time.Minute),
+		LoginLockoutMax:                      getEnvDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		OIDCIssuer:                           os.Getenv("OIDC_ISSUER"),
+		OIDCClientID:                         os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:                     os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:                      os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCScopes:                           strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+		OIDCAllowSignupValue:                 getEnvBool("OIDC_ALLOW_SIGNUP", true),
 	}
+	cfg.AppBaseURL = getEnv("APP_BASE_URL", cfg.AllowedOrigin)
 	cfg.SessionCookieSecure = getEnvBool("SESSION_COOKIE_SECURE", env == "production")
 	if ttlRaw := os.Getenv("SESSION_TTL"); ttlRaw != "" {
 		if parsed, err := time.ParseDuration(ttlRaw); err == nil {
@@ -51,6 +149,39 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// getEnvList はカンマ区切りの値を空白を除いて返す。空の要素は捨てる。
+func getEnvList(key string) []string {
+	var values []string
+	for _, val := range strings.Split(os.Getenv(key), ",") {
+		if val = strings.TrimSpace(val); val != "" {
+			values = append(values, val)
+		}
+	}
+	return values
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	if parsed, err := time.ParseDuration(val); err == nil {
+		return parsed
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	if parsed, err := strconv.Atoi(val); err == nil {
+		return parsed
+	}
+	return fallback
+}
+
 func getEnvBool(key string, fallback bool) bool {
 	val := os.Getenv(key)
 	if val == "" {
@@ -74,3 +205,63 @@ func (c Config) DefaultProjectColor() string {
 func (c Config) SessionTTL() time.Duration {
 	return c.SessionTTLValue
 }
+
+// IdleThreshold は実行中エントリをアイドルとみなすまでの無操作時間を返す。
+func (c Config) IdleThreshold() time.Duration {
+	return c.IdleThresholdValue
+}
+
+// AutoStopAfter は heartbeat がないまま実行中エントリを自動停止するまでの時間を返す。0 は無効。
+func (c Config) AutoStopAfter() time.Duration {
+	return c.AutoStopAfterValue
+}
+
+// AccessTokenTTL は Bearer 認証のアクセストークンの有効期限を返す。
+func (c Config) AccessTokenTTL() time.Duration {
+	return c.AccessTokenTTLValue
+}
+
+// RefreshTokenTTL はリフレッシュトークンの有効期限を返す。リフレッシュのたびに発行時点から数え直す。
+func (c Config) RefreshTokenTTL() time.Duration {
+	return c.RefreshTokenTTLValue
+}
+
+// PasswordResetTTL はパスワード再設定トークンの有効期限を返す。
+func (c Config) PasswordResetTTL() time.Duration {
+	return c.PasswordResetTTLValue
+}
+
+// PasswordResetURL はメールに載せる再設定画面の URL を返す。トークンはクエリで付け足す。
+func (c Config) PasswordResetURL() string {
+	return strings.TrimSuffix(c.AppBaseURL, "/") + "/reset-password"
+}
+
+// EmailVerificationTTL はメールアドレス確認トークンの有効期限を返す。
+func (c Config) EmailVerificationTTL() time.Duration {
+	return c.EmailVerificationTTLValue
+}
+
+// EmailVerificationURL はメールに載せるアドレス確認画面の URL を返す。トークンはクエリで付け足す。
+func (c Config) EmailVerificationURL() string {
+	return strings.TrimSuffix(c.AppBaseURL, "/") + "/verify-email"
+}
+
+// EmailVerificationMode は未確認ユーザーの扱いを返す。
+func (c Config) EmailVerificationMode() provider.EmailVerificationMode {
+	return provider.EmailVerificationMode(c.EmailVerificationModeValue)
+}
+
+// EmailVerificationResendInterval は確認メールを再送できるまでの間隔を返す。
+func (c Config) EmailVerificationResendInterval() time.Duration {
+	return c.EmailVerificationResendIntervalValue
+}
+
+// OIDCEnabled は OpenID Connect でのログインが設定されているかを返す。
+func (c Config) OIDCEnabled() bool {
+	return c.OIDCIssuer != ""
+}
+
+// OIDCAllowSignup は ID プロバイダーでの初回ログイン時にユーザーを作成するかを返す。
+func (c Config) OIDCAllowSignup() bool {
+	return c.OIDCAllowSignupValue
+}
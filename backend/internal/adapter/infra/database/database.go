@@ -9,6 +9,8 @@ import (
 	"gorm.io/gorm/logger"
 
 	"chronome/internal/adapter/infra/config"
+	"chronome/internal/adapter/infra/ratelimit"
+	"chronome/internal/adapter/infra/session"
 	"chronome/internal/domain/entity"
 )
 
@@ -42,6 +44,26 @@ func Automigrate(db *gorm.DB) error {
 		&entity.Tag{},
 		&entity.EntryTag{},
 		&entity.AllocationRequest{},
+		&entity.AllocationBatch{},
 		&entity.TaskAllocation{},
+		&entity.AllocationTemplate{},
+		&entity.AllocationTemplateTask{},
+		&entity.Favorite{},
+		&entity.FavoriteTag{},
+		&entity.PomodoroSession{},
+		&entity.Goal{},
+		&entity.WorkSchedule{},
+		&entity.Holiday{},
+		&entity.TimeOff{},
+		&entity.RefreshToken{},
+		&entity.PersonalAccessToken{},
+		&entity.PasswordResetToken{},
+		&entity.EmailVerificationToken{},
+		&entity.RecoveryCode{},
+		&entity.LoginChallenge{},
+		&entity.ExternalIdentity{},
+		&entity.OIDCLoginRequest{},
+		&ratelimit.CounterRecord{},
+		&session.Record{},
 	)
 }
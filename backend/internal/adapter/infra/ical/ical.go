@@ -0,0 +1,218 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	maxEvents      = 5000
+	maxEventDays   = 366
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+)
+
+// Event は VEVENT のうち休日カレンダーの取り込みに必要な項目だけを保持する。
+// End は DTEND と同じく排他的な終端で、未指定の場合は Start の翌日として扱う。
+type Event struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+	// startFloating / endFloating は DATE 値か TZID のない時刻で、どのタイムゾーンでも同じ日付を表すことを示す。
+	startFloating bool
+	endFloating   bool
+}
+
+// Dates はイベントが覆う loc での日付を YYYY-MM-DD で返す。
+// UTC (末尾 Z) と TZID 付きの時刻は loc に変換してから日付を取り、DATE 値と TZID のない時刻はそのままの日付を使う。
+func (e Event) Dates(loc *time.Location) []string {
+	start := localDate(e.Start, e.startFloating, loc)
+	end := start.AddDate(0, 0, 1)
+	if !e.End.IsZero() {
+		local := e.End
+		if !e.endFloating {
+			local = e.End.In(loc)
+		}
+		candidate := localDate(e.End, e.endFloating, loc)
+		// 時刻付きの DTEND は終了日も含むため、日付の切り捨て後に 1 日進める。
+		if local.Hour() != 0 || local.Minute() != 0 || local.Second() != 0 {
+			candidate = candidate.AddDate(0, 0, 1)
+		}
+		if candidate.After(start) {
+			end = candidate
+		}
+	}
+	var dates []string
+	for d := start; d.Before(end) && len(dates) < maxEventDays; d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	return dates
+}
+
+// localDate は t の loc での日付を、日付の計算に使う UTC の 0 時で返す。
+func localDate(t time.Time, floating bool, loc *time.Location) time.Time {
+	if !floating {
+		t = t.In(loc)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Parse は iCalendar (RFC 5545) から VEVENT を読み出す。RRULE などの繰り返しは展開しない。
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		events     []Event
+		current    *Event
+		inCalendar bool
+	)
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		name, params, value, ok := splitLine(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: malformed content line", i+1)
+		}
+		switch name {
+		case "BEGIN":
+			switch strings.ToUpper(value) {
+			case "VCALENDAR":
+				inCalendar = true
+			case "VEVENT":
+				if !inCalendar {
+					return nil, fmt.Errorf("line %d: VEVENT outside VCALENDAR", i+1)
+				}
+				current = &Event{}
+			}
+		case "END":
+			if strings.ToUpper(value) == "VEVENT" && current != nil {
+				if current.Start.IsZero() {
+					return nil, fmt.Errorf("line %d: VEVENT without DTSTART", i+1)
+				}
+				events = append(events, *current)
+				if len(events) > maxEvents {
+					return nil, errors.New("too many events")
+				}
+				current = nil
+			}
+		case "UID":
+			if current != nil {
+				current.UID = value
+			}
+		case "SUMMARY":
+			if current != nil {
+				current.Summary = unescapeText(value)
+			}
+		case "DTSTART", "DTEND":
+			if current == nil {
+				continue
+			}
+			t, floating, err := parseDateValue(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			if name == "DTSTART" {
+				current.Start, current.startFloating = t, floating
+			} else {
+				current.End, current.endFloating = t, floating
+			}
+		}
+	}
+	if !inCalendar {
+		return nil, errors.New("missing VCALENDAR")
+	}
+	return events, nil
+}
+
+// unfold は 75 オクテットで折り返された行を連結する。
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// splitLine は "NAME;PARAM=VALUE:value" を分解する。引用符内の区切り文字は無視する。
+func splitLine(line string) (string, map[string]string, string, bool) {
+	inQuote := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuote = !inQuote
+		}
+		if r == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return "", nil, "", false
+	}
+	head := line[:colon]
+	value := line[colon+1:]
+	parts := strings.Split(head, ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		key, val, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, value, true
+}
+
+// parseDateValue は DATE / DATE-TIME 値を読む。floating は DATE 値か TZID のないローカル時刻であることを表す。
+func parseDateValue(value string, params map[string]string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		t, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, false, errors.New("invalid DATE value")
+		}
+		return t, true, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeLayout, strings.TrimSuffix(value, "Z"))
+		if err != nil {
+			return time.Time{}, false, errors.New("invalid DATE-TIME value")
+		}
+		return t, false, nil
+	}
+	// Outlook の "Tokyo Standard Time" など読み込めない TZID は、TZID がない場合と同じくそのままの時刻として扱う。
+	loc, floating := time.UTC, true
+	if tzid := params["TZID"]; tzid != "" {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			loc, floating = loaded, false
+		}
+	}
+	t, err := time.ParseInLocation(dateTimeLayout, value, loc)
+	if err != nil {
+		return time.Time{}, false, errors.New("invalid DATE-TIME value")
+	}
+	return t, floating, nil
+}
+
+func unescapeText(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(value)
+}
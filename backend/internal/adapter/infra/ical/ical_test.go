@@ -0,0 +1,94 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_AllDayAndMultiDayEvents(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:newyear@example.com",
+		"DTSTART;VALUE=DATE:20240101",
+		"DTEND;VALUE=DATE:20240102",
+		"SUMMARY:New Year\\, Day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:golden-week@example.com",
+		"DTSTART;VALUE=DATE:20240503",
+		"DTEND;VALUE=DATE:20240506",
+		"SUMMARY:Golden",
+		"  Week",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:meeting@example.com",
+		"DTSTART;TZID=\"Asia/Tokyo\":20240610T090000",
+		"SUMMARY:Offsite",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, "New Year, Day", events[0].Summary)
+	require.Equal(t, []string{"2024-01-01"}, events[0].Dates(time.UTC))
+	require.Equal(t, "Golden Week", events[1].Summary)
+	require.Equal(t, []string{"2024-05-03", "2024-05-04", "2024-05-05"}, events[1].Dates(time.UTC))
+	require.Equal(t, []string{"2024-06-10"}, events[2].Dates(time.UTC))
+}
+
+func TestEvent_DatesConvertsTimedEventsToLocation(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:utc@example.com",
+		"DTSTART:20250101T150000Z",
+		"DTEND:20250102T150000Z",
+		"SUMMARY:New Year (JST)",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:tzid@example.com",
+		"DTSTART;TZID=America/Los_Angeles:20250101T200000",
+		"SUMMARY:Evening",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:floating@example.com",
+		"DTSTART:20250101T230000",
+		"DTEND:20250102T000000",
+		"SUMMARY:Floating",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:date@example.com",
+		"DTSTART;VALUE=DATE:20250101",
+		"SUMMARY:All day",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	events, err := Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	// UTC の 15:00 は日本時間の翌日 0:00 なので、JST の終日イベントは 1/2 の 1 日だけになる。
+	require.Equal(t, []string{"2025-01-02"}, events[0].Dates(tokyo))
+	require.Equal(t, []string{"2025-01-01", "2025-01-02"}, events[0].Dates(time.UTC))
+	require.Equal(t, []string{"2025-01-02"}, events[1].Dates(tokyo))
+	// TZID のない時刻と DATE 値はタイムゾーンによらず同じ日付になる。
+	require.Equal(t, []string{"2025-01-01"}, events[2].Dates(tokyo))
+	require.Equal(t, []string{"2025-01-01"}, events[3].Dates(tokyo))
+}
+
+func TestParse_RejectsMissingCalendar(t *testing.T) {
+	_, err := Parse(strings.NewReader("BEGIN:VEVENT\nDTSTART:20240101\nEND:VEVENT\n"))
+	require.Error(t, err)
+
+	_, err = Parse(strings.NewReader("BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:No start\nEND:VEVENT\nEND:VCALENDAR\n"))
+	require.Error(t, err)
+}
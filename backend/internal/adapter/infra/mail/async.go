@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"chronome/internal/usecase/provider"
+)
+
+// asyncSendTimeout は 1 通の送信にかける上限時間。
+const asyncSendTimeout = 30 * time.Second
+
+// AsyncMailer は送信をバックグラウンドで行い、呼び出し元をすぐに返す。
+// メールアドレスの有無で応答時間が変わらないようにするため、パスワード再設定などの公開エンドポイントで使う。
+// 送信エラーは呼び出し元に返せないのでログに残す。
+type AsyncMailer struct {
+	next   provider.Mailer
+	logger *log.Logger
+	wg     sync.WaitGroup
+}
+
+func NewAsyncMailer(next provider.Mailer, logger *log.Logger) *AsyncMailer {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &AsyncMailer{next: next, logger: logger}
+}
+
+func (m *AsyncMailer) Send(ctx context.Context, msg provider.MailMessage) error {
+	// リクエストの context は応答後にキャンセルされるため、値だけ引き継いで切り離す。
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asyncSendTimeout)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+		if err := m.next.Send(sendCtx, msg); err != nil {
+			m.logger.Printf("mail delivery failed to=%s subject=%q: %v", msg.To, msg.Subject, err)
+		}
+	}()
+	return nil
+}
+
+// Wait は送信中のメールがすべて終わるまで待つ。シャットダウン時に使う。
+func (m *AsyncMailer) Wait() {
+	m.wg.Wait()
+}
+
+var _ provider.Mailer = (*AsyncMailer)(nil)
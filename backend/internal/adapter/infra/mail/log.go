@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"chronome/internal/usecase/provider"
+)
+
+// LogMailer はローカル開発向けに、メールを送らずファイルまたはログへ書き出す。
+// dir を指定するとメールごとに .eml ファイルを作り、未指定なら本文ごとログへ出す。
+// 本文にはリセット用トークンなどが含まれるため、本番では使わない。
+type LogMailer struct {
+	dir    string
+	from   string
+	logger *log.Logger
+	now    func() time.Time
+}
+
+func NewLogMailer(dir, from string, logger *log.Logger) *LogMailer {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &LogMailer{dir: dir, from: from, logger: logger, now: time.Now}
+}
+
+func (m *LogMailer) Send(_ context.Context, msg provider.MailMessage) error {
+	raw, err := buildMessage(m.from, msg, m.now())
+	if err != nil {
+		return err
+	}
+	if m.dir == "" {
+		m.logger.Printf("mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := m.now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix) + ".eml"
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		return err
+	}
+	m.logger.Printf("mail to=%s subject=%q written to %s", msg.To, msg.Subject, path)
+	return nil
+}
+
+var _ provider.Mailer = (*LogMailer)(nil)
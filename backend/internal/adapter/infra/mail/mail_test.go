@@ -0,0 +1,153 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"log"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/usecase/provider"
+)
+
+// fakeSMTPSession はテスト用 SMTP サーバーが受け取った内容を保持する。
+type fakeSMTPSession struct {
+	auth string
+	from string
+	rcpt []string
+	data string
+}
+
+// startFakeSMTPServer は 1 接続だけ受け付ける最小限の SMTP サーバーを起動する。STARTTLS は提供しない。
+func startFakeSMTPServer(t *testing.T) (string, int, <-chan fakeSMTPSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	done := make(chan fakeSMTPSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		tp := textproto.NewConn(conn)
+		var session fakeSMTPSession
+		_ = tp.PrintfLine("220 fake.local ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				_ = tp.PrintfLine("250-fake.local")
+				_ = tp.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				_, payload, _ := strings.Cut(arg, " ")
+				decoded, _ := base64.StdEncoding.DecodeString(payload)
+				session.auth = string(decoded)
+				_ = tp.PrintfLine("235 ok")
+			case "MAIL":
+				session.from = arg
+				_ = tp.PrintfLine("250 ok")
+			case "RCPT":
+				session.rcpt = append(session.rcpt, arg)
+				_ = tp.PrintfLine("250 ok")
+			case "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				session.data = string(data)
+				_ = tp.PrintfLine("250 queued")
+			case "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				done <- session
+				return
+			default:
+				_ = tp.PrintfLine("502 unsupported")
+			}
+		}
+	}()
+	host, portRaw, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portRaw)
+	require.NoError(t, err)
+	return host, port, done
+}
+
+func TestSMTPMailer_DeliversToLocalServer(t *testing.T) {
+	host, port, sessions := startFakeSMTPServer(t)
+	mailer := NewSMTPMailer(host, port, "mailer", "s3cret", "ChronoMe <no-reply@example.com>")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := mailer.Send(ctx, provider.MailMessage{
+		To:      "user@example.com",
+		Subject: "パスワード再設定",
+		Body:    "以下のリンクから再設定してください。\nhttps://example.com/reset?token=abc",
+	})
+	require.NoError(t, err)
+
+	session := <-sessions
+	require.Equal(t, "\x00mailer\x00s3cret", session.auth)
+	require.Equal(t, "FROM:<no-reply@example.com>", session.from)
+	require.Equal(t, []string{"TO:<user@example.com>"}, session.rcpt)
+	require.Contains(t, session.data, "To: user@example.com")
+	require.Contains(t, session.data, "Subject: =?utf-8?q?")
+
+	_, body, found := strings.Cut(session.data, "\n\n")
+	require.True(t, found)
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+	require.NoError(t, err)
+	require.Contains(t, string(decoded), "https://example.com/reset?token=abc")
+}
+
+func TestLogMailer_WritesMessageFiles(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewLogMailer(dir, "ChronoMe <no-reply@localhost>", log.New(io.Discard, "", 0))
+
+	err := mailer.Send(context.Background(), provider.MailMessage{To: "user@example.com", Subject: "hello", Body: "token=abc"})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	raw, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "To: user@example.com\r\n")
+	require.Contains(t, string(raw), "token=3Dabc")
+}
+
+func TestBuildMessage_RejectsHeaderInjection(t *testing.T) {
+	_, err := buildMessage("no-reply@example.com", provider.MailMessage{To: "user@example.com\r\nBcc: victim@example.com", Subject: "x"}, time.Now())
+	require.Error(t, err)
+	_, err = buildMessage("no-reply@example.com", provider.MailMessage{To: "user@example.com", Subject: "x\nBcc: victim@example.com"}, time.Now())
+	require.Error(t, err)
+}
+
+func TestAsyncMailer_DeliversInBackground(t *testing.T) {
+	var buf strings.Builder
+	next := NewLogMailer("", "no-reply@example.com", log.New(&buf, "", 0))
+	mailer := NewAsyncMailer(next, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, mailer.Send(ctx, provider.MailMessage{To: "user@example.com", Subject: "hi", Body: "body"}))
+	// 呼び出し元の context がキャンセルされても送信は続く。
+	cancel()
+	mailer.Wait()
+	require.Contains(t, buf.String(), "to=user@example.com")
+}
@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"chronome/internal/usecase/provider"
+)
+
+// buildMessage は UTF-8 のテキストメールを RFC 5322 形式に組み立てる。
+// ヘッダーへの改行混入はヘッダーインジェクションになるため拒否する。
+func buildMessage(from string, msg provider.MailMessage, at time.Time) ([]byte, error) {
+	if strings.ContainsAny(from+msg.To+msg.Subject, "\r\n") {
+		return nil, errors.New("mail headers must not contain line breaks")
+	}
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, errors.New("invalid recipient address")
+	}
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + msg.To + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + at.Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// envelopeAddress は From ヘッダー ("Name <addr>") から SMTP の MAIL FROM に使うアドレスを取り出す。
+func envelopeAddress(from string) (string, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return "", errors.New("invalid sender address")
+	}
+	return addr.Address, nil
+}
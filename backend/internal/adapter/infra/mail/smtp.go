@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"chronome/internal/usecase/provider"
+)
+
+// SMTPMailer は SMTP サーバー経由でメールを送る。
+// サーバーが STARTTLS を提供する場合は必ず TLS に切り替え、認証情報があれば AUTH PLAIN を使う。
+type SMTPMailer struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+	now      func() time.Time
+	// tlsConfig はテストで自己署名証明書を受け入れるために差し替える。
+	tlsConfig *tls.Config
+}
+
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr:      net.JoinHostPort(host, strconv.Itoa(port)),
+		host:      host,
+		from:      from,
+		username:  username,
+		password:  password,
+		now:       time.Now,
+		tlsConfig: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12},
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg provider.MailMessage) error {
+	raw, err := buildMessage(m.from, msg, m.now())
+	if err != nil {
+		return err
+	}
+	sender, err := envelopeAddress(m.from)
+	if err != nil {
+		return err
+	}
+	recipient, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return errors.New("invalid recipient address")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	// net/smtp は context を受け取らないので、期限は接続のデッドラインとして渡す。
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(m.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(sender); err != nil {
+		return err
+	}
+	if err := client.Rcpt(recipient.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+var _ provider.Mailer = (*SMTPMailer)(nil)
@@ -0,0 +1,205 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"chronome/internal/usecase/provider"
+)
+
+const (
+	// metadataTTL ごとにディスカバリー文書を取り直し、ID プロバイダー側のエンドポイントの変更に追従する。
+	metadataTTL = 24 * time.Hour
+	// maxResponseBytes は ID プロバイダーの応答として読む上限。
+	maxResponseBytes = 1 << 20
+)
+
+// Options は ID プロバイダーに登録したクライアントの設定。
+type Options struct {
+	// Issuer はディスカバリー文書の issuer と ID トークンの iss に一致する必要がある。
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes に openid がなければ先頭に加える。
+	Scopes []string
+	// HTTPClient を省略すると 10 秒でタイムアウトするクライアントを使う。
+	HTTPClient *http.Client
+}
+
+// Client は OpenID Connect の認可コードフローを PKCE 付きで実行し、ID トークンを検証する。
+// ディスカバリー文書と JWKS は初回の利用時に取得してキャッシュするので、起動時に ID プロバイダーへ接続しない。
+type Client struct {
+	opts Options
+	http *http.Client
+	now  func() time.Time
+
+	mu        sync.Mutex
+	metadata  *metadata
+	fetchedAt time.Time
+	keys      keySet
+}
+
+// metadata はディスカバリー文書のうち、ログインに使う項目。
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+func NewClient(opts Options) (*Client, error) {
+	opts.Issuer = strings.TrimSpace(opts.Issuer)
+	if opts.Issuer == "" || opts.ClientID == "" || opts.RedirectURL == "" {
+		return nil, errors.New("oidc issuer, client id and redirect url are required")
+	}
+	if !slices.Contains(opts.Scopes, "openid") {
+		opts.Scopes = append([]string{"openid"}, opts.Scopes...)
+	}
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{opts: opts, http: httpClient, now: time.Now}, nil
+}
+
+// AuthCodeURL は認可エンドポイントへのリダイレクト先を返す。
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	endpoint, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	// 認可エンドポイントがクエリを持っている場合もあるので、置き換えずに足す。
+	query := endpoint.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.opts.ClientID)
+	query.Set("redirect_uri", c.opts.RedirectURL)
+	query.Set("scope", strings.Join(c.opts.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String(), nil
+}
+
+// Exchange は認可コードを ID トークンと交換し、検証済みの claims を返す。
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*provider.IdentityClaims, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.opts.RedirectURL},
+		"code_verifier": {codeVerifier},
+		"client_id":     {c.opts.ClientID},
+	}
+	// client_secret_basic が仕様上の既定。ID プロバイダーが post だけを挙げている場合はフォームで送る。
+	basic := c.opts.ClientSecret != "" && (len(meta.TokenAuthMethods) == 0 || slices.Contains(meta.TokenAuthMethods, "client_secret_basic"))
+	if c.opts.ClientSecret != "" && !basic {
+		form.Set("client_secret", c.opts.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basic {
+		req.SetBasicAuth(url.QueryEscape(c.opts.ClientID), url.QueryEscape(c.opts.ClientSecret))
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := c.doJSON(req, &token)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		if token.Error != "" {
+			return nil, fmt.Errorf("token endpoint returned %s: %s", token.Error, token.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token endpoint returned status %d", status)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	return c.verifyIDToken(ctx, token.IDToken, nonce)
+}
+
+// discover はキャッシュしたディスカバリー文書を返し、期限が切れていれば取り直す。
+func (c *Client) discover(ctx context.Context) (*metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.metadata != nil && c.now().Sub(c.fetchedAt) < metadataTTL {
+		return c.metadata, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.opts.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta metadata
+	status, err := c.doJSON(req, &meta)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery returned status %d", status)
+	}
+	// 別の発行者の文書を掴まされないよう、issuer は設定と完全に一致させる。
+	if meta.Issuer != c.opts.Issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", meta.Issuer, c.opts.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+	c.metadata = &meta
+	c.fetchedAt = c.now()
+	return c.metadata, nil
+}
+
+// doJSON はリクエストを送り、応答のステータスにかかわらず JSON の本文を out に読み込む。
+func (c *Client) doJSON(req *http.Request, out any) (int, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(body, out); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("invalid response from %s: %w", req.URL.Host, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// codeChallenge は PKCE の S256 方式で code_verifier から code_challenge を作る。
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+var _ provider.IdentityProvider = (*Client)(nil)
@@ -0,0 +1,186 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"chronome/test/fakes"
+)
+
+func newClientForTest(t *testing.T, idp *fakes.FakeOIDCProvider) *Client {
+	t.Helper()
+	client, err := NewClient(Options{
+		Issuer:       idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  "https://chronome.example/api/auth/oidc/callback",
+		Scopes:       []string{"email", "profile"},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+// authorize は認可 URL を開いて、コールバックに渡された認可コードを返す。
+func authorize(t *testing.T, idp *fakes.FakeOIDCProvider, client *Client, nonce, verifier string) string {
+	t.Helper()
+	authURL, err := client.AuthCodeURL(context.Background(), "state-1", nonce, verifier)
+	require.NoError(t, err)
+	callback, err := idp.Authorize(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "chronome.example", callback.Host)
+	require.Equal(t, "state-1", callback.Query().Get("state"))
+	require.NotEmpty(t, callback.Query().Get("code"))
+	return callback.Query().Get("code")
+}
+
+func TestClient_ExchangeReturnsVerifiedClaims(t *testing.T) {
+	idp := fakes.NewFakeOIDCProvider(t)
+	idp.SignIn(&fakes.OIDCIdentity{Subject: "user-1", Email: "Alice@Example.com", EmailVerified: true, Name: "Alice"})
+	client := newClientForTest(t, idp)
+
+	code := authorize(t, idp, client, "nonce-1", "verifier-0123456789-0123456789-0123456789")
+	claims, err := client.Exchange(context.Background(), code, "verifier-0123456789-0123456789-0123456789", "nonce-1")
+	require.NoError(t, err)
+	require.Equal(t, idp.Issuer(), claims.Issuer)
+	require.Equal(t, "user-1", claims.Subject)
+	require.Equal(t, "Alice@Example.com", claims.Email)
+	require.True(t, claims.EmailVerified)
+	require.Equal(t, "Alice", claims.Name)
+
+	// 認可コードは 1 回だけ使える。
+	_, err = client.Exchange(context.Background(), code, "verifier-0123456789-0123456789-0123456789", "nonce-1")
+	require.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestClient_ExchangeRejectsInvalidTokens(t *testing.T) {
+	const verifier = "verifier-0123456789-0123456789-0123456789"
+	cases := []struct {
+		name     string
+		setup    func(t *testing.T, idp *fakes.FakeOIDCProvider)
+		verifier string
+		nonce    string
+		want     string
+	}{
+		{name: "pkce verifier", verifier: "another-verifier-0123456789-0123456789", want: "invalid_grant"},
+		{name: "nonce", nonce: "other-nonce", want: "nonce"},
+		{
+			name: "signature",
+			setup: func(t *testing.T, idp *fakes.FakeOIDCProvider) {
+				idp.ForgeSignatures(t, true)
+			},
+			want: "signature",
+		},
+		{
+			name: "audience",
+			setup: func(_ *testing.T, idp *fakes.FakeOIDCProvider) {
+				idp.ModifyClaims(func(claims map[string]any) { claims["aud"] = "other-client" })
+			},
+			want: "audience",
+		},
+		{
+			name: "authorized party",
+			setup: func(_ *testing.T, idp *fakes.FakeOIDCProvider) {
+				idp.ModifyClaims(func(claims map[string]any) { claims["aud"] = []string{idp.ClientID, "other-client"} })
+			},
+			want: "authorized party",
+		},
+		{
+			name: "issuer",
+			setup: func(_ *testing.T, idp *fakes.FakeOIDCProvider) {
+				idp.ModifyClaims(func(claims map[string]any) { claims["iss"] = "https://evil.example" })
+			},
+			want: "issuer",
+		},
+		{
+			name: "expired",
+			setup: func(_ *testing.T, idp *fakes.FakeOIDCProvider) {
+				idp.ModifyClaims(func(claims map[string]any) { claims["exp"] = time.Now().Add(-2 * time.Minute).Unix() })
+			},
+			want: "expired",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			idp := fakes.NewFakeOIDCProvider(t)
+			idp.SignIn(&fakes.OIDCIdentity{Subject: "user-1", Email: "alice@example.com", EmailVerified: true})
+			if tc.setup != nil {
+				tc.setup(t, idp)
+			}
+			client := newClientForTest(t, idp)
+			code := authorize(t, idp, client, "nonce-1", verifier)
+			exchangeVerifier, nonce := verifier, "nonce-1"
+			if tc.verifier != "" {
+				exchangeVerifier = tc.verifier
+			}
+			if tc.nonce != "" {
+				nonce = tc.nonce
+			}
+			_, err := client.Exchange(context.Background(), code, exchangeVerifier, nonce)
+			require.ErrorContains(t, err, tc.want)
+		})
+	}
+}
+
+func TestClient_RefetchesKeysAfterRotation(t *testing.T) {
+	const verifier = "verifier-0123456789-0123456789-0123456789"
+	idp := fakes.NewFakeOIDCProvider(t)
+	idp.SignIn(&fakes.OIDCIdentity{Subject: "user-1", Email: "alice@example.com", EmailVerified: true})
+	client := newClientForTest(t, idp)
+	now := time.Now()
+	client.now = func() time.Time { return now }
+
+	_, err := client.Exchange(context.Background(), authorize(t, idp, client, "n", verifier), verifier, "n")
+	require.NoError(t, err)
+
+	// 取得した直後は未知の kid でも JWKS を取り直さない。
+	idp.RotateKey(t)
+	_, err = client.Exchange(context.Background(), authorize(t, idp, client, "n", verifier), verifier, "n")
+	require.ErrorContains(t, err, "unknown signing key")
+
+	now = now.Add(minKeyRefreshInterval)
+	_, err = client.Exchange(context.Background(), authorize(t, idp, client, "n", verifier), verifier, "n")
+	require.NoError(t, err)
+}
+
+func TestClient_RejectsMismatchedDiscoveryIssuer(t *testing.T) {
+	idp := fakes.NewFakeOIDCProvider(t)
+	client, err := NewClient(Options{Issuer: idp.Issuer() + "/", ClientID: idp.ClientID, RedirectURL: "https://chronome.example/callback"})
+	require.NoError(t, err)
+
+	_, err = client.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	require.ErrorContains(t, err, "does not match")
+}
+
+func TestVerifySignature_ES256(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	raw, err := private.PublicKey.Bytes()
+	require.NoError(t, err)
+	jwk := jsonWebKey{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(raw[1:33]),
+		Y:   base64.RawURLEncoding.EncodeToString(raw[33:]),
+	}
+	key, err := jwk.publicKey()
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("header.payload"))
+	r, s, err := ecdsa.Sign(rand.Reader, private, digest[:])
+	require.NoError(t, err)
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	require.True(t, verifySignature("ES256", crypto.SHA256, key, "header.payload", signature))
+	require.False(t, verifySignature("ES256", crypto.SHA256, key, "header.tampered", signature))
+	require.False(t, verifySignature("RS256", crypto.SHA256, key, "header.payload", signature))
+	require.False(t, verifySignature("ES384", crypto.SHA384, key, "header.payload", signature))
+}
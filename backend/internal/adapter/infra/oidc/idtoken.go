@@ -0,0 +1,195 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"chronome/internal/usecase/provider"
+)
+
+// clockSkew は ID プロバイダーとの時計のずれとして exp と iat に許す幅。
+const clockSkew = time.Minute
+
+// signingAlgorithms は受け入れる署名アルゴリズムとハッシュ関数。none と共通鍵の HS* は受け入れない。
+var signingAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// ecdsaCurves は ES* のアルゴリズムごとに使う曲線の名前。
+var ecdsaCurves = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+}
+
+type idTokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type idTokenClaims struct {
+	Issuer          string       `json:"iss"`
+	Subject         string       `json:"sub"`
+	Audience        audience     `json:"aud"`
+	AuthorizedParty string       `json:"azp"`
+	Expiry          float64      `json:"exp"`
+	IssuedAt        float64      `json:"iat"`
+	Nonce           string       `json:"nonce"`
+	Email           string       `json:"email"`
+	EmailVerified   flexibleBool `json:"email_verified"`
+	Name            string       `json:"name"`
+}
+
+// audience は文字列 1 つか文字列の配列で表される aud を受け取る。
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// flexibleBool は真偽値を文字列の "true" で返す ID プロバイダーにも対応する。
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	var value bool
+	if err := json.Unmarshal(data, &value); err == nil {
+		*b = flexibleBool(value)
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*b = flexibleBool(text == "true")
+	return nil
+}
+
+// verifyIDToken は ID トークンの署名を JWKS の鍵で検証し、発行者・audience・有効期限・nonce を確かめてから claims を返す。
+func (c *Client) verifyIDToken(ctx context.Context, raw, nonce string) (*provider.IdentityClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id token")
+	}
+	var header idTokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed id token header: %w", err)
+	}
+	hash, ok := signingAlgorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported id token algorithm %q", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed id token signature")
+	}
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := c.publicKey(ctx, meta.JWKSURI, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.alg != "" && key.alg != header.Alg {
+		return nil, errors.New("id token algorithm does not match the signing key")
+	}
+	if !verifySignature(header.Alg, hash, key.key, parts[0]+"."+parts[1], signature) {
+		return nil, errors.New("invalid id token signature")
+	}
+
+	var claims idTokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed id token claims: %w", err)
+	}
+	now := c.now()
+	switch {
+	case claims.Issuer != c.opts.Issuer:
+		return nil, errors.New("id token issuer does not match")
+	case !slices.Contains(claims.Audience, c.opts.ClientID):
+		return nil, errors.New("id token audience does not match")
+	// aud が複数あるときは、このクライアントに向けて発行したことを azp で確かめる。
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != c.opts.ClientID:
+		return nil, errors.New("id token authorized party does not match")
+	case claims.Expiry == 0 || !now.Before(unixTime(claims.Expiry).Add(clockSkew)):
+		return nil, errors.New("id token has expired")
+	case claims.IssuedAt != 0 && unixTime(claims.IssuedAt).After(now.Add(clockSkew)):
+		return nil, errors.New("id token is issued in the future")
+	case nonce == "" || claims.Nonce != nonce:
+		return nil, errors.New("id token nonce does not match")
+	case claims.Subject == "":
+		return nil, errors.New("id token has no subject")
+	}
+	return &provider.IdentityClaims{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+// verifySignature は alg に合った種類の鍵でだけ署名を検証する。
+func verifySignature(alg string, hash crypto.Hash, key crypto.PublicKey, signingInput string, signature []byte) bool {
+	hasher := hash.New()
+	_, _ = hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		if curves[ecdsaCurves[alg]] != key.Curve {
+			return false
+		}
+		// JWS の ECDSA 署名は DER ではなく、固定長の r と s を連結した形。
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(key, digest, r, s)
+	default:
+		return false
+	}
+}
+
+func decodeSegment(segment string, out any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// unixTime は JWT の NumericDate（小数を含みうる秒）を time.Time にする。
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
@@ -0,0 +1,169 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// minKeyRefreshInterval より短い間隔では JWKS を取り直さない。未知の kid を付けたトークンで ID プロバイダーへの要求を増やされないようにする。
+	minKeyRefreshInterval = time.Minute
+	// minRSAKeyBits 未満の RSA 鍵は受け入れない。
+	minRSAKeyBits = 2048
+)
+
+// keySet は JWKS から読み込んだ署名検証用の公開鍵を kid ごとに保持する。
+type keySet struct {
+	mu        sync.Mutex
+	keys      map[string]publicKey
+	fetchedAt time.Time
+}
+
+// publicKey は JWK 1 つ分の公開鍵。alg が空なら鍵の種類に合うアルゴリズムをすべて受け入れる。
+type publicKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// jsonWebKey は JWKS の鍵のうち、RSA と EC の公開鍵に必要な項目。
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey は kid の鍵を返す。キャッシュにない場合は、鍵の入れ替えに追従するため JWKS を取り直してから探す。
+func (c *Client) publicKey(ctx context.Context, jwksURI, kid string) (publicKey, error) {
+	c.keys.mu.Lock()
+	defer c.keys.mu.Unlock()
+	if key, ok := c.keys.lookup(kid); ok {
+		return key, nil
+	}
+	if c.keys.keys != nil && c.now().Sub(c.keys.fetchedAt) < minKeyRefreshInterval {
+		return publicKey{}, fmt.Errorf("unknown signing key %q", kid)
+	}
+	keys, err := c.fetchKeys(ctx, jwksURI)
+	if err != nil {
+		return publicKey{}, err
+	}
+	c.keys.keys = keys
+	c.keys.fetchedAt = c.now()
+	if key, ok := c.keys.lookup(kid); ok {
+		return key, nil
+	}
+	return publicKey{}, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup は kid の鍵を返す。kid のないトークンは、鍵が 1 つだけの場合に限りその鍵で検証する。
+func (s *keySet) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (c *Client) fetchKeys(ctx context.Context, jwksURI string) (map[string]publicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	status, err := c.doJSON(req, &set)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("jwks returned status %d", status)
+	}
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// 暗号化用の鍵と、扱えない種類の鍵は読み飛ばす。
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = publicKey{alg: jwk.Alg, key: key}
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if n.BitLen() < minRSAKeyBits || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("unsupported rsa key")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid ec key")
+		}
+		// 非圧縮形式（0x04 || X || Y）にして、曲線上の点かどうかも確かめる。
+		point := append(append([]byte{4}, x...), y...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CounterRecord は GormStore がカウンタを保存する行。
+type CounterRecord struct {
+	Key       string    `gorm:"column:counter_key;primaryKey;size:255"`
+	Count     int       `gorm:"column:hits;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+func (CounterRecord) TableName() string {
+	return "rate_limit_counters"
+}
+
+// GormStore はカウンタを DB に保存し、複数インスタンスで制限を共有する。
+type GormStore struct {
+	db        *gorm.DB
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Incr(ctx context.Context, key string, window time.Duration, now time.Time) (Counter, error) {
+	s.sweep(ctx, now)
+	var record CounterRecord
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1 文の upsert で数え、同時リクエストが同じ key の行を作り合っても数え漏らさない。
+		// 期限内の行は件数だけを増やし、期限切れの行だけを 1 件目として数え直す。
+		fresh := CounterRecord{Key: key, Count: 1, ExpiresAt: now.Add(window)}
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "counter_key"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"hits":       gorm.Expr("CASE WHEN rate_limit_counters.expires_at > ? THEN rate_limit_counters.hits + 1 ELSE 1 END", now),
+				"expires_at": gorm.Expr("CASE WHEN rate_limit_counters.expires_at > ? THEN rate_limit_counters.expires_at ELSE excluded.expires_at END", now),
+			}),
+		}).Create(&fresh).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("counter_key = ?", key).First(&record).Error
+	})
+	if err != nil {
+		return Counter{}, err
+	}
+	return Counter{Count: record.Count, ExpiresAt: record.ExpiresAt}, nil
+}
+
+func (s *GormStore) Get(ctx context.Context, key string, now time.Time) (Counter, bool, error) {
+	var records []CounterRecord
+	err := s.db.WithContext(ctx).
+		Where("counter_key = ? AND expires_at > ?", key, now).
+		Limit(1).
+		Find(&records).Error
+	if err != nil || len(records) == 0 {
+		return Counter{}, false, err
+	}
+	return Counter{Count: records[0].Count, ExpiresAt: records[0].ExpiresAt}, true, nil
+}
+
+func (s *GormStore) Set(ctx context.Context, key string, counter Counter) error {
+	record := CounterRecord{Key: key, Count: counter.Count, ExpiresAt: counter.ExpiresAt}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "counter_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hits", "expires_at"}),
+	}).Create(&record).Error
+}
+
+func (s *GormStore) Delete(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("counter_key = ?", key).Delete(&CounterRecord{}).Error
+}
+
+// sweep は sweepInterval に 1 回だけ期限切れの行を消す。失敗しても制限には影響しないので無視する。
+func (s *GormStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+	_ = s.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&CounterRecord{}).Error
+}
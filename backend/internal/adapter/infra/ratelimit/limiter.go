@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Counter は key ごとの件数と、その件数を数え直すまでの期限。
+type Counter struct {
+	Count     int
+	ExpiresAt time.Time
+}
+
+// Store はカウンタの保存先。複数インスタンスで制限を共有する場合は DB の実装を使う。
+type Store interface {
+	// Incr は key の件数を 1 増やして返す。期限切れか未作成なら 1 から数え、期限を now+window にする。
+	Incr(ctx context.Context, key string, window time.Duration, now time.Time) (Counter, error)
+	// Get は期限内のカウンタを返す。なければ false を返す。
+	Get(ctx context.Context, key string, now time.Time) (Counter, bool, error)
+	// Set は key のカウンタを上書きする。
+	Set(ctx context.Context, key string, counter Counter) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Policy は window ごとに許すリクエスト数。Requests が 0 なら制限しない。
+type Policy struct {
+	Requests int
+	Window   time.Duration
+}
+
+// LockoutPolicy は失敗が続いたときの締め出し方。
+// Window 内の失敗が MaxFailures に達すると BaseLockout だけ締め出し、以降は失敗するたびに倍にする（MaxLockout が上限）。
+// 失敗回数は最初の失敗から Window の間数えるので、Window は MaxLockout より長くしておく。
+// MaxFailures が 0 なら締め出さない。
+type LockoutPolicy struct {
+	MaxFailures int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// Limiter は Store の上でリクエスト数の制限と、失敗による段階的な締め出しを扱う。
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{
+		store: store,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// Allow は key のリクエストを 1 件数え、上限を超えた場合は再試行まで待つべき時間を返す。0 なら許可する。
+func (l *Limiter) Allow(ctx context.Context, key string, policy Policy) (time.Duration, error) {
+	if policy.Requests <= 0 {
+		return 0, nil
+	}
+	now := l.now()
+	counter, err := l.store.Incr(ctx, "rate:"+key, policy.Window, now)
+	if err != nil {
+		return 0, err
+	}
+	if counter.Count > policy.Requests {
+		return counter.ExpiresAt.Sub(now), nil
+	}
+	return 0, nil
+}
+
+// Locked は key が締め出し中なら解除までの時間を返す。0 なら締め出していない。
+func (l *Limiter) Locked(ctx context.Context, key string) (time.Duration, error) {
+	now := l.now()
+	lock, ok, err := l.store.Get(ctx, "lock:"+key, now)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return lock.ExpiresAt.Sub(now), nil
+}
+
+// Fail は key の失敗を 1 件数え、上限に達していれば締め出す。締め出した場合は解除までの時間を返す。
+func (l *Limiter) Fail(ctx context.Context, key string, policy LockoutPolicy) (time.Duration, error) {
+	if policy.MaxFailures <= 0 {
+		return 0, nil
+	}
+	now := l.now()
+	failures, err := l.store.Incr(ctx, "fail:"+key, policy.Window, now)
+	if err != nil {
+		return 0, err
+	}
+	if failures.Count < policy.MaxFailures {
+		return 0, nil
+	}
+	lockout := policy.lockout(failures.Count - policy.MaxFailures)
+	if err := l.store.Set(ctx, "lock:"+key, Counter{Count: failures.Count, ExpiresAt: now.Add(lockout)}); err != nil {
+		return 0, err
+	}
+	return lockout, nil
+}
+
+// Succeed は key の失敗回数と締め出しを消す。ログインに成功したアカウントに使う。
+func (l *Limiter) Succeed(ctx context.Context, key string) error {
+	if err := l.store.Delete(ctx, "fail:"+key); err != nil {
+		return err
+	}
+	return l.store.Delete(ctx, "lock:"+key)
+}
+
+// lockout は上限を超えた回数 extra に応じた締め出し時間を返す。MaxLockout が BaseLockout より短い場合は BaseLockout で止める。
+func (p LockoutPolicy) lockout(extra int) time.Duration {
+	ceiling := max(p.MaxLockout, p.BaseLockout)
+	lockout := p.BaseLockout
+	for range extra {
+		if lockout >= ceiling {
+			break
+		}
+		lockout *= 2
+	}
+	return min(lockout, ceiling)
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newGormStoreForTest(t *testing.T) *GormStore {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&CounterRecord{}))
+	return NewGormStore(db)
+}
+
+// 同じ振る舞いをメモリと DB の両方の保存先で確かめる。
+func storesForTest(t *testing.T) map[string]Store {
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"gorm":   newGormStoreForTest(t),
+	}
+}
+
+func TestLimiter_AllowLimitsRequestsPerWindow(t *testing.T) {
+	for name, store := range storesForTest(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+			now := start
+			limiter := NewLimiter(store)
+			limiter.now = func() time.Time { return now }
+			policy := Policy{Requests: 2, Window: time.Minute}
+
+			for range 2 {
+				wait, err := limiter.Allow(ctx, "login:ip:192.0.2.1", policy)
+				require.NoError(t, err)
+				require.Zero(t, wait)
+			}
+			now = start.Add(15 * time.Second)
+			wait, err := limiter.Allow(ctx, "login:ip:192.0.2.1", policy)
+			require.NoError(t, err)
+			require.Equal(t, 45*time.Second, wait)
+
+			// 別の key は別々に数える。
+			wait, err = limiter.Allow(ctx, "login:ip:192.0.2.2", policy)
+			require.NoError(t, err)
+			require.Zero(t, wait)
+
+			now = start.Add(time.Minute)
+			wait, err = limiter.Allow(ctx, "login:ip:192.0.2.1", policy)
+			require.NoError(t, err)
+			require.Zero(t, wait)
+		})
+	}
+}
+
+func TestLimiter_FailLocksOutProgressively(t *testing.T) {
+	for name, store := range storesForTest(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+			limiter := NewLimiter(store)
+			limiter.now = func() time.Time { return now }
+			policy := LockoutPolicy{MaxFailures: 3, Window: 24 * time.Hour, BaseLockout: time.Minute, MaxLockout: 3 * time.Minute}
+
+			var lockouts []time.Duration
+			for range 5 {
+				lockout, err := limiter.Fail(ctx, "account:user@example.com", policy)
+				require.NoError(t, err)
+				lockouts = append(lockouts, lockout)
+			}
+			require.Equal(t, []time.Duration{0, 0, time.Minute, 2 * time.Minute, 3 * time.Minute}, lockouts)
+
+			wait, err := limiter.Locked(ctx, "account:user@example.com")
+			require.NoError(t, err)
+			require.Equal(t, 3*time.Minute, wait)
+			now = now.Add(3 * time.Minute)
+			wait, err = limiter.Locked(ctx, "account:user@example.com")
+			require.NoError(t, err)
+			require.Zero(t, wait)
+
+			// 締め出しが明けても失敗回数は残り、次の失敗で再び締め出す。
+			lockout, err := limiter.Fail(ctx, "account:user@example.com", policy)
+			require.NoError(t, err)
+			require.Equal(t, 3*time.Minute, lockout)
+
+			// 成功すると数え直すので、次の失敗ではすぐには締め出さない。
+			require.NoError(t, limiter.Succeed(ctx, "account:user@example.com"))
+			wait, err = limiter.Locked(ctx, "account:user@example.com")
+			require.NoError(t, err)
+			require.Zero(t, wait)
+			lockout, err = limiter.Fail(ctx, "account:user@example.com", policy)
+			require.NoError(t, err)
+			require.Zero(t, lockout)
+		})
+	}
+}
+
+func TestGormStore_IncrCountsExistingRowsUntilExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := newGormStoreForTest(t)
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	// 別のインスタンスが先に行を作っていても、期限内なら件数を増やして期限は動かさない。
+	require.NoError(t, store.Set(ctx, "signup:ip:192.0.2.1", Counter{Count: 3, ExpiresAt: start.Add(time.Minute)}))
+	counter, err := store.Incr(ctx, "signup:ip:192.0.2.1", time.Hour, start.Add(10*time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 4, counter.Count)
+	require.True(t, counter.ExpiresAt.Equal(start.Add(time.Minute)))
+
+	// 期限切れの行だけを数え直す。
+	counter, err = store.Incr(ctx, "signup:ip:192.0.2.1", time.Hour, start.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, counter.Count)
+	require.True(t, counter.ExpiresAt.Equal(start.Add(time.Minute+time.Hour)))
+}
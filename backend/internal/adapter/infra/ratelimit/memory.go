@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval ごとに期限切れのカウンタを掃除し、アクセス元が増えてもメモリを使い続けないようにする。
+const sweepInterval = time.Minute
+
+// MemoryStore はプロセス内にカウンタを保持する。インスタンスが 1 つの場合とローカル開発向け。
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]Counter
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]Counter)}
+}
+
+func (s *MemoryStore) Incr(_ context.Context, key string, window time.Duration, now time.Time) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	counter, ok := s.counters[key]
+	if !ok || !now.Before(counter.ExpiresAt) {
+		counter = Counter{ExpiresAt: now.Add(window)}
+	}
+	counter.Count++
+	s.counters[key] = counter
+	return counter, nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string, now time.Time) (Counter, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter, ok := s.counters[key]
+	if !ok || !now.Before(counter.ExpiresAt) {
+		return Counter{}, false, nil
+	}
+	return counter, true, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, counter Counter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters[key] = counter
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counters, key)
+	return nil
+}
+
+// sweep は呼び出し元でロックを取った状態で呼ぶ。
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, counter := range s.counters {
+		if !now.Before(counter.ExpiresAt) {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -1,10 +1,8 @@
 package session
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
+	"context"
 	"encoding/base64"
-	"encoding/hex"
 	"errors"
 	"strconv"
 	"strings"
@@ -15,124 +13,249 @@ import (
 )
 
 // SignedCookieStore は HMAC でセッション情報をクッキーにエンコードする。
+// Cookie には一覧の ID を含め、失効は Registry で確かめる。DB の Registry なら失効を全インスタンスで共有できる。
+// Cookie の値は "ユーザー ID|期限|発行時刻|一覧の ID|鍵 ID|署名" を base64 にしたもの。
 type SignedCookieStore struct {
-	secret  []byte
-	now     func() time.Time
-	mu      sync.Mutex
+	keys     keyring
+	now      func() time.Time
+	registry Registry
+	mu       sync.Mutex
+	// revoked と userCutoffs は一覧の ID を持たない旧形式の Cookie の失効で、プロセス内にだけ保持する。
 	revoked map[string]int64
+	// userCutoffs はユーザー単位の失効で、これより前に作成されたセッションを拒否する。
+	userCutoffs map[uuid.UUID]userCutoff
+	// maxTTL はこれまでに発行・検証したセッションの最長 TTL。ユーザー単位の失効をいつまで保持するかに使う。
+	// 再起動直後でも他インスタンスが発行したセッションを検証した時点で更新される。
+	maxTTL time.Duration
+}
+
+type userCutoff struct {
+	issuedBefore int64
+	keepUntil    int64
+}
+
+// cookieSession はセッション ID から取り出した署名済みの値。
+// issuedAt は RevokeUser 導入前に、recordID は一覧の導入前に、keyID は鍵の入れ替え導入前に発行したセッションではゼロ値になる。
+// issuedAt は Cookie を発行した時刻で、延長するたびに更新される。
+type cookieSession struct {
+	userID    uuid.UUID
+	expiresAt int64
+	issuedAt  int64
+	recordID  uuid.UUID
+	keyID     string
 }
 
 // NewSignedCookieStore はマルチインスタンス運用向けのストアを返す。
-func NewSignedCookieStore(secret string) (*SignedCookieStore, error) {
-	secret = strings.TrimSpace(secret)
-	if secret == "" {
+// secret で署名し、previous は入れ替え前のシークレットとして検証にだけ使う。
+func NewSignedCookieStore(secret string, registry Registry, previous ...string) (*SignedCookieStore, error) {
+	keys, err := newKeyring(secret, previous)
+	if err != nil {
 		return nil, errors.New("session secret is required")
 	}
+	if registry == nil {
+		return nil, errors.New("session registry is required")
+	}
 	return &SignedCookieStore{
-		secret: []byte(secret),
+		keys: keys,
 		now: func() time.Time {
 			return time.Now().UTC()
 		},
-		revoked: make(map[string]int64),
+		registry:    registry,
+		revoked:     make(map[string]int64),
+		userCutoffs: make(map[uuid.UUID]userCutoff),
 	}, nil
 }
 
-func (s *SignedCookieStore) Create(userID uuid.UUID, ttl time.Duration) (string, error) {
+func (s *SignedCookieStore) Create(ctx context.Context, userID uuid.UUID, ttl time.Duration, client Client) (string, error) {
 	if userID == uuid.Nil {
 		return "", errors.New("user id is required")
 	}
-	expiresAt := s.now().Add(ttl).Unix()
-	payload := userID.String() + "|" + strconv.FormatInt(expiresAt, 10)
-	sig := s.sign(payload)
-	token := payload + "|" + sig
-	sessionID := base64.RawURLEncoding.EncodeToString([]byte(token))
+	now := s.now()
+	record := newRecord(userID, now, ttl, client)
+	if err := s.registry.Create(ctx, record); err != nil {
+		return "", err
+	}
+	sessionID := s.encode(userID, record.ID, record.ExpiresAt, now)
 	s.mu.Lock()
+	if ttl > s.maxTTL {
+		s.maxTTL = ttl
+	}
 	s.cleanupExpiredLocked()
 	s.mu.Unlock()
 	return sessionID, nil
 }
 
-func (s *SignedCookieStore) Get(sessionID string) (uuid.UUID, bool) {
-	raw, err := base64.RawURLEncoding.DecodeString(sessionID)
-	if err != nil {
-		return uuid.Nil, false
-	}
-	parts := strings.Split(string(raw), "|")
-	if len(parts) != 3 {
-		return uuid.Nil, false
-	}
-	payload := strings.Join(parts[:2], "|")
-	if !s.verify(payload, parts[2]) {
-		return uuid.Nil, false
+func (s *SignedCookieStore) Get(ctx context.Context, sessionID string) (Info, bool) {
+	session, ok := s.parse(sessionID)
+	if !ok {
+		return Info{}, false
 	}
-	expiresUnix, err := strconv.ParseInt(parts[1], 10, 64)
-	if err != nil {
-		return uuid.Nil, false
+	now := s.now()
+	if now.Unix() > session.expiresAt {
+		return Info{}, false
 	}
-	if s.now().Unix() > expiresUnix {
-		return uuid.Nil, false
+	if session.recordID == uuid.Nil {
+		if s.isRevoked(sessionID, session) {
+			return Info{}, false
+		}
+		return Info{UserID: session.userID}, true
 	}
-	if s.isRevoked(sessionID, expiresUnix) {
-		return uuid.Nil, false
+	// 一覧を引けない場合は失効を確かめられないので、安全側に倒して拒否する。
+	record, err := s.registry.Get(ctx, session.recordID)
+	if err != nil || record.UserID != session.userID || !record.Active(now) {
+		return Info{}, false
 	}
-	userID, err := uuid.Parse(parts[0])
-	if err != nil {
-		return uuid.Nil, false
+	if now.Sub(record.LastSeenAt) >= lastSeenInterval {
+		_ = s.registry.Touch(ctx, record.ID, now)
 	}
-	return userID, true
+	return Info{ID: record.ID, UserID: record.UserID}, true
 }
 
-func (s *SignedCookieStore) Delete(sessionID string) {
+func (s *SignedCookieStore) Delete(ctx context.Context, sessionID string) error {
 	if sessionID == "" {
-		return
-	}
-	raw, err := base64.RawURLEncoding.DecodeString(sessionID)
-	if err != nil {
-		return
+		return nil
 	}
-	parts := strings.Split(string(raw), "|")
-	if len(parts) != 3 {
-		return
+	session, ok := s.parse(sessionID)
+	if !ok {
+		return nil
 	}
-	expiresUnix, err := strconv.ParseInt(parts[1], 10, 64)
-	if err != nil {
-		return
+	if session.recordID != uuid.Nil {
+		_, err := s.registry.Revoke(ctx, session.userID, session.recordID, s.now())
+		return err
 	}
 	s.mu.Lock()
 	s.cleanupExpiredLocked()
-	s.revoked[sessionID] = expiresUnix
+	s.revoked[sessionID] = session.expiresAt
 	s.mu.Unlock()
+	return nil
 }
 
-func (s *SignedCookieStore) sign(payload string) string {
-	mac := hmac.New(sha256.New, s.secret)
-	_, _ = mac.Write([]byte(payload))
-	return hex.EncodeToString(mac.Sum(nil))
+func (s *SignedCookieStore) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	now := s.now()
+	if err := s.registry.RevokeUser(ctx, userID, now); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanupExpiredLocked()
+	// 旧形式の Cookie 向けに、失効前に発行されたセッションがすべて期限切れになるまで保持する。
+	s.userCutoffs[userID] = userCutoff{
+		issuedBefore: now.UnixNano(),
+		keepUntil:    now.Add(s.maxTTL).Unix(),
+	}
+	return nil
+}
+
+// Renew は有効期間の半分を過ぎたか、入れ替え前の鍵で署名した Cookie を発行し直す。
+// 一覧の期限も now+ttl に延ばし、新しい Cookie の値と期限を返す。延長しない場合は空文字を返す。
+// 一覧の ID を持たない旧形式の Cookie は延長せず、期限が来たらログインし直してもらう。
+func (s *SignedCookieStore) Renew(ctx context.Context, sessionID string, ttl time.Duration) (string, time.Time, error) {
+	session, ok := s.parse(sessionID)
+	if !ok || session.recordID == uuid.Nil {
+		return "", time.Time{}, nil
+	}
+	now := s.now()
+	issuedAt := time.Unix(0, session.issuedAt)
+	expiresAt := time.Unix(session.expiresAt, 0)
+	halfway := issuedAt.Add(expiresAt.Sub(issuedAt) / 2)
+	if now.Before(halfway) && session.keyID == s.keys.current().id {
+		return "", time.Time{}, nil
+	}
+	if !now.Before(expiresAt) {
+		return "", time.Time{}, nil
+	}
+	renewedUntil := now.Add(ttl)
+	extended, err := s.registry.Extend(ctx, session.userID, session.recordID, renewedUntil)
+	if err != nil || !extended {
+		return "", time.Time{}, err
+	}
+	return s.encode(session.userID, session.recordID, renewedUntil, now), renewedUntil, nil
+}
+
+func (s *SignedCookieStore) List(ctx context.Context, userID uuid.UUID) ([]Record, error) {
+	return s.registry.ListActive(ctx, userID, s.now())
+}
+
+func (s *SignedCookieStore) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	return revokeRecord(ctx, s.registry, userID, id, s.now())
 }
 
-func (s *SignedCookieStore) verify(payload, sig string) bool {
-	expected, err := hex.DecodeString(sig)
+// encode は現在の鍵で署名した Cookie の値を作る。
+func (s *SignedCookieStore) encode(userID, recordID uuid.UUID, expiresAt, issuedAt time.Time) string {
+	key := s.keys.current()
+	// 発行時刻はナノ秒で持ち、RevokeUser の直後に作ったセッションと直前のセッションを区別できるようにする。
+	payload := userID.String() + "|" + strconv.FormatInt(expiresAt.Unix(), 10) + "|" + strconv.FormatInt(issuedAt.UnixNano(), 10) + "|" + recordID.String() + "|" + key.id
+	return base64.RawURLEncoding.EncodeToString([]byte(payload + "|" + key.sign(payload)))
+}
+
+// parse は署名を検証してセッション ID を分解する。発行時刻・一覧の ID・鍵 ID を持たない旧形式も受け付ける。
+// 鍵 ID があればその鍵だけで、なければすべての鍵で検証する。
+func (s *SignedCookieStore) parse(sessionID string) (cookieSession, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(sessionID)
+	if err != nil {
+		return cookieSession{}, false
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) < 3 || len(parts) > 6 {
+		return cookieSession{}, false
+	}
+	last := len(parts) - 1
+	payload := strings.Join(parts[:last], "|")
+	if len(parts) == 6 {
+		key, ok := s.keys.find(parts[4])
+		if !ok || !key.verify(payload, parts[last]) {
+			return cookieSession{}, false
+		}
+	} else if !s.keys.verifyAny(payload, parts[last]) {
+		return cookieSession{}, false
+	}
+	userID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return cookieSession{}, false
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
 	if err != nil {
-		return false
+		return cookieSession{}, false
+	}
+	session := cookieSession{userID: userID, expiresAt: expiresAt}
+	if len(parts) >= 4 {
+		if session.issuedAt, err = strconv.ParseInt(parts[2], 10, 64); err != nil {
+			return cookieSession{}, false
+		}
+	}
+	if len(parts) >= 5 {
+		if session.recordID, err = uuid.Parse(parts[3]); err != nil {
+			return cookieSession{}, false
+		}
 	}
-	mac := hmac.New(sha256.New, s.secret)
-	_, _ = mac.Write([]byte(payload))
-	return hmac.Equal(mac.Sum(nil), expected)
+	if len(parts) == 6 {
+		session.keyID = parts[4]
+	}
+	return session, true
+}
+
+// sign は現在の鍵で署名する。
+func (s *SignedCookieStore) sign(payload string) string {
+	return s.keys.current().sign(payload)
 }
 
-func (s *SignedCookieStore) isRevoked(sessionID string, expiresUnix int64) bool {
+func (s *SignedCookieStore) isRevoked(sessionID string, session cookieSession) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.cleanupExpiredLocked()
-	exp, ok := s.revoked[sessionID]
-	if !ok {
-		return false
+	// 旧形式は作成時刻がないため、残りの有効期間を下限として使う。
+	issuedAt := s.now()
+	if session.issuedAt > 0 {
+		issuedAt = time.Unix(0, session.issuedAt)
+	}
+	if ttl := time.Unix(session.expiresAt, 0).Sub(issuedAt); ttl > s.maxTTL {
+		s.maxTTL = ttl
 	}
-	if exp <= s.now().Unix() {
-		delete(s.revoked, sessionID)
-		return false
+	if cutoff, ok := s.userCutoffs[session.userID]; ok && session.issuedAt < cutoff.issuedBefore {
+		return true
 	}
-	return true
+	_, ok := s.revoked[sessionID]
+	return ok
 }
 
 func (s *SignedCookieStore) cleanupExpiredLocked() {
@@ -142,4 +265,9 @@ func (s *SignedCookieStore) cleanupExpiredLocked() {
 			delete(s.revoked, token)
 		}
 	}
+	for userID, cutoff := range s.userCutoffs {
+		if cutoff.keepUntil <= now {
+			delete(s.userCutoffs, userID)
+		}
+	}
 }
@@ -1,7 +1,9 @@
 package session
 
 import (
+	"context"
 	"encoding/base64"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -11,28 +13,28 @@ import (
 )
 
 func TestSignedCookieStore_RoundTrip(t *testing.T) {
-	store, err := NewSignedCookieStore("super-secret")
+	store, err := NewSignedCookieStore("super-secret", NewMemoryRegistry())
 	require.NoError(t, err)
 	fixed := time.Unix(1_700_000_000, 0).UTC()
 	store.now = func() time.Time { return fixed }
 
 	userID := uuid.New()
-	token, err := store.Create(userID, time.Hour)
+	token, err := store.Create(context.Background(), userID, time.Hour, Client{})
 	require.NoError(t, err)
 
-	got, ok := store.Get(token)
+	got, ok := store.Get(context.Background(), token)
 	require.True(t, ok)
-	require.Equal(t, userID, got)
+	require.Equal(t, userID, got.UserID)
 
-	store.Delete(token)
-	_, ok = store.Get(token)
+	require.NoError(t, store.Delete(context.Background(), token))
+	_, ok = store.Get(context.Background(), token)
 	require.False(t, ok)
 }
 
 func TestSignedCookieStore_DetectsTampering(t *testing.T) {
-	store, err := NewSignedCookieStore("super-secret")
+	store, err := NewSignedCookieStore("super-secret", NewMemoryRegistry())
 	require.NoError(t, err)
-	token, err := store.Create(uuid.New(), time.Hour)
+	token, err := store.Create(context.Background(), uuid.New(), time.Hour, Client{})
 	require.NoError(t, err)
 
 	raw, err := base64.RawURLEncoding.DecodeString(token)
@@ -41,24 +43,185 @@ func TestSignedCookieStore_DetectsTampering(t *testing.T) {
 	mutated = strings.Replace(mutated, "|", "/|", 1)
 	broken := base64.RawURLEncoding.EncodeToString([]byte(mutated))
 
-	_, ok := store.Get(broken)
+	_, ok := store.Get(context.Background(), broken)
 	require.False(t, ok)
 }
 
 func TestSignedCookieStore_ExpiresTokens(t *testing.T) {
-	store, err := NewSignedCookieStore("super-secret")
+	store, err := NewSignedCookieStore("super-secret", NewMemoryRegistry())
 	require.NoError(t, err)
 	start := time.Unix(1_700_000_000, 0).UTC()
 	store.now = func() time.Time { return start }
-	token, err := store.Create(uuid.New(), time.Hour)
+	token, err := store.Create(context.Background(), uuid.New(), time.Hour, Client{})
 	require.NoError(t, err)
 
 	store.now = func() time.Time { return start.Add(2 * time.Hour) }
-	_, ok := store.Get(token)
+	_, ok := store.Get(context.Background(), token)
 	require.False(t, ok)
 }
 
 func TestSignedCookieStoreRequiresSecret(t *testing.T) {
-	_, err := NewSignedCookieStore("  ")
+	_, err := NewSignedCookieStore("  ", NewMemoryRegistry())
 	require.Error(t, err)
 }
+
+func TestSignedCookieStore_RevokeUserKeepsLaterSessions(t *testing.T) {
+	store, err := NewSignedCookieStore("super-secret", NewMemoryRegistry())
+	require.NoError(t, err)
+	start := time.Unix(1_700_000_000, 0).UTC()
+	store.now = func() time.Time { return start }
+	userID := uuid.New()
+	other := uuid.New()
+	before, err := store.Create(context.Background(), userID, time.Hour, Client{})
+	require.NoError(t, err)
+	otherToken, err := store.Create(context.Background(), other, time.Hour, Client{})
+	require.NoError(t, err)
+
+	store.now = func() time.Time { return start.Add(time.Minute) }
+	require.NoError(t, store.RevokeUser(context.Background(), userID))
+	store.now = func() time.Time { return start.Add(time.Minute + time.Nanosecond) }
+	after, err := store.Create(context.Background(), userID, time.Hour, Client{})
+	require.NoError(t, err)
+
+	_, ok := store.Get(context.Background(), before)
+	require.False(t, ok)
+	got, ok := store.Get(context.Background(), after)
+	require.True(t, ok)
+	require.Equal(t, userID, got.UserID)
+	_, ok = store.Get(context.Background(), otherToken)
+	require.True(t, ok)
+}
+
+func TestSignedCookieStore_AcceptsLegacyTokens(t *testing.T) {
+	store, err := NewSignedCookieStore("super-secret", NewMemoryRegistry())
+	require.NoError(t, err)
+	userID := uuid.New()
+	payload := userID.String() + "|" + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	legacy := base64.RawURLEncoding.EncodeToString([]byte(payload + "|" + store.sign(payload)))
+
+	got, ok := store.Get(context.Background(), legacy)
+	require.True(t, ok)
+	require.Equal(t, userID, got.UserID)
+
+	// 作成時刻を持たない旧形式のセッションもユーザー単位の失効対象になる。
+	require.NoError(t, store.RevokeUser(context.Background(), userID))
+	_, ok = store.Get(context.Background(), legacy)
+	require.False(t, ok)
+}
+
+func TestSignedCookieStore_SharesRevocationAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	registry := newGormRegistryForTest(t)
+	first, err := NewSignedCookieStore("super-secret", registry)
+	require.NoError(t, err)
+	second, err := NewSignedCookieStore("super-secret", registry)
+	require.NoError(t, err)
+	userID := uuid.New()
+	loggedOut, err := first.Create(ctx, userID, time.Hour, Client{UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) Safari/605.1.15", IPAddress: "192.0.2.1"})
+	require.NoError(t, err)
+	remote, err := first.Create(ctx, userID, time.Hour, Client{})
+	require.NoError(t, err)
+
+	info, ok := second.Get(ctx, loggedOut)
+	require.True(t, ok)
+	require.Equal(t, userID, info.UserID)
+	require.NoError(t, first.Delete(ctx, loggedOut))
+	_, ok = second.Get(ctx, loggedOut)
+	require.False(t, ok)
+
+	records, err := second.List(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	remoteInfo, ok := first.Get(ctx, remote)
+	require.True(t, ok)
+	require.Equal(t, records[0].ID, remoteInfo.ID)
+
+	// 他ユーザーの ID では失効できない。
+	require.ErrorIs(t, second.Revoke(ctx, uuid.New(), remoteInfo.ID), ErrRecordNotFound)
+	require.NoError(t, second.Revoke(ctx, userID, remoteInfo.ID))
+	_, ok = first.Get(ctx, remote)
+	require.False(t, ok)
+	require.ErrorIs(t, second.Revoke(ctx, userID, remoteInfo.ID), ErrRecordNotFound)
+}
+
+func TestSignedCookieStore_RenewsPastHalfLifetime(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSignedCookieStore("super-secret", NewMemoryRegistry())
+	require.NoError(t, err)
+	start := time.Unix(1_700_000_000, 0).UTC()
+	now := start
+	store.now = func() time.Time { return now }
+	userID := uuid.New()
+	token, err := store.Create(ctx, userID, time.Hour, Client{})
+	require.NoError(t, err)
+
+	now = start.Add(20 * time.Minute)
+	renewed, _, err := store.Renew(ctx, token, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, renewed)
+
+	now = start.Add(40 * time.Minute)
+	renewed, expiresAt, err := store.Renew(ctx, token, time.Hour)
+	require.NoError(t, err)
+	require.NotEmpty(t, renewed)
+	require.Equal(t, now.Add(time.Hour), expiresAt)
+	records, err := store.List(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, expiresAt, records[0].ExpiresAt.UTC())
+
+	// 元の Cookie は元の期限で切れ、延長した Cookie はその先も使える。
+	now = start.Add(65 * time.Minute)
+	_, ok := store.Get(ctx, token)
+	require.False(t, ok)
+	info, ok := store.Get(ctx, renewed)
+	require.True(t, ok)
+	require.Equal(t, userID, info.UserID)
+	// 次の延長は延長した時点から数えて半分を過ぎてから。
+	again, _, err := store.Renew(ctx, renewed, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, again)
+
+	// 失効したセッションは延長しない。
+	require.NoError(t, store.Delete(ctx, renewed))
+	now = start.Add(80 * time.Minute)
+	again, _, err = store.Renew(ctx, renewed, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, again)
+}
+
+func TestSignedCookieStore_RotatesSecrets(t *testing.T) {
+	ctx := context.Background()
+	registry := NewMemoryRegistry()
+	before, err := NewSignedCookieStore("old-secret", registry)
+	require.NoError(t, err)
+	userID := uuid.New()
+	token, err := before.Create(ctx, userID, time.Hour, Client{})
+	require.NoError(t, err)
+	payload := userID.String() + "|" + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	legacy := base64.RawURLEncoding.EncodeToString([]byte(payload + "|" + before.sign(payload)))
+
+	rotated, err := NewSignedCookieStore("new-secret", registry, "old-secret")
+	require.NoError(t, err)
+	info, ok := rotated.Get(ctx, token)
+	require.True(t, ok)
+	require.Equal(t, userID, info.UserID)
+	_, ok = rotated.Get(ctx, legacy)
+	require.True(t, ok)
+
+	// 古い鍵で署名した Cookie は期限の半分を待たずに新しい鍵で発行し直す。
+	renewed, _, err := rotated.Renew(ctx, token, time.Hour)
+	require.NoError(t, err)
+	require.NotEmpty(t, renewed)
+
+	// 古いシークレットを外した後は、発行し直した Cookie だけが通る。
+	after, err := NewSignedCookieStore("new-secret", registry)
+	require.NoError(t, err)
+	_, ok = after.Get(ctx, token)
+	require.False(t, ok)
+	_, ok = after.Get(ctx, legacy)
+	require.False(t, ok)
+	info, ok = after.Get(ctx, renewed)
+	require.True(t, ok)
+	require.Equal(t, userID, info.UserID)
+}
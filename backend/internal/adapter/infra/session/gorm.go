@@ -0,0 +1,96 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// sweepInterval ごとに期限切れの行を掃除する。
+const sweepInterval = time.Hour
+
+// GormRegistry は一覧と失効を DB に保存し、複数インスタンスで共有する。
+type GormRegistry struct {
+	db        *gorm.DB
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewGormRegistry(db *gorm.DB) *GormRegistry {
+	return &GormRegistry{db: db}
+}
+
+func (r *GormRegistry) Create(ctx context.Context, record *Record) error {
+	r.sweep(ctx, record.CreatedAt)
+	return r.db.WithContext(ctx).Create(record).Error
+}
+
+func (r *GormRegistry) Get(ctx context.Context, id uuid.UUID) (*Record, error) {
+	var record Record
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *GormRegistry) ListActive(ctx context.Context, userID uuid.UUID, at time.Time) ([]Record, error) {
+	var records []Record
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, at).
+		Order("created_at desc").
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *GormRegistry) Touch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&Record{}).Where("id = ?", id).Update("last_seen_at", at).Error
+}
+
+func (r *GormRegistry) Extend(ctx context.Context, userID, id uuid.UUID, expiresAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&Record{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("expires_at", expiresAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormRegistry) Revoke(ctx context.Context, userID, id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&Record{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormRegistry) RevokeUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&Record{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+// sweep は sweepInterval に 1 回だけ期限切れの行を消す。失敗しても認証には影響しないので無視する。
+func (r *GormRegistry) sweep(ctx context.Context, now time.Time) {
+	r.mu.Lock()
+	if now.Sub(r.lastSweep) < sweepInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastSweep = now
+	r.mu.Unlock()
+	_ = r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&Record{}).Error
+}
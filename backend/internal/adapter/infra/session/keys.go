@@ -0,0 +1,85 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// keyIDLength はトークンに含める鍵 ID の長さ（16 進数の文字数）。
+const keyIDLength = 8
+
+// signingKey は署名に使うシークレットと、トークンに含めて照合に使う ID。
+type signingKey struct {
+	id     string
+	secret []byte
+}
+
+// keyring は先頭の鍵で署名し、残りの鍵は検証にだけ使う。
+// シークレットを入れ替えるときは新しい値を先頭に、古い値を後ろに並べ、古い値で署名したトークンが切れてから外す。
+type keyring []signingKey
+
+// newKeyring は現在のシークレットと、検証だけに使う以前のシークレットから鍵を作る。空の値は無視する。
+func newKeyring(current string, previous []string) (keyring, error) {
+	current = strings.TrimSpace(current)
+	if current == "" {
+		return nil, errors.New("secret is required")
+	}
+	keys := keyring{newSigningKey(current)}
+	for _, secret := range previous {
+		if secret = strings.TrimSpace(secret); secret != "" && secret != current {
+			keys = append(keys, newSigningKey(secret))
+		}
+	}
+	return keys, nil
+}
+
+// newSigningKey はシークレットから鍵 ID を導く。ID からシークレットを推測できないよう HMAC を使う。
+func newSigningKey(secret string) signingKey {
+	key := signingKey{secret: []byte(secret)}
+	key.id = key.sign("chronome-key-id")[:keyIDLength]
+	return key
+}
+
+func (k signingKey) sign(payload string) string {
+	mac := hmac.New(sha256.New, k.secret)
+	_, _ = mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (k signingKey) verify(payload, sig string) bool {
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, k.secret)
+	_, _ = mac.Write([]byte(payload))
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// current は署名に使う鍵を返す。
+func (k keyring) current() signingKey {
+	return k[0]
+}
+
+// find は ID の一致する鍵を返す。
+func (k keyring) find(id string) (signingKey, bool) {
+	for _, key := range k {
+		if key.id == id {
+			return key, true
+		}
+	}
+	return signingKey{}, false
+}
+
+// verifyAny は鍵 ID を持たない旧形式のトークンを、すべての鍵で検証する。
+func (k keyring) verifyAny(payload, sig string) bool {
+	for _, key := range k {
+		if key.verify(payload, sig) {
+			return true
+		}
+	}
+	return false
+}
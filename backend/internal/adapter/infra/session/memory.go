@@ -1,62 +1,179 @@
 package session
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
 // Store はハンドラ/ミドルウェアで使う操作を定義する。
 type Store interface {
-	Create(userID uuid.UUID, ttl time.Duration) (string, error)
-	Get(sessionID string) (uuid.UUID, bool)
-	Delete(sessionID string)
+	Create(ctx context.Context, userID uuid.UUID, ttl time.Duration, client Client) (string, error)
+	Get(ctx context.Context, token string) (Info, bool)
+	Delete(ctx context.Context, token string) error
+	// RevokeUser はユーザーがこれまでに作成したセッションをすべて無効にする。呼び出し後に作成したセッションは有効。
+	RevokeUser(ctx context.Context, userID uuid.UUID) error
+	// Renew は期限の近づいたセッションを ttl 延長し、新しい Cookie の値と期限を返す。延長しない場合は空文字を返す。
+	Renew(ctx context.Context, token string, ttl time.Duration) (string, time.Time, error)
+	// List はユーザーの有効なセッションを新しい順に返す。
+	List(ctx context.Context, userID uuid.UUID) ([]Record, error)
+	// Revoke は一覧の ID でセッションを失効させる。他ユーザーのセッションや失効済みなら ErrRecordNotFound を返す。
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
 }
 
+// Info は Cookie から取り出した有効なセッション。ID は一覧の ID で、一覧を導入する前の Cookie では uuid.Nil になる。
+type Info struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// lastSeenInterval より短い間隔のアクセスでは最終利用時刻を更新しない。
+const lastSeenInterval = time.Minute
+
 // MemoryStore はプロセス内にセッションを保持し、ローカル開発向け。
 type MemoryStore struct {
 	mu       sync.RWMutex
-	sessions map[string]sessionValue
+	sessions map[string]memorySession
+	registry *MemoryRegistry
 }
 
-type sessionValue struct {
-	userID    uuid.UUID
-	expiresAt time.Time
+// memorySession は Cookie の値に対応する一覧の ID と、Cookie を発行（延長）した時刻。
+type memorySession struct {
+	id       uuid.UUID
+	issuedAt time.Time
 }
 
 func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{sessions: make(map[string]sessionValue)}
+	return &MemoryStore{sessions: make(map[string]memorySession), registry: NewMemoryRegistry()}
 }
 
-func (s *MemoryStore) Create(userID uuid.UUID, ttl time.Duration) (string, error) {
+func (s *MemoryStore) Create(ctx context.Context, userID uuid.UUID, ttl time.Duration, client Client) (string, error) {
 	if userID == uuid.Nil {
 		return "", errors.New("user id is required")
 	}
-	id := uuid.NewString()
+	record := newRecord(userID, time.Now().UTC(), ttl, client)
+	if err := s.registry.Create(ctx, record); err != nil {
+		return "", err
+	}
+	token := uuid.NewString()
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.sessions[id] = sessionValue{userID: userID, expiresAt: time.Now().Add(ttl)}
-	return id, nil
+	s.sessions[token] = memorySession{id: record.ID, issuedAt: record.CreatedAt}
+	return token, nil
 }
 
-func (s *MemoryStore) Get(sessionID string) (uuid.UUID, bool) {
+func (s *MemoryStore) Get(ctx context.Context, token string) (Info, bool) {
 	s.mu.RLock()
-	session, ok := s.sessions[sessionID]
+	session, ok := s.sessions[token]
 	s.mu.RUnlock()
 	if !ok {
-		return uuid.Nil, false
+		return Info{}, false
+	}
+	now := time.Now().UTC()
+	record, err := s.registry.Get(ctx, session.id)
+	if err != nil || !record.Active(now) {
+		_ = s.Delete(ctx, token)
+		return Info{}, false
+	}
+	if now.Sub(record.LastSeenAt) >= lastSeenInterval {
+		_ = s.registry.Touch(ctx, session.id, now)
+	}
+	return Info{ID: record.ID, UserID: record.UserID}, true
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, token string) error {
+	s.mu.Lock()
+	session, ok := s.sessions[token]
+	delete(s.sessions, token)
+	s.mu.Unlock()
+	if !ok {
+		return nil
 	}
-	if time.Now().After(session.expiresAt) {
-		s.Delete(sessionID)
-		return uuid.Nil, false
+	record, err := s.registry.Get(ctx, session.id)
+	if err != nil {
+		return nil
 	}
-	return session.userID, true
+	_, err = s.registry.Revoke(ctx, record.UserID, session.id, time.Now().UTC())
+	return err
+}
+
+func (s *MemoryStore) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	return s.registry.RevokeUser(ctx, userID, time.Now().UTC())
 }
 
-func (s *MemoryStore) Delete(sessionID string) {
+// Renew は有効期間の半分を過ぎたセッションの期限を延ばす。Cookie の値は変えずに期限だけを返し直す。
+func (s *MemoryStore) Renew(ctx context.Context, token string, ttl time.Duration) (string, time.Time, error) {
+	s.mu.RLock()
+	session, ok := s.sessions[token]
+	s.mu.RUnlock()
+	if !ok {
+		return "", time.Time{}, nil
+	}
+	record, err := s.registry.Get(ctx, session.id)
+	if err != nil {
+		return "", time.Time{}, nil
+	}
+	now := time.Now().UTC()
+	halfway := session.issuedAt.Add(record.ExpiresAt.Sub(session.issuedAt) / 2)
+	if !record.Active(now) || now.Before(halfway) {
+		return "", time.Time{}, nil
+	}
+	expiresAt := now.Add(ttl)
+	extended, err := s.registry.Extend(ctx, record.UserID, session.id, expiresAt)
+	if err != nil || !extended {
+		return "", time.Time{}, err
+	}
 	s.mu.Lock()
-	delete(s.sessions, sessionID)
+	s.sessions[token] = memorySession{id: session.id, issuedAt: now}
 	s.mu.Unlock()
+	return token, expiresAt, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, userID uuid.UUID) ([]Record, error) {
+	return s.registry.ListActive(ctx, userID, time.Now().UTC())
+}
+
+func (s *MemoryStore) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	return revokeRecord(ctx, s.registry, userID, id, time.Now().UTC())
+}
+
+// newRecord は作成したリクエストの端末情報を添えて一覧の行を作る。
+func newRecord(userID uuid.UUID, now time.Time, ttl time.Duration, client Client) *Record {
+	return &Record{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Device:     deviceName(client.UserAgent),
+		UserAgent:  truncate(client.UserAgent, 512),
+		IPAddress:  truncate(client.IPAddress, 64),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+}
+
+func revokeRecord(ctx context.Context, registry Registry, userID, id uuid.UUID, at time.Time) error {
+	revoked, err := registry.Revoke(ctx, userID, id, at)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// truncate は列の長さに収まるよう、UTF-8 の文字の途中で切らずに limit バイト以内へ縮める。
+func truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
 }
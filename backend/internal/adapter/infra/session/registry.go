@@ -0,0 +1,200 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrRecordNotFound は一覧にないセッションを表す。
+var ErrRecordNotFound = errors.New("session not found")
+
+// Record はログイン中のセッションの一覧用の情報。失効もこの行で共有する。
+type Record struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;index;not null" json:"-"`
+	Device     string     `gorm:"size:64" json:"device"`
+	UserAgent  string     `gorm:"size:512" json:"user_agent"`
+	IPAddress  string     `gorm:"size:64" json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"index;not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+func (Record) TableName() string {
+	return "sessions"
+}
+
+// Active は at の時点で失効も期限切れもしていないかを返す。
+func (r Record) Active(at time.Time) bool {
+	return r.RevokedAt == nil && at.Before(r.ExpiresAt)
+}
+
+// Client はセッションを作成したリクエストの端末情報。
+type Client struct {
+	UserAgent string
+	IPAddress string
+}
+
+// Registry はセッションの一覧と失効を保存する。複数インスタンスで失効を共有する場合は DB の実装を使う。
+type Registry interface {
+	Create(ctx context.Context, record *Record) error
+	// Get は失効済みの行も返す。なければ ErrRecordNotFound を返す。
+	Get(ctx context.Context, id uuid.UUID) (*Record, error)
+	// ListActive は at の時点で有効な行を新しい順に返す。
+	ListActive(ctx context.Context, userID uuid.UUID, at time.Time) ([]Record, error)
+	Touch(ctx context.Context, id uuid.UUID, at time.Time) error
+	// Extend は失効していないセッションの期限を expiresAt にし、延ばせたかを返す。
+	Extend(ctx context.Context, userID, id uuid.UUID, expiresAt time.Time) (bool, error)
+	// Revoke は失効できたかを返す。他ユーザーのセッションと失効済みのセッションは対象にしない。
+	Revoke(ctx context.Context, userID, id uuid.UUID, at time.Time) (bool, error)
+	RevokeUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+}
+
+// MemoryRegistry はプロセス内に一覧を保持する。インスタンスが 1 つの場合とテスト向け。
+type MemoryRegistry struct {
+	mu      sync.Mutex
+	records map[uuid.UUID]Record
+}
+
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{records: make(map[uuid.UUID]Record)}
+}
+
+func (m *MemoryRegistry) Create(_ context.Context, record *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweepLocked(record.CreatedAt)
+	m.records[record.ID] = *record
+	return nil
+}
+
+func (m *MemoryRegistry) Get(_ context.Context, id uuid.UUID) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+func (m *MemoryRegistry) ListActive(_ context.Context, userID uuid.UUID, at time.Time) ([]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var records []Record
+	for _, record := range m.records {
+		if record.UserID == userID && record.Active(at) {
+			records = append(records, record)
+		}
+	}
+	slices.SortFunc(records, func(a, b Record) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return records, nil
+}
+
+func (m *MemoryRegistry) Touch(_ context.Context, id uuid.UUID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if record, ok := m.records[id]; ok {
+		record.LastSeenAt = at
+		m.records[id] = record
+	}
+	return nil
+}
+
+func (m *MemoryRegistry) Extend(_ context.Context, userID, id uuid.UUID, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[id]
+	if !ok || record.UserID != userID || record.RevokedAt != nil {
+		return false, nil
+	}
+	record.ExpiresAt = expiresAt
+	m.records[id] = record
+	return true, nil
+}
+
+func (m *MemoryRegistry) Revoke(_ context.Context, userID, id uuid.UUID, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[id]
+	if !ok || record.UserID != userID || record.RevokedAt != nil {
+		return false, nil
+	}
+	record.RevokedAt = &at
+	m.records[id] = record
+	return true, nil
+}
+
+func (m *MemoryRegistry) RevokeUser(_ context.Context, userID uuid.UUID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, record := range m.records {
+		if record.UserID == userID && record.RevokedAt == nil {
+			record.RevokedAt = &at
+			m.records[id] = record
+		}
+	}
+	return nil
+}
+
+// sweepLocked は期限切れの行を消す。失効済みの行も期限までは残し、Get で拒否できるようにする。
+func (m *MemoryRegistry) sweepLocked(now time.Time) {
+	for id, record := range m.records {
+		if !now.Before(record.ExpiresAt) {
+			delete(m.records, id)
+		}
+	}
+}
+
+// deviceName は User-Agent から一覧に表示する大まかな端末名を作る。判別できなければ空文字を返す。
+func deviceName(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	var platform string
+	switch {
+	case strings.Contains(ua, "iphone"):
+		platform = "iPhone"
+	case strings.Contains(ua, "ipad"):
+		platform = "iPad"
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "mac os"), strings.Contains(ua, "macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "cros"):
+		platform = "ChromeOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+	// Edge と Chrome は Safari を、Edge は Chrome も名乗るので、固有の名前から順に調べる。
+	var browser string
+	switch {
+	case strings.Contains(ua, "chronome"):
+		browser = "ChronoMe"
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "firefox/"), strings.Contains(ua, "fxios/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"), strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	}
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	default:
+		return platform
+	}
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newGormRegistryForTest(t *testing.T) *GormRegistry {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Record{}))
+	return NewGormRegistry(db)
+}
+
+// 同じ振る舞いをメモリと DB の両方の保存先で確かめる。
+func registriesForTest(t *testing.T) map[string]Registry {
+	return map[string]Registry{
+		"memory": NewMemoryRegistry(),
+		"gorm":   newGormRegistryForTest(t),
+	}
+}
+
+func TestRegistry_ListsActiveSessionsNewestFirst(t *testing.T) {
+	for name, registry := range registriesForTest(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+			userID := uuid.New()
+			older := newRecord(userID, now.Add(-time.Hour), 2*time.Hour, Client{})
+			newer := newRecord(userID, now.Add(-time.Minute), 2*time.Hour, Client{})
+			expired := newRecord(userID, now.Add(-3*time.Hour), time.Hour, Client{})
+			other := newRecord(uuid.New(), now, time.Hour, Client{})
+			for _, record := range []*Record{older, newer, expired, other} {
+				require.NoError(t, registry.Create(ctx, record))
+			}
+
+			records, err := registry.ListActive(ctx, userID, now)
+			require.NoError(t, err)
+			require.Len(t, records, 2)
+			require.Equal(t, newer.ID, records[0].ID)
+			require.Equal(t, older.ID, records[1].ID)
+
+			revoked, err := registry.Revoke(ctx, other.UserID, older.ID, now)
+			require.NoError(t, err)
+			require.False(t, revoked)
+			require.NoError(t, registry.RevokeUser(ctx, userID, now))
+			records, err = registry.ListActive(ctx, userID, now)
+			require.NoError(t, err)
+			require.Empty(t, records)
+			// 失効した行も期限までは残し、Cookie を拒否できるようにする。
+			got, err := registry.Get(ctx, newer.ID)
+			require.NoError(t, err)
+			require.False(t, got.Active(now))
+
+			_, err = registry.Get(ctx, uuid.New())
+			require.ErrorIs(t, err, ErrRecordNotFound)
+		})
+	}
+}
+
+func TestDeviceName(t *testing.T) {
+	cases := map[string]string{
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15":                     "Safari on macOS",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0":             "Edge on Windows",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36":                     "Chrome on Android",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/120.0 Mobile/15E148 Safari/605.1.15": "Firefox on iPhone",
+		"ChronoMe/1.0 CFNetwork/1490.0.4 Darwin/23.2.0": "ChronoMe",
+		"curl/8.4.0": "",
+	}
+	for userAgent, want := range cases {
+		require.Equal(t, want, deviceName(userAgent), userAgent)
+	}
+}
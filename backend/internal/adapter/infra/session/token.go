@@ -0,0 +1,93 @@
+package session
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// accessTokenPurpose は署名対象に含め、セッション Cookie の値をアクセストークンとして使い回せないようにする。
+const accessTokenPurpose = "access"
+
+// TokenSigner は Bearer 認証のアクセストークンを発行・検証する。
+type TokenSigner interface {
+	Issue(userID uuid.UUID, ttl time.Duration) (string, error)
+	Verify(token string) (AccessClaims, bool)
+}
+
+// AccessClaims はアクセストークンから取り出した署名済みの値。
+type AccessClaims struct {
+	UserID uuid.UUID
+	// IssuedAt は発行時刻。発行時刻を含める前に発行したトークンではゼロ値になる。
+	IssuedAt time.Time
+}
+
+// HMACTokenSigner はユーザー ID・有効期限・発行時刻を HMAC で署名した短命のアクセストークンを扱う。
+// サーバー側に状態を持たないため、ユーザー単位の失効は呼び出し側が発行時刻と突き合わせて行う。
+type HMACTokenSigner struct {
+	keys keyring
+	now  func() time.Time
+}
+
+// NewHMACTokenSigner はセッションと同じシークレットから署名器を作る。
+// previous は入れ替え前のシークレットで、発行済みのトークンの検証にだけ使う。
+func NewHMACTokenSigner(secret string, previous ...string) (*HMACTokenSigner, error) {
+	keys, err := newKeyring(secret, previous)
+	if err != nil {
+		return nil, errors.New("token secret is required")
+	}
+	return &HMACTokenSigner{
+		keys: keys,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}, nil
+}
+
+func (s *HMACTokenSigner) Issue(userID uuid.UUID, ttl time.Duration) (string, error) {
+	if userID == uuid.Nil {
+		return "", errors.New("user id is required")
+	}
+	now := s.now()
+	// 発行時刻はナノ秒で持ち、失効の直後に発行したトークンと直前のトークンを区別できるようにする。
+	payload := accessTokenPurpose + "|" + userID.String() + "|" + strconv.FormatInt(now.Add(ttl).Unix(), 10) + "|" + strconv.FormatInt(now.UnixNano(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload + "|" + s.keys.current().sign(payload))), nil
+}
+
+// Verify は署名と期限を検証する。発行時刻を持たない旧形式のトークンも受け付ける。
+func (s *HMACTokenSigner) Verify(token string) (AccessClaims, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return AccessClaims{}, false
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) < 4 || len(parts) > 5 || parts[0] != accessTokenPurpose {
+		return AccessClaims{}, false
+	}
+	last := len(parts) - 1
+	// アクセストークンは短命なので鍵 ID を持たせず、すべての鍵で検証する。
+	if !s.keys.verifyAny(strings.Join(parts[:last], "|"), parts[last]) {
+		return AccessClaims{}, false
+	}
+	expiresUnix, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || s.now().Unix() > expiresUnix {
+		return AccessClaims{}, false
+	}
+	userID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return AccessClaims{}, false
+	}
+	claims := AccessClaims{UserID: userID}
+	if len(parts) == 5 {
+		issuedNano, err := strconv.ParseInt(parts[3], 10, 64)
+		if err != nil {
+			return AccessClaims{}, false
+		}
+		claims.IssuedAt = time.Unix(0, issuedNano).UTC()
+	}
+	return claims, true
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACTokenSigner_RoundTripAndExpiry(t *testing.T) {
+	signer, err := NewHMACTokenSigner("super-secret")
+	require.NoError(t, err)
+	start := time.Unix(1_700_000_000, 0).UTC()
+	signer.now = func() time.Time { return start }
+
+	userID := uuid.New()
+	token, err := signer.Issue(userID, 15*time.Minute)
+	require.NoError(t, err)
+
+	got, ok := signer.Verify(token)
+	require.True(t, ok)
+	require.Equal(t, userID, got.UserID)
+	require.True(t, start.Equal(got.IssuedAt))
+
+	signer.now = func() time.Time { return start.Add(16 * time.Minute) }
+	_, ok = signer.Verify(token)
+	require.False(t, ok)
+}
+
+func TestHMACTokenSigner_AcceptsTokensWithoutIssuedAt(t *testing.T) {
+	signer, err := NewHMACTokenSigner("super-secret")
+	require.NoError(t, err)
+	userID := uuid.New()
+	payload := accessTokenPurpose + "|" + userID.String() + "|" + strconv.FormatInt(signer.now().Add(time.Hour).Unix(), 10)
+	legacy := base64.RawURLEncoding.EncodeToString([]byte(payload + "|" + signer.keys.current().sign(payload)))
+
+	got, ok := signer.Verify(legacy)
+	require.True(t, ok)
+	require.Equal(t, userID, got.UserID)
+	require.True(t, got.IssuedAt.IsZero())
+}
+
+func TestHMACTokenSigner_RejectsForeignTokens(t *testing.T) {
+	signer, err := NewHMACTokenSigner("super-secret")
+	require.NoError(t, err)
+	other, err := NewHMACTokenSigner("other-secret")
+	require.NoError(t, err)
+	token, err := other.Issue(uuid.New(), time.Hour)
+	require.NoError(t, err)
+	_, ok := signer.Verify(token)
+	require.False(t, ok)
+
+	// 同じシークレットで署名したセッション Cookie はアクセストークンとして通らない。
+	store, err := NewSignedCookieStore("super-secret", NewMemoryRegistry())
+	require.NoError(t, err)
+	sessionID, err := store.Create(context.Background(), uuid.New(), time.Hour, Client{})
+	require.NoError(t, err)
+	_, ok = signer.Verify(sessionID)
+	require.False(t, ok)
+}
+
+func TestHMACTokenSigner_VerifiesWithPreviousSecrets(t *testing.T) {
+	before, err := NewHMACTokenSigner("old-secret")
+	require.NoError(t, err)
+	userID := uuid.New()
+	token, err := before.Issue(userID, time.Hour)
+	require.NoError(t, err)
+
+	rotated, err := NewHMACTokenSigner("new-secret", "old-secret")
+	require.NoError(t, err)
+	got, ok := rotated.Verify(token)
+	require.True(t, ok)
+	require.Equal(t, userID, got.UserID)
+
+	after, err := NewHMACTokenSigner("new-secret")
+	require.NoError(t, err)
+	_, ok = after.Verify(token)
+	require.False(t, ok)
+}
@@ -7,16 +7,39 @@ import (
 )
 
 // AllocationRequest は分配リクエストの履歴を保持する。
+// UserID 導入前の履歴は所有者を持たないため、どのユーザーの一覧にも現れない。
+// Strategy は分配に使った戦略名で、GranularityMinutes は granularity 戦略のときだけ設定される。
+// BatchID はまとめて分配したときの親 AllocationBatch で、BatchIndex / Label はバッチ内の順序と日付などの表示名。
 type AllocationRequest struct {
-	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	TotalMinutes int       `gorm:"not null" json:"total_minutes"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID                 uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID             uuid.UUID  `gorm:"type:uuid;index" json:"user_id"`
+	TotalMinutes       int        `gorm:"not null" json:"total_minutes"`
+	Strategy           string     `gorm:"size:32;not null;default:largest_remainder" json:"strategy"`
+	GranularityMinutes int        `gorm:"not null;default:0" json:"granularity_minutes,omitempty"`
+	BatchID            *uuid.UUID `gorm:"type:uuid;index" json:"batch_id,omitempty"`
+	BatchIndex         int        `gorm:"not null;default:0" json:"-"`
+	Label              string     `gorm:"size:40" json:"label,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
 }
 
 func (AllocationRequest) TableName() string {
 	return "allocation_requests"
 }
 
+// AllocationBatch は複数の合計分数を同じタスク集合でまとめて分配したときの親。
+// BalanceResidue が true の場合は、各日の端数をまたいでタスクごとの合計が比率に沿うよう調整している。
+type AllocationBatch struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID         uuid.UUID `gorm:"type:uuid;index;not null" json:"user_id"`
+	TotalMinutes   int       `gorm:"not null" json:"total_minutes"`
+	BalanceResidue bool      `gorm:"not null;default:false" json:"balance_residue"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (AllocationBatch) TableName() string {
+	return "allocation_batches"
+}
+
 // TaskAllocation はタスクごとの分配結果を保持する。
 type TaskAllocation struct {
 	ID               uint              `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -27,6 +50,7 @@ type TaskAllocation struct {
 	AllocatedMinutes int               `gorm:"not null" json:"allocated_minutes"`
 	MinMinutes       *int              `json:"min_minutes,omitempty"`
 	MaxMinutes       *int              `json:"max_minutes,omitempty"`
+	Priority         int               `gorm:"not null;default:0" json:"priority,omitempty"`
 	CreatedAt        time.Time         `json:"created_at"`
 	UpdatedAt        time.Time         `json:"updated_at"`
 }
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AllocationTemplate は繰り返し使う分配条件を名前付きで保存する。
+// タスクは自由形式の task_id ではなく、ユーザーのプロジェクトまたはタグを参照する。
+type AllocationTemplate struct {
+	ID                 uuid.UUID                `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID             uuid.UUID                `gorm:"type:uuid;index;not null" json:"user_id"`
+	Name               string                   `gorm:"size:80;not null" json:"name"`
+	Strategy           string                   `gorm:"size:32;not null;default:largest_remainder" json:"strategy"`
+	GranularityMinutes int                      `gorm:"not null;default:0" json:"granularity_minutes,omitempty"`
+	Tasks              []AllocationTemplateTask `gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE;" json:"tasks"`
+	CreatedAt          time.Time                `json:"created_at"`
+	UpdatedAt          time.Time                `json:"updated_at"`
+}
+
+// AllocationTemplateTask はテンプレート内の 1 タスク。ProjectID と TagID のどちらか一方だけを持つ。
+// Position はテンプレート内の並び順で、分配結果もこの順に並ぶ。
+type AllocationTemplateTask struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	TemplateID uuid.UUID  `gorm:"type:uuid;index;not null" json:"-"`
+	Position   int        `gorm:"not null" json:"-"`
+	ProjectID  *uuid.UUID `gorm:"type:uuid" json:"project_id,omitempty"`
+	TagID      *uuid.UUID `gorm:"type:uuid" json:"tag_id,omitempty"`
+	Ratio      float64    `gorm:"not null" json:"ratio"`
+	MinMinutes *int       `json:"min_minutes,omitempty"`
+	MaxMinutes *int       `json:"max_minutes,omitempty"`
+	Priority   int        `gorm:"not null;default:0" json:"priority,omitempty"`
+}
+
+// TaskID は分配結果で使う task_id を返す。参照先の種類を接頭辞で区別する。
+func (t AllocationTemplateTask) TaskID() string {
+	if t.ProjectID != nil {
+		return "project:" + t.ProjectID.String()
+	}
+	if t.TagID != nil {
+		return "tag:" + t.TagID.String()
+	}
+	return ""
+}
+
+func (t *AllocationTemplate) Validate() error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(t.Name) > 80 {
+		return errors.New("name is too long")
+	}
+	if len(t.Tasks) == 0 {
+		return errors.New("template must include at least one task")
+	}
+	for _, task := range t.Tasks {
+		if (task.ProjectID == nil) == (task.TagID == nil) {
+			return errors.New("template task must reference exactly one project or tag")
+		}
+		if task.Ratio <= 0 {
+			return errors.New("ratio must be positive")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailVerificationToken はメールアドレスの確認リンクで送るトークンを表す。
+// Email は確認が済んだときにユーザーへ設定するアドレスで、メールアドレス変更では変更後のアドレスが入る。
+// PasswordResetToken と同じく保存するのは SHA-256 ハッシュだけで、一度使うと UsedAt が入る。
+type EmailVerificationToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	Email     string     `gorm:"size:254;not null" json:"email"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Usable は at の時点で未使用かつ期限内かを返す。
+func (t *EmailVerificationToken) Usable(at time.Time) bool {
+	return t.UsedAt == nil && at.Before(t.ExpiresAt)
+}
@@ -8,20 +8,26 @@ import (
 )
 
 // Entry は EndedAt がゼロの間は実行中になり得る時間ブロックを表す。
+// LastActivityAt はクライアントの heartbeat で更新され、IdleSince は永続化せずアイドル判定時だけ設定する。
+// PomodoroSessionID はポモドーロが自動作成したエントリに付き、PomodoroCompleted は最後まで終えた work 区間を示す。
 type Entry struct {
-	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
-	UserID      uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
-	ProjectID   *uuid.UUID `gorm:"type:uuid" json:"project_id,omitempty"`
-	Title       string     `gorm:"size:120;not null" json:"title"`
-	Notes       string     `gorm:"type:text" json:"notes"`
-	StartedAt   time.Time  `gorm:"not null" json:"started_at"`
-	EndedAt     *time.Time `json:"ended_at,omitempty"`
-	DurationSec int64      `gorm:"not null;default:0" json:"duration_sec"`
-	IsBreak     bool       `gorm:"not null;default:false" json:"is_break"`
-	Ratio       float64    `gorm:"not null;default:1" json:"ratio"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	Tags        []Tag      `gorm:"many2many:entry_tags;constraint:OnDelete:CASCADE;" json:"tags,omitempty"`
+	ID                uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID            uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	ProjectID         *uuid.UUID `gorm:"type:uuid" json:"project_id,omitempty"`
+	Title             string     `gorm:"size:120;not null" json:"title"`
+	Notes             string     `gorm:"type:text" json:"notes"`
+	StartedAt         time.Time  `gorm:"not null" json:"started_at"`
+	EndedAt           *time.Time `json:"ended_at,omitempty"`
+	DurationSec       int64      `gorm:"not null;default:0" json:"duration_sec"`
+	IsBreak           bool       `gorm:"not null;default:false" json:"is_break"`
+	Ratio             float64    `gorm:"not null;default:1" json:"ratio"`
+	LastActivityAt    *time.Time `json:"last_activity_at,omitempty"`
+	IdleSince         *time.Time `gorm:"-" json:"idle_since,omitempty"`
+	PomodoroSessionID *uuid.UUID `gorm:"type:uuid;index" json:"pomodoro_session_id,omitempty"`
+	PomodoroCompleted bool       `gorm:"not null;default:false" json:"pomodoro_completed,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	Tags              []Tag      `gorm:"many2many:entry_tags;constraint:OnDelete:CASCADE;" json:"tags,omitempty"`
 }
 
 func (e *Entry) Validate() error {
@@ -45,5 +51,48 @@ func (e *Entry) UpdateDuration(now time.Time) {
 	}
 	if end.After(e.StartedAt) {
 		e.DurationSec = int64(end.Sub(e.StartedAt).Seconds())
+		return
+	}
+	e.DurationSec = 0
+}
+
+// IsRunning は EndedAt が未設定の実行中エントリかを返す。
+func (e *Entry) IsRunning() bool {
+	return e.EndedAt == nil
+}
+
+// LastActiveAt は最終アクティビティの時刻を返す。開始より前の heartbeat は開始時刻に丸める。
+func (e *Entry) LastActiveAt() time.Time {
+	if e.LastActivityAt != nil && e.LastActivityAt.After(e.StartedAt) {
+		return *e.LastActivityAt
+	}
+	return e.StartedAt
+}
+
+// MarkIdle は実行中エントリの最終アクティビティから threshold 以上経過していれば IdleSince を設定する。
+// heartbeat を送らないクライアントのタイマーを止めないよう、一度も heartbeat を受けていないエントリはアイドルにしない。
+func (e *Entry) MarkIdle(now time.Time, threshold time.Duration) {
+	e.IdleSince = nil
+	if !e.IsRunning() || e.LastActivityAt == nil || threshold <= 0 {
+		return
+	}
+	last := e.LastActiveAt()
+	if now.Sub(last) >= threshold {
+		e.IdleSince = &last
+	}
+}
+
+// ContinueAt は同じ内容で startedAt から始まる新しい実行中エントリを組み立てる。
+func (e *Entry) ContinueAt(startedAt time.Time) *Entry {
+	return &Entry{
+		ID:        uuid.New(),
+		UserID:    e.UserID,
+		ProjectID: e.ProjectID,
+		Title:     e.Title,
+		Notes:     e.Notes,
+		StartedAt: startedAt,
+		IsBreak:   e.IsBreak,
+		Ratio:     e.Ratio,
+		Tags:      e.Tags,
 	}
 }
@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExternalIdentity は OpenID Connect の ID プロバイダーのアカウントと User の紐付けを表す。
+// プロバイダー側のアカウントは発行者と subject の組で特定する。Email は紐付けた時点の値で、照合には使わない。
+type ExternalIdentity struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;index;not null" json:"user_id"`
+	Issuer      string    `gorm:"size:255;not null;uniqueIndex:idx_external_identities_subject" json:"issuer"`
+	Subject     string    `gorm:"size:255;not null;uniqueIndex:idx_external_identities_subject" json:"subject"`
+	Email       string    `gorm:"size:255" json:"email"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// OIDCLoginRequest は ID プロバイダーへリダイレクトしてから、コールバックを受けるまでのログインを表す。
+// state はハッシュだけを保存する。nonce と PKCE の code_verifier はコールバックでのトークン交換と検証に使う。
+type OIDCLoginRequest struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	StateHash    string    `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Nonce        string    `gorm:"size:64;not null" json:"-"`
+	CodeVerifier string    `gorm:"size:128;not null" json:"-"`
+	// RedirectPath はログイン後に戻すフロントエンドのパス。
+	RedirectPath string     `gorm:"size:512" json:"redirect_path"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Usable は at の時点で未使用かつ期限内かを返す。
+func (r *OIDCLoginRequest) Usable(at time.Time) bool {
+	return r.UsedAt == nil && at.Before(r.ExpiresAt)
+}
@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Favorite はエントリをワンタップで開始するための保存済みテンプレートを表す。
+type Favorite struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	ProjectID *uuid.UUID `gorm:"type:uuid" json:"project_id,omitempty"`
+	Title     string     `gorm:"size:120;not null" json:"title"`
+	Notes     string     `gorm:"type:text" json:"notes"`
+	IsBreak   bool       `gorm:"not null;default:false" json:"is_break"`
+	Ratio     float64    `gorm:"not null;default:1" json:"ratio"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Tags      []Tag      `gorm:"many2many:favorite_tags;constraint:OnDelete:CASCADE;" json:"tags,omitempty"`
+}
+
+func (f *Favorite) Validate() error {
+	if f.Title == "" {
+		return errors.New("title is required")
+	}
+	if len(f.Title) > 120 {
+		return errors.New("title is too long")
+	}
+	if f.Ratio <= 0 {
+		return errors.New("ratio must be positive")
+	}
+	return nil
+}
+
+// NewEntry はお気に入りの内容を引き継いだ実行中エントリを組み立てる。
+func (f *Favorite) NewEntry(startedAt time.Time) *Entry {
+	return &Entry{
+		ID:        uuid.New(),
+		UserID:    f.UserID,
+		ProjectID: f.ProjectID,
+		Title:     f.Title,
+		Notes:     f.Notes,
+		StartedAt: startedAt,
+		IsBreak:   f.IsBreak,
+		Ratio:     f.Ratio,
+		Tags:      f.Tags,
+	}
+}
+
+// FavoriteTag はお気に入りとタグの関連テーブルを表す。
+type FavoriteTag struct {
+	FavoriteID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TagID      uuid.UUID `gorm:"type:uuid;primaryKey"`
+	CreatedAt  time.Time
+}
+
+func (FavoriteTag) TableName() string {
+	return "favorite_tags"
+}
@@ -0,0 +1,200 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GoalScope は目標の集計対象を表す。
+type GoalScope string
+
+const (
+	GoalScopeUser    GoalScope = "user"
+	GoalScopeProject GoalScope = "project"
+	GoalScopeTag     GoalScope = "tag"
+)
+
+// GoalPeriod は目標の達成判定を行う期間の単位を表す。
+type GoalPeriod string
+
+const (
+	GoalPeriodDaily   GoalPeriod = "daily"
+	GoalPeriodWeekly  GoalPeriod = "weekly"
+	GoalPeriodMonthly GoalPeriod = "monthly"
+)
+
+// WeekdayMask は time.Weekday をビットで保持する曜日集合。JSON では曜日番号 (0=日曜) の配列になる。
+type WeekdayMask uint8
+
+// DefaultWorkingDays は月曜から金曜までを稼働日とする。
+const DefaultWorkingDays = WeekdayMask(1<<time.Monday | 1<<time.Tuesday | 1<<time.Wednesday | 1<<time.Thursday | 1<<time.Friday)
+
+// NewWeekdayMask は曜日の列から集合を組み立てる。
+func NewWeekdayMask(days ...time.Weekday) WeekdayMask {
+	var mask WeekdayMask
+	for _, day := range days {
+		mask |= 1 << day
+	}
+	return mask
+}
+
+func (m WeekdayMask) Contains(day time.Weekday) bool {
+	return m&(1<<day) != 0
+}
+
+func (m WeekdayMask) Weekdays() []time.Weekday {
+	days := make([]time.Weekday, 0, 7)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if m.Contains(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+func (m WeekdayMask) MarshalJSON() ([]byte, error) {
+	days := make([]int, 0, 7)
+	for _, day := range m.Weekdays() {
+		days = append(days, int(day))
+	}
+	return json.Marshal(days)
+}
+
+func (m *WeekdayMask) UnmarshalJSON(data []byte) error {
+	var days []int
+	if err := json.Unmarshal(data, &days); err != nil {
+		return err
+	}
+	var mask WeekdayMask
+	for _, day := range days {
+		if day < 0 || day > 6 {
+			return errors.New("weekday must be between 0 and 6")
+		}
+		mask |= 1 << day
+	}
+	*m = mask
+	return nil
+}
+
+// Goal はユーザー・プロジェクト・タグ単位の作業時間目標を表す。
+// WorkingDays は daily 目標の連続達成 (streak) で、稼働日以外を判定対象から外すために使う。
+type Goal struct {
+	ID          uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID   `gorm:"type:uuid;index;not null" json:"user_id"`
+	Name        string      `gorm:"size:80;not null" json:"name"`
+	Scope       GoalScope   `gorm:"size:16;not null" json:"scope"`
+	ProjectID   *uuid.UUID  `gorm:"type:uuid" json:"project_id,omitempty"`
+	TagID       *uuid.UUID  `gorm:"type:uuid" json:"tag_id,omitempty"`
+	Period      GoalPeriod  `gorm:"size:16;not null" json:"period"`
+	TargetSec   int64       `gorm:"not null" json:"target_sec"`
+	WorkingDays WeekdayMask `gorm:"not null;default:62" json:"working_days"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+func (g *Goal) Validate() error {
+	if g.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(g.Name) > 80 {
+		return errors.New("name is too long")
+	}
+	switch g.Scope {
+	case GoalScopeUser:
+		if g.ProjectID != nil || g.TagID != nil {
+			return errors.New("user scope must not reference project or tag")
+		}
+	case GoalScopeProject:
+		if g.ProjectID == nil || g.TagID != nil {
+			return errors.New("project scope requires project_id only")
+		}
+	case GoalScopeTag:
+		if g.TagID == nil || g.ProjectID != nil {
+			return errors.New("tag scope requires tag_id only")
+		}
+	default:
+		return errors.New("scope must be user, project or tag")
+	}
+	switch g.Period {
+	case GoalPeriodDaily, GoalPeriodWeekly, GoalPeriodMonthly:
+	default:
+		return errors.New("period must be daily, weekly or monthly")
+	}
+	if g.TargetSec <= 0 {
+		return errors.New("target must be positive")
+	}
+	if g.WorkingDays == 0 {
+		return errors.New("working_days must include at least one day")
+	}
+	return nil
+}
+
+// Matches はエントリが目標の集計対象かを返す。休憩エントリは常に対象外。
+func (g *Goal) Matches(entry Entry) bool {
+	if entry.IsBreak {
+		return false
+	}
+	switch g.Scope {
+	case GoalScopeProject:
+		return entry.ProjectID != nil && g.ProjectID != nil && *entry.ProjectID == *g.ProjectID
+	case GoalScopeTag:
+		for _, tag := range entry.Tags {
+			if g.TagID != nil && tag.ID == *g.TagID {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// PeriodStart は t を含む期間の開始時刻を t のロケーションで返す。週は月曜始まり。
+func (g *Goal) PeriodStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch g.Period {
+	case GoalPeriodWeekly:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case GoalPeriodMonthly:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	default:
+		return day
+	}
+}
+
+// NextPeriod は期間開始時刻 start の次の期間開始時刻を返す。
+func (g *Goal) NextPeriod(start time.Time) time.Time {
+	switch g.Period {
+	case GoalPeriodWeekly:
+		return start.AddDate(0, 0, 7)
+	case GoalPeriodMonthly:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// PrevPeriod は期間開始時刻 start の前の期間開始時刻を返す。
+func (g *Goal) PrevPeriod(start time.Time) time.Time {
+	switch g.Period {
+	case GoalPeriodWeekly:
+		return start.AddDate(0, 0, -7)
+	case GoalPeriodMonthly:
+		return start.AddDate(0, -1, 0)
+	default:
+		return start.AddDate(0, 0, -1)
+	}
+}
+
+// CountsForStreak は期間開始時刻 start が streak の判定対象かを返す。
+// 日次目標では稼働日以外を飛ばし、週次・月次は常に対象とする。
+func (g *Goal) CountsForStreak(start time.Time) bool {
+	if g.Period != GoalPeriodDaily {
+		return true
+	}
+	return g.WorkingDays.Contains(start.Weekday())
+}
@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PersonalTokenPrefix はパーソナルアクセストークンの平文に付ける接頭辞。
+// Bearer ヘッダーで受け取ったときに短命のアクセストークンと見分けるのに使う。
+const PersonalTokenPrefix = "chrpat_"
+
+// パーソナルアクセストークンに付与できるスコープ。
+// read はそのルートグループの参照系、write は状態変更系を許可する。write は read を含まない。
+const (
+	ScopeEntriesRead      = "entries:read"
+	ScopeEntriesWrite     = "entries:write"
+	ScopeProjectsRead     = "projects:read"
+	ScopeProjectsWrite    = "projects:write"
+	ScopeReportsRead      = "reports:read"
+	ScopeAllocationsRead  = "allocations:read"
+	ScopeAllocationsWrite = "allocations:write"
+)
+
+// PersonalTokenScopes は付与可能なスコープの一覧。
+var PersonalTokenScopes = []string{
+	ScopeEntriesRead,
+	ScopeEntriesWrite,
+	ScopeProjectsRead,
+	ScopeProjectsWrite,
+	ScopeReportsRead,
+	ScopeAllocationsRead,
+	ScopeAllocationsWrite,
+}
+
+// PersonalAccessToken はスクリプトから API を呼ぶための長期トークンを表す。
+// 平文は作成時にだけ返し、保存するのは SHA-256 ハッシュと表示用の先頭部分のみ。
+// Scopes は OAuth と同じく空白区切りで保存する。
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	Name       string     `gorm:"size:80;not null" json:"name"`
+	TokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`
+	Scopes     string     `gorm:"size:255;not null" json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *PersonalAccessToken) Validate() error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(t.Name) > 80 {
+		return errors.New("name is too long")
+	}
+	scopes := t.ScopeList()
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(PersonalTokenScopes, scope) {
+			return errors.New("unknown scope: " + scope)
+		}
+	}
+	return nil
+}
+
+// ScopeList は保存済みのスコープを配列で返す。
+func (t *PersonalAccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+// Expired は at の時点で有効期限を過ぎているかを返す。期限なしのトークンは失効しない。
+func (t *PersonalAccessToken) Expired(at time.Time) bool {
+	return t.ExpiresAt != nil && !at.Before(*t.ExpiresAt)
+}
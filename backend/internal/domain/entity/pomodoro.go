@@ -0,0 +1,121 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PomodoroPhase はポモドーロセッションの現在フェーズを表す。
+type PomodoroPhase string
+
+const (
+	PomodoroPhaseWork       PomodoroPhase = "work"
+	PomodoroPhaseShortBreak PomodoroPhase = "short_break"
+	PomodoroPhaseLongBreak  PomodoroPhase = "long_break"
+	PomodoroPhaseFinished   PomodoroPhase = "finished"
+)
+
+// PomodoroSession は作業と休憩を交互に繰り返すセッションを表す。
+// フェーズは work と short_break を交互に並べ、Cycles 回目の work の後に long_break を置いて終了する。
+// PhaseIndex と PhaseStartedAt はサーバーが境界時刻まで進めた位置を保持する。
+type PomodoroSession struct {
+	ID                 uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID             uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	ProjectID          *uuid.UUID `gorm:"type:uuid" json:"project_id,omitempty"`
+	Title              string     `gorm:"size:120;not null" json:"title"`
+	WorkSec            int64      `gorm:"not null" json:"work_sec"`
+	ShortBreakSec      int64      `gorm:"not null" json:"short_break_sec"`
+	LongBreakSec       int64      `gorm:"not null" json:"long_break_sec"`
+	Cycles             int        `gorm:"not null" json:"cycles"`
+	PhaseIndex         int        `gorm:"not null;default:0" json:"phase_index"`
+	PhaseStartedAt     time.Time  `gorm:"not null" json:"phase_started_at"`
+	CompletedPomodoros int        `gorm:"not null;default:0" json:"completed_pomodoros"`
+	CurrentEntryID     *uuid.UUID `gorm:"type:uuid" json:"current_entry_id,omitempty"`
+	StartedAt          time.Time  `gorm:"not null" json:"started_at"`
+	EndedAt            *time.Time `json:"ended_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+func (s *PomodoroSession) Validate() error {
+	if s.Title == "" {
+		return errors.New("title is required")
+	}
+	if len(s.Title) > 120 {
+		return errors.New("title is too long")
+	}
+	if s.WorkSec <= 0 || s.ShortBreakSec <= 0 || s.LongBreakSec <= 0 {
+		return errors.New("phase lengths must be positive")
+	}
+	if s.Cycles <= 0 {
+		return errors.New("cycles must be positive")
+	}
+	return nil
+}
+
+// PhaseAt は index 番目のフェーズ種別を返す。範囲外は finished になる。
+func (s *PomodoroSession) PhaseAt(index int) PomodoroPhase {
+	if index < 0 || index >= s.Cycles*2 {
+		return PomodoroPhaseFinished
+	}
+	if index%2 == 0 {
+		return PomodoroPhaseWork
+	}
+	if index == s.Cycles*2-1 {
+		return PomodoroPhaseLongBreak
+	}
+	return PomodoroPhaseShortBreak
+}
+
+// Phase は現在のフェーズを返す。停止済みセッションは finished になる。
+func (s *PomodoroSession) Phase() PomodoroPhase {
+	if s.EndedAt != nil {
+		return PomodoroPhaseFinished
+	}
+	return s.PhaseAt(s.PhaseIndex)
+}
+
+// PhaseLength はフェーズ種別ごとの長さを返す。
+func (s *PomodoroSession) PhaseLength(phase PomodoroPhase) time.Duration {
+	switch phase {
+	case PomodoroPhaseWork:
+		return time.Duration(s.WorkSec) * time.Second
+	case PomodoroPhaseShortBreak:
+		return time.Duration(s.ShortBreakSec) * time.Second
+	case PomodoroPhaseLongBreak:
+		return time.Duration(s.LongBreakSec) * time.Second
+	default:
+		return 0
+	}
+}
+
+// PhaseEndsAt は現在フェーズの終了予定時刻を返す。
+func (s *PomodoroSession) PhaseEndsAt() time.Time {
+	return s.PhaseStartedAt.Add(s.PhaseLength(s.Phase()))
+}
+
+// NewPhaseEntry は現在フェーズに対応する実行中エントリを組み立てる。
+func (s *PomodoroSession) NewPhaseEntry() *Entry {
+	entry := &Entry{
+		ID:                uuid.New(),
+		UserID:            s.UserID,
+		ProjectID:         s.ProjectID,
+		Title:             s.Title,
+		StartedAt:         s.PhaseStartedAt,
+		Ratio:             1,
+		PomodoroSessionID: &s.ID,
+	}
+	switch s.Phase() {
+	case PomodoroPhaseShortBreak:
+		entry.Title = "Short break"
+		entry.ProjectID = nil
+		entry.IsBreak = true
+	case PomodoroPhaseLongBreak:
+		entry.Title = "Long break"
+		entry.ProjectID = nil
+		entry.IsBreak = true
+	}
+	return entry
+}
@@ -15,8 +15,10 @@ type Project struct {
 	Description string    `gorm:"size:255" json:"description"`
 	Color       string    `gorm:"size:7;not null" json:"color"`
 	IsArchived  bool      `gorm:"not null;default:false" json:"is_archived"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	// Rounding が設定されている場合、このプロジェクトのエントリはユーザー既定より優先して丸める。
+	Rounding  RoundingRule `gorm:"embedded;embeddedPrefix:rounding_" json:"rounding"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 func (p *Project) Validate() error {
@@ -32,5 +34,8 @@ func (p *Project) Validate() error {
 	if len(p.Description) > 255 {
 		return errors.New("description is too long")
 	}
+	if err := p.Rounding.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
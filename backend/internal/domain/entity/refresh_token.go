@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken は Bearer 認証クライアントへ発行したリフレッシュトークンを表す。
+// 平文は発行時にだけ返し、保存するのは SHA-256 ハッシュのみ。
+// リフレッシュのたびに同じ FamilyID で新しいトークンへ置き換え、使用済みトークンの再提示はファミリーごと失効させる。
+type RefreshToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	FamilyID  uuid.UUID  `gorm:"type:uuid;index;not null" json:"family_id"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Active は at の時点でトークンが未使用・未失効かつ期限内かを返す。
+func (t *RefreshToken) Active(at time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && at.Before(t.ExpiresAt)
+}
@@ -0,0 +1,68 @@
+package entity
+
+import "errors"
+
+// RoundingMode は請求単位へ丸める方向を表す。空文字は丸めなし。
+type RoundingMode string
+
+const (
+	RoundingNone    RoundingMode = ""
+	RoundingUp      RoundingMode = "up"
+	RoundingDown    RoundingMode = "down"
+	RoundingNearest RoundingMode = "nearest"
+)
+
+// maxRoundingIncrementMinutes は丸め単位の上限 (1 日)。
+const maxRoundingIncrementMinutes = 24 * 60
+
+// RoundingRule はエントリの秒数を IncrementMinutes 単位へ丸めるルール。
+// 保存済みの DurationSec は変更せず、レポート集計時にだけ適用する。
+type RoundingRule struct {
+	Mode             RoundingMode `gorm:"column:mode;size:16;not null;default:''" json:"mode"`
+	IncrementMinutes int          `gorm:"column:increment_minutes;not null;default:0" json:"increment_minutes"`
+}
+
+// IsSet は丸めが有効かを返す。
+func (r RoundingRule) IsSet() bool {
+	return r.Mode != RoundingNone && r.IncrementMinutes > 0
+}
+
+func (r RoundingRule) Validate() error {
+	switch r.Mode {
+	case RoundingNone:
+		if r.IncrementMinutes != 0 {
+			return errors.New("rounding increment requires a mode")
+		}
+		return nil
+	case RoundingUp, RoundingDown, RoundingNearest:
+	default:
+		return errors.New("rounding mode must be up, down or nearest")
+	}
+	if r.IncrementMinutes <= 0 || r.IncrementMinutes > maxRoundingIncrementMinutes {
+		return errors.New("rounding increment must be between 1 and 1440 minutes")
+	}
+	return nil
+}
+
+// Apply は秒数を丸める。nearest はちょうど半分を切り上げる。
+func (r RoundingRule) Apply(seconds int64) int64 {
+	if !r.IsSet() || seconds <= 0 {
+		return seconds
+	}
+	step := int64(r.IncrementMinutes) * 60
+	lower := seconds / step * step
+	if lower == seconds {
+		return seconds
+	}
+	switch r.Mode {
+	case RoundingUp:
+		return lower + step
+	case RoundingDown:
+		return lower
+	default:
+		if seconds-lower >= step-(seconds-lower) {
+			return lower + step
+		}
+		return lower
+	}
+}
@@ -0,0 +1,124 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WorkSchedule はユーザーごとの曜日別の所定労働時間を表す。
+type WorkSchedule struct {
+	UserID       uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	SundaySec    int64     `gorm:"not null;default:0" json:"sunday_sec"`
+	MondaySec    int64     `gorm:"not null;default:0" json:"monday_sec"`
+	TuesdaySec   int64     `gorm:"not null;default:0" json:"tuesday_sec"`
+	WednesdaySec int64     `gorm:"not null;default:0" json:"wednesday_sec"`
+	ThursdaySec  int64     `gorm:"not null;default:0" json:"thursday_sec"`
+	FridaySec    int64     `gorm:"not null;default:0" json:"friday_sec"`
+	SaturdaySec  int64     `gorm:"not null;default:0" json:"saturday_sec"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (s *WorkSchedule) Validate() error {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		sec := s.SecondsFor(day)
+		if sec < 0 || sec > 24*3600 {
+			return errors.New("scheduled hours must be between 0 and 24")
+		}
+	}
+	return nil
+}
+
+// SecondsFor は曜日の所定労働時間を秒で返す。
+func (s *WorkSchedule) SecondsFor(day time.Weekday) int64 {
+	return *s.field(day)
+}
+
+// SetSecondsFor は曜日の所定労働時間を秒で設定する。
+func (s *WorkSchedule) SetSecondsFor(day time.Weekday, sec int64) {
+	*s.field(day) = sec
+}
+
+func (s *WorkSchedule) field(day time.Weekday) *int64 {
+	switch day {
+	case time.Monday:
+		return &s.MondaySec
+	case time.Tuesday:
+		return &s.TuesdaySec
+	case time.Wednesday:
+		return &s.WednesdaySec
+	case time.Thursday:
+		return &s.ThursdaySec
+	case time.Friday:
+		return &s.FridaySec
+	case time.Saturday:
+		return &s.SaturdaySec
+	default:
+		return &s.SundaySec
+	}
+}
+
+// Holiday は所定労働時間を 0 とする祝日・休業日を表す。Date はユーザーのローカル日付 (YYYY-MM-DD)。
+// UID は iCalendar から取り込んだ場合の VEVENT UID で、再取り込み時の照合に使う。
+type Holiday struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_holidays_user_date" json:"user_id"`
+	Date      string    `gorm:"size:10;not null;uniqueIndex:idx_holidays_user_date" json:"date"`
+	Name      string    `gorm:"size:120;not null" json:"name"`
+	UID       string    `gorm:"size:255" json:"uid,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TimeOffKind は休暇の種別を表す。
+type TimeOffKind string
+
+const (
+	TimeOffVacation TimeOffKind = "vacation"
+	TimeOffSick     TimeOffKind = "sick"
+)
+
+// TimeOff は休暇・病欠の記録を表す。DurationSec が 0 の場合は終日扱い。
+type TimeOff struct {
+	ID          uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_time_offs_user_date" json:"user_id"`
+	Date        string      `gorm:"size:10;not null;uniqueIndex:idx_time_offs_user_date" json:"date"`
+	Kind        TimeOffKind `gorm:"size:16;not null" json:"kind"`
+	DurationSec int64       `gorm:"not null;default:0" json:"duration_sec"`
+	Note        string      `gorm:"size:255" json:"note"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+func (t *TimeOff) Validate() error {
+	if _, err := time.Parse("2006-01-02", t.Date); err != nil {
+		return errors.New("date must be YYYY-MM-DD")
+	}
+	if t.Kind != TimeOffVacation && t.Kind != TimeOffSick {
+		return errors.New("kind must be vacation or sick")
+	}
+	if t.DurationSec < 0 || t.DurationSec > 24*3600 {
+		return errors.New("duration must be between 0 and 24 hours")
+	}
+	return nil
+}
+
+// ExpectedSeconds は祝日と休暇を反映したその日の所定労働時間を返す。
+func ExpectedSeconds(schedule *WorkSchedule, day time.Weekday, holiday *Holiday, timeOff *TimeOff) int64 {
+	if schedule == nil || holiday != nil {
+		return 0
+	}
+	expected := schedule.SecondsFor(day)
+	if timeOff != nil {
+		if timeOff.DurationSec == 0 {
+			return 0
+		}
+		expected -= timeOff.DurationSec
+	}
+	if expected < 0 {
+		return 0
+	}
+	return expected
+}
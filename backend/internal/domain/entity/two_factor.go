@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryCode は認証アプリを使えなくなったときに TOTP コードの代わりに使う使い捨てのコードを表す。
+// 平文は発行時に一度だけ返し、保存するのは SHA-256 ハッシュのみ。
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoginChallenge はパスワード確認後、二要素目を待っているログインを表す。
+// session はチャレンジを完了するまで作らない。トークンはハッシュだけを保存する。
+type LoginChallenge struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null" json:"user_id"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	// Attempts は誤ったコードの入力回数。上限に達したチャレンジは使えない。
+	Attempts  int        `gorm:"not null;default:0" json:"attempts"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Usable は at の時点で未使用かつ期限内で、入力回数が maxAttempts 未満かを返す。
+func (c *LoginChallenge) Usable(at time.Time, maxAttempts int) bool {
+	return c.UsedAt == nil && at.Before(c.ExpiresAt) && c.Attempts < maxAttempts
+}
@@ -15,8 +15,20 @@ type User struct {
 	PasswordHash string    `gorm:"not null" json:"-"`
 	DisplayName  string    `gorm:"size:50" json:"display_name"`
 	TimeZone     string    `gorm:"size:40;default:UTC" json:"time_zone"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	// EmailVerifiedAt は現在の Email の所有を確認した時刻。未確認なら nil。
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// TOTPSecret は base32 の TOTP 共有鍵。登録を始めてから有効化するまでの間も保持する。
+	TOTPSecret string `gorm:"size:64" json:"-"`
+	// TOTPEnabledAt は二要素認証を有効にした時刻。nil の間はパスワードだけでログインできる。
+	TOTPEnabledAt *time.Time `json:"-"`
+	// TOTPLastStep は最後に受け付けたコードの時間ステップ。同じコードの使い回しを拒否する。
+	TOTPLastStep int64 `json:"-"`
+	// TokensNotBefore より前に発行したアクセストークンは期限内でも拒否する。パスワード変更などで更新する。
+	TokensNotBefore *time.Time `json:"-"`
+	// Rounding はプロジェクトに丸めルールがないエントリへ適用する既定のルール。
+	Rounding  RoundingRule `gorm:"embedded;embeddedPrefix:rounding_" json:"rounding"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 // Normalize は永続化前にエンティティを整形する。
@@ -27,6 +39,16 @@ func (u *User) Normalize() {
 	}
 }
 
+// EmailVerified は現在のメールアドレスが確認済みかを返す。
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// TwoFactorEnabled はログインに TOTP コードを求めるかを返す。
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
 // Validate は最小限のサーバー側チェックを行う。
 func (u *User) Validate() error {
 	if u.Email == "" {
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,11 +10,22 @@ import (
 	"chronome/internal/domain/entity"
 )
 
+// ErrNotFound は取得対象の行がないことを表す。返すメソッドはコメントに明記する。
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict は読み込んだ後に別のリクエストが同じ行を更新していたことを表す。返すメソッドはコメントに明記する。
+var ErrConflict = errors.New("record was changed concurrently")
+
 // UserRepository はユーザーモデルの永続化を抽象化する。
 type UserRepository interface {
 	Create(ctx context.Context, user *entity.User) error
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error)
+	Update(ctx context.Context, user *entity.User) error
+	// RevokeAccessTokens は at より前に発行したアクセストークンを拒否するよう TokensNotBefore を更新する。
+	RevokeAccessTokens(ctx context.Context, id uuid.UUID, at time.Time) error
+	// Delete はユーザーと、ユーザーが所有するすべてのデータを 1 トランザクションで削除する。
+	Delete(ctx context.Context, id uuid.UUID) error
 }
 
 // ProjectRepository はプロジェクトの CRUD を扱う。
@@ -31,16 +43,36 @@ type EntryFilter struct {
 	To        *time.Time
 	ProjectID *uuid.UUID
 	TagID     *uuid.UUID
+	// RunningOnly は ended_at が未設定の実行中エントリだけに絞る。
+	RunningOnly bool
+	// Limit が正の場合は started_at の新しい順に先頭から件数を絞る。
+	Limit int
+}
+
+// EntryChanges はまとめて適用するエントリの変更。Delete、Stop、Update、Create の順に適用し、
+// Stop・Update と Create はエントリの Tags の ID で紐付けを置き換える。
+// Stop は実行中のエントリだけを更新するので、同時に停止された場合も含めて対象が実行中でなければ ErrNotFound になる。
+// 削除・更新対象が見つからない場合は ErrNotFound になる。
+type EntryChanges struct {
+	Delete []uuid.UUID
+	Stop   []entity.Entry
+	Update []entity.Entry
+	Create []entity.Entry
 }
 
 // EntryRepository はエントリの CRUD を提供する。
 type EntryRepository interface {
 	Create(ctx context.Context, entry *entity.Entry) error
 	ListByUser(ctx context.Context, userID uuid.UUID, filter EntryFilter) ([]entity.Entry, error)
+	// GetByID はユーザー所有のエントリがなければ ErrNotFound を返す。
 	GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Entry, error)
 	Update(ctx context.Context, entry *entity.Entry) error
 	Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
 	ReplaceTags(ctx context.Context, entry *entity.Entry, tagIDs []uuid.UUID) error
+	// ApplyChanges は changes をひとつのトランザクションで適用し、途中で失敗した場合は何も変更しない。
+	ApplyChanges(ctx context.Context, userID uuid.UUID, changes EntryChanges) error
+	// ListIdleRunning は全ユーザーの実行中エントリのうち、heartbeat を受けたことがあり、最終アクティビティが inactiveBefore より前のものを返す。
+	ListIdleRunning(ctx context.Context, inactiveBefore time.Time) ([]entity.Entry, error)
 }
 
 // TagRepository は現時点では未使用だが将来の拡張用に用意している。
@@ -55,4 +87,155 @@ type TagRepository interface {
 // AllocationRepository は分配リクエストの永続化を担う。
 type AllocationRepository interface {
 	Create(ctx context.Context, request *entity.AllocationRequest, allocations []entity.TaskAllocation) error
+	// CreateWithEntries は分配履歴と、EntryRepository.ApplyChanges と同じ順で適用する changes をひとつのトランザクションで保存する。
+	CreateWithEntries(ctx context.Context, request *entity.AllocationRequest, allocations []entity.TaskAllocation, changes EntryChanges) error
+	// ListByUser は作成日時の新しい順に limit 件を offset から返し、総件数も併せて返す。
+	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]entity.AllocationRequest, int64, error)
+	// GetByID はユーザー所有の分配結果がなければ ErrNotFound を返す。
+	GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationRequest, []entity.TaskAllocation, error)
+	Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	// CreateBatch は親バッチと、requests[i] とその分配行 allocations[i] をひとつのトランザクションで保存する。
+	CreateBatch(ctx context.Context, batch *entity.AllocationBatch, requests []entity.AllocationRequest, allocations [][]entity.TaskAllocation) error
+	// GetBatch はバッチと、BatchIndex 順の子リクエスト、それらの分配行をまとめて返す。ユーザー所有のバッチがなければ ErrNotFound を返す。
+	GetBatch(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationBatch, []entity.AllocationRequest, []entity.TaskAllocation, error)
+}
+
+// AllocationTemplateRepository は分配テンプレートとタスクをまとめて永続化する。
+// 取得系はタスクを Position 順に読み込み、Update はタスクを丸ごと置き換える。
+type AllocationTemplateRepository interface {
+	Create(ctx context.Context, template *entity.AllocationTemplate) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.AllocationTemplate, error)
+	// GetByID はユーザー所有のテンプレートがなければ ErrNotFound を返す。
+	GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationTemplate, error)
+	Update(ctx context.Context, template *entity.AllocationTemplate) error
+	Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+}
+
+// FavoriteRepository はお気に入りの CRUD を扱う。
+type FavoriteRepository interface {
+	Create(ctx context.Context, favorite *entity.Favorite) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.Favorite, error)
+	GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Favorite, error)
+	Update(ctx context.Context, favorite *entity.Favorite) error
+	Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	ReplaceTags(ctx context.Context, favorite *entity.Favorite, tagIDs []uuid.UUID) error
+}
+
+// PomodoroRepository はポモドーロセッションの永続化を扱う。
+type PomodoroRepository interface {
+	// Create はセッションと最初のフェーズのエントリをひとつのトランザクションで保存する。
+	Create(ctx context.Context, session *entity.PomodoroSession, entry *entity.Entry) error
+	GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.PomodoroSession, error)
+	// GetActive は ended_at が未設定のセッションを返し、存在しなければエラーを返す。
+	GetActive(ctx context.Context, userID uuid.UUID) (*entity.PomodoroSession, error)
+	// ListActive は全ユーザーの ended_at が未設定のセッションを返す。
+	ListActive(ctx context.Context) ([]entity.PomodoroSession, error)
+	// Advance は phase_index が fromIndex のまま進行中のセッションだけを session の内容で更新し、
+	// changes も EntryRepository.ApplyChanges と同じ順で同じトランザクションに適用する。
+	// 別のリクエストが先にフェーズを進めたか停止していれば ErrConflict を返し、何も変更しない。
+	Advance(ctx context.Context, session *entity.PomodoroSession, fromIndex int, changes EntryChanges) error
+}
+
+// GoalRepository は作業時間目標の CRUD を扱う。
+type GoalRepository interface {
+	Create(ctx context.Context, goal *entity.Goal) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.Goal, error)
+	GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Goal, error)
+	Update(ctx context.Context, goal *entity.Goal) error
+	Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+}
+
+// ScheduleRepository は所定労働時間・祝日・休暇の永続化を扱う。
+// from / to は YYYY-MM-DD の両端を含む日付で、空文字は無制限を表す。
+type ScheduleRepository interface {
+	// GetSchedule は未設定なら ErrNotFound を返す。
+	GetSchedule(ctx context.Context, userID uuid.UUID) (*entity.WorkSchedule, error)
+	SaveSchedule(ctx context.Context, schedule *entity.WorkSchedule) error
+	ListHolidays(ctx context.Context, userID uuid.UUID, from, to string) ([]entity.Holiday, error)
+	// UpsertHolidays は同じ日付の祝日を上書きしながら保存する。
+	UpsertHolidays(ctx context.Context, holidays []entity.Holiday) error
+	DeleteHoliday(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	ListTimeOff(ctx context.Context, userID uuid.UUID, from, to string) ([]entity.TimeOff, error)
+	CreateTimeOff(ctx context.Context, timeOff *entity.TimeOff) error
+	DeleteTimeOff(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+}
+
+// RefreshTokenRepository は Bearer 認証のリフレッシュトークンを扱う。
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *entity.RefreshToken) error
+	// GetByHash はトークンハッシュで検索する。使用済み・失効済みのトークンも返す。
+	GetByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	// MarkUsed は未使用のトークンだけを使用済みにし、更新できたかを返す。同時リフレッシュの二重成功を防ぐ。
+	MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	// RevokeFamily は同じファミリーの未失効トークンをすべて失効させる。
+	RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error
+	// RevokeByUser はユーザーの未失効トークンをすべて失効させる。
+	RevokeByUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+}
+
+// PersonalTokenRepository はパーソナルアクセストークンを扱う。
+type PersonalTokenRepository interface {
+	Create(ctx context.Context, token *entity.PersonalAccessToken) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.PersonalAccessToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (*entity.PersonalAccessToken, error)
+	// Delete は削除できたかを返す。他ユーザーのトークンは削除しない。
+	Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error)
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// PasswordResetRepository はパスワード再設定トークンを扱う。
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *entity.PasswordResetToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*entity.PasswordResetToken, error)
+	// MarkUsed は未使用のトークンだけを使用済みにし、更新できたかを返す。
+	MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	// InvalidateByUser はユーザーの未使用トークンをすべて使用済みにする。
+	InvalidateByUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+}
+
+// EmailVerificationRepository はメールアドレス確認トークンを扱う。
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, token *entity.EmailVerificationToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*entity.EmailVerificationToken, error)
+	// MarkUsed は未使用のトークンだけを使用済みにし、更新できたかを返す。
+	MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	// InvalidateByUser はユーザーの未使用トークンをすべて使用済みにする。
+	InvalidateByUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+	// ListCreatedSince は since 以降に作成したユーザーのトークンを新しい順に返す。再送の間隔制限に使う。
+	ListCreatedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]entity.EmailVerificationToken, error)
+}
+
+// RecoveryCodeRepository は二要素認証のリカバリーコードを扱う。
+type RecoveryCodeRepository interface {
+	// Replace はユーザーの既存のコードを削除してから codes を保存する。再発行すると古いコードは使えなくなる。
+	Replace(ctx context.Context, userID uuid.UUID, codes []entity.RecoveryCode) error
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+	// Use は未使用のコードだけを使用済みにし、更新できたかを返す。
+	Use(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) (bool, error)
+	CountUnused(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+// LoginChallengeRepository は二要素目を待っているログインを扱う。
+type LoginChallengeRepository interface {
+	Create(ctx context.Context, challenge *entity.LoginChallenge) error
+	GetByHash(ctx context.Context, tokenHash string) (*entity.LoginChallenge, error)
+	// RecordFailure は誤ったコードの入力回数を 1 増やす。
+	RecordFailure(ctx context.Context, id uuid.UUID) error
+	// MarkUsed は未使用のチャレンジだけを使用済みにし、更新できたかを返す。
+	MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+}
+
+// ExternalIdentityRepository は ID プロバイダーのアカウントとの紐付けを扱う。
+type ExternalIdentityRepository interface {
+	Create(ctx context.Context, identity *entity.ExternalIdentity) error
+	GetBySubject(ctx context.Context, issuer, subject string) (*entity.ExternalIdentity, error)
+	TouchLastLogin(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// OIDCLoginRequestRepository は ID プロバイダーからのコールバックを待っているログインを扱う。
+type OIDCLoginRequestRepository interface {
+	Create(ctx context.Context, request *entity.OIDCLoginRequest) error
+	GetByStateHash(ctx context.Context, stateHash string) (*entity.OIDCLoginRequest, error)
+	// MarkUsed は未使用のリクエストだけを使用済みにし、更新できたかを返す。同じコールバックの再送を拒否する。
+	MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
 }
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+var (
+	// ErrIncorrectPassword は本人確認のために入力された現在のパスワードが一致しないことを表す。
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	// ErrEmailTaken は変更先のメールアドレスが他のアカウントで使われていることを表す。
+	ErrEmailTaken = errors.New("email is already in use")
+)
+
+// AccountUsecase はログイン中ユーザーによるアカウント情報の変更と削除を扱う。
+// セッションの失効は HTTP 層の関心事なので、パスワード変更後の失効は handler 側で行う。
+type AccountUsecase struct {
+	users        repository.UserRepository
+	verification *EmailVerificationUsecase
+	mailer       provider.Mailer
+}
+
+func NewAccountUsecase(users repository.UserRepository, verification *EmailVerificationUsecase, mailer provider.Mailer) *AccountUsecase {
+	return &AccountUsecase{users: users, verification: verification, mailer: mailer}
+}
+
+// ChangePassword は現在のパスワードを確認してから新しいパスワードに置き換える。
+func (u *AccountUsecase) ChangePassword(ctx context.Context, userID uuid.UUID, input dto.PasswordChangeRequest) error {
+	data, err := input.Normalize()
+	if err != nil {
+		return err
+	}
+	user, err := reauthenticate(ctx, u.users, userID, data.CurrentPassword)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(data.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+	return u.users.Update(ctx, user)
+}
+
+// UpdateProfile は表示名とタイムゾーンを更新する。
+func (u *AccountUsecase) UpdateProfile(ctx context.Context, userID uuid.UUID, input dto.ProfileUpdateRequest) (*entity.User, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	user, err := u.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if data.DisplayName != nil {
+		user.DisplayName = *data.DisplayName
+	}
+	if data.TimeZone != nil {
+		user.TimeZone = *data.TimeZone
+	}
+	if err := u.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RequestEmailChange は新しいアドレスに確認リンクを送る。アドレスはリンクを開くまで変わらない。
+// 乗っ取りに気付けるよう、現在のアドレスにも変更依頼があったことを知らせる。
+func (u *AccountUsecase) RequestEmailChange(ctx context.Context, userID uuid.UUID, input dto.EmailChangeRequest) error {
+	data, err := input.Normalize()
+	if err != nil {
+		return err
+	}
+	user, err := reauthenticate(ctx, u.users, userID, data.Password)
+	if err != nil {
+		return err
+	}
+	if data.Email == user.Email {
+		return dto.ValidationError{Field: "email", Message: "must differ from the current email"}
+	}
+	if _, err := u.users.GetByEmail(ctx, data.Email); err == nil {
+		return ErrEmailTaken
+	}
+	if err := u.verification.SendChange(ctx, user, data.Email); err != nil {
+		return err
+	}
+	return u.mailer.Send(ctx, provider.MailMessage{
+		To:      user.Email,
+		Subject: "ChronoMe メールアドレス変更のお知らせ",
+		Body: fmt.Sprintf("ChronoMe のメールアドレスを %s に変更する依頼がありました。\n"+
+			"変更は新しいアドレスで確認リンクを開いたときに反映されます。\n\n"+
+			"心当たりがない場合は、すぐにパスワードを変更してください。\n", data.Email),
+	})
+}
+
+// Delete は現在のパスワードを確認してから、ユーザーと所有するすべてのデータを削除する。
+func (u *AccountUsecase) Delete(ctx context.Context, userID uuid.UUID, input dto.AccountDeleteRequest) error {
+	password, err := input.Normalize()
+	if err != nil {
+		return err
+	}
+	if _, err := reauthenticate(ctx, u.users, userID, password); err != nil {
+		return err
+	}
+	return u.users.Delete(ctx, userID)
+}
+
+// reauthenticate はログイン中ユーザーを再認証する。重要な変更の前に現在のパスワードを確かめる。
+func reauthenticate(ctx context.Context, users repository.UserRepository, userID uuid.UUID, password string) (*entity.User, error) {
+	user, err := users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrIncorrectPassword
+	}
+	return user, nil
+}
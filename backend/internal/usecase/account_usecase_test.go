@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+// memoryEmailVerifications はハッシュで引ける最小限の確認トークン保存先を fake に被せる。
+func memoryEmailVerifications() *fakes.FakeEmailVerificationRepository {
+	stored := make(map[string]*entity.EmailVerificationToken)
+	return &fakes.FakeEmailVerificationRepository{
+		CreateFn: func(_ context.Context, token *entity.EmailVerificationToken) error {
+			stored[token.TokenHash] = token
+			return nil
+		},
+		GetByHashFn: func(_ context.Context, hash string) (*entity.EmailVerificationToken, error) {
+			token, ok := stored[hash]
+			if !ok {
+				return nil, errors.New("not found")
+			}
+			copied := *token
+			return &copied, nil
+		},
+		MarkUsedFn: func(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+			for _, token := range stored {
+				if token.ID == id && token.UsedAt == nil {
+					token.UsedAt = &at
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		InvalidateByUserFn: func(_ context.Context, userID uuid.UUID, at time.Time) error {
+			for _, token := range stored {
+				if token.UserID == userID && token.UsedAt == nil {
+					token.UsedAt = &at
+				}
+			}
+			return nil
+		},
+		ListCreatedSinceFn: func(_ context.Context, userID uuid.UUID, since time.Time) ([]entity.EmailVerificationToken, error) {
+			var tokens []entity.EmailVerificationToken
+			for _, token := range stored {
+				if token.UserID == userID && !token.CreatedAt.Before(since) {
+					tokens = append(tokens, *token)
+				}
+			}
+			sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+			return tokens, nil
+		},
+	}
+}
+
+// memoryUsers はメールアドレスと ID で引けるユーザー一覧を fake に被せる。
+func memoryUsers(users ...*entity.User) *fakes.FakeUserRepository {
+	return &fakes.FakeUserRepository{
+		GetByEmailFn: func(_ context.Context, email string) (*entity.User, error) {
+			for _, user := range users {
+				if user.Email == email {
+					copied := *user
+					return &copied, nil
+				}
+			}
+			return nil, errors.New("not found")
+		},
+		GetByIDFn: func(_ context.Context, id uuid.UUID) (*entity.User, error) {
+			for _, user := range users {
+				if user.ID == id {
+					copied := *user
+					return &copied, nil
+				}
+			}
+			return nil, errors.New("not found")
+		},
+		UpdateFn: func(_ context.Context, updated *entity.User) error {
+			for _, user := range users {
+				if user.ID == updated.ID {
+					*user = *updated
+				}
+			}
+			return nil
+		},
+	}
+}
+
+var verifyLinkPattern = regexp.MustCompile(`https://chronome\.example/verify-email\?token=(\S+)`)
+
+func verifyTokenFromMail(t *testing.T, body string) string {
+	t.Helper()
+	match := verifyLinkPattern.FindStringSubmatch(body)
+	require.Len(t, match, 2, body)
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+	return token
+}
+
+func newAccountUsecaseForTest(users *fakes.FakeUserRepository, mailer *fakes.RecordingMailer, clock fakes.FixedTimeProvider) (*AccountUsecase, *EmailVerificationUsecase) {
+	verification := NewEmailVerificationUsecase(users, memoryEmailVerifications(), mailer, stubConfig{}, clock)
+	return NewAccountUsecase(users, verification, mailer), verification
+}
+
+func userWithPassword(t *testing.T, email, password string) *entity.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	return &entity.User{ID: uuid.New(), Email: email, PasswordHash: string(hash), TimeZone: "UTC"}
+}
+
+func TestAccountUsecase_ChangePasswordRequiresCurrentPassword(t *testing.T) {
+	user := userWithPassword(t, "user@example.com", "old-password")
+	uc, _ := newAccountUsecaseForTest(memoryUsers(user), &fakes.RecordingMailer{}, fakes.FixedTimeProvider{})
+
+	err := uc.ChangePassword(context.Background(), user.ID, dto.PasswordChangeRequest{CurrentPassword: "wrong-password", NewPassword: "new-password"})
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+	err = uc.ChangePassword(context.Background(), user.ID, dto.PasswordChangeRequest{CurrentPassword: "old-password", NewPassword: "short"})
+	var valErr dto.ValidationError
+	require.ErrorAs(t, err, &valErr)
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("old-password")))
+
+	require.NoError(t, uc.ChangePassword(context.Background(), user.ID, dto.PasswordChangeRequest{CurrentPassword: "old-password", NewPassword: "new-password"}))
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("new-password")))
+}
+
+func TestAccountUsecase_EmailChangeAppliesOnlyAfterVerification(t *testing.T) {
+	user := userWithPassword(t, "user@example.com", "password-1")
+	other := userWithPassword(t, "other@example.com", "password-2")
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }}
+	mailer := &fakes.RecordingMailer{}
+	uc, verification := newAccountUsecaseForTest(memoryUsers(user, other), mailer, clock)
+
+	err := uc.RequestEmailChange(context.Background(), user.ID, dto.EmailChangeRequest{Email: "other@example.com", Password: "password-1"})
+	require.ErrorIs(t, err, ErrEmailTaken)
+	err = uc.RequestEmailChange(context.Background(), user.ID, dto.EmailChangeRequest{Email: "new@example.com", Password: "password-2"})
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+	require.Empty(t, mailer.Messages())
+
+	require.NoError(t, uc.RequestEmailChange(context.Background(), user.ID, dto.EmailChangeRequest{Email: " New@Example.com ", Password: "password-1"}))
+	sent := mailer.Messages()
+	require.Len(t, sent, 2)
+	require.Equal(t, "new@example.com", sent[0].To)
+	require.Equal(t, "user@example.com", sent[1].To)
+	require.Contains(t, sent[1].Body, "new@example.com")
+	require.NotContains(t, sent[1].Body, "verify-email")
+	// 確認するまではアドレスは変わらない。
+	require.Equal(t, "user@example.com", user.Email)
+
+	raw := verifyTokenFromMail(t, sent[0].Body)
+	confirmed, err := verification.Confirm(context.Background(), dto.EmailVerifyRequest{Token: raw})
+	require.NoError(t, err)
+	require.Equal(t, "new@example.com", confirmed.Email)
+	require.Equal(t, "new@example.com", user.Email)
+
+	_, err = verification.Confirm(context.Background(), dto.EmailVerifyRequest{Token: raw})
+	require.ErrorIs(t, err, ErrInvalidEmailVerificationToken)
+
+	// 依頼後に他のアカウントが同じアドレスを使い始めた場合は確定しない。
+	require.NoError(t, uc.RequestEmailChange(context.Background(), other.ID, dto.EmailChangeRequest{Email: "shared@example.com", Password: "password-2"}))
+	pending := verifyTokenFromMail(t, mailer.Messages()[2].Body)
+	user.Email = "shared@example.com"
+	_, err = verification.Confirm(context.Background(), dto.EmailVerifyRequest{Token: pending})
+	require.ErrorIs(t, err, ErrEmailTaken)
+	require.Equal(t, "other@example.com", other.Email)
+}
+
+func TestAccountUsecase_UpdateProfileValidatesTimeZone(t *testing.T) {
+	user := userWithPassword(t, "user@example.com", "password-1")
+	uc, _ := newAccountUsecaseForTest(memoryUsers(user), &fakes.RecordingMailer{}, fakes.FixedTimeProvider{})
+	name := "  山田 太郎  "
+	tz := "Asia/Tokyo"
+
+	updated, err := uc.UpdateProfile(context.Background(), user.ID, dto.ProfileUpdateRequest{DisplayName: &name, TimeZone: &tz})
+	require.NoError(t, err)
+	require.Equal(t, "山田 太郎", updated.DisplayName)
+	require.Equal(t, "Asia/Tokyo", user.TimeZone)
+
+	for _, invalid := range []string{"", "Local", "Mars/Olympus"} {
+		_, err = uc.UpdateProfile(context.Background(), user.ID, dto.ProfileUpdateRequest{TimeZone: &invalid})
+		var valErr dto.ValidationError
+		require.ErrorAs(t, err, &valErr, invalid)
+	}
+	_, err = uc.UpdateProfile(context.Background(), user.ID, dto.ProfileUpdateRequest{})
+	require.Error(t, err)
+	require.Equal(t, "Asia/Tokyo", user.TimeZone)
+}
@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+)
+
+// allocationApplyLookback は時間帯の開始より前に始まった既存エントリを拾うための遡り幅。
+const allocationApplyLookback = 24 * time.Hour
+
+// ErrAllocationNotFound はユーザー所有の分配結果が見つからないことを表す。
+var ErrAllocationNotFound = errors.New("allocation not found")
+
+// Apply は保存済みの分配結果を、指定時間帯の空き時間へ先頭から連続したエントリとして配置する。
+// 休憩と既存エントリの区間は避け、空きが途切れたタスクは複数のエントリに分かれる。
+func (u *AllocationUsecase) Apply(ctx context.Context, userID uuid.UUID, id uuid.UUID, input dto.AllocationApplyRequest) ([]entity.Entry, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	_, allocations, err := u.repo.GetByID(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrAllocationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 配置前にすべての対応付けを検証し、途中まで作成された状態を残さない。
+	known := make(map[string]struct{}, len(allocations))
+	totalMinutes := 0
+	for _, allocation := range allocations {
+		known[allocation.TaskID] = struct{}{}
+		if allocation.AllocatedMinutes == 0 {
+			continue
+		}
+		if _, ok := data.Mappings[allocation.TaskID]; !ok {
+			return nil, dto.ValidationError{Field: "mappings", Message: fmt.Sprintf("task_id %q is not mapped", allocation.TaskID)}
+		}
+		totalMinutes += allocation.AllocatedMinutes
+	}
+	for taskID := range data.Mappings {
+		if _, ok := known[taskID]; !ok {
+			return nil, dto.ValidationError{Field: "mappings", Message: fmt.Sprintf("task_id %q is not in the allocation", taskID)}
+		}
+	}
+	templates, err := u.entryTemplates(ctx, userID, data.Mappings)
+	if err != nil {
+		return nil, err
+	}
+
+	busy, err := u.busyRanges(ctx, userID, data.WindowStart, data.WindowEnd, data.Breaks)
+	if err != nil {
+		return nil, err
+	}
+	free := freeRanges(data.WindowStart, data.WindowEnd, busy)
+	var capacity time.Duration
+	for _, slot := range free {
+		capacity += slot.End.Sub(slot.Start)
+	}
+	if capacity < time.Duration(totalMinutes)*time.Minute {
+		return nil, AllocationConstraintError{Message: "window does not have enough free time for the allocation"}
+	}
+
+	placements := make([]allocationPlacement, 0, len(allocations))
+	for _, allocation := range allocations {
+		placements = append(placements, allocationPlacement{TaskID: allocation.TaskID, Minutes: allocation.AllocatedMinutes})
+	}
+	return u.createEntries(ctx, userID, layoutAllocations(placements, templates, free, u.clock.Now()))
+}
+
+// allocationPlacement は時間帯へ配置する 1 タスク分の分数。
+type allocationPlacement struct {
+	TaskID  string
+	Minutes int
+}
+
+// entryTemplates は対応付けを作成するエントリのひな形へ変換する。プロジェクトとタグは所有者を確認する。
+func (u *AllocationUsecase) entryTemplates(ctx context.Context, userID uuid.UUID, mappings map[string]dto.AllocationMappingData) (map[string]entity.Entry, error) {
+	templates := make(map[string]entity.Entry, len(mappings))
+	for taskID, mapping := range mappings {
+		if mapping.ProjectID != nil {
+			if _, err := u.projects.GetByID(ctx, userID, *mapping.ProjectID); err != nil {
+				return nil, dto.ValidationError{Field: "project_id", Message: "refers to unknown project"}
+			}
+		}
+		tags, err := loadOwnedTags(ctx, u.tags, userID, mapping.TagIDs)
+		if err != nil {
+			return nil, err
+		}
+		templates[taskID] = entity.Entry{
+			UserID:    userID,
+			ProjectID: mapping.ProjectID,
+			Title:     mapping.Title,
+			Ratio:     1,
+			Tags:      tags,
+		}
+	}
+	return templates, nil
+}
+
+// layoutAllocations はタスクを順に free の先頭から連続して配置したエントリを返す。
+// 空きが途切れたタスクは複数のエントリに分かれる。free の合計は配置する分数以上であること。
+func layoutAllocations(placements []allocationPlacement, templates map[string]entity.Entry, free []dto.TimeRange, now time.Time) []entity.Entry {
+	slots := append([]dto.TimeRange{}, free...)
+	laid := make([]entity.Entry, 0, len(placements))
+	slot := 0
+	for _, placement := range placements {
+		remaining := time.Duration(placement.Minutes) * time.Minute
+		for remaining > 0 {
+			current := &slots[slot]
+			length := current.End.Sub(current.Start)
+			if length > remaining {
+				length = remaining
+			}
+			entry := templates[placement.TaskID]
+			entry.ID = uuid.New()
+			entry.StartedAt = current.Start
+			end := current.Start.Add(length)
+			entry.EndedAt = &end
+			entry.UpdateDuration(now)
+			laid = append(laid, entry)
+			remaining -= length
+			current.Start = end
+			if !current.End.After(current.Start) {
+				slot++
+			}
+		}
+	}
+	return laid
+}
+
+// createEntries は配置済みのエントリをタグとともにひとつのトランザクションで保存する。
+func (u *AllocationUsecase) createEntries(ctx context.Context, userID uuid.UUID, laid []entity.Entry) ([]entity.Entry, error) {
+	if err := u.entries.ApplyChanges(ctx, userID, repository.EntryChanges{Create: laid}); err != nil {
+		return nil, err
+	}
+	return laid, nil
+}
+
+// busyRanges は [start, end) に重なる既存エントリ (休憩を含む) と指定された休憩を返す。
+func (u *AllocationUsecase) busyRanges(ctx context.Context, userID uuid.UUID, start, end time.Time, breaks []dto.TimeRange) ([]dto.TimeRange, error) {
+	from := start.Add(-allocationApplyLookback)
+	entries, err := u.entries.ListByUser(ctx, userID, repository.EntryFilter{From: &from, To: &end})
+	if err != nil {
+		return nil, err
+	}
+	// 遡り幅より前に始まった実行中エントリも現在時刻まで埋まっているとみなす。
+	running, err := u.entries.ListByUser(ctx, userID, repository.EntryFilter{RunningOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	now := u.clock.Now()
+	seen := make(map[uuid.UUID]struct{}, len(entries)+len(running))
+	busy := append([]dto.TimeRange{}, breaks...)
+	for _, entry := range append(entries, running...) {
+		if _, ok := seen[entry.ID]; ok {
+			continue
+		}
+		seen[entry.ID] = struct{}{}
+		entryEnd := now
+		if entry.EndedAt != nil {
+			entryEnd = *entry.EndedAt
+		}
+		busy = append(busy, dto.TimeRange{Start: entry.StartedAt, End: entryEnd})
+	}
+	return busy, nil
+}
+
+// freeRanges は [start, end) から busy を除いた空き区間を時刻順に返す。
+func freeRanges(start, end time.Time, busy []dto.TimeRange) []dto.TimeRange {
+	sorted := append([]dto.TimeRange{}, busy...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	var free []dto.TimeRange
+	cursor := start
+	for _, b := range sorted {
+		if !b.End.After(cursor) {
+			continue
+		}
+		if !b.Start.Before(end) {
+			break
+		}
+		if b.Start.After(cursor) {
+			free = append(free, dto.TimeRange{Start: cursor, End: b.Start})
+		}
+		cursor = b.End
+	}
+	if end.After(cursor) {
+		free = append(free, dto.TimeRange{Start: cursor, End: end})
+	}
+	return free
+}
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+func newApplyFixture(t *testing.T, userID, requestID uuid.UUID, existing []entity.Entry) (*AllocationUsecase, *[]entity.Entry) {
+	t.Helper()
+	allocations := &fakes.FakeAllocationRepository{
+		GetByIDFn: func(_ context.Context, uid uuid.UUID, id uuid.UUID) (*entity.AllocationRequest, []entity.TaskAllocation, error) {
+			require.Equal(t, userID, uid)
+			require.Equal(t, requestID, id)
+			return &entity.AllocationRequest{ID: requestID, UserID: userID, TotalMinutes: 90},
+				[]entity.TaskAllocation{
+					{RequestID: requestID, TaskID: "design", AllocatedMinutes: 60},
+					{RequestID: requestID, TaskID: "review", AllocatedMinutes: 30},
+				}, nil
+		},
+	}
+	var created []entity.Entry
+	entries := &fakes.FakeEntryRepository{
+		ListFn: func(_ context.Context, _ uuid.UUID, filter repository.EntryFilter) ([]entity.Entry, error) {
+			if filter.RunningOnly {
+				return nil, nil
+			}
+			return existing, nil
+		},
+		CreateFn: func(_ context.Context, entry *entity.Entry) error {
+			created = append(created, *entry)
+			return nil
+		},
+	}
+	projects := &fakes.FakeProjectRepository{
+		GetByIDFn: func(_ context.Context, _ uuid.UUID, id uuid.UUID) (*entity.Project, error) {
+			return &entity.Project{ID: id, UserID: userID, Name: "Client"}, nil
+		},
+	}
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC) }}
+	return NewAllocationUsecase(allocations, entries, projects, &fakes.FakeTagRepository{}, clock), &created
+}
+
+func TestAllocationUsecase_ApplySkipsBreaksAndExistingEntries(t *testing.T) {
+	userID := uuid.New()
+	requestID := uuid.New()
+	projectID := uuid.New()
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	existingEnd := day.Add(9*time.Hour + 30*time.Minute)
+	existing := []entity.Entry{
+		{ID: uuid.New(), UserID: userID, Title: "Standup", StartedAt: day.Add(9 * time.Hour), EndedAt: &existingEnd},
+	}
+	uc, created := newApplyFixture(t, userID, requestID, existing)
+	pid := projectID.String()
+
+	entries, err := uc.Apply(context.Background(), userID, requestID, dto.AllocationApplyRequest{
+		WindowStart: "2024-05-01T08:30:00Z",
+		WindowEnd:   "2024-05-01T12:00:00Z",
+		Breaks:      []dto.TimeRangeRequest{{Start: "2024-05-01T09:45:00Z", End: "2024-05-01T10:00:00Z"}},
+		Mappings: []dto.AllocationMappingRequest{
+			{TaskID: "design", Title: "Design", ProjectID: &pid},
+			{TaskID: "review", ProjectID: &pid},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, *created, 4)
+	require.Len(t, entries, 4)
+
+	type span struct{ start, end string }
+	var spans []span
+	for _, entry := range entries {
+		spans = append(spans, span{entry.StartedAt.Format("15:04"), entry.EndedAt.Format("15:04")})
+		require.Equal(t, &projectID, entry.ProjectID)
+	}
+	// design 60 分は 08:30-09:00 / 09:30-09:45 / 10:00-10:15 に分かれ、review が続く。
+	require.Equal(t, []span{{"08:30", "09:00"}, {"09:30", "09:45"}, {"10:00", "10:15"}, {"10:15", "10:45"}}, spans)
+	require.Equal(t, "Design", entries[0].Title)
+	require.Equal(t, "review", entries[3].Title)
+	require.Equal(t, int64(1800), entries[3].DurationSec)
+}
+
+func TestAllocationUsecase_ApplyRejectsWindowWithoutEnoughFreeTime(t *testing.T) {
+	userID := uuid.New()
+	requestID := uuid.New()
+	projectID := uuid.New()
+	uc, created := newApplyFixture(t, userID, requestID, nil)
+	pid := projectID.String()
+
+	_, err := uc.Apply(context.Background(), userID, requestID, dto.AllocationApplyRequest{
+		WindowStart: "2024-05-01T09:00:00Z",
+		WindowEnd:   "2024-05-01T10:00:00Z",
+		Mappings: []dto.AllocationMappingRequest{
+			{TaskID: "design", ProjectID: &pid},
+			{TaskID: "review", ProjectID: &pid},
+		},
+	})
+	var constraintErr AllocationConstraintError
+	require.ErrorAs(t, err, &constraintErr)
+	require.Empty(t, *created)
+}
+
+func TestAllocationUsecase_ApplyRequiresMappingForEveryTask(t *testing.T) {
+	userID := uuid.New()
+	requestID := uuid.New()
+	projectID := uuid.New()
+	uc, created := newApplyFixture(t, userID, requestID, nil)
+	pid := projectID.String()
+
+	_, err := uc.Apply(context.Background(), userID, requestID, dto.AllocationApplyRequest{
+		WindowStart: "2024-05-01T09:00:00Z",
+		WindowEnd:   "2024-05-01T12:00:00Z",
+		Mappings:    []dto.AllocationMappingRequest{{TaskID: "design", ProjectID: &pid}},
+	})
+	var valErr dto.ValidationError
+	require.ErrorAs(t, err, &valErr)
+	require.Equal(t, "mappings", valErr.Field)
+	require.Empty(t, *created)
+}
+
+func TestAllocationUsecase_ApplySavesAllEntriesInOneChange(t *testing.T) {
+	userID := uuid.New()
+	requestID := uuid.New()
+	projectID := uuid.New()
+	uc, _ := newApplyFixture(t, userID, requestID, nil)
+	pid := projectID.String()
+	// リポジトリが途中で失敗しても作成済みのエントリを残さないよう、すべてのエントリを 1 回の変更で渡す。
+	var calls []repository.EntryChanges
+	uc.entries.(*fakes.FakeEntryRepository).ApplyChangesFn = func(_ context.Context, uid uuid.UUID, changes repository.EntryChanges) error {
+		require.Equal(t, userID, uid)
+		calls = append(calls, changes)
+		return errors.New("disk full")
+	}
+
+	_, err := uc.Apply(context.Background(), userID, requestID, dto.AllocationApplyRequest{
+		WindowStart: "2024-05-01T09:00:00Z",
+		WindowEnd:   "2024-05-01T12:00:00Z",
+		Breaks:      []dto.TimeRangeRequest{{Start: "2024-05-01T09:30:00Z", End: "2024-05-01T10:00:00Z"}},
+		Mappings: []dto.AllocationMappingRequest{
+			{TaskID: "design", ProjectID: &pid},
+			{TaskID: "review", ProjectID: &pid},
+		},
+	})
+	require.EqualError(t, err, "disk full")
+	require.Len(t, calls, 1)
+	require.Len(t, calls[0].Create, 3)
+	require.Empty(t, calls[0].Delete)
+}
+
+func TestAllocationUsecase_ApplyReportsOnlyMissingAllocationAsNotFound(t *testing.T) {
+	userID := uuid.New()
+	requestID := uuid.New()
+	uc, created := newApplyFixture(t, userID, requestID, nil)
+	pid := uuid.NewString()
+	input := dto.AllocationApplyRequest{
+		WindowStart: "2024-05-01T09:00:00Z",
+		WindowEnd:   "2024-05-01T12:00:00Z",
+		Mappings:    []dto.AllocationMappingRequest{{TaskID: "design", ProjectID: &pid}},
+	}
+	repo := uc.repo.(*fakes.FakeAllocationRepository)
+
+	repo.GetByIDFn = func(context.Context, uuid.UUID, uuid.UUID) (*entity.AllocationRequest, []entity.TaskAllocation, error) {
+		return nil, nil, repository.ErrNotFound
+	}
+	_, err := uc.Apply(context.Background(), userID, requestID, input)
+	require.ErrorIs(t, err, ErrAllocationNotFound)
+
+	// DB の障害は見つからない扱いにせず、そのまま返す。
+	repo.GetByIDFn = func(context.Context, uuid.UUID, uuid.UUID) (*entity.AllocationRequest, []entity.TaskAllocation, error) {
+		return nil, nil, errors.New("connection refused")
+	}
+	_, err = uc.Apply(context.Background(), userID, requestID, input)
+	require.EqualError(t, err, "connection refused")
+	require.NotErrorIs(t, err, ErrAllocationNotFound)
+	require.Empty(t, *created)
+}
@@ -0,0 +1,272 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+)
+
+// ErrAllocationBatchNotFound はユーザー所有のバッチが見つからないことを表す。
+var ErrAllocationBatchNotFound = errors.New("allocation batch not found")
+
+// AllocationBatchResult はバッチで保存した子の分配結果をまとめて返す。
+type AllocationBatchResult struct {
+	BatchID        uuid.UUID          `json:"batch_id"`
+	TotalMinutes   int                `json:"total_minutes"`
+	BalanceResidue bool               `json:"balance_residue"`
+	CreatedAt      time.Time          `json:"created_at"`
+	Results        []AllocationResult `json:"results"`
+}
+
+// AllocateBatch は同じタスク集合で複数の合計分数を分配し、親バッチの下にひとつのトランザクションで保存する。
+// balance_residue の場合は、日ごとの端数の偏りでタスク別の合計が比率からずれないよう日をまたいで戦略の刻みずつ付け替える。
+func (u *AllocationUsecase) AllocateBatch(ctx context.Context, userID uuid.UUID, input dto.AllocationBatchRequest) (AllocationBatchResult, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return AllocationBatchResult{}, err
+	}
+	totals := data.Totals
+	if len(data.Splits) > 0 {
+		totals, err = splitBatchTotal(data.SplitTotal, data.Splits)
+		if err != nil {
+			return AllocationBatchResult{}, err
+		}
+	}
+
+	days := make([]dto.AllocationRequestData, 0, len(totals))
+	results := make([][]allocationDistribution, 0, len(totals))
+	grandTotal := 0
+	for _, total := range totals {
+		normalized, err := dto.AllocationRequest{
+			TotalMinutes:       total.TotalMinutes,
+			Strategy:           input.Strategy,
+			GranularityMinutes: input.GranularityMinutes,
+			Tasks:              input.Tasks,
+		}.Normalize()
+		if err != nil {
+			return AllocationBatchResult{}, err
+		}
+		allocations, err := distributeAllocations(normalized)
+		if err != nil {
+			return AllocationBatchResult{}, AllocationConstraintError{Message: fmt.Sprintf("totals[%d]: %s", len(days), err.Error())}
+		}
+		days = append(days, normalized)
+		results = append(results, allocations)
+		grandTotal += total.TotalMinutes
+	}
+	if data.BalanceResidue {
+		if err := balanceBatchResidue(days, results, grandTotal); err != nil {
+			return AllocationBatchResult{}, err
+		}
+	}
+
+	now := u.clock.Now()
+	batch := &entity.AllocationBatch{
+		ID:             uuid.New(),
+		UserID:         userID,
+		TotalMinutes:   grandTotal,
+		BalanceResidue: data.BalanceResidue,
+		CreatedAt:      now,
+	}
+	requests := make([]entity.AllocationRequest, 0, len(days))
+	rows := make([][]entity.TaskAllocation, 0, len(days))
+	for i, day := range days {
+		request := entity.AllocationRequest{
+			ID:                 uuid.New(),
+			UserID:             userID,
+			TotalMinutes:       day.TotalMinutes,
+			Strategy:           string(day.Strategy),
+			GranularityMinutes: day.GranularityMinutes,
+			BatchID:            &batch.ID,
+			BatchIndex:         i,
+			Label:              totals[i].Label,
+			CreatedAt:          now,
+		}
+		dayRows := make([]entity.TaskAllocation, 0, len(results[i]))
+		for _, allocation := range results[i] {
+			dayRows = append(dayRows, entity.TaskAllocation{
+				RequestID:        request.ID,
+				TaskID:           allocation.TaskID,
+				Ratio:            allocation.Ratio,
+				AllocatedMinutes: allocation.AllocatedMinutes,
+				MinMinutes:       allocation.MinMinutes,
+				MaxMinutes:       allocation.MaxMinutes,
+				Priority:         allocation.Priority,
+				CreatedAt:        now,
+				UpdatedAt:        now,
+			})
+		}
+		requests = append(requests, request)
+		rows = append(rows, dayRows)
+	}
+	if err := u.repo.CreateBatch(ctx, batch, requests, rows); err != nil {
+		return AllocationBatchResult{}, err
+	}
+	return newAllocationBatchResult(*batch, requests, rows), nil
+}
+
+// GetBatch はユーザー所有のバッチを子の分配結果付きで返す。
+func (u *AllocationUsecase) GetBatch(ctx context.Context, userID uuid.UUID, id uuid.UUID) (AllocationBatchResult, error) {
+	batch, requests, allocations, err := u.repo.GetBatch(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return AllocationBatchResult{}, ErrAllocationBatchNotFound
+	}
+	if err != nil {
+		return AllocationBatchResult{}, err
+	}
+	byRequest := make(map[uuid.UUID][]entity.TaskAllocation, len(requests))
+	for _, allocation := range allocations {
+		byRequest[allocation.RequestID] = append(byRequest[allocation.RequestID], allocation)
+	}
+	rows := make([][]entity.TaskAllocation, 0, len(requests))
+	for _, request := range requests {
+		rows = append(rows, byRequest[request.ID])
+	}
+	return newAllocationBatchResult(*batch, requests, rows), nil
+}
+
+func newAllocationBatchResult(batch entity.AllocationBatch, requests []entity.AllocationRequest, rows [][]entity.TaskAllocation) AllocationBatchResult {
+	results := make([]AllocationResult, 0, len(requests))
+	for i, request := range requests {
+		results = append(results, newAllocationResult(request, rows[i]))
+	}
+	return AllocationBatchResult{
+		BatchID:        batch.ID,
+		TotalMinutes:   batch.TotalMinutes,
+		BalanceResidue: batch.BalanceResidue,
+		CreatedAt:      batch.CreatedAt,
+		Results:        results,
+	}
+}
+
+// splitBatchTotal は total を重みで最大剰余法により日ごとの分数へ分ける。
+func splitBatchTotal(total int, splits []dto.AllocationBatchSplitRequest) ([]dto.AllocationBatchTotalRequest, error) {
+	tasks := make([]dto.AllocationTaskData, 0, len(splits))
+	for i, split := range splits {
+		tasks = append(tasks, dto.AllocationTaskData{TaskID: strconv.Itoa(i), Ratio: split.Weight})
+	}
+	shares, err := distributeAllocations(dto.AllocationRequestData{
+		TotalMinutes: total,
+		Strategy:     dto.AllocationStrategyLargestRemainder,
+		Tasks:        tasks,
+	})
+	if err != nil {
+		return nil, err
+	}
+	totals := make([]dto.AllocationBatchTotalRequest, 0, len(splits))
+	for i, share := range shares {
+		if share.AllocatedMinutes == 0 {
+			return nil, dto.ValidationError{Field: "splits", Message: "must give every item at least one minute"}
+		}
+		totals = append(totals, dto.AllocationBatchTotalRequest{Label: splits[i].Label, TotalMinutes: share.AllocatedMinutes})
+	}
+	return totals, nil
+}
+
+// balanceBatchResidue は日ごとの分配結果を、タスク別の合計が grandTotal を比率で分けた目標に近づくよう調整する。
+// 目標は min_minutes / max_minutes を日数倍した範囲で、リクエストと同じ戦略で求める。各日の合計と min / max は保ったまま、
+// 目標を超えたタスクから不足しているタスクへ刻み (granularity は granularity_minutes、それ以外は 1 分) ずつ付け替え、
+// 動かせなくなった時点で止める。
+func balanceBatchResidue(days []dto.AllocationRequestData, results [][]allocationDistribution, grandTotal int) error {
+	if len(days) < 2 {
+		return nil
+	}
+	count := len(days)
+	weekly := dto.AllocationRequestData{TotalMinutes: grandTotal, Strategy: days[0].Strategy, GranularityMinutes: days[0].GranularityMinutes}
+	step := 1
+	if weekly.Strategy == dto.AllocationStrategyGranularity {
+		step = weekly.GranularityMinutes
+	}
+	for _, task := range days[0].Tasks {
+		scaled := task
+		if task.MinMinutes != nil {
+			scaled.MinMinutes = intPtrOf(*task.MinMinutes * count)
+		}
+		if task.MaxMinutes != nil {
+			scaled.MaxMinutes = intPtrOf(*task.MaxMinutes * count)
+		}
+		weekly.Tasks = append(weekly.Tasks, scaled)
+	}
+	targets, err := distributeAllocations(weekly)
+	if err != nil {
+		return AllocationConstraintError{Message: err.Error()}
+	}
+
+	tasks := days[0].Tasks
+	diff := make([]int, len(tasks))
+	for j := range tasks {
+		diff[j] = -targets[j].AllocatedMinutes
+		for d := range results {
+			diff[j] += results[d][j].AllocatedMinutes
+		}
+	}
+	canGive := func(d, j int) bool {
+		return results[d][j].AllocatedMinutes-step >= derefInt(tasks[j].MinMinutes)
+	}
+	canTake := func(d, k int) bool {
+		return tasks[k].MaxMinutes == nil || results[d][k].AllocatedMinutes+step <= *tasks[k].MaxMinutes
+	}
+	// 刻みに満たないずれは動かすと逆側にずれるため残す。1 回の付け替えで |diff| の合計が 2*step 減るため、grandTotal 回で必ず止まる。
+	for moves := 0; moves < grandTotal; moves++ {
+		surplus := tasksByDiff(diff, func(v int) bool { return v >= step })
+		deficit := tasksByDiff(diff, func(v int) bool { return v <= -step })
+		moved := false
+		for _, j := range surplus {
+			for _, k := range deficit {
+				// 配分の多い日から動かし、1 日に調整が偏らないようにする。
+				best := -1
+				for d := range results {
+					if canGive(d, j) && canTake(d, k) && (best < 0 || results[d][j].AllocatedMinutes > results[best][j].AllocatedMinutes) {
+						best = d
+					}
+				}
+				if best < 0 {
+					continue
+				}
+				results[best][j].AllocatedMinutes -= step
+				results[best][k].AllocatedMinutes += step
+				diff[j] -= step
+				diff[k] += step
+				moved = true
+				break
+			}
+			if moved {
+				break
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return nil
+}
+
+// tasksByDiff は条件に合うタスクの添字を |diff| の大きい順 (同値は入力順) に返す。
+func tasksByDiff(diff []int, keep func(int) bool) []int {
+	var order []int
+	for j, v := range diff {
+		if keep(v) {
+			order = append(order, j)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return absInt(diff[order[a]]) > absInt(diff[order[b]])
+	})
+	return order
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+func taskTotals(result AllocationBatchResult) map[string]int {
+	totals := make(map[string]int)
+	for _, day := range result.Results {
+		for _, item := range day.Allocations {
+			totals[item.TaskID] += item.AllocatedMinutes
+		}
+	}
+	return totals
+}
+
+func TestAllocationUsecase_AllocateBatchBalancesResidue(t *testing.T) {
+	input := dto.AllocationBatchRequest{
+		Totals: []dto.AllocationBatchTotalRequest{
+			{Label: "mon", TotalMinutes: 10},
+			{Label: "tue", TotalMinutes: 10},
+			{Label: "wed", TotalMinutes: 10},
+		},
+		Tasks: []dto.AllocationTaskRequest{
+			{TaskID: "a", Ratio: 1},
+			{TaskID: "b", Ratio: 1},
+			{TaskID: "c", Ratio: 1},
+		},
+	}
+	uc := NewAllocationUsecase(&fakes.FakeAllocationRepository{}, nil, nil, nil, fakes.FixedTimeProvider{})
+
+	// 毎日 4/3/3 になるため、調整しないと a に端数が偏る。
+	plain, err := uc.AllocateBatch(context.Background(), uuid.New(), input)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"a": 12, "b": 9, "c": 9}, taskTotals(plain))
+
+	input.BalanceResidue = true
+	balanced, err := uc.AllocateBatch(context.Background(), uuid.New(), input)
+	require.NoError(t, err)
+	require.True(t, balanced.BalanceResidue)
+	require.Equal(t, map[string]int{"a": 10, "b": 10, "c": 10}, taskTotals(balanced))
+	for _, day := range balanced.Results {
+		sum := 0
+		for _, item := range day.Allocations {
+			sum += item.AllocatedMinutes
+		}
+		require.Equal(t, 10, sum)
+	}
+}
+
+func TestAllocationUsecase_AllocateBatchBalancesResidueWithPriority(t *testing.T) {
+	input := dto.AllocationBatchRequest{
+		Totals: []dto.AllocationBatchTotalRequest{
+			{Label: "mon", TotalMinutes: 60},
+			{Label: "tue", TotalMinutes: 60},
+		},
+		Strategy: "priority",
+		Tasks: []dto.AllocationTaskRequest{
+			{TaskID: "urgent", Ratio: 1, Priority: 0},
+			{TaskID: "later", Ratio: 1, Priority: 1},
+		},
+		BalanceResidue: true,
+	}
+	uc := NewAllocationUsecase(&fakes.FakeAllocationRepository{}, nil, nil, nil, fakes.FixedTimeProvider{})
+
+	// 目標も priority で求めるため、比率で 60/60 に寄せず優先タスクに 60 分ずつ残す。
+	result, err := uc.AllocateBatch(context.Background(), uuid.New(), input)
+	require.NoError(t, err)
+	for _, day := range result.Results {
+		require.Equal(t, 60, day.Allocations[0].AllocatedMinutes)
+		require.Equal(t, 0, day.Allocations[1].AllocatedMinutes)
+	}
+}
+
+func TestAllocationUsecase_AllocateBatchBalancesResidueInGranularitySteps(t *testing.T) {
+	input := dto.AllocationBatchRequest{
+		Totals: []dto.AllocationBatchTotalRequest{
+			{Label: "mon", TotalMinutes: 30},
+			{Label: "tue", TotalMinutes: 30},
+			{Label: "wed", TotalMinutes: 30},
+		},
+		Strategy:           "granularity",
+		GranularityMinutes: 15,
+		Tasks: []dto.AllocationTaskRequest{
+			{TaskID: "a", Ratio: 1},
+			{TaskID: "b", Ratio: 1},
+			{TaskID: "c", Ratio: 1},
+		},
+	}
+	uc := NewAllocationUsecase(&fakes.FakeAllocationRepository{}, nil, nil, nil, fakes.FixedTimeProvider{})
+
+	// 毎日 15/15/0 になるため、調整しないと c が 0 分になる。
+	plain, err := uc.AllocateBatch(context.Background(), uuid.New(), input)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"a": 45, "b": 45, "c": 0}, taskTotals(plain))
+
+	// 15 分単位で付け替え、10/10/10 のような刻みを崩す分配にしない。
+	input.BalanceResidue = true
+	balanced, err := uc.AllocateBatch(context.Background(), uuid.New(), input)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"a": 30, "b": 30, "c": 30}, taskTotals(balanced))
+	for _, day := range balanced.Results {
+		sum := 0
+		for _, item := range day.Allocations {
+			require.Zero(t, item.AllocatedMinutes%15, "allocation %+v", item)
+			sum += item.AllocatedMinutes
+		}
+		require.Equal(t, 30, sum)
+	}
+}
+
+func TestAllocationUsecase_AllocateBatchSplitsTotalInOneBatch(t *testing.T) {
+	var savedBatch *entity.AllocationBatch
+	var savedRequests []entity.AllocationRequest
+	var savedRows [][]entity.TaskAllocation
+	repo := &fakes.FakeAllocationRepository{
+		CreateFn: func(context.Context, *entity.AllocationRequest, []entity.TaskAllocation) error {
+			t.Fatal("batch must not be saved request by request")
+			return nil
+		},
+		CreateBatchFn: func(_ context.Context, batch *entity.AllocationBatch, requests []entity.AllocationRequest, rows [][]entity.TaskAllocation) error {
+			savedBatch, savedRequests, savedRows = batch, requests, rows
+			return nil
+		},
+	}
+	uc := NewAllocationUsecase(repo, nil, nil, nil, fakes.FixedTimeProvider{})
+	min10 := 10
+
+	result, err := uc.AllocateBatch(context.Background(), uuid.New(), dto.AllocationBatchRequest{
+		TotalMinutes: 100,
+		Splits: []dto.AllocationBatchSplitRequest{
+			{Label: "2024-05-01", Weight: 1},
+			{Label: "2024-05-02", Weight: 1},
+			{Label: "2024-05-03", Weight: 2},
+		},
+		Tasks: []dto.AllocationTaskRequest{
+			{TaskID: "dev", Ratio: 3},
+			{TaskID: "ops", Ratio: 1, MinMinutes: &min10},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 100, savedBatch.TotalMinutes)
+	require.Len(t, savedRequests, 3)
+	require.Len(t, savedRows, 3)
+	for i, request := range savedRequests {
+		require.Equal(t, savedBatch.ID, *request.BatchID)
+		require.Equal(t, i, request.BatchIndex)
+		require.Equal(t, request.ID, savedRows[i][0].RequestID)
+	}
+	require.Equal(t, []int{25, 25, 50}, []int{result.Results[0].TotalMinutes, result.Results[1].TotalMinutes, result.Results[2].TotalMinutes})
+	require.Equal(t, "2024-05-03", result.Results[2].Label)
+	require.Equal(t, savedBatch.ID, *result.Results[0].BatchID)
+}
+
+func TestAllocationUsecase_AllocateBatchReportsFailingItem(t *testing.T) {
+	uc := NewAllocationUsecase(&fakes.FakeAllocationRepository{}, nil, nil, nil, fakes.FixedTimeProvider{})
+	min30 := 30
+	_, err := uc.AllocateBatch(context.Background(), uuid.New(), dto.AllocationBatchRequest{
+		Totals: []dto.AllocationBatchTotalRequest{{TotalMinutes: 60}, {TotalMinutes: 20}},
+		Tasks:  []dto.AllocationTaskRequest{{TaskID: "a", Ratio: 1, MinMinutes: &min30}},
+	})
+	var constraintErr AllocationConstraintError
+	require.ErrorAs(t, err, &constraintErr)
+	require.Contains(t, constraintErr.Message, "totals[1]")
+}
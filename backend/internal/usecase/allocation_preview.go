@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"sort"
+
+	"chronome/internal/usecase/dto"
+)
+
+// AllocationPreview は保存せずに計算した分配結果を表す。
+// Feasible が false の場合は Allocations を持たず、Diagnosis に失敗理由と修正案が入る。
+type AllocationPreview struct {
+	Feasible           bool                `json:"feasible"`
+	TotalMinutes       int                 `json:"total_minutes"`
+	Strategy           string              `json:"strategy"`
+	GranularityMinutes int                 `json:"granularity_minutes,omitempty"`
+	Allocations        []AllocationItem    `json:"allocations,omitempty"`
+	Diagnosis          AllocationDiagnosis `json:"diagnosis"`
+}
+
+// AllocationDiagnosis は min / max 制約と total_minutes の関係を説明する。
+// MaxTotalMinutes が nil の場合は上限のないタスクがあり、total_minutes の上限もない。
+type AllocationDiagnosis struct {
+	Message         string               `json:"message,omitempty"`
+	MinTotalMinutes int                  `json:"min_total_minutes"`
+	MaxTotalMinutes *int                 `json:"max_total_minutes"`
+	Binding         []AllocationBinding  `json:"binding"`
+	Suggestions     []AllocationProposal `json:"suggestions"`
+}
+
+// AllocationBinding は結果 (または失敗) を決めている制約を表す。Bound は "min" か "max"。
+type AllocationBinding struct {
+	TaskID  string `json:"task_id"`
+	Bound   string `json:"bound"`
+	Minutes int    `json:"minutes"`
+}
+
+// AllocationProposal は実行可能にするための修正案の 1 つ。
+// Field が total_minutes の場合 TaskID は空で、それ以外は TaskID の min_minutes / max_minutes を To に変える案を表す。
+type AllocationProposal struct {
+	Field  string `json:"field"`
+	TaskID string `json:"task_id,omitempty"`
+	From   *int   `json:"from,omitempty"`
+	To     *int   `json:"to,omitempty"`
+}
+
+// Preview は分配結果を保存せずに計算する。入力形式の誤りはエラーで返し、
+// min / max 制約を満たせない場合は Feasible=false の診断を返す。
+func (u *AllocationUsecase) Preview(input dto.AllocationRequest) (AllocationPreview, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return AllocationPreview{}, err
+	}
+	preview := AllocationPreview{
+		TotalMinutes:       data.TotalMinutes,
+		Strategy:           string(data.Strategy),
+		GranularityMinutes: data.GranularityMinutes,
+	}
+	allocations, err := distributeAllocations(data)
+	if err != nil {
+		preview.Diagnosis = diagnoseAllocation(data, err)
+		return preview, nil
+	}
+	preview.Feasible = true
+	preview.Allocations = make([]AllocationItem, 0, len(allocations))
+	for _, allocation := range allocations {
+		preview.Allocations = append(preview.Allocations, AllocationItem{
+			TaskID:           allocation.TaskID,
+			Ratio:            allocation.Ratio,
+			AllocatedMinutes: allocation.AllocatedMinutes,
+			MinMinutes:       allocation.MinMinutes,
+			MaxMinutes:       allocation.MaxMinutes,
+			Priority:         allocation.Priority,
+		})
+	}
+	preview.Diagnosis = diagnoseAllocation(data, nil)
+	preview.Diagnosis.Binding = boundTasks(data, allocations)
+	return preview, nil
+}
+
+// diagnoseAllocation は total_minutes の実行可能範囲を計算し、失敗時は原因の制約と修正案を組み立てる。
+func diagnoseAllocation(data dto.AllocationRequestData, failure error) AllocationDiagnosis {
+	minSum, maxSum, bounded := 0, 0, true
+	for _, task := range data.Tasks {
+		if task.MinMinutes != nil {
+			minSum += *task.MinMinutes
+		}
+		if task.MaxMinutes != nil {
+			maxSum += *task.MaxMinutes
+		} else {
+			bounded = false
+		}
+	}
+	diagnosis := AllocationDiagnosis{
+		MinTotalMinutes: minSum,
+		Binding:         []AllocationBinding{},
+		Suggestions:     []AllocationProposal{},
+	}
+	if bounded {
+		diagnosis.MaxTotalMinutes = intPtrOf(maxSum)
+	}
+	if failure == nil {
+		return diagnosis
+	}
+	diagnosis.Message = failure.Error()
+
+	switch {
+	case data.TotalMinutes < minSum:
+		// min_minutes の合計が超過している。total を増やすか、大きい min から順に下げる。
+		diagnosis.Suggestions = append(diagnosis.Suggestions, AllocationProposal{Field: "total_minutes", From: intPtrOf(data.TotalMinutes), To: intPtrOf(minSum)})
+		deficit := minSum - data.TotalMinutes
+		for _, idx := range tasksBy(data, func(t dto.AllocationTaskData) int { return derefInt(t.MinMinutes) }) {
+			task := data.Tasks[idx]
+			if task.MinMinutes == nil || *task.MinMinutes == 0 {
+				continue
+			}
+			diagnosis.Binding = append(diagnosis.Binding, AllocationBinding{TaskID: task.TaskID, Bound: "min", Minutes: *task.MinMinutes})
+			if deficit > 0 {
+				cut := minInt(deficit, *task.MinMinutes)
+				deficit -= cut
+				diagnosis.Suggestions = append(diagnosis.Suggestions, AllocationProposal{Field: "min_minutes", TaskID: task.TaskID, From: task.MinMinutes, To: intPtrOf(*task.MinMinutes - cut)})
+			}
+		}
+	case bounded && data.TotalMinutes > maxSum:
+		// すべてのタスクに上限があり合計が足りない。total を減らすか、比率の大きいタスクの上限を上げる。
+		diagnosis.Suggestions = append(diagnosis.Suggestions, AllocationProposal{Field: "total_minutes", From: intPtrOf(data.TotalMinutes), To: intPtrOf(maxSum)})
+		order := tasksBy(data, func(t dto.AllocationTaskData) int { return 0 })
+		sort.SliceStable(order, func(i, j int) bool {
+			return data.Tasks[order[i]].Ratio > data.Tasks[order[j]].Ratio
+		})
+		for _, idx := range order {
+			task := data.Tasks[idx]
+			diagnosis.Binding = append(diagnosis.Binding, AllocationBinding{TaskID: task.TaskID, Bound: "max", Minutes: *task.MaxMinutes})
+		}
+		top := data.Tasks[order[0]]
+		excess := data.TotalMinutes - maxSum
+		diagnosis.Suggestions = append(diagnosis.Suggestions, AllocationProposal{Field: "max_minutes", TaskID: top.TaskID, From: top.MaxMinutes, To: intPtrOf(*top.MaxMinutes + excess)})
+	}
+	return diagnosis
+}
+
+// boundTasks は成功した分配結果のうち min または max にちょうど張り付いたタスクを返す。
+func boundTasks(data dto.AllocationRequestData, allocations []allocationDistribution) []AllocationBinding {
+	binding := []AllocationBinding{}
+	for i, allocation := range allocations {
+		task := data.Tasks[i]
+		switch {
+		case task.MaxMinutes != nil && allocation.AllocatedMinutes == *task.MaxMinutes:
+			binding = append(binding, AllocationBinding{TaskID: task.TaskID, Bound: "max", Minutes: *task.MaxMinutes})
+		case task.MinMinutes != nil && *task.MinMinutes > 0 && allocation.AllocatedMinutes == *task.MinMinutes:
+			binding = append(binding, AllocationBinding{TaskID: task.TaskID, Bound: "min", Minutes: *task.MinMinutes})
+		}
+	}
+	return binding
+}
+
+// tasksBy はタスクの添字を key の大きい順 (同値は入力順) に並べる。
+func tasksBy(data dto.AllocationRequestData, key func(dto.AllocationTaskData) int) []int {
+	order := make([]int, len(data.Tasks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return key(data.Tasks[order[i]]) > key(data.Tasks[order[j]])
+	})
+	return order
+}
+
+func intPtrOf(v int) *int {
+	return &v
+}
+
+func derefInt(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/usecase/dto"
+)
+
+func TestAllocationPreview_DiagnosesMinOverflow(t *testing.T) {
+	u := NewAllocationUsecase(nil, nil, nil, nil, nil)
+	min40, min30 := 40, 30
+	preview, err := u.Preview(dto.AllocationRequest{TotalMinutes: 50, Tasks: []dto.AllocationTaskRequest{
+		{TaskID: "a", Ratio: 1, MinMinutes: &min30},
+		{TaskID: "b", Ratio: 1, MinMinutes: &min40},
+		{TaskID: "c", Ratio: 1},
+	}})
+	require.NoError(t, err)
+	require.False(t, preview.Feasible)
+	require.Empty(t, preview.Allocations)
+	require.Equal(t, 70, preview.Diagnosis.MinTotalMinutes)
+	require.Nil(t, preview.Diagnosis.MaxTotalMinutes)
+	require.Equal(t, []AllocationBinding{
+		{TaskID: "b", Bound: "min", Minutes: 40},
+		{TaskID: "a", Bound: "min", Minutes: 30},
+	}, preview.Diagnosis.Binding)
+	// total を 70 に上げるか、最大の min から順に 20 分下げる。
+	require.Len(t, preview.Diagnosis.Suggestions, 2)
+	require.Equal(t, "total_minutes", preview.Diagnosis.Suggestions[0].Field)
+	require.Equal(t, 70, *preview.Diagnosis.Suggestions[0].To)
+	require.Equal(t, "b", preview.Diagnosis.Suggestions[1].TaskID)
+	require.Equal(t, 20, *preview.Diagnosis.Suggestions[1].To)
+}
+
+func TestAllocationPreview_DiagnosesMaxShortfall(t *testing.T) {
+	u := NewAllocationUsecase(nil, nil, nil, nil, nil)
+	max20, max30 := 20, 30
+	preview, err := u.Preview(dto.AllocationRequest{TotalMinutes: 60, Tasks: []dto.AllocationTaskRequest{
+		{TaskID: "a", Ratio: 1, MaxMinutes: &max20},
+		{TaskID: "b", Ratio: 3, MaxMinutes: &max30},
+	}})
+	require.NoError(t, err)
+	require.False(t, preview.Feasible)
+	require.Equal(t, 50, *preview.Diagnosis.MaxTotalMinutes)
+	require.Equal(t, "b", preview.Diagnosis.Binding[0].TaskID)
+	require.Equal(t, "max", preview.Diagnosis.Binding[0].Bound)
+	require.Equal(t, 50, *preview.Diagnosis.Suggestions[0].To)
+	require.Equal(t, "max_minutes", preview.Diagnosis.Suggestions[1].Field)
+	require.Equal(t, "b", preview.Diagnosis.Suggestions[1].TaskID)
+	require.Equal(t, 40, *preview.Diagnosis.Suggestions[1].To)
+}
+
+func TestAllocationPreview_FeasibleReportsBoundTasks(t *testing.T) {
+	u := NewAllocationUsecase(nil, nil, nil, nil, nil)
+	max10 := 10
+	preview, err := u.Preview(dto.AllocationRequest{TotalMinutes: 60, Tasks: []dto.AllocationTaskRequest{
+		{TaskID: "a", Ratio: 5, MaxMinutes: &max10},
+		{TaskID: "b", Ratio: 1},
+	}})
+	require.NoError(t, err)
+	require.True(t, preview.Feasible)
+	require.Len(t, preview.Allocations, 2)
+	require.Equal(t, 10, preview.Allocations[0].AllocatedMinutes)
+	require.Equal(t, []AllocationBinding{{TaskID: "a", Bound: "max", Minutes: 10}}, preview.Diagnosis.Binding)
+	require.Empty(t, preview.Diagnosis.Suggestions)
+}
@@ -0,0 +1,474 @@
+package usecase
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/usecase/dto"
+)
+
+// プロパティテストで検証するすべての分配戦略。granularity は 5 分刻みで検証する。
+var propertyStrategies = []dto.AllocationStrategy{
+	dto.AllocationStrategyLargestRemainder,
+	dto.AllocationStrategyDHondt,
+	dto.AllocationStrategySainteLague,
+	dto.AllocationStrategyGranularity,
+	dto.AllocationStrategyPriority,
+}
+
+const propertyIterations = 500
+
+// randomAllocationInput は整数比率と任意の min/max を持つ小さな入力を作る。
+// distinct が true の場合は比率と priority をタスクごとに重複させない。
+func randomAllocationInput(rng *rand.Rand, strategy dto.AllocationStrategy, maxTasks, maxTotal int, bounded, distinct bool) dto.AllocationRequestData {
+	count := 1 + rng.Intn(maxTasks)
+	ratios := rng.Perm(9)
+	priorities := rng.Perm(count)
+	input := dto.AllocationRequestData{TotalMinutes: 1 + rng.Intn(maxTotal), Strategy: strategy}
+	if strategy == dto.AllocationStrategyGranularity {
+		input.GranularityMinutes = 5
+	}
+	for i := 0; i < count; i++ {
+		task := dto.AllocationTaskData{TaskID: fmt.Sprintf("t%d", i), Ratio: float64(1 + rng.Intn(9)), Priority: rng.Intn(3)}
+		if distinct {
+			task.Ratio = float64(1 + ratios[i])
+			task.Priority = priorities[i]
+		}
+		if bounded && rng.Intn(3) == 0 {
+			task.MinMinutes = intPtr(rng.Intn(maxTotal/count + 1))
+		}
+		if bounded && rng.Intn(3) == 0 {
+			low := 1
+			if task.MinMinutes != nil && *task.MinMinutes > low {
+				low = *task.MinMinutes
+			}
+			task.MaxMinutes = intPtr(low + rng.Intn(maxTotal))
+		}
+		input.Tasks = append(input.Tasks, task)
+	}
+	return input
+}
+
+// tiedAllocationInput は 1〜3 通りの条件 (比率・priority・min/max) を 3 件以上のタスクで使い回し、
+// 条件がまったく同じタスクを必ず含む入力を作る。distinctKinds が false の場合は条件の異なるタスク同士でも
+// 比率と priority が同値になりやすいよう狭い範囲から選ぶ。
+func tiedAllocationInput(rng *rand.Rand, strategy dto.AllocationStrategy, maxTasks, maxTotal int, distinctKinds bool) dto.AllocationRequestData {
+	count := 3 + rng.Intn(maxTasks-2)
+	input := dto.AllocationRequestData{TotalMinutes: 1 + rng.Intn(maxTotal), Strategy: strategy}
+	if strategy == dto.AllocationStrategyGranularity {
+		input.GranularityMinutes = 5
+	}
+	ratios := rng.Perm(9)
+	kinds := make([]dto.AllocationTaskData, 1+rng.Intn(3))
+	for k := range kinds {
+		kind := dto.AllocationTaskData{Ratio: float64(1 + rng.Intn(2)), Priority: rng.Intn(2)}
+		if distinctKinds {
+			kind.Ratio = float64(1 + ratios[k])
+			kind.Priority = k
+		}
+		if rng.Intn(3) == 0 {
+			kind.MinMinutes = intPtr(rng.Intn(maxTotal/count + 1))
+		}
+		if rng.Intn(2) == 0 {
+			kind.MaxMinutes = intPtr(derefInt(kind.MinMinutes) + 1 + rng.Intn(maxTotal/count+1))
+		}
+		kinds[k] = kind
+	}
+	for i := 0; i < count; i++ {
+		task := kinds[rng.Intn(len(kinds))]
+		task.TaskID = fmt.Sprintf("t%d", i)
+		input.Tasks = append(input.Tasks, task)
+	}
+	return input
+}
+
+// allocationFeasible は min の合計が total 以下で、max の合計が total 以上 (上限のないタスクがあれば常に真) かを返す。
+func allocationFeasible(input dto.AllocationRequestData) bool {
+	minSum, maxSum, bounded := 0, 0, true
+	for _, task := range input.Tasks {
+		if task.MinMinutes != nil {
+			minSum += *task.MinMinutes
+		}
+		if task.MaxMinutes != nil {
+			maxSum += *task.MaxMinutes
+		} else {
+			bounded = false
+		}
+	}
+	return minSum <= input.TotalMinutes && (!bounded || maxSum >= input.TotalMinutes)
+}
+
+// checkAllocationInvariants は合計の一致と min/max の遵守を検証し、分配結果を返す。実行不能な入力はエラーであること。
+func checkAllocationInvariants(t *testing.T, input dto.AllocationRequestData) ([]int, bool) {
+	t.Helper()
+	allocations, err := distributeAllocations(input)
+	if !allocationFeasible(input) {
+		require.Error(t, err, "infeasible input must fail: %+v", input)
+		return nil, false
+	}
+	require.NoError(t, err, "feasible input must succeed: %+v", input)
+	require.Len(t, allocations, len(input.Tasks))
+	minutes := make([]int, len(allocations))
+	sum := 0
+	for i, allocation := range allocations {
+		task := input.Tasks[i]
+		require.Equal(t, task.TaskID, allocation.TaskID)
+		if task.MinMinutes != nil {
+			require.GreaterOrEqual(t, allocation.AllocatedMinutes, *task.MinMinutes, "min bound: %+v", input)
+		}
+		if task.MaxMinutes != nil {
+			require.LessOrEqual(t, allocation.AllocatedMinutes, *task.MaxMinutes, "max bound: %+v", input)
+		}
+		require.GreaterOrEqual(t, allocation.AllocatedMinutes, 0)
+		minutes[i] = allocation.AllocatedMinutes
+		sum += allocation.AllocatedMinutes
+	}
+	require.Equal(t, input.TotalMinutes, sum, "sum: %+v", input)
+	return minutes, true
+}
+
+func TestDistributeAllocations_PropertySumAndBounds(t *testing.T) {
+	for _, strategy := range propertyStrategies {
+		t.Run(string(strategy), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < propertyIterations; i++ {
+				checkAllocationInvariants(t, randomAllocationInput(rng, strategy, 6, 120, true, false))
+			}
+		})
+	}
+}
+
+func TestDistributeAllocations_PropertyPermutationInvariant(t *testing.T) {
+	// 比率と priority が重複しない入力では、同値の並べ替えに頼らないため入力順に依存しない。
+	// 比率と priority が同じタスクを含む場合も、条件がまったく同じタスク同士の結果の集合は変わらない。
+	for _, strategy := range propertyStrategies {
+		t.Run(string(strategy), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(2))
+			for i := 0; i < propertyIterations; i++ {
+				input := randomAllocationInput(rng, strategy, 5, 120, true, true)
+				switch rng.Intn(3) {
+				case 0:
+					clone := input.Tasks[rng.Intn(len(input.Tasks))]
+					clone.TaskID += "-copy"
+					input.Tasks = append(input.Tasks, clone)
+				case 1:
+					input = tiedAllocationInput(rng, strategy, 6, 120, true)
+				}
+				original, ok := checkAllocationInvariants(t, input)
+				if !ok {
+					continue
+				}
+				permuted := input
+				permuted.Tasks = append([]dto.AllocationTaskData{}, input.Tasks...)
+				rng.Shuffle(len(permuted.Tasks), func(a, b int) {
+					permuted.Tasks[a], permuted.Tasks[b] = permuted.Tasks[b], permuted.Tasks[a]
+				})
+				shuffled, _ := checkAllocationInvariants(t, permuted)
+				require.Equal(t, allocationsBySignature(input, original), allocationsBySignature(permuted, shuffled), "input: %+v", input)
+
+				again, _ := checkAllocationInvariants(t, input)
+				require.Equal(t, original, again, "same input must give the same result")
+			}
+		})
+	}
+}
+
+func TestDistributeAllocations_PropertyTiesFollowInputOrder(t *testing.T) {
+	// 比率と priority が同じタスク同士では入力順が早いほうを優先する。前のタスクがまだ 1 刻み (granularity 以外は 1 分)
+	// 受け取れる場合、min_minutes を除いた分配は後ろのタスク以上になる。
+	for _, strategy := range propertyStrategies {
+		t.Run(string(strategy), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(6))
+			for i := 0; i < propertyIterations; i++ {
+				input := tiedAllocationInput(rng, strategy, 6, 120, false)
+				minutes, ok := checkAllocationInvariants(t, input)
+				if !ok {
+					continue
+				}
+				step := 1
+				if input.GranularityMinutes > 0 {
+					step = input.GranularityMinutes
+				}
+				for a, earlier := range input.Tasks {
+					if earlier.MaxMinutes != nil && minutes[a]+step > *earlier.MaxMinutes {
+						continue
+					}
+					for b := a + 1; b < len(input.Tasks); b++ {
+						later := input.Tasks[b]
+						if earlier.Ratio != later.Ratio || earlier.Priority != later.Priority {
+							continue
+						}
+						require.GreaterOrEqual(t, minutes[a]-derefInt(earlier.MinMinutes), minutes[b]-derefInt(later.MinMinutes), "tasks %d and %d in %v: %+v", a, b, minutes, input)
+					}
+				}
+			}
+		})
+	}
+}
+
+// allocationsBySignature は task_id 以外の条件が同じタスクごとに、分配結果を昇順にまとめる。
+func allocationsBySignature(input dto.AllocationRequestData, minutes []int) map[string][]int {
+	groups := make(map[string][]int)
+	for i, task := range input.Tasks {
+		max := -1
+		if task.MaxMinutes != nil {
+			max = *task.MaxMinutes
+		}
+		key := fmt.Sprintf("%v/%d/%d/%d", task.Ratio, derefInt(task.MinMinutes), max, task.Priority)
+		groups[key] = append(groups[key], minutes[i])
+	}
+	for _, values := range groups {
+		sort.Ints(values)
+	}
+	return groups
+}
+
+func TestDistributeAllocations_PropertyMonotonicInRatio(t *testing.T) {
+	// 1 つのタスクの比率だけを上げても、そのタスクの分配は減らない。priority 戦略は比率を使わないため対象外。
+	strategies := []dto.AllocationStrategy{
+		dto.AllocationStrategyLargestRemainder,
+		dto.AllocationStrategyDHondt,
+		dto.AllocationStrategySainteLague,
+	}
+	for _, strategy := range strategies {
+		t.Run(string(strategy), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(3))
+			for i := 0; i < propertyIterations; i++ {
+				input := randomAllocationInput(rng, strategy, 5, 120, false, false)
+				before, _ := checkAllocationInvariants(t, input)
+				k := rng.Intn(len(input.Tasks))
+				raised := input
+				raised.Tasks = append([]dto.AllocationTaskData{}, input.Tasks...)
+				raised.Tasks[k].Ratio += float64(1 + rng.Intn(5))
+				after, _ := checkAllocationInvariants(t, raised)
+				require.GreaterOrEqual(t, after[k], before[k], "raising ratio of %d: %+v", k, input)
+			}
+		})
+	}
+}
+
+func TestDistributeAllocations_MatchesBruteForceReference(t *testing.T) {
+	// 半分は同じ条件のタスクを含む入力にし、同値の扱いも参照解と突き合わせる。
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < propertyIterations; i++ {
+		strategy := propertyStrategies[rng.Intn(len(propertyStrategies))]
+		if strategy == dto.AllocationStrategyGranularity {
+			continue
+		}
+		input := randomAllocationInput(rng, strategy, 4, 24, true, false)
+		if rng.Intn(2) == 0 {
+			input = tiedAllocationInput(rng, strategy, 4, 24, false)
+		}
+		candidates := bruteForceAllocations(input)
+		got, ok := checkAllocationInvariants(t, input)
+		require.Equal(t, len(candidates) > 0, ok, "brute force feasibility: %+v", input)
+		if !ok {
+			continue
+		}
+		require.True(t, bruteForceAccepts(input, candidates, got), "strategy %s gave %v for %+v", strategy, got, input)
+	}
+}
+
+// bruteForceAllocations は min/max を満たし合計が total になる分配をすべて列挙する。
+func bruteForceAllocations(input dto.AllocationRequestData) [][]int {
+	var results [][]int
+	current := make([]int, len(input.Tasks))
+	var walk func(i, left int)
+	walk = func(i, left int) {
+		if i == len(input.Tasks) {
+			if left == 0 {
+				results = append(results, append([]int{}, current...))
+			}
+			return
+		}
+		task := input.Tasks[i]
+		low, high := derefInt(task.MinMinutes), left
+		if task.MaxMinutes != nil && *task.MaxMinutes < high {
+			high = *task.MaxMinutes
+		}
+		for v := low; v <= high; v++ {
+			current[i] = v
+			walk(i+1, left-v)
+		}
+	}
+	walk(0, input.TotalMinutes)
+	return results
+}
+
+// bruteForceAccepts は got が列挙した候補のうち、戦略の定義を満たすものに含まれるかを返す。
+//   - largest_remainder: isLargestRemainder の条件 (上限がなければ各タスクは割当量 q の切り捨てか切り上げで、端数の大きい順に切り上げる)。
+//   - dhondt / sainte_lague: 除数方式の最適条件 max(ratio/d(n)) <= min(ratio/d(n-1)) を満たす。
+//   - priority: priority 順 (同値は入力順) に並べた辞書式順序で最大。
+func bruteForceAccepts(input dto.AllocationRequestData, candidates [][]int, got []int) bool {
+	var accepted [][]int
+	switch input.Strategy {
+	case dto.AllocationStrategyLargestRemainder:
+		for _, candidate := range candidates {
+			if isLargestRemainder(input, candidate) {
+				accepted = append(accepted, candidate)
+			}
+		}
+	case dto.AllocationStrategyDHondt:
+		accepted = divisorOptimal(input, candidates, func(n int) float64 { return float64(n + 1) })
+	case dto.AllocationStrategySainteLague:
+		accepted = divisorOptimal(input, candidates, func(n int) float64 { return float64(2*n + 1) })
+	case dto.AllocationStrategyPriority:
+		accepted = [][]int{lexicographicMax(input, candidates)}
+	}
+	for _, candidate := range accepted {
+		if fmt.Sprint(candidate) == fmt.Sprint(got) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLargestRemainder は上限付きの最大剰余法を満たすかを返す。切り捨て値は上限で打ち切り、残りは上限に達していない
+// タスクへ端数の大きい順 (同値は比率の大きい順、入力順) に 1 分ずつ巡回して配る。そのため切り捨て値からの追加分は
+// 最大値 m か m-1 で (上限に達したタスクは m 以下)、m を得たタスクは m-1 で止まったタスクより順位が高い。
+func isLargestRemainder(input dto.AllocationRequestData, candidate []int) bool {
+	// 整数比率なので割当量は pool*ratio/sum を整数演算で比較できる。
+	pool, ratioSum := input.TotalMinutes, 0
+	for _, task := range input.Tasks {
+		pool -= derefInt(task.MinMinutes)
+		ratioSum += int(task.Ratio)
+	}
+	remainders := make([]int, len(input.Tasks))
+	extras := make([]int, len(input.Tasks))
+	open := make([]bool, len(input.Tasks))
+	most := 0
+	for i, task := range input.Tasks {
+		share := pool * int(task.Ratio)
+		base := share / ratioSum
+		remainders[i] = share % ratioSum
+		seats := candidate[i] - derefInt(task.MinMinutes)
+		open[i] = true
+		if task.MaxMinutes != nil {
+			capacity := *task.MaxMinutes - derefInt(task.MinMinutes)
+			if base > capacity {
+				base = capacity
+			}
+			open[i] = candidate[i] < *task.MaxMinutes
+		}
+		extras[i] = seats - base
+		if extras[i] < 0 {
+			return false
+		}
+		if extras[i] > most {
+			most = extras[i]
+		}
+	}
+	ranksBefore := func(i, j int) bool {
+		if remainders[i] != remainders[j] {
+			return remainders[i] > remainders[j]
+		}
+		if input.Tasks[i].Ratio != input.Tasks[j].Ratio {
+			return input.Tasks[i].Ratio > input.Tasks[j].Ratio
+		}
+		return i < j
+	}
+	for i := range input.Tasks {
+		if !open[i] {
+			continue
+		}
+		if extras[i] < most-1 {
+			return false
+		}
+		for j := range input.Tasks {
+			if extras[i] == most-1 && extras[j] == most && !ranksBefore(j, i) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func divisorOptimal(input dto.AllocationRequestData, candidates [][]int, divisor func(int) float64) [][]int {
+	var accepted [][]int
+	for _, candidate := range candidates {
+		maxNext, minLast := 0.0, -1.0
+		for i, task := range input.Tasks {
+			seats := candidate[i] - derefInt(task.MinMinutes)
+			if task.MaxMinutes == nil || candidate[i] < *task.MaxMinutes {
+				if q := task.Ratio / divisor(seats); q > maxNext {
+					maxNext = q
+				}
+			}
+			if seats > 0 {
+				if q := task.Ratio / divisor(seats-1); minLast < 0 || q < minLast {
+					minLast = q
+				}
+			}
+		}
+		if minLast < 0 || maxNext <= minLast+allocationEpsilon {
+			accepted = append(accepted, candidate)
+		}
+	}
+	return accepted
+}
+
+func lexicographicMax(input dto.AllocationRequestData, candidates [][]int) []int {
+	order := make([]int, len(input.Tasks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return input.Tasks[order[a]].Priority < input.Tasks[order[b]].Priority
+	})
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for _, idx := range order {
+			if candidate[idx] != best[idx] {
+				if candidate[idx] > best[idx] {
+					best = candidate
+				}
+				break
+			}
+		}
+	}
+	return best
+}
+
+// FuzzDistributeAllocations は任意の入力で、実行可能なら合計と min/max が守られ、実行不能ならエラーになることを確かめる。
+func FuzzDistributeAllocations(f *testing.F) {
+	f.Add(uint16(235), uint8(0), uint8(5), []byte{3, 0, 0, 2, 0, 0, 1, 0, 0})
+	f.Add(uint16(10), uint8(0), uint8(5), []byte{9, 0, 6, 1, 0, 11})
+	f.Add(uint16(60), uint8(3), uint8(15), []byte{1, 10, 30, 2, 0, 0, 5, 0, 20})
+	f.Add(uint16(7), uint8(4), uint8(1), []byte{1, 0, 3, 1, 0, 3, 1, 0, 0})
+	f.Fuzz(func(t *testing.T, total uint16, strategyIndex uint8, granularity uint8, raw []byte) {
+		input := dto.AllocationRequestData{
+			TotalMinutes: int(total%2000) + 1,
+			Strategy:     propertyStrategies[int(strategyIndex)%len(propertyStrategies)],
+		}
+		if input.Strategy == dto.AllocationStrategyGranularity {
+			input.GranularityMinutes = int(granularity%60) + 1
+		}
+		// 3 バイトで 1 タスク: 比率 (0 は 1 扱い)、min (0 は未指定)、max (0 は未指定)。
+		for i := 0; i+2 < len(raw) && len(input.Tasks) < 12; i += 3 {
+			task := dto.AllocationTaskData{
+				TaskID:   fmt.Sprintf("t%d", len(input.Tasks)),
+				Ratio:    float64(raw[i]%50 + 1),
+				Priority: int(raw[i] % 3),
+			}
+			if raw[i+1] != 0 {
+				task.MinMinutes = intPtr(int(raw[i+1]))
+			}
+			if raw[i+2] != 0 {
+				max := int(raw[i+2]) * 4
+				if task.MinMinutes != nil && max < *task.MinMinutes {
+					max = *task.MinMinutes
+				}
+				task.MaxMinutes = intPtr(max)
+			}
+			input.Tasks = append(input.Tasks, task)
+		}
+		if len(input.Tasks) == 0 {
+			return
+		}
+		checkAllocationInvariants(t, input)
+	})
+}
@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+)
+
+// ErrEntryNotFound はユーザー所有のエントリが見つからないことを表す。
+var ErrEntryNotFound = errors.New("entry not found")
+
+// SplitEntry は終了済みエントリの時間を distributeAllocations で区間ごとに分配し、
+// 元のエントリを同じ時間帯を隙間なく埋める子エントリへ置き換える。
+// 分配は分単位で行い、1 分未満の端数秒は最後の子エントリに含めて元の終了時刻と一致させる。
+func (u *AllocationUsecase) SplitEntry(ctx context.Context, userID uuid.UUID, id uuid.UUID, input dto.EntrySplitRequest) ([]entity.Entry, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	original, err := u.entries.GetByID(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if original.IsRunning() {
+		return nil, dto.ValidationError{Field: "entry", Message: "must be finished before splitting"}
+	}
+	if original.IsBreak {
+		return nil, dto.ValidationError{Field: "entry", Message: "cannot split a break"}
+	}
+	end := *original.EndedAt
+	totalMinutes := int(end.Sub(original.StartedAt) / time.Minute)
+	if totalMinutes <= 0 {
+		return nil, dto.ValidationError{Field: "entry", Message: "must be at least one minute long"}
+	}
+
+	distributions, err := distributeAllocations(dto.AllocationRequestData{TotalMinutes: totalMinutes, Tasks: data.Tasks})
+	if err != nil {
+		return nil, AllocationConstraintError{Message: err.Error()}
+	}
+
+	// 保存前にすべての区間のプロジェクト・タグを検証し、元エントリを残したまま失敗させる。
+	children := make([]entity.Entry, 0, len(distributions))
+	lengths := make([]time.Duration, 0, len(distributions))
+	for i, part := range data.Parts {
+		if distributions[i].AllocatedMinutes == 0 {
+			continue
+		}
+		child := entity.Entry{
+			UserID:    userID,
+			ProjectID: original.ProjectID,
+			Title:     original.Title,
+			Notes:     original.Notes,
+			Ratio:     original.Ratio,
+			Tags:      original.Tags,
+		}
+		if part.ProjectID != nil {
+			if _, err := u.projects.GetByID(ctx, userID, *part.ProjectID); err != nil {
+				return nil, dto.ValidationError{Field: "project_id", Message: "refers to unknown project"}
+			}
+			child.ProjectID = part.ProjectID
+		}
+		if part.Title != "" {
+			child.Title = part.Title
+		}
+		if part.TagIDsSet {
+			tags, err := loadOwnedTags(ctx, u.tags, userID, part.TagIDs)
+			if err != nil {
+				return nil, err
+			}
+			child.Tags = tags
+		}
+		children = append(children, child)
+		lengths = append(lengths, time.Duration(distributions[i].AllocatedMinutes)*time.Minute)
+	}
+
+	now := u.clock.Now()
+	cursor := original.StartedAt
+	for i := range children {
+		child := &children[i]
+		child.ID = uuid.New()
+		child.StartedAt = cursor
+		childEnd := cursor.Add(lengths[i])
+		if i == len(children)-1 {
+			childEnd = end
+		}
+		child.EndedAt = &childEnd
+		child.UpdateDuration(now)
+		cursor = childEnd
+	}
+	// 子エントリの作成と元エントリの削除は同じトランザクションで行い、時間を二重に数える状態を残さない。
+	changes := repository.EntryChanges{Delete: []uuid.UUID{original.ID}, Create: children}
+	if err := u.entries.ApplyChanges(ctx, userID, changes); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrEntryNotFound
+		}
+		return nil, err
+	}
+	return children, nil
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+func TestAllocationUsecase_SplitEntryTilesOriginalSpan(t *testing.T) {
+	userID := uuid.New()
+	entryID := uuid.New()
+	projectA := uuid.New()
+	projectB := uuid.New()
+	start := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	end := start.Add(100*time.Minute + 30*time.Second)
+	var applied []repository.EntryChanges
+	entries := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, uid uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			require.Equal(t, userID, uid)
+			return &entity.Entry{ID: entryID, UserID: userID, Title: "Pairing", StartedAt: start, EndedAt: &end, Ratio: 1}, nil
+		},
+		ApplyChangesFn: func(_ context.Context, uid uuid.UUID, changes repository.EntryChanges) error {
+			require.Equal(t, userID, uid)
+			applied = append(applied, changes)
+			return nil
+		},
+	}
+	projects := &fakes.FakeProjectRepository{
+		GetByIDFn: func(_ context.Context, _ uuid.UUID, id uuid.UUID) (*entity.Project, error) {
+			return &entity.Project{ID: id, UserID: userID}, nil
+		},
+	}
+	uc := NewAllocationUsecase(&fakes.FakeAllocationRepository{}, entries, projects, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+	a, b := projectA.String(), projectB.String()
+
+	children, err := uc.SplitEntry(context.Background(), userID, entryID, dto.EntrySplitRequest{Parts: []dto.EntrySplitPartRequest{
+		{ProjectID: &a, Ratio: 3},
+		{ProjectID: &b, Ratio: 1, Title: "Review"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	// 子エントリの作成と元エントリの削除はひとつの変更としてまとめて保存する。
+	require.Len(t, applied, 1)
+	require.Equal(t, []uuid.UUID{entryID}, applied[0].Delete)
+	require.Equal(t, children, applied[0].Create)
+
+	// 100 分を 3:1 で分けると 75 / 25 分になり、端数の 30 秒は最後の区間に含まれる。
+	require.Equal(t, &projectA, children[0].ProjectID)
+	require.Equal(t, "Pairing", children[0].Title)
+	require.Equal(t, int64(75*60), children[0].DurationSec)
+	require.Equal(t, &projectB, children[1].ProjectID)
+	require.Equal(t, "Review", children[1].Title)
+	require.Equal(t, int64(25*60+30), children[1].DurationSec)
+	require.Equal(t, start, children[0].StartedAt)
+	require.Equal(t, *children[0].EndedAt, children[1].StartedAt)
+	require.Equal(t, end, *children[1].EndedAt)
+}
+
+func TestAllocationUsecase_SplitEntryReturnsRepositoryFailure(t *testing.T) {
+	userID := uuid.New()
+	start := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	failure := errors.New("disk full")
+	entries := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, _ uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			return &entity.Entry{ID: id, UserID: userID, Title: "Pairing", StartedAt: start, EndedAt: &end, Ratio: 1}, nil
+		},
+		ApplyChangesFn: func(context.Context, uuid.UUID, repository.EntryChanges) error {
+			return failure
+		},
+		CreateFn: func(context.Context, *entity.Entry) error {
+			t.Fatal("child entries must be saved together with the delete")
+			return nil
+		},
+		DeleteFn: func(context.Context, uuid.UUID, uuid.UUID) error {
+			t.Fatal("the original must be deleted together with the creates")
+			return nil
+		},
+	}
+	uc := NewAllocationUsecase(&fakes.FakeAllocationRepository{}, entries, &fakes.FakeProjectRepository{}, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+
+	_, err := uc.SplitEntry(context.Background(), userID, uuid.New(), dto.EntrySplitRequest{Parts: []dto.EntrySplitPartRequest{
+		{Ratio: 1},
+		{Ratio: 1},
+	}})
+	require.ErrorIs(t, err, failure)
+}
+
+func TestAllocationUsecase_SplitEntryReportsOnlyMissingEntryAsNotFound(t *testing.T) {
+	userID := uuid.New()
+	lookupErr := repository.ErrNotFound
+	entries := &fakes.FakeEntryRepository{
+		GetByIDFn: func(context.Context, uuid.UUID, uuid.UUID) (*entity.Entry, error) {
+			return nil, lookupErr
+		},
+	}
+	uc := NewAllocationUsecase(&fakes.FakeAllocationRepository{}, entries, &fakes.FakeProjectRepository{}, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+	input := dto.EntrySplitRequest{Parts: []dto.EntrySplitPartRequest{{Ratio: 1}, {Ratio: 1}}}
+
+	_, err := uc.SplitEntry(context.Background(), userID, uuid.New(), input)
+	require.ErrorIs(t, err, ErrEntryNotFound)
+
+	// DB の障害は見つからない扱いにせず、そのまま返す。
+	lookupErr = errors.New("connection refused")
+	_, err = uc.SplitEntry(context.Background(), userID, uuid.New(), input)
+	require.EqualError(t, err, "connection refused")
+	require.NotErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestAllocationUsecase_SplitEntryRejectsRunningEntry(t *testing.T) {
+	userID := uuid.New()
+	entries := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, _ uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			return &entity.Entry{ID: id, UserID: userID, Title: "Running", StartedAt: time.Now().Add(-time.Hour), Ratio: 1}, nil
+		},
+		CreateFn: func(context.Context, *entity.Entry) error {
+			t.Fatal("no child entries should be created")
+			return nil
+		},
+	}
+	uc := NewAllocationUsecase(&fakes.FakeAllocationRepository{}, entries, &fakes.FakeProjectRepository{}, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+
+	_, err := uc.SplitEntry(context.Background(), userID, uuid.New(), dto.EntrySplitRequest{Parts: []dto.EntrySplitPartRequest{
+		{Ratio: 1},
+		{Ratio: 1},
+	}})
+	var valErr dto.ValidationError
+	require.ErrorAs(t, err, &valErr)
+	require.Equal(t, "entry", valErr.Field)
+}
@@ -0,0 +1,268 @@
+package usecase
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"sort"
+
+	"chronome/internal/usecase/dto"
+)
+
+var errMaxConstraints = errors.New("unable to satisfy max constraints with provided total_minutes")
+
+// allocationStrategy は min_minutes を確保した後の残り pool 分を states へ配る。
+// 各戦略は max_minutes を超えて配ってはならず、配り切れない場合はエラーを返す。
+type allocationStrategy interface {
+	distribute(states []allocationState, pool int) error
+}
+
+func newAllocationStrategy(input dto.AllocationRequestData) allocationStrategy {
+	switch input.Strategy {
+	case dto.AllocationStrategyDHondt:
+		return divisorStrategy{divisor: func(seats int) float64 { return float64(seats + 1) }}
+	case dto.AllocationStrategySainteLague:
+		return divisorStrategy{divisor: func(seats int) float64 { return float64(2*seats + 1) }}
+	case dto.AllocationStrategyGranularity:
+		return granularityStrategy{step: input.GranularityMinutes}
+	case dto.AllocationStrategyPriority:
+		return priorityStrategy{}
+	default:
+		return largestRemainderStrategy{}
+	}
+}
+
+// remainingCapacity は max_minutes までの残り容量を返す。上限がなければ ok=false。
+func (s allocationState) remainingCapacity() (capacity int, ok bool) {
+	if s.MaxMinutes == nil {
+		return 0, false
+	}
+	capacity = *s.MaxMinutes - s.Allocation
+	if capacity < 0 {
+		capacity = 0
+	}
+	return capacity, true
+}
+
+// largestRemainderStrategy は比率で切り捨てた後、端数の大きい順に 1 分ずつ配る。
+type largestRemainderStrategy struct{}
+
+func (largestRemainderStrategy) distribute(states []allocationState, pool int) error {
+	carried := 0
+	for i := range states {
+		task := &states[i]
+		// 小数点以下は一旦切り捨て、余りは後段で大きい順に 1 分ずつ配る。
+		desired := float64(pool) * task.Normalized
+		capacity := pool
+		if capRemaining, bounded := task.remainingCapacity(); bounded {
+			capacity = capRemaining
+		}
+		if capacity <= 0 {
+			task.Remainder = -1
+			continue
+		}
+		baseAdd := int(math.Floor(desired))
+		if baseAdd > capacity {
+			baseAdd = capacity
+		}
+		task.Allocation += baseAdd
+		carried += baseAdd
+		task.Remainder = desired - float64(baseAdd)
+	}
+
+	remaining := pool - carried
+	for remaining > 0 {
+		eligible := make([]*allocationState, 0, len(states))
+		for i := range states {
+			task := &states[i]
+			if task.MaxMinutes != nil && task.Allocation >= *task.MaxMinutes {
+				continue
+			}
+			eligible = append(eligible, task)
+		}
+		if len(eligible) == 0 {
+			return errMaxConstraints
+		}
+		// 余りは「端数が大きい」「比率が大きい」「入力順が早い」の優先順位で安定的に配る。
+		sort.Slice(eligible, func(i, j int) bool {
+			ai := eligible[i]
+			aj := eligible[j]
+			if math.Abs(aj.Remainder-ai.Remainder) > allocationEpsilon {
+				return ai.Remainder > aj.Remainder
+			}
+			if math.Abs(aj.Normalized-ai.Normalized) > allocationEpsilon {
+				return ai.Normalized > aj.Normalized
+			}
+			return ai.Index < aj.Index
+		})
+
+		if len(eligible) == 1 {
+			task := eligible[0]
+			available := remaining
+			if task.MaxMinutes != nil {
+				available = *task.MaxMinutes - task.Allocation
+			}
+			if available <= 0 {
+				return errMaxConstraints
+			}
+			chunk := available
+			if chunk > remaining {
+				chunk = remaining
+			}
+			task.Allocation += chunk
+			remaining -= chunk
+			continue
+		}
+
+		distributed := 0
+		for _, task := range eligible {
+			if remaining == 0 {
+				break
+			}
+			if task.MaxMinutes != nil && task.Allocation >= *task.MaxMinutes {
+				continue
+			}
+			task.Allocation++
+			remaining--
+			distributed++
+		}
+		if distributed == 0 {
+			return errors.New("unable to distribute remaining minutes due to max constraints")
+		}
+	}
+	return nil
+}
+
+// divisorStrategy は D'Hondt / Sainte-Laguë などの除数方式で 1 分ずつ配る。
+// seats は min_minutes を除いて pool から配った分数で、商 ratio / divisor(seats) が最大のタスクが次の 1 分を得る。
+type divisorStrategy struct {
+	divisor func(seats int) float64
+}
+
+func (s divisorStrategy) distribute(states []allocationState, pool int) error {
+	seats := make([]int, len(states))
+	queue := &quotientQueue{states: states}
+	for i := range states {
+		if capacity, bounded := states[i].remainingCapacity(); bounded && capacity == 0 {
+			continue
+		}
+		heap.Push(queue, quotientItem{index: i, quotient: states[i].Ratio / s.divisor(0)})
+	}
+	for ; pool > 0; pool-- {
+		if queue.Len() == 0 {
+			return errMaxConstraints
+		}
+		item := heap.Pop(queue).(quotientItem)
+		task := &states[item.index]
+		task.Allocation++
+		seats[item.index]++
+		if capacity, bounded := task.remainingCapacity(); bounded && capacity == 0 {
+			continue
+		}
+		heap.Push(queue, quotientItem{index: item.index, quotient: task.Ratio / s.divisor(seats[item.index])})
+	}
+	return nil
+}
+
+type quotientItem struct {
+	index    int
+	quotient float64
+}
+
+// quotientQueue は商の大きい順、同値なら比率の大きい順、入力順の早い順に取り出す。
+type quotientQueue struct {
+	states []allocationState
+	items  []quotientItem
+}
+
+func (q quotientQueue) Len() int { return len(q.items) }
+
+func (q quotientQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if math.Abs(a.quotient-b.quotient) > allocationEpsilon {
+		return a.quotient > b.quotient
+	}
+	sa, sb := q.states[a.index], q.states[b.index]
+	if math.Abs(sa.Normalized-sb.Normalized) > allocationEpsilon {
+		return sa.Normalized > sb.Normalized
+	}
+	return sa.Index < sb.Index
+}
+
+func (q quotientQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *quotientQueue) Push(x any) { q.items = append(q.items, x.(quotientItem)) }
+
+func (q *quotientQueue) Pop() any {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
+
+// granularityStrategy は step 分のブロック単位で最大剰余法を適用する。
+// pool が step で割り切れない分と、max_minutes がブロックに収まらず配れなかった分は分単位の最大剰余法で配る。
+type granularityStrategy struct {
+	step int
+}
+
+func (s granularityStrategy) distribute(states []allocationState, pool int) error {
+	blocks := pool / s.step
+	blockStates := make([]allocationState, len(states))
+	blockCapacity := 0
+	allBounded := true
+	for i, state := range states {
+		blockStates[i] = state
+		blockStates[i].Allocation = 0
+		if capacity, bounded := state.remainingCapacity(); bounded {
+			limit := capacity / s.step
+			blockStates[i].MaxMinutes = &limit
+			blockCapacity += limit
+		} else {
+			allBounded = false
+		}
+	}
+	if allBounded && blocks > blockCapacity {
+		blocks = blockCapacity
+	}
+	if blocks > 0 {
+		if err := (largestRemainderStrategy{}).distribute(blockStates, blocks); err != nil {
+			return err
+		}
+		for i := range states {
+			states[i].Allocation += blockStates[i].Allocation * s.step
+		}
+	}
+	if rest := pool - blocks*s.step; rest > 0 {
+		return largestRemainderStrategy{}.distribute(states, rest)
+	}
+	return nil
+}
+
+// priorityStrategy は priority の小さい順 (同値は入力順) に max_minutes まで埋める。
+// 上限のないタスクに到達した時点で残りをすべて割り当てる。
+type priorityStrategy struct{}
+
+func (priorityStrategy) distribute(states []allocationState, pool int) error {
+	order := make([]int, len(states))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return states[order[i]].Priority < states[order[j]].Priority
+	})
+	for _, idx := range order {
+		if pool == 0 {
+			break
+		}
+		take := pool
+		if capacity, bounded := states[idx].remainingCapacity(); bounded && capacity < take {
+			take = capacity
+		}
+		states[idx].Allocation += take
+		pool -= take
+	}
+	if pool > 0 {
+		return errMaxConstraints
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/usecase/dto"
+)
+
+func allocatedMinutes(t *testing.T, input dto.AllocationRequestData) []int {
+	t.Helper()
+	allocations, err := distributeAllocations(input)
+	require.NoError(t, err)
+	minutes := make([]int, len(allocations))
+	sum := 0
+	for i, allocation := range allocations {
+		minutes[i] = allocation.AllocatedMinutes
+		sum += allocation.AllocatedMinutes
+	}
+	require.Equal(t, input.TotalMinutes, sum)
+	return minutes
+}
+
+func TestDistributeAllocations_Strategies(t *testing.T) {
+	votes := []dto.AllocationTaskData{
+		{TaskID: "a", Ratio: 100000},
+		{TaskID: "b", Ratio: 80000},
+		{TaskID: "c", Ratio: 30000},
+		{TaskID: "d", Ratio: 20000},
+	}
+	cases := []struct {
+		name  string
+		input dto.AllocationRequestData
+		want  []int
+	}{
+		{
+			name: "largest remainder gives leftovers to the largest fraction",
+			input: dto.AllocationRequestData{TotalMinutes: 235, Tasks: []dto.AllocationTaskData{
+				{TaskID: "a", Ratio: 3},
+				{TaskID: "b", Ratio: 2},
+				{TaskID: "c", Ratio: 1},
+			}},
+			want: []int{118, 78, 39},
+		},
+		{
+			name:  "dhondt favours larger ratios",
+			input: dto.AllocationRequestData{TotalMinutes: 8, Strategy: dto.AllocationStrategyDHondt, Tasks: votes},
+			want:  []int{4, 3, 1, 0},
+		},
+		{
+			name:  "sainte-lague is closer to proportional for small ratios",
+			input: dto.AllocationRequestData{TotalMinutes: 8, Strategy: dto.AllocationStrategySainteLague, Tasks: votes},
+			want:  []int{3, 3, 1, 1},
+		},
+		{
+			name: "granularity rounds to blocks",
+			input: dto.AllocationRequestData{TotalMinutes: 60, Strategy: dto.AllocationStrategyGranularity, GranularityMinutes: 15, Tasks: []dto.AllocationTaskData{
+				{TaskID: "a", Ratio: 2},
+				{TaskID: "b", Ratio: 1},
+			}},
+			want: []int{45, 15},
+		},
+		{
+			name: "granularity spreads the indivisible rest by minute",
+			input: dto.AllocationRequestData{TotalMinutes: 70, Strategy: dto.AllocationStrategyGranularity, GranularityMinutes: 15, Tasks: []dto.AllocationTaskData{
+				{TaskID: "a", Ratio: 2},
+				{TaskID: "b", Ratio: 1, MaxMinutes: intPtr(20)},
+			}},
+			want: []int{52, 18},
+		},
+		{
+			name: "priority fills in order up to max",
+			input: dto.AllocationRequestData{TotalMinutes: 100, Strategy: dto.AllocationStrategyPriority, Tasks: []dto.AllocationTaskData{
+				{TaskID: "a", Ratio: 1, MaxMinutes: intPtr(30), Priority: 2},
+				{TaskID: "b", Ratio: 1, MaxMinutes: intPtr(50), Priority: 1},
+				{TaskID: "c", Ratio: 1, MinMinutes: intPtr(5), Priority: 3},
+			}},
+			want: []int{30, 50, 20},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, allocatedMinutes(t, tc.input))
+		})
+	}
+}
+
+func TestDistributeAllocations_LargestRemainderGivesLeftoversToLargestFractions(t *testing.T) {
+	// 以前は端数の小さい順に配っていたため、c の比率を 9 から 10 に上げると分配が 3 分から 2 分に減っていた。
+	tasks := func(ratio float64) []dto.AllocationTaskData {
+		return []dto.AllocationTaskData{
+			{TaskID: "a", Ratio: 9},
+			{TaskID: "b", Ratio: 6},
+			{TaskID: "c", Ratio: ratio},
+			{TaskID: "d", Ratio: 2},
+		}
+	}
+	require.Equal(t, []int{2, 2, 2, 1}, allocatedMinutes(t, dto.AllocationRequestData{TotalMinutes: 7, Tasks: tasks(9)}))
+	require.Equal(t, []int{2, 2, 3, 0}, allocatedMinutes(t, dto.AllocationRequestData{TotalMinutes: 7, Tasks: tasks(10)}))
+}
+
+func TestDistributeAllocations_StrategiesShareConstraintErrors(t *testing.T) {
+	strategies := []dto.AllocationStrategy{
+		dto.AllocationStrategyLargestRemainder,
+		dto.AllocationStrategyDHondt,
+		dto.AllocationStrategySainteLague,
+		dto.AllocationStrategyGranularity,
+		dto.AllocationStrategyPriority,
+	}
+	for _, strategy := range strategies {
+		_, err := distributeAllocations(dto.AllocationRequestData{
+			TotalMinutes:       50,
+			Strategy:           strategy,
+			GranularityMinutes: 15,
+			Tasks: []dto.AllocationTaskData{
+				{TaskID: "a", Ratio: 1, MaxMinutes: intPtr(10)},
+				{TaskID: "b", Ratio: 2, MaxMinutes: intPtr(20)},
+			},
+		})
+		require.EqualError(t, err, "total_minutes exceeds the sum of max_minutes", string(strategy))
+	}
+}
+
+func TestAllocationRequest_NormalizeStrategy(t *testing.T) {
+	_, err := dto.AllocationRequest{TotalMinutes: 60, Strategy: "granularity", Tasks: []dto.AllocationTaskRequest{{TaskID: "a", Ratio: 1}}}.Normalize()
+	var valErr dto.ValidationError
+	require.ErrorAs(t, err, &valErr)
+	require.Equal(t, "granularity_minutes", valErr.Field)
+
+	_, err = dto.AllocationRequest{TotalMinutes: 60, Strategy: "random", Tasks: []dto.AllocationTaskRequest{{TaskID: "a", Ratio: 1}}}.Normalize()
+	require.ErrorAs(t, err, &valErr)
+	require.Equal(t, "strategy", valErr.Field)
+
+	data, err := dto.AllocationRequest{TotalMinutes: 60, Tasks: []dto.AllocationTaskRequest{{TaskID: "a", Ratio: 1}}}.Normalize()
+	require.NoError(t, err)
+	require.Equal(t, dto.AllocationStrategyLargestRemainder, data.Strategy)
+}
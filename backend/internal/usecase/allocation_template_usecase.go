@@ -0,0 +1,289 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+// ErrAllocationTemplateNotFound はユーザー所有の分配テンプレートが見つからないことを表す。
+var ErrAllocationTemplateNotFound = errors.New("allocation template not found")
+
+// AllocationTemplateUsecase は分配テンプレートの管理と実行を扱う。実行結果は AllocationUsecase の履歴として保存する。
+type AllocationTemplateUsecase struct {
+	templates repository.AllocationTemplateRepository
+	allocs    *AllocationUsecase
+	entries   repository.EntryRepository
+	projects  repository.ProjectRepository
+	tags      repository.TagRepository
+	clock     provider.Clock
+}
+
+func NewAllocationTemplateUsecase(templates repository.AllocationTemplateRepository, allocs *AllocationUsecase, entries repository.EntryRepository, projects repository.ProjectRepository, tags repository.TagRepository, clock provider.Clock) *AllocationTemplateUsecase {
+	return &AllocationTemplateUsecase{templates: templates, allocs: allocs, entries: entries, projects: projects, tags: tags, clock: clock}
+}
+
+// AllocationTemplateView はテンプレートと、参照先の削除などで実行できなくなった理由を返す。
+type AllocationTemplateView struct {
+	entity.AllocationTemplate
+	Errors []string `json:"errors"`
+}
+
+func (u *AllocationTemplateUsecase) List(ctx context.Context, userID uuid.UUID) ([]AllocationTemplateView, error) {
+	templates, err := u.templates.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := u.loadReferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]AllocationTemplateView, 0, len(templates))
+	for _, template := range templates {
+		views = append(views, newAllocationTemplateView(template, refs))
+	}
+	return views, nil
+}
+
+func (u *AllocationTemplateUsecase) Get(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*AllocationTemplateView, error) {
+	template, err := u.getTemplate(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := u.loadReferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	view := newAllocationTemplateView(*template, refs)
+	return &view, nil
+}
+
+// getTemplate はテンプレートを取得し、見つからない場合だけ ErrAllocationTemplateNotFound に変換する。
+func (u *AllocationTemplateUsecase) getTemplate(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationTemplate, error) {
+	template, err := u.templates.GetByID(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrAllocationTemplateNotFound
+	}
+	return template, err
+}
+
+func (u *AllocationTemplateUsecase) Create(ctx context.Context, userID uuid.UUID, input dto.AllocationTemplateRequest) (*entity.AllocationTemplate, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	template := &entity.AllocationTemplate{ID: uuid.New(), UserID: userID}
+	if err := u.assign(ctx, template, data); err != nil {
+		return nil, err
+	}
+	if err := u.templates.Create(ctx, template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// Update はテンプレートの名前・戦略・タスクを入力で置き換える。
+func (u *AllocationTemplateUsecase) Update(ctx context.Context, userID uuid.UUID, id uuid.UUID, input dto.AllocationTemplateRequest) (*entity.AllocationTemplate, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	template, err := u.getTemplate(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.assign(ctx, template, data); err != nil {
+		return nil, err
+	}
+	if err := u.templates.Update(ctx, template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (u *AllocationTemplateUsecase) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return errors.New("id is required")
+	}
+	return u.templates.Delete(ctx, userID, id)
+}
+
+// Run はテンプレートで分配を行い、結果を分配履歴として保存する。
+// 合計分数は total_minutes か、date の日 (ユーザーのタイムゾーン) に記録された休憩以外の時間から決める。
+func (u *AllocationTemplateUsecase) Run(ctx context.Context, userID uuid.UUID, id uuid.UUID, input dto.AllocationTemplateRunRequest, loc *time.Location) (AllocationResult, error) {
+	data, err := input.Normalize(loc)
+	if err != nil {
+		return AllocationResult{}, err
+	}
+	template, err := u.getTemplate(ctx, userID, id)
+	if err != nil {
+		return AllocationResult{}, err
+	}
+	refs, err := u.loadReferences(ctx, userID)
+	if err != nil {
+		return AllocationResult{}, err
+	}
+	// 参照先が削除されたテンプレートは、残りのタスクだけで配らずに実行を止める。
+	if problems := refs.check(*template); len(problems) > 0 {
+		return AllocationResult{}, problems[0]
+	}
+	total := data.TotalMinutes
+	if data.Day != nil {
+		total, err = u.trackedMinutes(ctx, userID, *data.Day)
+		if err != nil {
+			return AllocationResult{}, err
+		}
+		if total <= 0 {
+			return AllocationResult{}, dto.ValidationError{Field: "date", Message: "has no tracked time"}
+		}
+	}
+	request := dto.AllocationRequest{
+		TotalMinutes:       total,
+		Strategy:           template.Strategy,
+		GranularityMinutes: template.GranularityMinutes,
+		Tasks:              make([]dto.AllocationTaskRequest, 0, len(template.Tasks)),
+	}
+	for _, task := range template.Tasks {
+		request.Tasks = append(request.Tasks, dto.AllocationTaskRequest{
+			TaskID:     task.TaskID(),
+			Ratio:      task.Ratio,
+			MinMinutes: task.MinMinutes,
+			MaxMinutes: task.MaxMinutes,
+			Priority:   task.Priority,
+		})
+	}
+	return u.allocs.Allocate(ctx, userID, request)
+}
+
+// assign は正規化済みの入力をテンプレートへ反映する。参照先はユーザー所有であることを確認する。
+func (u *AllocationTemplateUsecase) assign(ctx context.Context, template *entity.AllocationTemplate, data dto.AllocationTemplateData) error {
+	tasks := make([]entity.AllocationTemplateTask, 0, len(data.Tasks))
+	for i, task := range data.Tasks {
+		field := fmt.Sprintf("tasks[%d]", i)
+		if task.ProjectID != nil {
+			if _, err := u.projects.GetByID(ctx, template.UserID, *task.ProjectID); err != nil {
+				return dto.ValidationError{Field: field + ".project_id", Message: "refers to unknown project"}
+			}
+		}
+		if task.TagID != nil {
+			if _, err := u.tags.GetByID(ctx, template.UserID, *task.TagID); err != nil {
+				return dto.ValidationError{Field: field + ".tag_id", Message: "refers to unknown tag"}
+			}
+		}
+		tasks = append(tasks, entity.AllocationTemplateTask{
+			TemplateID: template.ID,
+			Position:   i,
+			ProjectID:  task.ProjectID,
+			TagID:      task.TagID,
+			Ratio:      task.Ratio,
+			MinMinutes: task.MinMinutes,
+			MaxMinutes: task.MaxMinutes,
+			Priority:   task.Priority,
+		})
+	}
+	template.Name = data.Name
+	template.Strategy = string(data.Strategy)
+	template.GranularityMinutes = data.GranularityMinutes
+	template.Tasks = tasks
+	return template.Validate()
+}
+
+// trackedMinutes は day から 24 時間の範囲に記録された休憩以外の時間を分単位 (切り捨て) で返す。
+// 日をまたぐエントリは範囲内の部分だけを数え、実行中のエントリは現在時刻までを数える。
+func (u *AllocationTemplateUsecase) trackedMinutes(ctx context.Context, userID uuid.UUID, day time.Time) (int, error) {
+	dayEnd := day.AddDate(0, 0, 1)
+	from := day.Add(-allocationApplyLookback).UTC()
+	to := dayEnd.UTC()
+	entries, err := u.entries.ListByUser(ctx, userID, repository.EntryFilter{From: &from, To: &to})
+	if err != nil {
+		return 0, err
+	}
+	now := u.clock.Now()
+	var tracked time.Duration
+	for _, entry := range entries {
+		if entry.IsBreak {
+			continue
+		}
+		end := now
+		if entry.EndedAt != nil {
+			end = *entry.EndedAt
+		}
+		start := entry.StartedAt
+		if start.Before(day) {
+			start = day
+		}
+		if end.After(dayEnd) {
+			end = dayEnd
+		}
+		if end.After(start) {
+			tracked += end.Sub(start)
+		}
+	}
+	return int(tracked / time.Minute), nil
+}
+
+// allocationTemplateRefs はユーザーが現在持っているプロジェクトとタグの ID 集合。
+type allocationTemplateRefs struct {
+	projects map[uuid.UUID]struct{}
+	tags     map[uuid.UUID]struct{}
+}
+
+func (u *AllocationTemplateUsecase) loadReferences(ctx context.Context, userID uuid.UUID) (allocationTemplateRefs, error) {
+	projects, err := u.projects.ListByUser(ctx, userID)
+	if err != nil {
+		return allocationTemplateRefs{}, err
+	}
+	tags, err := u.tags.ListByUser(ctx, userID)
+	if err != nil {
+		return allocationTemplateRefs{}, err
+	}
+	refs := allocationTemplateRefs{
+		projects: make(map[uuid.UUID]struct{}, len(projects)),
+		tags:     make(map[uuid.UUID]struct{}, len(tags)),
+	}
+	for _, project := range projects {
+		refs.projects[project.ID] = struct{}{}
+	}
+	for _, tag := range tags {
+		refs.tags[tag.ID] = struct{}{}
+	}
+	return refs, nil
+}
+
+// check は削除済みのプロジェクト・タグを参照しているタスクを ValidationError として返す。
+func (r allocationTemplateRefs) check(template entity.AllocationTemplate) []dto.ValidationError {
+	var problems []dto.ValidationError
+	for i, task := range template.Tasks {
+		field := fmt.Sprintf("tasks[%d]", i)
+		if task.ProjectID != nil {
+			if _, ok := r.projects[*task.ProjectID]; !ok {
+				problems = append(problems, dto.ValidationError{Field: field + ".project_id", Message: "refers to deleted project"})
+			}
+		}
+		if task.TagID != nil {
+			if _, ok := r.tags[*task.TagID]; !ok {
+				problems = append(problems, dto.ValidationError{Field: field + ".tag_id", Message: "refers to deleted tag"})
+			}
+		}
+	}
+	return problems
+}
+
+func newAllocationTemplateView(template entity.AllocationTemplate, refs allocationTemplateRefs) AllocationTemplateView {
+	view := AllocationTemplateView{AllocationTemplate: template, Errors: []string{}}
+	if view.Tasks == nil {
+		view.Tasks = []entity.AllocationTemplateTask{}
+	}
+	for _, problem := range refs.check(template) {
+		view.Errors = append(view.Errors, problem.Error())
+	}
+	return view
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+func TestAllocationTemplateUsecase_RunWithDayTrackedTime(t *testing.T) {
+	userID := uuid.New()
+	templateID := uuid.New()
+	projectA, projectB := uuid.New(), uuid.New()
+	tokyo := time.FixedZone("JST", 9*60*60)
+	templates := &fakes.FakeAllocationTemplateRepository{
+		GetByIDFn: func(_ context.Context, _ uuid.UUID, id uuid.UUID) (*entity.AllocationTemplate, error) {
+			return &entity.AllocationTemplate{ID: id, UserID: userID, Name: "Daily", Strategy: "largest_remainder", Tasks: []entity.AllocationTemplateTask{
+				{ProjectID: &projectA, Ratio: 2},
+				{ProjectID: &projectB, Ratio: 1},
+			}}, nil
+		},
+	}
+	// JST の 5/1 は UTC の 4/30 15:00 から。日をまたぐエントリは範囲内の 60 分だけ、休憩は数えない。
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, tokyo)
+	crossStart := day.Add(-30 * time.Minute)
+	crossEnd := day.Add(60 * time.Minute)
+	workStart := day.Add(10 * time.Hour)
+	workEnd := workStart.Add(120 * time.Minute)
+	breakEnd := workEnd.Add(30 * time.Minute)
+	entries := &fakes.FakeEntryRepository{
+		ListFn: func(_ context.Context, _ uuid.UUID, filter repository.EntryFilter) ([]entity.Entry, error) {
+			require.True(t, filter.To.Equal(day.AddDate(0, 0, 1)))
+			return []entity.Entry{
+				{StartedAt: crossStart.UTC(), EndedAt: &crossEnd},
+				{StartedAt: workStart.UTC(), EndedAt: &workEnd},
+				{StartedAt: workEnd.UTC(), EndedAt: &breakEnd, IsBreak: true},
+			}, nil
+		},
+	}
+	projects := &fakes.FakeProjectRepository{
+		ListFn: func(context.Context, uuid.UUID) ([]entity.Project, error) {
+			return []entity.Project{{ID: projectA}, {ID: projectB}}, nil
+		},
+	}
+	var saved []entity.TaskAllocation
+	allocations := &fakes.FakeAllocationRepository{
+		CreateFn: func(_ context.Context, _ *entity.AllocationRequest, items []entity.TaskAllocation) error {
+			saved = items
+			return nil
+		},
+	}
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return day.AddDate(0, 0, 2) }}
+	allocs := NewAllocationUsecase(allocations, entries, projects, &fakes.FakeTagRepository{}, clock)
+	uc := NewAllocationTemplateUsecase(templates, allocs, entries, projects, &fakes.FakeTagRepository{}, clock)
+
+	result, err := uc.Run(context.Background(), userID, templateID, dto.AllocationTemplateRunRequest{Date: "2024-05-01"}, tokyo)
+	require.NoError(t, err)
+	require.Equal(t, 180, result.TotalMinutes)
+	require.Len(t, saved, 2)
+	require.Equal(t, "project:"+projectA.String(), result.Allocations[0].TaskID)
+	require.Equal(t, 120, result.Allocations[0].AllocatedMinutes)
+	require.Equal(t, 60, result.Allocations[1].AllocatedMinutes)
+}
+
+func TestAllocationTemplateUsecase_DeletedProjectIsValidationError(t *testing.T) {
+	userID := uuid.New()
+	kept, deleted := uuid.New(), uuid.New()
+	templates := &fakes.FakeAllocationTemplateRepository{
+		GetByIDFn: func(_ context.Context, _ uuid.UUID, id uuid.UUID) (*entity.AllocationTemplate, error) {
+			return &entity.AllocationTemplate{ID: id, UserID: userID, Name: "Daily", Tasks: []entity.AllocationTemplateTask{
+				{ProjectID: &kept, Ratio: 1},
+				{ProjectID: &deleted, Ratio: 1},
+			}}, nil
+		},
+	}
+	projects := &fakes.FakeProjectRepository{
+		ListFn: func(context.Context, uuid.UUID) ([]entity.Project, error) {
+			return []entity.Project{{ID: kept}}, nil
+		},
+	}
+	created := false
+	allocations := &fakes.FakeAllocationRepository{
+		CreateFn: func(context.Context, *entity.AllocationRequest, []entity.TaskAllocation) error {
+			created = true
+			return nil
+		},
+	}
+	allocs := NewAllocationUsecase(allocations, &fakes.FakeEntryRepository{}, projects, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+	uc := NewAllocationTemplateUsecase(templates, allocs, &fakes.FakeEntryRepository{}, projects, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+
+	view, err := uc.Get(context.Background(), userID, uuid.New())
+	require.NoError(t, err)
+	require.Equal(t, []string{"tasks[1].project_id: refers to deleted project"}, view.Errors)
+
+	_, err = uc.Run(context.Background(), userID, uuid.New(), dto.AllocationTemplateRunRequest{TotalMinutes: 60}, time.UTC)
+	var valErr dto.ValidationError
+	require.ErrorAs(t, err, &valErr)
+	require.Equal(t, "tasks[1].project_id", valErr.Field)
+	require.False(t, created)
+}
+
+func TestAllocationTemplateUsecase_OnlyMissingTemplateIsNotFound(t *testing.T) {
+	failure := errors.New("connection reset")
+	templates := &fakes.FakeAllocationTemplateRepository{
+		GetByIDFn: func(_ context.Context, _ uuid.UUID, id uuid.UUID) (*entity.AllocationTemplate, error) {
+			return nil, failure
+		},
+	}
+	projectID := uuid.NewString()
+	uc := NewAllocationTemplateUsecase(templates, nil, &fakes.FakeEntryRepository{}, &fakes.FakeProjectRepository{}, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+
+	_, err := uc.Get(context.Background(), uuid.New(), uuid.New())
+	require.ErrorIs(t, err, failure)
+	require.NotErrorIs(t, err, ErrAllocationTemplateNotFound)
+	_, err = uc.Update(context.Background(), uuid.New(), uuid.New(), dto.AllocationTemplateRequest{Name: "Daily", Tasks: []dto.AllocationTemplateTaskRequest{{ProjectID: &projectID, Ratio: 1}}})
+	require.ErrorIs(t, err, failure)
+	_, err = uc.Run(context.Background(), uuid.New(), uuid.New(), dto.AllocationTemplateRunRequest{TotalMinutes: 60}, time.UTC)
+	require.ErrorIs(t, err, failure)
+
+	templates.GetByIDFn = nil
+	_, err = uc.Get(context.Background(), uuid.New(), uuid.New())
+	require.ErrorIs(t, err, ErrAllocationTemplateNotFound)
+}
@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+)
+
+// TrackedAllocationResult は記録済みの時間から求めた分配結果と、作成したエントリを返す。
+type TrackedAllocationResult struct {
+	AllocationResult
+	Source  string         `json:"source"`
+	Entries []entity.Entry `json:"entries,omitempty"`
+}
+
+// trackedTotal は記録済みエントリから求めた合計分数と、エントリを作成する場合の配置先の区間。
+// originals は unassigned で slots と同じ順に並んだ元エントリで、spanEnds は区間の切り捨て前の終了時刻。
+type trackedTotal struct {
+	minutes   int
+	slots     []dto.TimeRange
+	spanEnds  map[int64]time.Time
+	originals []entity.Entry
+}
+
+// AllocateFromTracked は指定日の記録から合計分数を求めて分配し、履歴として保存する。
+// create_entries の場合、untracked は空き時間へエントリを作成し、unassigned は未割り当てのエントリを分配結果で付け替える。
+// 履歴とエントリの変更はひとつのトランザクションで保存する。
+func (u *AllocationUsecase) AllocateFromTracked(ctx context.Context, userID uuid.UUID, input dto.AllocationFromTrackedRequest, loc *time.Location) (TrackedAllocationResult, error) {
+	data, err := input.Normalize(loc)
+	if err != nil {
+		return TrackedAllocationResult{}, err
+	}
+	var total trackedTotal
+	switch data.Source {
+	case dto.TrackedSourceUntracked:
+		total, err = u.untrackedTotal(ctx, userID, data.Window)
+	case dto.TrackedSourceUnassigned:
+		total, err = u.unassignedTotal(ctx, userID, data.Day)
+	default:
+		total, err = u.taggedWeekTotal(ctx, userID, data.Day, *data.TagID)
+	}
+	if err != nil {
+		return TrackedAllocationResult{}, err
+	}
+	if total.minutes <= 0 {
+		return TrackedAllocationResult{}, dto.ValidationError{Field: "source", Message: "has no time to allocate"}
+	}
+
+	// 履歴を保存する前に対応付けを検証し、エントリを作れない分配結果を残さない。
+	var templates map[string]entity.Entry
+	if data.Mappings != nil {
+		templates, err = u.entryTemplates(ctx, userID, data.Mappings)
+		if err != nil {
+			return TrackedAllocationResult{}, err
+		}
+	}
+	request, allocations, result, err := u.computeAllocation(userID, dto.AllocationRequest{
+		TotalMinutes:       total.minutes,
+		Strategy:           input.Strategy,
+		GranularityMinutes: input.GranularityMinutes,
+		Tasks:              input.Tasks,
+	})
+	if err != nil {
+		return TrackedAllocationResult{}, err
+	}
+	tracked := TrackedAllocationResult{AllocationResult: result, Source: string(data.Source)}
+	if templates == nil {
+		if err := u.repo.Create(ctx, request, allocations); err != nil {
+			return TrackedAllocationResult{}, err
+		}
+		return tracked, nil
+	}
+
+	placements := make([]allocationPlacement, 0, len(result.Allocations))
+	for _, allocation := range result.Allocations {
+		placements = append(placements, allocationPlacement{TaskID: allocation.TaskID, Minutes: allocation.AllocatedMinutes})
+	}
+	now := u.clock.Now()
+	laid := layoutAllocations(placements, templates, total.slots, now)
+	// 置き換える元エントリの 1 分未満の端数は、その区間の最後のエントリに含めて合計を保つ。
+	for i := range laid {
+		if end, ok := total.spanEnds[laid[i].EndedAt.UnixNano()]; ok {
+			laid[i].EndedAt = &end
+			laid[i].UpdateDuration(now)
+		}
+	}
+	changes := repository.EntryChanges{Create: laid}
+	if total.originals != nil {
+		changes = adoptOriginals(laid, total)
+	}
+	if err := u.repo.CreateWithEntries(ctx, request, allocations, changes); err != nil {
+		return TrackedAllocationResult{}, err
+	}
+	tracked.Entries = laid
+	return tracked, nil
+}
+
+// adoptOriginals は unassigned で配置したエントリに元エントリのタイトル・メモ・比率を引き継ぎ、タグは対応付けのタグと合わせる。
+// 各区間の最初のエントリは元エントリ自体の更新にし、区間が複数のタスクに分かれた分だけ新しいエントリを作る。
+func adoptOriginals(laid []entity.Entry, total trackedTotal) repository.EntryChanges {
+	var changes repository.EntryChanges
+	adopted := make([]bool, len(total.originals))
+	span := 0
+	for i := range laid {
+		for !laid[i].StartedAt.Before(total.slots[span].End) {
+			span++
+		}
+		original := total.originals[span]
+		entry := &laid[i]
+		if original.Title != "" {
+			entry.Title = original.Title
+		}
+		entry.Notes = original.Notes
+		entry.Ratio = original.Ratio
+		entry.Tags = mergeTags(original.Tags, entry.Tags)
+		if adopted[span] {
+			changes.Create = append(changes.Create, *entry)
+			continue
+		}
+		adopted[span] = true
+		updated := original
+		updated.ProjectID = entry.ProjectID
+		updated.Title = entry.Title
+		updated.StartedAt = entry.StartedAt
+		updated.EndedAt = entry.EndedAt
+		updated.DurationSec = entry.DurationSec
+		updated.Tags = entry.Tags
+		*entry = updated
+		changes.Update = append(changes.Update, updated)
+	}
+	return changes
+}
+
+// mergeTags は base の後ろに extra のうち base にないタグを並べる。
+func mergeTags(base, extra []entity.Tag) []entity.Tag {
+	merged := append([]entity.Tag{}, base...)
+	for _, tag := range extra {
+		seen := false
+		for _, existing := range base {
+			if existing.ID == tag.ID {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+// untrackedTotal は時間帯のうち既存エントリ (休憩・実行中を含む) がない空き時間を合計する。
+func (u *AllocationUsecase) untrackedTotal(ctx context.Context, userID uuid.UUID, window dto.TimeRange) (trackedTotal, error) {
+	busy, err := u.busyRanges(ctx, userID, window.Start, window.End, nil)
+	if err != nil {
+		return trackedTotal{}, err
+	}
+	free := freeRanges(window.Start, window.End, busy)
+	var capacity time.Duration
+	for _, slot := range free {
+		capacity += slot.End.Sub(slot.Start)
+	}
+	return trackedTotal{minutes: int(capacity / time.Minute), slots: free}, nil
+}
+
+// unassignedTotal は day に始まった終了済みのプロジェクト未設定エントリ (休憩を除く) を合計する。
+// エントリごとに 1 分未満を切り捨て、作成時はその区間へ配置する。
+func (u *AllocationUsecase) unassignedTotal(ctx context.Context, userID uuid.UUID, day time.Time) (trackedTotal, error) {
+	from := day.UTC()
+	to := day.AddDate(0, 0, 1).UTC()
+	entries, err := u.entries.ListByUser(ctx, userID, repository.EntryFilter{From: &from, To: &to})
+	if err != nil {
+		return trackedTotal{}, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].StartedAt.Before(entries[j].StartedAt)
+	})
+	total := trackedTotal{spanEnds: make(map[int64]time.Time)}
+	for _, entry := range entries {
+		if entry.IsBreak || entry.ProjectID != nil || entry.EndedAt == nil {
+			continue
+		}
+		minutes := int(entry.EndedAt.Sub(entry.StartedAt) / time.Minute)
+		if minutes <= 0 {
+			continue
+		}
+		end := entry.StartedAt.Add(time.Duration(minutes) * time.Minute)
+		total.minutes += minutes
+		total.slots = append(total.slots, dto.TimeRange{Start: entry.StartedAt, End: end})
+		total.spanEnds[end.UnixNano()] = *entry.EndedAt
+		total.originals = append(total.originals, entry)
+	}
+	return total, nil
+}
+
+// taggedWeekTotal は day を含む週 (月曜始まり) に始まった tagID 付きのエントリ (休憩を除く) を合計する。
+// 実行中のエントリは現在時刻までを数える。
+func (u *AllocationUsecase) taggedWeekTotal(ctx context.Context, userID uuid.UUID, day time.Time, tagID uuid.UUID) (trackedTotal, error) {
+	weekStart := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	from := weekStart.UTC()
+	to := weekStart.AddDate(0, 0, 7).UTC()
+	entries, err := u.entries.ListByUser(ctx, userID, repository.EntryFilter{From: &from, To: &to, TagID: &tagID})
+	if err != nil {
+		return trackedTotal{}, err
+	}
+	now := u.clock.Now()
+	var tracked time.Duration
+	for _, entry := range entries {
+		if entry.IsBreak {
+			continue
+		}
+		end := now
+		if entry.EndedAt != nil {
+			end = *entry.EndedAt
+		}
+		if end.After(entry.StartedAt) {
+			tracked += end.Sub(entry.StartedAt)
+		}
+	}
+	return trackedTotal{minutes: int(tracked / time.Minute)}, nil
+}
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+// trackedFixture は AllocateFromTracked が履歴と一緒に保存したエントリの変更を記録する。
+type trackedFixture struct {
+	uc      *AllocationUsecase
+	changes repository.EntryChanges
+	saved   int
+}
+
+func newTrackedFixture(t *testing.T, existing []entity.Entry, now time.Time) *trackedFixture {
+	t.Helper()
+	fixture := &trackedFixture{}
+	entries := &fakes.FakeEntryRepository{
+		ListFn: func(_ context.Context, _ uuid.UUID, filter repository.EntryFilter) ([]entity.Entry, error) {
+			if filter.RunningOnly {
+				return nil, nil
+			}
+			var matched []entity.Entry
+			for _, entry := range existing {
+				if !entry.StartedAt.Before(*filter.From) && entry.StartedAt.Before(*filter.To) {
+					matched = append(matched, entry)
+				}
+			}
+			return matched, nil
+		},
+		ApplyChangesFn: func(context.Context, uuid.UUID, repository.EntryChanges) error {
+			t.Fatal("entries must be saved together with the allocation history")
+			return nil
+		},
+	}
+	allocations := &fakes.FakeAllocationRepository{
+		CreateFn: func(context.Context, *entity.AllocationRequest, []entity.TaskAllocation) error {
+			fixture.saved++
+			return nil
+		},
+		CreateWithEntriesFn: func(_ context.Context, _ *entity.AllocationRequest, _ []entity.TaskAllocation, changes repository.EntryChanges) error {
+			fixture.saved++
+			fixture.changes = changes
+			return nil
+		},
+	}
+	projects := &fakes.FakeProjectRepository{
+		GetByIDFn: func(_ context.Context, _ uuid.UUID, id uuid.UUID) (*entity.Project, error) {
+			return &entity.Project{ID: id}, nil
+		},
+	}
+	tags := &fakes.FakeTagRepository{
+		GetByIDFn: func(_ context.Context, _ uuid.UUID, id uuid.UUID) (*entity.Tag, error) {
+			return &entity.Tag{ID: id}, nil
+		},
+	}
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }}
+	fixture.uc = NewAllocationUsecase(allocations, entries, projects, tags, clock)
+	return fixture
+}
+
+func TestAllocationUsecase_AllocateFromTrackedFillsUntrackedTime(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, tokyo)
+	meetingEnd := day.Add(11 * time.Hour)
+	existing := []entity.Entry{
+		{ID: uuid.New(), StartedAt: day.Add(10 * time.Hour).UTC(), EndedAt: &meetingEnd},
+	}
+	fixture := newTrackedFixture(t, existing, day.AddDate(0, 0, 1))
+	projectA := uuid.NewString()
+	projectB := uuid.NewString()
+
+	// 09:00-13:00 のうち 10:00-11:00 は記録済みなので 180 分を 2:1 で配る。
+	result, err := fixture.uc.AllocateFromTracked(context.Background(), uuid.New(), dto.AllocationFromTrackedRequest{
+		Source:    "untracked",
+		Date:      "2024-05-01",
+		StartTime: "09:00",
+		EndTime:   "13:00",
+		Tasks: []dto.AllocationTaskRequest{
+			{TaskID: "dev", Ratio: 2},
+			{TaskID: "ops", Ratio: 1},
+		},
+		CreateEntries: true,
+		Mappings: []dto.AllocationMappingRequest{
+			{TaskID: "dev", ProjectID: &projectA},
+			{TaskID: "ops", ProjectID: &projectB},
+		},
+	}, tokyo)
+	require.NoError(t, err)
+	require.Equal(t, 180, result.TotalMinutes)
+	require.Equal(t, "untracked", result.Source)
+	require.Equal(t, 1, fixture.saved)
+	created := fixture.changes.Create
+	require.Empty(t, fixture.changes.Update)
+	require.Empty(t, fixture.changes.Delete)
+	require.Len(t, created, 3)
+	require.True(t, created[0].StartedAt.Equal(day.Add(9*time.Hour)))
+	require.True(t, created[1].StartedAt.Equal(day.Add(11*time.Hour)))
+	require.True(t, created[2].EndedAt.Equal(day.Add(13*time.Hour)))
+	require.Equal(t, "ops", created[2].Title)
+}
+
+func TestAllocationUsecase_AllocateFromTrackedReplacesUnassignedEntries(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	projectID := uuid.New()
+	originalTag, mappedTag := uuid.New(), uuid.New()
+	firstEnd := day.Add(9*time.Hour + 60*time.Minute + 30*time.Second)
+	secondEnd := day.Add(14*time.Hour + 30*time.Minute)
+	assignedEnd := day.Add(16 * time.Hour)
+	existing := []entity.Entry{
+		{ID: uuid.New(), Title: "Inbox", Notes: "mail", Ratio: 0.5, StartedAt: day.Add(14 * time.Hour), EndedAt: &secondEnd},
+		{ID: uuid.New(), Title: "Support", Notes: "ticket #12", Ratio: 1, StartedAt: day.Add(9 * time.Hour), EndedAt: &firstEnd, Tags: []entity.Tag{{ID: originalTag}}},
+		{ID: uuid.New(), StartedAt: day.Add(15 * time.Hour), EndedAt: &assignedEnd, ProjectID: &projectID},
+	}
+	fixture := newTrackedFixture(t, existing, day.AddDate(0, 0, 1))
+	project := uuid.NewString()
+	tag := mappedTag.String()
+
+	result, err := fixture.uc.AllocateFromTracked(context.Background(), uuid.New(), dto.AllocationFromTrackedRequest{
+		Source: "unassigned",
+		Date:   "2024-05-01",
+		Tasks: []dto.AllocationTaskRequest{
+			{TaskID: "a", Ratio: 1},
+			{TaskID: "b", Ratio: 1},
+		},
+		CreateEntries: true,
+		Mappings: []dto.AllocationMappingRequest{
+			{TaskID: "a", ProjectID: &project, TagIDs: []string{tag}},
+			{TaskID: "b", ProjectID: &project},
+		},
+	}, time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, 90, result.TotalMinutes)
+	require.Equal(t, 1, fixture.saved)
+	// 元エントリは削除せず、各区間の最初のエントリとして付け替える。
+	require.Empty(t, fixture.changes.Delete)
+	updated := fixture.changes.Update
+	require.Len(t, updated, 2)
+	require.Equal(t, existing[1].ID, updated[0].ID)
+	require.Equal(t, existing[0].ID, updated[1].ID)
+	require.Equal(t, project, updated[0].ProjectID.String())
+
+	// a は 09:00-09:45、b は 09:45-10:00:30 と 14:00-14:30 に分かれ、端数秒も元の区間に残る。
+	require.Len(t, result.Entries, 3)
+	require.True(t, result.Entries[0].EndedAt.Equal(day.Add(9*time.Hour+45*time.Minute)))
+	require.True(t, result.Entries[1].EndedAt.Equal(firstEnd))
+	require.True(t, result.Entries[2].EndedAt.Equal(secondEnd))
+	var seconds int64
+	for _, entry := range result.Entries {
+		seconds += entry.DurationSec
+	}
+	require.Equal(t, int64(90*60+30), seconds)
+
+	// 区間を分けた b の前半だけが新しいエントリになり、タイトル・メモ・比率とタグを元エントリから引き継ぐ。
+	require.Len(t, fixture.changes.Create, 1)
+	split := fixture.changes.Create[0]
+	require.NotEqual(t, existing[1].ID, split.ID)
+	require.Equal(t, "Support", split.Title)
+	require.Equal(t, "ticket #12", split.Notes)
+	require.Equal(t, []entity.Tag{{ID: originalTag}}, split.Tags)
+	require.Equal(t, "Support", updated[0].Title)
+	require.Equal(t, []entity.Tag{{ID: originalTag}, {ID: mappedTag}}, updated[0].Tags)
+	require.Equal(t, "Inbox", updated[1].Title)
+	require.Equal(t, "mail", updated[1].Notes)
+	require.Equal(t, 0.5, updated[1].Ratio)
+}
+
+func TestAllocationUsecase_AllocateFromTrackedTagWeek(t *testing.T) {
+	// 2024-05-01 は水曜日。週は 4/29 (月) から 5/6 (月) まで。
+	monday := time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC)
+	tagID := uuid.New()
+	end1 := monday.Add(10 * time.Hour)
+	end2 := monday.AddDate(0, 0, 4).Add(11 * time.Hour)
+	breakEnd := monday.AddDate(0, 0, 4).Add(12 * time.Hour)
+	existing := []entity.Entry{
+		{ID: uuid.New(), StartedAt: monday.Add(9 * time.Hour), EndedAt: &end1},
+		{ID: uuid.New(), StartedAt: monday.AddDate(0, 0, 4).Add(9 * time.Hour), EndedAt: &end2},
+		{ID: uuid.New(), StartedAt: monday.AddDate(0, 0, 4).Add(11 * time.Hour), EndedAt: &breakEnd, IsBreak: true},
+		{ID: uuid.New(), StartedAt: monday.Add(-time.Hour), EndedAt: &end1},
+	}
+	fixture := newTrackedFixture(t, existing, monday.AddDate(0, 0, 10))
+	uc := fixture.uc
+	tag := tagID.String()
+
+	result, err := uc.AllocateFromTracked(context.Background(), uuid.New(), dto.AllocationFromTrackedRequest{
+		Source: "tag",
+		Date:   "2024-05-01",
+		TagID:  &tag,
+		Tasks:  []dto.AllocationTaskRequest{{TaskID: "x", Ratio: 1}},
+	}, time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, 180, result.TotalMinutes)
+	require.Empty(t, result.Entries)
+
+	_, err = uc.AllocateFromTracked(context.Background(), uuid.New(), dto.AllocationFromTrackedRequest{
+		Source:        "tag",
+		Date:          "2024-05-01",
+		TagID:         &tag,
+		Tasks:         []dto.AllocationTaskRequest{{TaskID: "x", Ratio: 1}},
+		CreateEntries: true,
+	}, time.UTC)
+	var valErr dto.ValidationError
+	require.ErrorAs(t, err, &valErr)
+	require.Equal(t, "create_entries", valErr.Field)
+}
@@ -3,8 +3,7 @@ package usecase
 import (
 	"context"
 	"errors"
-	"math"
-	"sort"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -16,6 +15,11 @@ import (
 
 const allocationEpsilon = 1e-9
 
+const (
+	defaultAllocationPageSize = 20
+	maxAllocationPageSize     = 100
+)
+
 // AllocationConstraintError は制約違反の分配失敗を表す。
 type AllocationConstraintError struct {
 	Message string
@@ -26,20 +30,29 @@ func (e AllocationConstraintError) Error() string {
 }
 
 // AllocationUsecase は分配ロジックと永続化を担当する。
+// entries / projects / tags は分配結果をエントリへ展開するときに使う。
 type AllocationUsecase struct {
-	repo  repository.AllocationRepository
-	clock provider.Clock
+	repo     repository.AllocationRepository
+	entries  repository.EntryRepository
+	projects repository.ProjectRepository
+	tags     repository.TagRepository
+	clock    provider.Clock
 }
 
-func NewAllocationUsecase(repo repository.AllocationRepository, clock provider.Clock) *AllocationUsecase {
-	return &AllocationUsecase{repo: repo, clock: clock}
+func NewAllocationUsecase(repo repository.AllocationRepository, entries repository.EntryRepository, projects repository.ProjectRepository, tags repository.TagRepository, clock provider.Clock) *AllocationUsecase {
+	return &AllocationUsecase{repo: repo, entries: entries, projects: projects, tags: tags, clock: clock}
 }
 
 // AllocationResult は API に返す結果。
 type AllocationResult struct {
-	RequestID    uuid.UUID        `json:"request_id"`
-	TotalMinutes int              `json:"total_minutes"`
-	Allocations  []AllocationItem `json:"allocations"`
+	RequestID          uuid.UUID        `json:"request_id"`
+	TotalMinutes       int              `json:"total_minutes"`
+	Strategy           string           `json:"strategy"`
+	GranularityMinutes int              `json:"granularity_minutes,omitempty"`
+	BatchID            *uuid.UUID       `json:"batch_id,omitempty"`
+	Label              string           `json:"label,omitempty"`
+	CreatedAt          time.Time        `json:"created_at"`
+	Allocations        []AllocationItem `json:"allocations"`
 }
 
 // AllocationItem は分配結果の1行。
@@ -47,6 +60,17 @@ type AllocationItem struct {
 	TaskID           string  `json:"task_id"`
 	Ratio            float64 `json:"ratio"`
 	AllocatedMinutes int     `json:"allocated_minutes"`
+	MinMinutes       *int    `json:"min_minutes,omitempty"`
+	MaxMinutes       *int    `json:"max_minutes,omitempty"`
+	Priority         int     `json:"priority,omitempty"`
+}
+
+// AllocationPage は分配履歴一覧の 1 ページ分を表す。
+type AllocationPage struct {
+	Allocations []entity.AllocationRequest `json:"allocations"`
+	Page        int                        `json:"page"`
+	PerPage     int                        `json:"per_page"`
+	Total       int64                      `json:"total"`
 }
 
 type allocationDistribution struct {
@@ -55,27 +79,43 @@ type allocationDistribution struct {
 	AllocatedMinutes int
 	MinMinutes       *int
 	MaxMinutes       *int
+	Priority         int
 }
 
-// Allocate は分配計算と保存を行う。
-func (u *AllocationUsecase) Allocate(ctx context.Context, input dto.AllocationRequest) (AllocationResult, error) {
+// Allocate は分配計算を行い、userID の履歴として保存する。
+func (u *AllocationUsecase) Allocate(ctx context.Context, userID uuid.UUID, input dto.AllocationRequest) (AllocationResult, error) {
+	request, allocations, result, err := u.computeAllocation(userID, input)
+	if err != nil {
+		return AllocationResult{}, err
+	}
+	if err := u.repo.Create(ctx, request, allocations); err != nil {
+		return AllocationResult{}, err
+	}
+	return result, nil
+}
+
+// computeAllocation は保存せずに分配を計算し、履歴の行と API response を同じ結果から作る。
+func (u *AllocationUsecase) computeAllocation(userID uuid.UUID, input dto.AllocationRequest) (*entity.AllocationRequest, []entity.TaskAllocation, AllocationResult, error) {
 	// 入力の正規化と制約付き分配を分け、保存前に計算結果を確定させる。
 	data, err := input.Normalize()
 	if err != nil {
-		return AllocationResult{}, err
+		return nil, nil, AllocationResult{}, err
 	}
 
 	allocations, err := distributeAllocations(data)
 	if err != nil {
-		return AllocationResult{}, AllocationConstraintError{Message: err.Error()}
+		return nil, nil, AllocationResult{}, AllocationConstraintError{Message: err.Error()}
 	}
 
 	requestID := uuid.New()
 	now := u.clock.Now()
 	request := &entity.AllocationRequest{
-		ID:           requestID,
-		TotalMinutes: data.TotalMinutes,
-		CreatedAt:    now,
+		ID:                 requestID,
+		UserID:             userID,
+		TotalMinutes:       data.TotalMinutes,
+		Strategy:           string(data.Strategy),
+		GranularityMinutes: data.GranularityMinutes,
+		CreatedAt:          now,
 	}
 	allocationEntities := make([]entity.TaskAllocation, 0, len(allocations))
 	responseItems := make([]AllocationItem, 0, len(allocations))
@@ -88,6 +128,7 @@ func (u *AllocationUsecase) Allocate(ctx context.Context, input dto.AllocationRe
 			AllocatedMinutes: allocation.AllocatedMinutes,
 			MinMinutes:       allocation.MinMinutes,
 			MaxMinutes:       allocation.MaxMinutes,
+			Priority:         allocation.Priority,
 			CreatedAt:        now,
 			UpdatedAt:        now,
 		})
@@ -95,18 +136,85 @@ func (u *AllocationUsecase) Allocate(ctx context.Context, input dto.AllocationRe
 			TaskID:           allocation.TaskID,
 			Ratio:            allocation.Ratio,
 			AllocatedMinutes: allocation.AllocatedMinutes,
+			MinMinutes:       allocation.MinMinutes,
+			MaxMinutes:       allocation.MaxMinutes,
+			Priority:         allocation.Priority,
 		})
 	}
 
-	if err := u.repo.Create(ctx, request, allocationEntities); err != nil {
+	return request, allocationEntities, AllocationResult{
+		RequestID:          requestID,
+		TotalMinutes:       data.TotalMinutes,
+		Strategy:           request.Strategy,
+		GranularityMinutes: request.GranularityMinutes,
+		CreatedAt:          now,
+		Allocations:        responseItems,
+	}, nil
+}
+
+// List はユーザーの分配履歴を新しい順にページ単位で返す。page は 1 始まり。
+func (u *AllocationUsecase) List(ctx context.Context, userID uuid.UUID, page, perPage int) (AllocationPage, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if perPage <= 0 {
+		perPage = defaultAllocationPageSize
+	}
+	if perPage > maxAllocationPageSize {
+		perPage = maxAllocationPageSize
+	}
+	requests, total, err := u.repo.ListByUser(ctx, userID, perPage, (page-1)*perPage)
+	if err != nil {
+		return AllocationPage{}, err
+	}
+	if requests == nil {
+		requests = []entity.AllocationRequest{}
+	}
+	return AllocationPage{Allocations: requests, Page: page, PerPage: perPage, Total: total}, nil
+}
+
+// Get はユーザー所有の分配結果をタスク行付きで返す。見つからない場合は ErrAllocationNotFound を返す。
+func (u *AllocationUsecase) Get(ctx context.Context, userID uuid.UUID, id uuid.UUID) (AllocationResult, error) {
+	request, allocations, err := u.repo.GetByID(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return AllocationResult{}, ErrAllocationNotFound
+	}
+	if err != nil {
 		return AllocationResult{}, err
 	}
+	return newAllocationResult(*request, allocations), nil
+}
 
+// newAllocationResult は保存済みのリクエストと分配行を API の結果へ変換する。
+func newAllocationResult(request entity.AllocationRequest, allocations []entity.TaskAllocation) AllocationResult {
+	items := make([]AllocationItem, 0, len(allocations))
+	for _, allocation := range allocations {
+		items = append(items, AllocationItem{
+			TaskID:           allocation.TaskID,
+			Ratio:            allocation.Ratio,
+			AllocatedMinutes: allocation.AllocatedMinutes,
+			MinMinutes:       allocation.MinMinutes,
+			MaxMinutes:       allocation.MaxMinutes,
+			Priority:         allocation.Priority,
+		})
+	}
 	return AllocationResult{
-		RequestID:    requestID,
-		TotalMinutes: data.TotalMinutes,
-		Allocations:  responseItems,
-	}, nil
+		RequestID:          request.ID,
+		TotalMinutes:       request.TotalMinutes,
+		Strategy:           request.Strategy,
+		GranularityMinutes: request.GranularityMinutes,
+		BatchID:            request.BatchID,
+		Label:              request.Label,
+		CreatedAt:          request.CreatedAt,
+		Allocations:        items,
+	}
+}
+
+func (u *AllocationUsecase) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return errors.New("id is required")
+	}
+	return u.repo.Delete(ctx, userID, id)
 }
 
 type allocationState struct {
@@ -117,6 +225,7 @@ type allocationState struct {
 	Allocation int
 	Remainder  float64
 	Normalized float64
+	Priority   int
 	Index      int
 }
 
@@ -159,6 +268,7 @@ func distributeAllocations(input dto.AllocationRequestData) ([]allocationDistrib
 			Allocation: minMinutes,
 			Remainder:  0,
 			Normalized: task.Ratio / ratioSum,
+			Priority:   task.Priority,
 			Index:      i,
 		})
 	}
@@ -173,94 +283,10 @@ func distributeAllocations(input dto.AllocationRequestData) ([]allocationDistrib
 	if remainingPool == 0 {
 		return mapAllocations(states), nil
 	}
-
-	carried := 0
-	for i := range states {
-		task := &states[i]
-		// 小数点以下は一旦切り捨て、余りは後段で大きい順に 1 分ずつ配る。
-		desired := float64(remainingPool) * task.Normalized
-		capacity := remainingPool
-		if task.MaxMinutes != nil {
-			capRemaining := *task.MaxMinutes - task.Allocation
-			if capRemaining < 0 {
-				capRemaining = 0
-			}
-			capacity = capRemaining
-		}
-		if capacity <= 0 {
-			task.Remainder = -1
-			continue
-		}
-		baseAdd := int(math.Floor(desired))
-		if baseAdd > capacity {
-			baseAdd = capacity
-		}
-		task.Allocation += baseAdd
-		carried += baseAdd
-		task.Remainder = desired - float64(baseAdd)
-	}
-
-	remaining := input.TotalMinutes - (allocated + carried)
-	for remaining > 0 {
-		eligible := make([]*allocationState, 0, len(states))
-		for i := range states {
-			task := &states[i]
-			if task.MaxMinutes != nil && task.Allocation >= *task.MaxMinutes {
-				continue
-			}
-			eligible = append(eligible, task)
-		}
-		if len(eligible) == 0 {
-			return nil, errors.New("unable to satisfy max constraints with provided total_minutes")
-		}
-		// 余りは「端数が大きい」「比率が大きい」「入力順が早い」の優先順位で安定的に配る。
-		sort.Slice(eligible, func(i, j int) bool {
-			ai := eligible[i]
-			aj := eligible[j]
-			if math.Abs(aj.Remainder-ai.Remainder) > allocationEpsilon {
-				return aj.Remainder > ai.Remainder
-			}
-			if math.Abs(aj.Normalized-ai.Normalized) > allocationEpsilon {
-				return aj.Normalized > ai.Normalized
-			}
-			return ai.Index < aj.Index
-		})
-
-		if len(eligible) == 1 {
-			task := eligible[0]
-			available := remaining
-			if task.MaxMinutes != nil {
-				available = *task.MaxMinutes - task.Allocation
-			}
-			if available <= 0 {
-				return nil, errors.New("unable to satisfy max constraints with provided total_minutes")
-			}
-			chunk := available
-			if chunk > remaining {
-				chunk = remaining
-			}
-			task.Allocation += chunk
-			remaining -= chunk
-			continue
-		}
-
-		distributed := 0
-		for _, task := range eligible {
-			if remaining == 0 {
-				break
-			}
-			if task.MaxMinutes != nil && task.Allocation >= *task.MaxMinutes {
-				continue
-			}
-			task.Allocation++
-			remaining--
-			distributed++
-		}
-		if distributed == 0 {
-			return nil, errors.New("unable to distribute remaining minutes due to max constraints")
-		}
+	// min / max の扱いは共通で、残り時間の配り方だけを戦略ごとに切り替える。
+	if err := newAllocationStrategy(input).distribute(states, remainingPool); err != nil {
+		return nil, err
 	}
-
 	return mapAllocations(states), nil
 }
 
@@ -273,6 +299,7 @@ func mapAllocations(states []allocationState) []allocationDistribution {
 			AllocatedMinutes: task.Allocation,
 			MinMinutes:       task.MinMinutesPtr(),
 			MaxMinutes:       task.MaxMinutes,
+			Priority:         task.Priority,
 		})
 	}
 	return results
@@ -10,15 +10,21 @@ import (
 
 	"chronome/internal/domain/entity"
 	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
 )
 
+// ErrEmailNotVerified はメールアドレスの確認が済むまでログインできない設定で、未確認のユーザーがログインしようとしたことを表す。
+var ErrEmailNotVerified = errors.New("email not verified")
+
 // AuthUsecase はユーザー登録と認証を調整する。
 type AuthUsecase struct {
 	users repository.UserRepository
+	cfg   provider.AppConfig
 }
 
-func NewAuthUsecase(users repository.UserRepository) *AuthUsecase {
-	return &AuthUsecase{users: users}
+func NewAuthUsecase(users repository.UserRepository, cfg provider.AppConfig) *AuthUsecase {
+	return &AuthUsecase{users: users, cfg: cfg}
 }
 
 // SignupParams はユーザー作成に必要なデータをまとめる。
@@ -73,9 +79,30 @@ func (u *AuthUsecase) Login(ctx context.Context, email, password string) (*entit
 	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
 		return nil, errors.New("invalid credentials")
 	}
+	// 未確認であることはパスワードが正しい場合にだけ伝える。
+	if u.cfg.EmailVerificationMode() == provider.EmailVerificationRequired && !user.EmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
 	return user, nil
 }
 
 func (u *AuthUsecase) GetProfile(ctx context.Context, userID uuid.UUID) (*entity.User, error) {
 	return u.users.GetByID(ctx, userID)
 }
+
+// UpdateRounding はユーザー既定の丸めルールを更新する。保存済みのエントリは変更しない。
+func (u *AuthUsecase) UpdateRounding(ctx context.Context, userID uuid.UUID, input dto.RoundingRuleRequest) (*entity.User, error) {
+	rule, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	user, err := u.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Rounding = rule
+	if err := u.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
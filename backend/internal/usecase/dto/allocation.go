@@ -1,25 +1,52 @@
 package dto
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AllocationStrategy は min_minutes を確保した後の残り時間の配り方を表す。
+type AllocationStrategy string
+
+const (
+	// AllocationStrategyLargestRemainder は切り捨て後の端数が大きい順に 1 分ずつ配る (既定)。
+	AllocationStrategyLargestRemainder AllocationStrategy = "largest_remainder"
+	// AllocationStrategyDHondt は ratio / (n+1) の商が最大のタスクへ 1 分ずつ配る。
+	AllocationStrategyDHondt AllocationStrategy = "dhondt"
+	// AllocationStrategySainteLague は ratio / (2n+1) の商が最大のタスクへ 1 分ずつ配る。
+	AllocationStrategySainteLague AllocationStrategy = "sainte_lague"
+	// AllocationStrategyGranularity は granularity_minutes 単位のブロックで配り、割り切れない分だけ分単位で配る。
+	AllocationStrategyGranularity AllocationStrategy = "granularity"
+	// AllocationStrategyPriority は priority の小さい順に max_minutes まで埋める。
+	AllocationStrategyPriority AllocationStrategy = "priority"
+)
 
 // AllocationRequest は分配 API の入力ペイロードを表す。
 type AllocationRequest struct {
-	TotalMinutes int                     `json:"total_minutes"`
-	Tasks        []AllocationTaskRequest `json:"tasks"`
+	TotalMinutes       int                     `json:"total_minutes"`
+	Strategy           string                  `json:"strategy"`
+	GranularityMinutes int                     `json:"granularity_minutes"`
+	Tasks              []AllocationTaskRequest `json:"tasks"`
 }
 
-// AllocationTaskRequest は各タスクの分配条件。
+// AllocationTaskRequest は各タスクの分配条件。Priority は priority 戦略でだけ使う。
 type AllocationTaskRequest struct {
 	TaskID     string  `json:"task_id"`
 	Ratio      float64 `json:"ratio"`
 	MinMinutes *int    `json:"min_minutes,omitempty"`
 	MaxMinutes *int    `json:"max_minutes,omitempty"`
+	Priority   int     `json:"priority,omitempty"`
 }
 
-// AllocationRequestData は正規化後の入力。
+// AllocationRequestData は正規化後の入力。Strategy の空文字は最大剰余法として扱う。
 type AllocationRequestData struct {
-	TotalMinutes int
-	Tasks        []AllocationTaskData
+	TotalMinutes       int
+	Strategy           AllocationStrategy
+	GranularityMinutes int
+	Tasks              []AllocationTaskData
 }
 
 // AllocationTaskData は正規化されたタスク情報。
@@ -28,6 +55,7 @@ type AllocationTaskData struct {
 	Ratio      float64
 	MinMinutes *int
 	MaxMinutes *int
+	Priority   int
 }
 
 // Normalize は入力を検証して整形する。
@@ -38,6 +66,10 @@ func (r AllocationRequest) Normalize() (AllocationRequestData, error) {
 	if len(r.Tasks) == 0 {
 		return AllocationRequestData{}, ValidationError{Field: "tasks", Message: "must include at least one task"}
 	}
+	strategy, err := normalizeAllocationStrategy(r.Strategy, r.GranularityMinutes)
+	if err != nil {
+		return AllocationRequestData{}, err
+	}
 	seen := make(map[string]struct{}, len(r.Tasks))
 	tasks := make([]AllocationTaskData, 0, len(r.Tasks))
 	for _, task := range r.Tasks {
@@ -49,24 +81,246 @@ func (r AllocationRequest) Normalize() (AllocationRequestData, error) {
 			return AllocationRequestData{}, ValidationError{Field: "tasks", Message: "task_id must be unique"}
 		}
 		seen[id] = struct{}{}
-		if task.Ratio <= 0 {
-			return AllocationRequestData{}, ValidationError{Field: "ratio", Message: "must be positive"}
+		data, err := normalizeAllocationTask(id, task.Ratio, task.MinMinutes, task.MaxMinutes)
+		if err != nil {
+			return AllocationRequestData{}, err
+		}
+		data.Priority = task.Priority
+		tasks = append(tasks, data)
+	}
+	return AllocationRequestData{
+		TotalMinutes:       r.TotalMinutes,
+		Strategy:           strategy,
+		GranularityMinutes: r.GranularityMinutes,
+		Tasks:              tasks,
+	}, nil
+}
+
+// ParseAllocationStrategy は戦略名を検証する。空文字は最大剰余法になる。
+func ParseAllocationStrategy(raw string) (AllocationStrategy, error) {
+	strategy := AllocationStrategy(strings.ToLower(strings.TrimSpace(raw)))
+	switch strategy {
+	case "":
+		return AllocationStrategyLargestRemainder, nil
+	case AllocationStrategyLargestRemainder, AllocationStrategyDHondt, AllocationStrategySainteLague,
+		AllocationStrategyGranularity, AllocationStrategyPriority:
+		return strategy, nil
+	default:
+		return "", ValidationError{Field: "strategy", Message: "must be largest_remainder, dhondt, sainte_lague, granularity or priority"}
+	}
+}
+
+// normalizeAllocationStrategy は戦略名と granularity_minutes の組み合わせを検証する。
+func normalizeAllocationStrategy(raw string, granularityMinutes int) (AllocationStrategy, error) {
+	strategy, err := ParseAllocationStrategy(raw)
+	if err != nil {
+		return "", err
+	}
+	if strategy == AllocationStrategyGranularity && granularityMinutes <= 0 {
+		return "", ValidationError{Field: "granularity_minutes", Message: "must be positive"}
+	}
+	if strategy != AllocationStrategyGranularity && granularityMinutes != 0 {
+		return "", ValidationError{Field: "granularity_minutes", Message: "is only allowed with the granularity strategy"}
+	}
+	return strategy, nil
+}
+
+// normalizeAllocationTask は ratio と min/max の組み合わせを検証する。
+func normalizeAllocationTask(id string, ratio float64, minMinutes, maxMinutes *int) (AllocationTaskData, error) {
+	if ratio <= 0 {
+		return AllocationTaskData{}, ValidationError{Field: "ratio", Message: "must be positive"}
+	}
+	if minMinutes != nil && *minMinutes < 0 {
+		return AllocationTaskData{}, ValidationError{Field: "min_minutes", Message: "must be >= 0"}
+	}
+	if maxMinutes != nil && *maxMinutes <= 0 {
+		return AllocationTaskData{}, ValidationError{Field: "max_minutes", Message: "must be positive"}
+	}
+	if minMinutes != nil && maxMinutes != nil && *minMinutes > *maxMinutes {
+		return AllocationTaskData{}, ValidationError{Field: "max_minutes", Message: "must be >= min_minutes"}
+	}
+	return AllocationTaskData{
+		TaskID:     id,
+		Ratio:      ratio,
+		MinMinutes: minMinutes,
+		MaxMinutes: maxMinutes,
+	}, nil
+}
+
+// AllocationApplyRequest は分配結果を実エントリへ展開する入力を表す。
+type AllocationApplyRequest struct {
+	WindowStart string                     `json:"window_start"`
+	WindowEnd   string                     `json:"window_end"`
+	Breaks      []TimeRangeRequest         `json:"breaks"`
+	Mappings    []AllocationMappingRequest `json:"mappings"`
+}
+
+// TimeRangeRequest は RFC3339 の開始・終了で表す時間帯。
+type TimeRangeRequest struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// AllocationMappingRequest は task_id を作成するエントリの内容へ対応付ける。
+type AllocationMappingRequest struct {
+	TaskID    string   `json:"task_id"`
+	Title     string   `json:"title"`
+	ProjectID *string  `json:"project_id"`
+	TagIDs    []string `json:"tag_ids"`
+}
+
+// AllocationApplyData は正規化後の入力。
+type AllocationApplyData struct {
+	WindowStart time.Time
+	WindowEnd   time.Time
+	Breaks      []TimeRange
+	Mappings    map[string]AllocationMappingData
+}
+
+// TimeRange は [Start, End) の時間帯。
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// AllocationMappingData は正規化された対応付け。Title が空なら task_id を使う。
+type AllocationMappingData struct {
+	Title     string
+	ProjectID *uuid.UUID
+	TagIDs    []uuid.UUID
+}
+
+// Normalize は時間帯と対応付けを検証する。
+func (r AllocationApplyRequest) Normalize() (AllocationApplyData, error) {
+	start, err := parseTimePtr(&r.WindowStart, "window_start")
+	if err != nil {
+		return AllocationApplyData{}, err
+	}
+	if start == nil {
+		return AllocationApplyData{}, ValidationError{Field: "window_start", Message: "is required"}
+	}
+	end, err := parseTimePtr(&r.WindowEnd, "window_end")
+	if err != nil {
+		return AllocationApplyData{}, err
+	}
+	if end == nil {
+		return AllocationApplyData{}, ValidationError{Field: "window_end", Message: "is required"}
+	}
+	if !end.After(*start) {
+		return AllocationApplyData{}, ValidationError{Field: "window_end", Message: "must be after window_start"}
+	}
+	breaks := make([]TimeRange, 0, len(r.Breaks))
+	for _, b := range r.Breaks {
+		bs, err := parseTimePtr(&b.Start, "breaks")
+		if err != nil {
+			return AllocationApplyData{}, err
+		}
+		be, err := parseTimePtr(&b.End, "breaks")
+		if err != nil {
+			return AllocationApplyData{}, err
+		}
+		if bs == nil || be == nil || !be.After(*bs) {
+			return AllocationApplyData{}, ValidationError{Field: "breaks", Message: "must have start before end"}
+		}
+		breaks = append(breaks, TimeRange{Start: *bs, End: *be})
+	}
+	mappings, err := normalizeAllocationMappings(r.Mappings)
+	if err != nil {
+		return AllocationApplyData{}, err
+	}
+	return AllocationApplyData{WindowStart: *start, WindowEnd: *end, Breaks: breaks, Mappings: mappings}, nil
+}
+
+// normalizeAllocationMappings は task_id ごとの対応付けを検証する。title を省略した場合は task_id を使う。
+func normalizeAllocationMappings(raw []AllocationMappingRequest) (map[string]AllocationMappingData, error) {
+	if len(raw) == 0 {
+		return nil, ValidationError{Field: "mappings", Message: "must include at least one mapping"}
+	}
+	mappings := make(map[string]AllocationMappingData, len(raw))
+	for _, m := range raw {
+		taskID := strings.TrimSpace(m.TaskID)
+		if taskID == "" {
+			return nil, ValidationError{Field: "task_id", Message: "is required"}
+		}
+		if _, exists := mappings[taskID]; exists {
+			return nil, ValidationError{Field: "mappings", Message: "task_id must be unique"}
 		}
-		if task.MinMinutes != nil && *task.MinMinutes < 0 {
-			return AllocationRequestData{}, ValidationError{Field: "min_minutes", Message: "must be >= 0"}
+		projectID, err := parseUUIDPtr(m.ProjectID, "project_id")
+		if err != nil {
+			return nil, err
+		}
+		tagIDs, err := parseUUIDList(m.TagIDs, "tag_ids")
+		if err != nil {
+			return nil, err
+		}
+		if projectID == nil && len(tagIDs) == 0 {
+			return nil, ValidationError{Field: "mappings", Message: "must set project_id or tag_ids"}
+		}
+		title := strings.TrimSpace(m.Title)
+		if title == "" {
+			title = taskID
+		}
+		mappings[taskID] = AllocationMappingData{Title: title, ProjectID: projectID, TagIDs: tagIDs}
+	}
+	return mappings, nil
+}
+
+// EntrySplitRequest は 1 件のエントリを複数のプロジェクトへ比率で分割する入力を表す。
+type EntrySplitRequest struct {
+	Parts []EntrySplitPartRequest `json:"parts"`
+}
+
+// EntrySplitPartRequest は分割後の 1 区間の条件。project_id / tag_ids を省略すると元エントリの値を引き継ぐ。
+type EntrySplitPartRequest struct {
+	ProjectID  *string  `json:"project_id"`
+	Title      string   `json:"title"`
+	TagIDs     []string `json:"tag_ids"`
+	Ratio      float64  `json:"ratio"`
+	MinMinutes *int     `json:"min_minutes,omitempty"`
+	MaxMinutes *int     `json:"max_minutes,omitempty"`
+}
+
+// EntrySplitData は正規化後の入力。Tasks は Parts と同じ順で分配計算に渡す。
+type EntrySplitData struct {
+	Parts []EntrySplitPartData
+	Tasks []AllocationTaskData
+}
+
+// EntrySplitPartData は正規化された区間の内容。TagIDsSet が false なら元のタグを使う。
+type EntrySplitPartData struct {
+	ProjectID *uuid.UUID
+	Title     string
+	TagIDs    []uuid.UUID
+	TagIDsSet bool
+}
+
+// Normalize は 2 件以上の区間と各区間の比率・制約を検証する。
+func (r EntrySplitRequest) Normalize() (EntrySplitData, error) {
+	if len(r.Parts) < 2 {
+		return EntrySplitData{}, ValidationError{Field: "parts", Message: "must include at least two parts"}
+	}
+	parts := make([]EntrySplitPartData, 0, len(r.Parts))
+	tasks := make([]AllocationTaskData, 0, len(r.Parts))
+	for i, part := range r.Parts {
+		task, err := normalizeAllocationTask(strconv.Itoa(i), part.Ratio, part.MinMinutes, part.MaxMinutes)
+		if err != nil {
+			return EntrySplitData{}, err
 		}
-		if task.MaxMinutes != nil && *task.MaxMinutes <= 0 {
-			return AllocationRequestData{}, ValidationError{Field: "max_minutes", Message: "must be positive"}
+		projectID, err := parseUUIDPtr(part.ProjectID, "project_id")
+		if err != nil {
+			return EntrySplitData{}, err
 		}
-		if task.MinMinutes != nil && task.MaxMinutes != nil && *task.MinMinutes > *task.MaxMinutes {
-			return AllocationRequestData{}, ValidationError{Field: "max_minutes", Message: "must be >= min_minutes"}
+		tagIDs, err := parseUUIDList(part.TagIDs, "tag_ids")
+		if err != nil {
+			return EntrySplitData{}, err
 		}
-		tasks = append(tasks, AllocationTaskData{
-			TaskID:     id,
-			Ratio:      task.Ratio,
-			MinMinutes: task.MinMinutes,
-			MaxMinutes: task.MaxMinutes,
+		parts = append(parts, EntrySplitPartData{
+			ProjectID: projectID,
+			Title:     strings.TrimSpace(part.Title),
+			TagIDs:    tagIDs,
+			TagIDsSet: part.TagIDs != nil,
 		})
+		tasks = append(tasks, task)
 	}
-	return AllocationRequestData{TotalMinutes: r.TotalMinutes, Tasks: tasks}, nil
+	return EntrySplitData{Parts: parts, Tasks: tasks}, nil
 }
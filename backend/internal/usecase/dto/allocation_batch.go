@@ -0,0 +1,92 @@
+package dto
+
+import (
+	"strings"
+)
+
+// MaxAllocationBatchSize は 1 回のバッチで分配できる合計分数の最大件数 (1 か月分)。
+const MaxAllocationBatchSize = 31
+
+// AllocationBatchRequest は同じタスク集合で複数の合計分数をまとめて分配する入力を表す。
+// totals か、total_minutes と splits (日ごとの重み) のどちらか一方を指定する。
+type AllocationBatchRequest struct {
+	Totals             []AllocationBatchTotalRequest `json:"totals"`
+	TotalMinutes       int                           `json:"total_minutes"`
+	Splits             []AllocationBatchSplitRequest `json:"splits"`
+	Strategy           string                        `json:"strategy"`
+	GranularityMinutes int                           `json:"granularity_minutes"`
+	Tasks              []AllocationTaskRequest       `json:"tasks"`
+	BalanceResidue     bool                          `json:"balance_residue"`
+}
+
+// AllocationBatchTotalRequest は 1 日分などの合計分数。Label は日付などの表示名で任意。
+type AllocationBatchTotalRequest struct {
+	Label        string `json:"label"`
+	TotalMinutes int    `json:"total_minutes"`
+}
+
+// AllocationBatchSplitRequest は total_minutes を分ける 1 区間の重み。
+type AllocationBatchSplitRequest struct {
+	Label  string  `json:"label"`
+	Weight float64 `json:"weight"`
+}
+
+// AllocationBatchData は正規化後の入力。Splits が空でなければ SplitTotal を重みで分けてから分配する。
+type AllocationBatchData struct {
+	Totals         []AllocationBatchTotalRequest
+	SplitTotal     int
+	Splits         []AllocationBatchSplitRequest
+	BalanceResidue bool
+}
+
+// Normalize は合計分数の指定方法と件数、ラベルを検証する。タスクと戦略の検証は各合計分数ごとに AllocationRequest で行う。
+func (r AllocationBatchRequest) Normalize() (AllocationBatchData, error) {
+	data := AllocationBatchData{BalanceResidue: r.BalanceResidue}
+	switch {
+	case len(r.Totals) > 0 && (len(r.Splits) > 0 || r.TotalMinutes != 0):
+		return AllocationBatchData{}, ValidationError{Field: "totals", Message: "cannot be combined with total_minutes or splits"}
+	case len(r.Totals) > 0:
+		if len(r.Totals) > MaxAllocationBatchSize {
+			return AllocationBatchData{}, ValidationError{Field: "totals", Message: "must include at most 31 items"}
+		}
+		for _, total := range r.Totals {
+			label, err := normalizeBatchLabel(total.Label)
+			if err != nil {
+				return AllocationBatchData{}, err
+			}
+			if total.TotalMinutes <= 0 {
+				return AllocationBatchData{}, ValidationError{Field: "totals", Message: "total_minutes must be positive"}
+			}
+			data.Totals = append(data.Totals, AllocationBatchTotalRequest{Label: label, TotalMinutes: total.TotalMinutes})
+		}
+	case len(r.Splits) > 0:
+		if r.TotalMinutes <= 0 {
+			return AllocationBatchData{}, ValidationError{Field: "total_minutes", Message: "must be positive"}
+		}
+		if len(r.Splits) > MaxAllocationBatchSize {
+			return AllocationBatchData{}, ValidationError{Field: "splits", Message: "must include at most 31 items"}
+		}
+		for _, split := range r.Splits {
+			label, err := normalizeBatchLabel(split.Label)
+			if err != nil {
+				return AllocationBatchData{}, err
+			}
+			if split.Weight <= 0 {
+				return AllocationBatchData{}, ValidationError{Field: "splits", Message: "weight must be positive"}
+			}
+			data.Splits = append(data.Splits, AllocationBatchSplitRequest{Label: label, Weight: split.Weight})
+		}
+		data.SplitTotal = r.TotalMinutes
+	default:
+		return AllocationBatchData{}, ValidationError{Field: "totals", Message: "either totals or total_minutes with splits is required"}
+	}
+	return data, nil
+}
+
+func normalizeBatchLabel(raw string) (string, error) {
+	label := strings.TrimSpace(raw)
+	if len(label) > 40 {
+		return "", ValidationError{Field: "label", Message: "must be at most 40 characters"}
+	}
+	return label, nil
+}
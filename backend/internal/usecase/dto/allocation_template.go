@@ -0,0 +1,140 @@
+package dto
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AllocationTemplateRequest は分配テンプレートの作成・更新ペイロード。更新時も全体を置き換える。
+type AllocationTemplateRequest struct {
+	Name               string                          `json:"name"`
+	Strategy           string                          `json:"strategy"`
+	GranularityMinutes int                             `json:"granularity_minutes"`
+	Tasks              []AllocationTemplateTaskRequest `json:"tasks"`
+}
+
+// AllocationTemplateTaskRequest は project_id か tag_id のどちらか一方を参照するタスク。
+type AllocationTemplateTaskRequest struct {
+	ProjectID  *string `json:"project_id"`
+	TagID      *string `json:"tag_id"`
+	Ratio      float64 `json:"ratio"`
+	MinMinutes *int    `json:"min_minutes,omitempty"`
+	MaxMinutes *int    `json:"max_minutes,omitempty"`
+	Priority   int     `json:"priority,omitempty"`
+}
+
+// AllocationTemplateData は正規化後のテンプレート。
+type AllocationTemplateData struct {
+	Name               string
+	Strategy           AllocationStrategy
+	GranularityMinutes int
+	Tasks              []AllocationTemplateTaskData
+}
+
+// AllocationTemplateTaskData は正規化されたテンプレートのタスク。
+type AllocationTemplateTaskData struct {
+	ProjectID  *uuid.UUID
+	TagID      *uuid.UUID
+	Ratio      float64
+	MinMinutes *int
+	MaxMinutes *int
+	Priority   int
+}
+
+// Normalize は名前・戦略・タスクを検証する。同じプロジェクトやタグを 2 回参照することはできない。
+func (r AllocationTemplateRequest) Normalize() (AllocationTemplateData, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return AllocationTemplateData{}, ValidationError{Field: "name", Message: "is required"}
+	}
+	if len(name) > 80 {
+		return AllocationTemplateData{}, ValidationError{Field: "name", Message: "must be at most 80 characters"}
+	}
+	if len(r.Tasks) == 0 {
+		return AllocationTemplateData{}, ValidationError{Field: "tasks", Message: "must include at least one task"}
+	}
+	strategy, err := normalizeAllocationStrategy(r.Strategy, r.GranularityMinutes)
+	if err != nil {
+		return AllocationTemplateData{}, err
+	}
+	seen := make(map[uuid.UUID]struct{}, len(r.Tasks))
+	tasks := make([]AllocationTemplateTaskData, 0, len(r.Tasks))
+	for i, task := range r.Tasks {
+		field := fmt.Sprintf("tasks[%d]", i)
+		projectID, err := parseUUIDPtr(task.ProjectID, field+".project_id")
+		if err != nil {
+			return AllocationTemplateData{}, err
+		}
+		tagID, err := parseUUIDPtr(task.TagID, field+".tag_id")
+		if err != nil {
+			return AllocationTemplateData{}, err
+		}
+		if (projectID == nil) == (tagID == nil) {
+			return AllocationTemplateData{}, ValidationError{Field: field, Message: "must set exactly one of project_id or tag_id"}
+		}
+		ref := projectID
+		if ref == nil {
+			ref = tagID
+		}
+		if _, exists := seen[*ref]; exists {
+			return AllocationTemplateData{}, ValidationError{Field: field, Message: "references the same project or tag twice"}
+		}
+		seen[*ref] = struct{}{}
+		data, err := normalizeAllocationTask("", task.Ratio, task.MinMinutes, task.MaxMinutes)
+		if err != nil {
+			return AllocationTemplateData{}, err
+		}
+		tasks = append(tasks, AllocationTemplateTaskData{
+			ProjectID:  projectID,
+			TagID:      tagID,
+			Ratio:      data.Ratio,
+			MinMinutes: data.MinMinutes,
+			MaxMinutes: data.MaxMinutes,
+			Priority:   task.Priority,
+		})
+	}
+	return AllocationTemplateData{
+		Name:               name,
+		Strategy:           strategy,
+		GranularityMinutes: r.GranularityMinutes,
+		Tasks:              tasks,
+	}, nil
+}
+
+// AllocationTemplateRunRequest はテンプレートの実行条件。total_minutes か date のどちらか一方を指定する。
+// date (YYYY-MM-DD) を指定した場合はその日の記録時間を合計分数として使う。
+type AllocationTemplateRunRequest struct {
+	TotalMinutes int    `json:"total_minutes"`
+	Date         string `json:"date"`
+}
+
+// AllocationTemplateRunData は正規化後の実行条件。Day が nil なら TotalMinutes を使う。
+type AllocationTemplateRunData struct {
+	TotalMinutes int
+	Day          *time.Time
+}
+
+// Normalize は実行条件を検証する。date は loc (ユーザーのタイムゾーン) の 0 時として解釈する。
+func (r AllocationTemplateRunRequest) Normalize(loc *time.Location) (AllocationTemplateRunData, error) {
+	date := strings.TrimSpace(r.Date)
+	switch {
+	case date != "" && r.TotalMinutes != 0:
+		return AllocationTemplateRunData{}, ValidationError{Field: "date", Message: "cannot be combined with total_minutes"}
+	case date != "":
+		if loc == nil {
+			loc = time.UTC
+		}
+		day, err := time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return AllocationTemplateRunData{}, ValidationError{Field: "date", Message: "must be YYYY-MM-DD"}
+		}
+		return AllocationTemplateRunData{Day: &day}, nil
+	case r.TotalMinutes <= 0:
+		return AllocationTemplateRunData{}, ValidationError{Field: "total_minutes", Message: "must be positive"}
+	default:
+		return AllocationTemplateRunData{TotalMinutes: r.TotalMinutes}, nil
+	}
+}
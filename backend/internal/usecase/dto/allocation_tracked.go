@@ -0,0 +1,118 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TrackedSource は記録済みエントリから合計分数を求める方法を表す。
+type TrackedSource string
+
+const (
+	// TrackedSourceUntracked は指定日の時間帯のうちエントリがない空き時間を合計する。
+	TrackedSourceUntracked TrackedSource = "untracked"
+	// TrackedSourceUnassigned は指定日に始まったプロジェクト未設定のエントリを合計する。
+	TrackedSourceUnassigned TrackedSource = "unassigned"
+	// TrackedSourceTag は指定日を含む週 (月曜始まり) に始まった、指定タグ付きのエントリを合計する。
+	TrackedSourceTag TrackedSource = "tag"
+)
+
+// AllocationFromTrackedRequest は記録済みの時間を分配する入力を表す。
+// start_time / end_time (HH:MM) は untracked、tag_id は tag のときだけ使う。
+// create_entries が true の場合は mappings に従って分配結果をエントリとして作成する。
+type AllocationFromTrackedRequest struct {
+	Source             string                     `json:"source"`
+	Date               string                     `json:"date"`
+	StartTime          string                     `json:"start_time"`
+	EndTime            string                     `json:"end_time"`
+	TagID              *string                    `json:"tag_id"`
+	Strategy           string                     `json:"strategy"`
+	GranularityMinutes int                        `json:"granularity_minutes"`
+	Tasks              []AllocationTaskRequest    `json:"tasks"`
+	CreateEntries      bool                       `json:"create_entries"`
+	Mappings           []AllocationMappingRequest `json:"mappings"`
+}
+
+// AllocationFromTrackedData は正規化後の入力。Window は untracked の時間帯、Day はユーザーのタイムゾーンの 0 時。
+type AllocationFromTrackedData struct {
+	Source   TrackedSource
+	Day      time.Time
+	Window   TimeRange
+	TagID    *uuid.UUID
+	Mappings map[string]AllocationMappingData
+}
+
+// Normalize は合計分数の取り出し方と対応付けを検証する。日付と時刻は loc で解釈する。
+// 分配条件 (tasks / strategy) の検証は合計分数が決まった後に AllocationRequest で行う。
+func (r AllocationFromTrackedRequest) Normalize(loc *time.Location) (AllocationFromTrackedData, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	day, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(r.Date), loc)
+	if err != nil {
+		return AllocationFromTrackedData{}, ValidationError{Field: "date", Message: "must be YYYY-MM-DD"}
+	}
+	data := AllocationFromTrackedData{Source: TrackedSource(strings.ToLower(strings.TrimSpace(r.Source))), Day: day}
+	switch data.Source {
+	case TrackedSourceUntracked:
+		start, err := parseClockOnDay(day, r.StartTime, "start_time")
+		if err != nil {
+			return AllocationFromTrackedData{}, err
+		}
+		end, err := parseClockOnDay(day, r.EndTime, "end_time")
+		if err != nil {
+			return AllocationFromTrackedData{}, err
+		}
+		if !end.After(start) {
+			return AllocationFromTrackedData{}, ValidationError{Field: "end_time", Message: "must be after start_time"}
+		}
+		data.Window = TimeRange{Start: start, End: end}
+	case TrackedSourceUnassigned:
+	case TrackedSourceTag:
+		tagID, err := parseUUIDPtr(r.TagID, "tag_id")
+		if err != nil {
+			return AllocationFromTrackedData{}, err
+		}
+		if tagID == nil {
+			return AllocationFromTrackedData{}, ValidationError{Field: "tag_id", Message: "is required for tag source"}
+		}
+		if r.CreateEntries {
+			// タグ付きエントリはすでに記録済みのため、新しいエントリを作ると二重計上になる。
+			return AllocationFromTrackedData{}, ValidationError{Field: "create_entries", Message: "is not supported for tag source"}
+		}
+		data.TagID = tagID
+	default:
+		return AllocationFromTrackedData{}, ValidationError{Field: "source", Message: "must be untracked, unassigned or tag"}
+	}
+	if r.CreateEntries {
+		mappings, err := normalizeAllocationMappings(r.Mappings)
+		if err != nil {
+			return AllocationFromTrackedData{}, err
+		}
+		for _, task := range r.Tasks {
+			if _, ok := mappings[strings.TrimSpace(task.TaskID)]; !ok {
+				return AllocationFromTrackedData{}, ValidationError{Field: "mappings", Message: "must map every task_id when create_entries is true"}
+			}
+		}
+		data.Mappings = mappings
+	}
+	return data, nil
+}
+
+// parseClockOnDay は HH:MM を day の時刻として解釈する。24:00 は翌日 0 時を表す。
+func parseClockOnDay(day time.Time, raw, field string) (time.Time, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return time.Time{}, ValidationError{Field: field, Message: "is required for untracked source"}
+	}
+	if value == "24:00" {
+		return day.AddDate(0, 0, 1), nil
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, ValidationError{Field: field, Message: "must be HH:MM"}
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location()), nil
+}
@@ -0,0 +1,178 @@
+package dto
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	minPasswordLength     = 8
+	maxPasswordLength     = 128
+	maxDisplayNameLength  = 50
+	maxTimeZoneNameLength = 40
+)
+
+// PasswordForgotRequest はパスワード再設定メールの送信依頼を受け取る。
+type PasswordForgotRequest struct {
+	Email string `json:"email"`
+}
+
+// Normalize はメールアドレスを小文字にそろえ、形式だけを検証する。
+func (r PasswordForgotRequest) Normalize() (string, error) {
+	return normalizeEmail("email", r.Email)
+}
+
+// PasswordResetRequest はメールのトークンと新しいパスワードを受け取る。
+type PasswordResetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// PasswordResetData はユースケースで使う正規化データ。
+type PasswordResetData struct {
+	Token    string
+	Password string
+}
+
+func (r PasswordResetRequest) Normalize() (PasswordResetData, error) {
+	token := strings.TrimSpace(r.Token)
+	if token == "" {
+		return PasswordResetData{}, ValidationError{Field: "token", Message: "is required"}
+	}
+	if err := validatePassword("password", r.Password); err != nil {
+		return PasswordResetData{}, err
+	}
+	return PasswordResetData{Token: token, Password: r.Password}, nil
+}
+
+// PasswordChangeRequest はログイン中のパスワード変更を受け取る。現在のパスワードで本人確認する。
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (r PasswordChangeRequest) Normalize() (PasswordChangeRequest, error) {
+	if r.CurrentPassword == "" {
+		return PasswordChangeRequest{}, ValidationError{Field: "current_password", Message: "is required"}
+	}
+	if err := validatePassword("new_password", r.NewPassword); err != nil {
+		return PasswordChangeRequest{}, err
+	}
+	return r, nil
+}
+
+// EmailChangeRequest は新しいメールアドレスへの変更依頼を受け取る。変更は確認メールのリンクを開くまで反映しない。
+type EmailChangeRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// EmailChangeData はユースケースで使う正規化データ。
+type EmailChangeData struct {
+	Email    string
+	Password string
+}
+
+func (r EmailChangeRequest) Normalize() (EmailChangeData, error) {
+	email, err := normalizeEmail("email", r.Email)
+	if err != nil {
+		return EmailChangeData{}, err
+	}
+	if r.Password == "" {
+		return EmailChangeData{}, ValidationError{Field: "password", Message: "is required"}
+	}
+	return EmailChangeData{Email: email, Password: r.Password}, nil
+}
+
+// EmailVerifyRequest は確認メールのトークンを受け取る。
+type EmailVerifyRequest struct {
+	Token string `json:"token"`
+}
+
+func (r EmailVerifyRequest) Normalize() (string, error) {
+	token := strings.TrimSpace(r.Token)
+	if token == "" {
+		return "", ValidationError{Field: "token", Message: "is required"}
+	}
+	return token, nil
+}
+
+// EmailVerifyResendRequest は確認メールの再送依頼を受け取る。
+type EmailVerifyResendRequest struct {
+	Email string `json:"email"`
+}
+
+func (r EmailVerifyResendRequest) Normalize() (string, error) {
+	return normalizeEmail("email", r.Email)
+}
+
+// ProfileUpdateRequest は表示名とタイムゾーンの部分更新を受け取る。省略したフィールドは変更しない。
+type ProfileUpdateRequest struct {
+	DisplayName *string `json:"display_name"`
+	TimeZone    *string `json:"time_zone"`
+}
+
+// ProfileUpdateData はユースケースで使う正規化データ。
+type ProfileUpdateData struct {
+	DisplayName *string
+	TimeZone    *string
+}
+
+func (r ProfileUpdateRequest) Normalize() (ProfileUpdateData, error) {
+	var data ProfileUpdateData
+	if r.DisplayName != nil {
+		name := strings.TrimSpace(*r.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			return ProfileUpdateData{}, ValidationError{Field: "display_name", Message: "must be 50 characters or less"}
+		}
+		data.DisplayName = &name
+	}
+	if r.TimeZone != nil {
+		tz := strings.TrimSpace(*r.TimeZone)
+		if tz == "" || tz == "Local" || len(tz) > maxTimeZoneNameLength {
+			return ProfileUpdateData{}, ValidationError{Field: "time_zone", Message: "must be an IANA time zone name"}
+		}
+		if _, err := time.LoadLocation(tz); err != nil {
+			return ProfileUpdateData{}, ValidationError{Field: "time_zone", Message: "must be an IANA time zone name"}
+		}
+		data.TimeZone = &tz
+	}
+	if data.DisplayName == nil && data.TimeZone == nil {
+		return ProfileUpdateData{}, ValidationError{Message: "no fields to update"}
+	}
+	return data, nil
+}
+
+// AccountDeleteRequest はアカウント削除の確認として現在のパスワードを受け取る。
+type AccountDeleteRequest struct {
+	Password string `json:"password"`
+}
+
+func (r AccountDeleteRequest) Normalize() (string, error) {
+	if r.Password == "" {
+		return "", ValidationError{Field: "password", Message: "is required"}
+	}
+	return r.Password, nil
+}
+
+func normalizeEmail(field, raw string) (string, error) {
+	email := strings.ToLower(strings.TrimSpace(raw))
+	if email == "" {
+		return "", ValidationError{Field: field, Message: "is required"}
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return "", ValidationError{Field: field, Message: "must be a valid email address"}
+	}
+	return email, nil
+}
+
+// validatePassword は新しく設定するパスワードの長さを検証する。前後の空白も意図した文字として扱う。
+func validatePassword(field, password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < minPasswordLength || length > maxPasswordLength {
+		return ValidationError{Field: field, Message: "must be between 8 and 128 characters"}
+	}
+	return nil
+}
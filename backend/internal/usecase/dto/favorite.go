@@ -0,0 +1,121 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// FavoriteCreateRequest はお気に入り作成の JSON ペイロードを受け取る。
+type FavoriteCreateRequest struct {
+	Title     string   `json:"title"`
+	Notes     string   `json:"notes"`
+	ProjectID *string  `json:"project_id"`
+	IsBreak   *bool    `json:"is_break"`
+	Ratio     *float64 `json:"ratio"`
+	TagIDs    []string `json:"tag_ids"`
+}
+
+// FavoriteCreateData はユースケースで使う正規化データ。
+type FavoriteCreateData struct {
+	Title     string
+	Notes     string
+	ProjectID *uuid.UUID
+	IsBreak   bool
+	Ratio     float64
+	TagIDs    []uuid.UUID
+}
+
+// Normalize はリクエストを検証し型付けデータへ変換する。
+func (r FavoriteCreateRequest) Normalize() (FavoriteCreateData, error) {
+	title := strings.TrimSpace(r.Title)
+	if title == "" {
+		return FavoriteCreateData{}, ValidationError{Field: "title", Message: "is required"}
+	}
+	projectID, err := parseUUIDPtr(r.ProjectID, "project_id")
+	if err != nil {
+		return FavoriteCreateData{}, err
+	}
+	isBreak := false
+	if r.IsBreak != nil {
+		isBreak = *r.IsBreak
+	}
+	ratio := 1.0
+	if r.Ratio != nil {
+		if *r.Ratio <= 0 {
+			return FavoriteCreateData{}, ValidationError{Field: "ratio", Message: "must be positive"}
+		}
+		ratio = *r.Ratio
+	}
+	tagIDs, err := parseUUIDList(r.TagIDs, "tag_ids")
+	if err != nil {
+		return FavoriteCreateData{}, err
+	}
+	return FavoriteCreateData{
+		Title:     title,
+		Notes:     r.Notes,
+		ProjectID: projectID,
+		IsBreak:   isBreak,
+		Ratio:     ratio,
+		TagIDs:    tagIDs,
+	}, nil
+}
+
+// FavoriteUpdateRequest は部分更新を扱う。
+type FavoriteUpdateRequest struct {
+	Title     *string   `json:"title"`
+	Notes     *string   `json:"notes"`
+	ProjectID *string   `json:"project_id"`
+	IsBreak   *bool     `json:"is_break"`
+	Ratio     *float64  `json:"ratio"`
+	TagIDs    *[]string `json:"tag_ids"`
+}
+
+// FavoriteUpdateData は型付けされた正規化表現。
+type FavoriteUpdateData struct {
+	Title        *string
+	Notes        *string
+	ProjectID    *uuid.UUID
+	ProjectIDSet bool
+	IsBreak      *bool
+	Ratio        *float64
+	TagIDs       []uuid.UUID
+	TagIDsSet    bool
+}
+
+// Normalize はパッチデータを検証する。空文字の project_id はプロジェクトの解除として扱う。
+func (r FavoriteUpdateRequest) Normalize() (FavoriteUpdateData, error) {
+	if r.Title != nil {
+		trimmed := strings.TrimSpace(*r.Title)
+		if trimmed == "" {
+			return FavoriteUpdateData{}, ValidationError{Field: "title", Message: "is required"}
+		}
+		r.Title = &trimmed
+	}
+	if r.Ratio != nil && *r.Ratio <= 0 {
+		return FavoriteUpdateData{}, ValidationError{Field: "ratio", Message: "must be positive"}
+	}
+	projectID, err := parseUUIDPtr(r.ProjectID, "project_id")
+	if err != nil {
+		return FavoriteUpdateData{}, err
+	}
+	var tagIDs []uuid.UUID
+	tagIDsSet := false
+	if r.TagIDs != nil {
+		tagIDsSet = true
+		tagIDs, err = parseUUIDList(*r.TagIDs, "tag_ids")
+		if err != nil {
+			return FavoriteUpdateData{}, err
+		}
+	}
+	return FavoriteUpdateData{
+		Title:        r.Title,
+		Notes:        r.Notes,
+		ProjectID:    projectID,
+		ProjectIDSet: r.ProjectID != nil,
+		IsBreak:      r.IsBreak,
+		Ratio:        r.Ratio,
+		TagIDs:       tagIDs,
+		TagIDsSet:    tagIDsSet,
+	}, nil
+}
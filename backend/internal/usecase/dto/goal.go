@@ -0,0 +1,155 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+)
+
+// GoalCreateRequest は目標作成の JSON ペイロードを受け取る。
+type GoalCreateRequest struct {
+	Name          string  `json:"name"`
+	Scope         string  `json:"scope"`
+	ProjectID     *string `json:"project_id"`
+	TagID         *string `json:"tag_id"`
+	Period        string  `json:"period"`
+	TargetMinutes int     `json:"target_minutes"`
+	WorkingDays   *[]int  `json:"working_days"`
+}
+
+// GoalCreateData はユースケースで使う正規化データ。
+type GoalCreateData struct {
+	Name        string
+	Scope       entity.GoalScope
+	ProjectID   *uuid.UUID
+	TagID       *uuid.UUID
+	Period      entity.GoalPeriod
+	TargetSec   int64
+	WorkingDays entity.WeekdayMask
+}
+
+// Normalize はスコープと期間を検証する。working_days 未指定時は月曜から金曜を稼働日とする。
+func (r GoalCreateRequest) Normalize() (GoalCreateData, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return GoalCreateData{}, ValidationError{Field: "name", Message: "is required"}
+	}
+	scope := entity.GoalScope(strings.ToLower(strings.TrimSpace(r.Scope)))
+	if scope == "" {
+		scope = entity.GoalScopeUser
+	}
+	projectID, err := parseUUIDPtr(r.ProjectID, "project_id")
+	if err != nil {
+		return GoalCreateData{}, err
+	}
+	tagID, err := parseUUIDPtr(r.TagID, "tag_id")
+	if err != nil {
+		return GoalCreateData{}, err
+	}
+	switch scope {
+	case entity.GoalScopeUser:
+		if projectID != nil || tagID != nil {
+			return GoalCreateData{}, ValidationError{Field: "scope", Message: "user scope must not set project_id or tag_id"}
+		}
+	case entity.GoalScopeProject:
+		if projectID == nil {
+			return GoalCreateData{}, ValidationError{Field: "project_id", Message: "is required for project scope"}
+		}
+		if tagID != nil {
+			return GoalCreateData{}, ValidationError{Field: "tag_id", Message: "is not allowed for project scope"}
+		}
+	case entity.GoalScopeTag:
+		if tagID == nil {
+			return GoalCreateData{}, ValidationError{Field: "tag_id", Message: "is required for tag scope"}
+		}
+		if projectID != nil {
+			return GoalCreateData{}, ValidationError{Field: "project_id", Message: "is not allowed for tag scope"}
+		}
+	default:
+		return GoalCreateData{}, ValidationError{Field: "scope", Message: "must be user, project or tag"}
+	}
+	period := entity.GoalPeriod(strings.ToLower(strings.TrimSpace(r.Period)))
+	switch period {
+	case entity.GoalPeriodDaily, entity.GoalPeriodWeekly, entity.GoalPeriodMonthly:
+	case "":
+		return GoalCreateData{}, ValidationError{Field: "period", Message: "is required"}
+	default:
+		return GoalCreateData{}, ValidationError{Field: "period", Message: "must be daily, weekly or monthly"}
+	}
+	if r.TargetMinutes <= 0 {
+		return GoalCreateData{}, ValidationError{Field: "target_minutes", Message: "must be positive"}
+	}
+	workingDays := entity.DefaultWorkingDays
+	if r.WorkingDays != nil {
+		workingDays, err = parseWorkingDays(*r.WorkingDays)
+		if err != nil {
+			return GoalCreateData{}, err
+		}
+	}
+	return GoalCreateData{
+		Name:        name,
+		Scope:       scope,
+		ProjectID:   projectID,
+		TagID:       tagID,
+		Period:      period,
+		TargetSec:   int64(r.TargetMinutes) * 60,
+		WorkingDays: workingDays,
+	}, nil
+}
+
+// GoalUpdateRequest は部分更新を扱う。スコープと期間は達成履歴の意味が変わるため変更できない。
+type GoalUpdateRequest struct {
+	Name          *string `json:"name"`
+	TargetMinutes *int    `json:"target_minutes"`
+	WorkingDays   *[]int  `json:"working_days"`
+}
+
+// GoalUpdateData は型付けされた正規化表現。
+type GoalUpdateData struct {
+	Name        *string
+	TargetSec   *int64
+	WorkingDays *entity.WeekdayMask
+}
+
+func (r GoalUpdateRequest) Normalize() (GoalUpdateData, error) {
+	var data GoalUpdateData
+	if r.Name != nil {
+		trimmed := strings.TrimSpace(*r.Name)
+		if trimmed == "" {
+			return GoalUpdateData{}, ValidationError{Field: "name", Message: "is required"}
+		}
+		data.Name = &trimmed
+	}
+	if r.TargetMinutes != nil {
+		if *r.TargetMinutes <= 0 {
+			return GoalUpdateData{}, ValidationError{Field: "target_minutes", Message: "must be positive"}
+		}
+		target := int64(*r.TargetMinutes) * 60
+		data.TargetSec = &target
+	}
+	if r.WorkingDays != nil {
+		mask, err := parseWorkingDays(*r.WorkingDays)
+		if err != nil {
+			return GoalUpdateData{}, err
+		}
+		data.WorkingDays = &mask
+	}
+	return data, nil
+}
+
+func parseWorkingDays(days []int) (entity.WeekdayMask, error) {
+	weekdays := make([]time.Weekday, 0, len(days))
+	for _, day := range days {
+		if day < 0 || day > 6 {
+			return 0, ValidationError{Field: "working_days", Message: "must be weekday numbers between 0 (Sunday) and 6"}
+		}
+		weekdays = append(weekdays, time.Weekday(day))
+	}
+	if len(weekdays) == 0 {
+		return 0, ValidationError{Field: "working_days", Message: "must include at least one day"}
+	}
+	return entity.NewWeekdayMask(weekdays...), nil
+}
@@ -0,0 +1,47 @@
+package dto
+
+import "strings"
+
+// IdleAction はアイドル区間の扱い方を表す。
+type IdleAction string
+
+const (
+	// IdleActionKeep はアイドル区間も作業時間として残す。
+	IdleActionKeep IdleAction = "keep"
+	// IdleActionDiscard はアイドル開始時刻でエントリを打ち切る。
+	IdleActionDiscard IdleAction = "discard"
+	// IdleActionBreak はアイドル区間を休憩エントリとして切り出す。
+	IdleActionBreak IdleAction = "break"
+)
+
+// IdleResolveRequest はアイドル区間の解決方法を受け取る。
+type IdleResolveRequest struct {
+	Action   string `json:"action"`
+	Continue *bool  `json:"continue"`
+}
+
+// IdleResolveData は正規化後の入力。
+type IdleResolveData struct {
+	Action   IdleAction
+	Continue bool
+}
+
+// Normalize は action を検証する。continue は discard/break で作業を現在時刻から再開するかを表す。
+func (r IdleResolveRequest) Normalize() (IdleResolveData, error) {
+	action := IdleAction(strings.ToLower(strings.TrimSpace(r.Action)))
+	switch action {
+	case IdleActionKeep, IdleActionDiscard, IdleActionBreak:
+	case "":
+		return IdleResolveData{}, ValidationError{Field: "action", Message: "is required"}
+	default:
+		return IdleResolveData{}, ValidationError{Field: "action", Message: "must be keep, discard or break"}
+	}
+	cont := false
+	if r.Continue != nil {
+		cont = *r.Continue
+	}
+	if action == IdleActionKeep && cont {
+		return IdleResolveData{}, ValidationError{Field: "continue", Message: "is only allowed with discard or break"}
+	}
+	return IdleResolveData{Action: action, Continue: cont}, nil
+}
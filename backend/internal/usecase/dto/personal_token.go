@@ -0,0 +1,64 @@
+package dto
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"chronome/internal/domain/entity"
+)
+
+// パーソナルアクセストークンの有効日数の上限。
+const maxPersonalTokenDays = 366
+
+// PersonalTokenCreateRequest はパーソナルアクセストークン作成の JSON ペイロードを受け取る。
+type PersonalTokenCreateRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expires_in_days"`
+}
+
+// PersonalTokenCreateData はユースケースで使う正規化データ。
+type PersonalTokenCreateData struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// Normalize はスコープを重複なく既知の順に並べ、expires_in_days を now からの期限に変換する。
+// expires_in_days を省略したトークンは期限なしになる。
+func (r PersonalTokenCreateRequest) Normalize(now time.Time) (PersonalTokenCreateData, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return PersonalTokenCreateData{}, ValidationError{Field: "name", Message: "is required"}
+	}
+	if len(name) > 80 {
+		return PersonalTokenCreateData{}, ValidationError{Field: "name", Message: "must be 80 characters or fewer"}
+	}
+	if len(r.Scopes) == 0 {
+		return PersonalTokenCreateData{}, ValidationError{Field: "scopes", Message: "must contain at least one scope"}
+	}
+	requested := make(map[string]bool, len(r.Scopes))
+	for _, raw := range r.Scopes {
+		scope := strings.ToLower(strings.TrimSpace(raw))
+		if !slices.Contains(entity.PersonalTokenScopes, scope) {
+			return PersonalTokenCreateData{}, ValidationError{Field: "scopes", Message: "contains unknown scope " + raw}
+		}
+		requested[scope] = true
+	}
+	data := PersonalTokenCreateData{Name: name}
+	for _, scope := range entity.PersonalTokenScopes {
+		if requested[scope] {
+			data.Scopes = append(data.Scopes, scope)
+		}
+	}
+	if r.ExpiresInDays != nil {
+		days := *r.ExpiresInDays
+		if days < 1 || days > maxPersonalTokenDays {
+			return PersonalTokenCreateData{}, ValidationError{Field: "expires_in_days", Message: "must be between 1 and 366"}
+		}
+		expiresAt := now.AddDate(0, 0, days)
+		data.ExpiresAt = &expiresAt
+	}
+	return data, nil
+}
@@ -0,0 +1,85 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPomodoroWorkMinutes       = 25
+	defaultPomodoroShortBreakMinutes = 5
+	defaultPomodoroLongBreakMinutes  = 15
+	defaultPomodoroCycles            = 4
+	maxPomodoroPhaseMinutes          = 240
+	maxPomodoroCycles                = 12
+)
+
+// PomodoroStartRequest はポモドーロセッション開始の JSON ペイロードを受け取る。
+type PomodoroStartRequest struct {
+	Title             string  `json:"title"`
+	ProjectID         *string `json:"project_id"`
+	WorkMinutes       *int    `json:"work_minutes"`
+	ShortBreakMinutes *int    `json:"short_break_minutes"`
+	LongBreakMinutes  *int    `json:"long_break_minutes"`
+	Cycles            *int    `json:"cycles"`
+}
+
+// PomodoroStartData はユースケースで使う正規化データ。長さは秒で保持する。
+type PomodoroStartData struct {
+	Title         string
+	ProjectID     *uuid.UUID
+	WorkSec       int64
+	ShortBreakSec int64
+	LongBreakSec  int64
+	Cycles        int
+}
+
+// Normalize は未指定の長さに標準的な 25/5/15 分と 4 サイクルを補う。
+func (r PomodoroStartRequest) Normalize() (PomodoroStartData, error) {
+	title := strings.TrimSpace(r.Title)
+	if title == "" {
+		return PomodoroStartData{}, ValidationError{Field: "title", Message: "is required"}
+	}
+	projectID, err := parseUUIDPtr(r.ProjectID, "project_id")
+	if err != nil {
+		return PomodoroStartData{}, err
+	}
+	work, err := pomodoroMinutes(r.WorkMinutes, defaultPomodoroWorkMinutes, "work_minutes")
+	if err != nil {
+		return PomodoroStartData{}, err
+	}
+	shortBreak, err := pomodoroMinutes(r.ShortBreakMinutes, defaultPomodoroShortBreakMinutes, "short_break_minutes")
+	if err != nil {
+		return PomodoroStartData{}, err
+	}
+	longBreak, err := pomodoroMinutes(r.LongBreakMinutes, defaultPomodoroLongBreakMinutes, "long_break_minutes")
+	if err != nil {
+		return PomodoroStartData{}, err
+	}
+	cycles := defaultPomodoroCycles
+	if r.Cycles != nil {
+		if *r.Cycles <= 0 || *r.Cycles > maxPomodoroCycles {
+			return PomodoroStartData{}, ValidationError{Field: "cycles", Message: "must be between 1 and 12"}
+		}
+		cycles = *r.Cycles
+	}
+	return PomodoroStartData{
+		Title:         title,
+		ProjectID:     projectID,
+		WorkSec:       int64(work) * 60,
+		ShortBreakSec: int64(shortBreak) * 60,
+		LongBreakSec:  int64(longBreak) * 60,
+		Cycles:        cycles,
+	}, nil
+}
+
+func pomodoroMinutes(value *int, fallback int, field string) (int, error) {
+	if value == nil {
+		return fallback, nil
+	}
+	if *value <= 0 || *value > maxPomodoroPhaseMinutes {
+		return 0, ValidationError{Field: field, Message: "must be between 1 and 240"}
+	}
+	return *value, nil
+}
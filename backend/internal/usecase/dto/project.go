@@ -2,6 +2,8 @@ package dto
 
 import (
 	"strings"
+
+	"chronome/internal/domain/entity"
 )
 
 // ProjectCreateRequest は作成リクエストの入力を表す。
@@ -9,6 +11,8 @@ type ProjectCreateRequest struct {
 	Name        string `json:"name"`
 	Color       string `json:"color"`
 	Description string `json:"description"`
+	// Rounding を省略したプロジェクトはユーザー既定の丸めルールに従う。
+	Rounding *RoundingRuleRequest `json:"rounding"`
 }
 
 // Normalize は検証して整形済みフィールドを返す。
@@ -21,10 +25,19 @@ func (r ProjectCreateRequest) Normalize(defaultColor string) (ProjectInput, erro
 	if color == "" {
 		color = defaultColor
 	}
+	var rounding entity.RoundingRule
+	if r.Rounding != nil {
+		rule, err := r.Rounding.Normalize()
+		if err != nil {
+			return ProjectInput{}, err
+		}
+		rounding = rule
+	}
 	return ProjectInput{
 		Name:        name,
 		Color:       color,
 		Description: strings.TrimSpace(r.Description),
+		Rounding:    rounding,
 	}, nil
 }
 
@@ -34,6 +47,8 @@ type ProjectUpdateRequest struct {
 	Color       *string `json:"color"`
 	Description *string `json:"description"`
 	IsArchived  *bool   `json:"is_archived"`
+	// Rounding に {"mode":"none"} を渡すとプロジェクト固有の丸めを解除する。
+	Rounding *RoundingRuleRequest `json:"rounding"`
 }
 
 // Normalize はトリム済み値を保証する。
@@ -56,11 +71,20 @@ func (r ProjectUpdateRequest) Normalize() (ProjectUpdateInput, error) {
 		trimmed := strings.TrimSpace(*r.Description)
 		r.Description = &trimmed
 	}
+	var rounding *entity.RoundingRule
+	if r.Rounding != nil {
+		rule, err := r.Rounding.Normalize()
+		if err != nil {
+			return ProjectUpdateInput{}, err
+		}
+		rounding = &rule
+	}
 	return ProjectUpdateInput{
 		Name:        r.Name,
 		Color:       r.Color,
 		Description: r.Description,
 		IsArchived:  r.IsArchived,
+		Rounding:    rounding,
 	}, nil
 }
 
@@ -69,6 +93,7 @@ type ProjectInput struct {
 	Name        string
 	Color       string
 	Description string
+	Rounding    entity.RoundingRule
 }
 
 // ProjectUpdateInput は任意更新を表す。
@@ -77,4 +102,5 @@ type ProjectUpdateInput struct {
 	Color       *string
 	Description *string
 	IsArchived  *bool
+	Rounding    *entity.RoundingRule
 }
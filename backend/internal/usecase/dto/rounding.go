@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"strings"
+
+	"chronome/internal/domain/entity"
+)
+
+// RoundingRuleRequest は丸めルールの入力。mode が空または "none" なら丸めを解除する。
+type RoundingRuleRequest struct {
+	Mode             string `json:"mode"`
+	IncrementMinutes int    `json:"increment_minutes"`
+}
+
+// Normalize は mode と increment_minutes の組み合わせを検証する。
+func (r RoundingRuleRequest) Normalize() (entity.RoundingRule, error) {
+	mode := entity.RoundingMode(strings.ToLower(strings.TrimSpace(r.Mode)))
+	if mode == "none" {
+		mode = entity.RoundingNone
+	}
+	switch mode {
+	case entity.RoundingNone:
+		return entity.RoundingRule{}, nil
+	case entity.RoundingUp, entity.RoundingDown, entity.RoundingNearest:
+	default:
+		return entity.RoundingRule{}, ValidationError{Field: "rounding.mode", Message: "must be up, down, nearest or none"}
+	}
+	rule := entity.RoundingRule{Mode: mode, IncrementMinutes: r.IncrementMinutes}
+	if err := rule.Validate(); err != nil {
+		return entity.RoundingRule{}, ValidationError{Field: "rounding.increment_minutes", Message: "must be between 1 and 1440"}
+	}
+	return rule, nil
+}
@@ -0,0 +1,120 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"chronome/internal/domain/entity"
+)
+
+// ScheduleUpdateRequest は曜日別の所定労働時間 (分) を受け取る。未指定の曜日は現在値を維持する。
+type ScheduleUpdateRequest struct {
+	SundayMinutes    *int `json:"sunday_minutes"`
+	MondayMinutes    *int `json:"monday_minutes"`
+	TuesdayMinutes   *int `json:"tuesday_minutes"`
+	WednesdayMinutes *int `json:"wednesday_minutes"`
+	ThursdayMinutes  *int `json:"thursday_minutes"`
+	FridayMinutes    *int `json:"friday_minutes"`
+	SaturdayMinutes  *int `json:"saturday_minutes"`
+}
+
+// ScheduleUpdateData は更新対象の曜日と秒数の組。
+type ScheduleUpdateData struct {
+	Seconds map[time.Weekday]int64
+}
+
+func (r ScheduleUpdateRequest) Normalize() (ScheduleUpdateData, error) {
+	fields := []struct {
+		day   time.Weekday
+		value *int
+		name  string
+	}{
+		{time.Sunday, r.SundayMinutes, "sunday_minutes"},
+		{time.Monday, r.MondayMinutes, "monday_minutes"},
+		{time.Tuesday, r.TuesdayMinutes, "tuesday_minutes"},
+		{time.Wednesday, r.WednesdayMinutes, "wednesday_minutes"},
+		{time.Thursday, r.ThursdayMinutes, "thursday_minutes"},
+		{time.Friday, r.FridayMinutes, "friday_minutes"},
+		{time.Saturday, r.SaturdayMinutes, "saturday_minutes"},
+	}
+	data := ScheduleUpdateData{Seconds: make(map[time.Weekday]int64)}
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		if *field.value < 0 || *field.value > 24*60 {
+			return ScheduleUpdateData{}, ValidationError{Field: field.name, Message: "must be between 0 and 1440"}
+		}
+		data.Seconds[field.day] = int64(*field.value) * 60
+	}
+	return data, nil
+}
+
+// HolidayImportItem は iCalendar などから取り込む 1 日分の祝日。
+type HolidayImportItem struct {
+	Date string
+	Name string
+	UID  string
+}
+
+// TimeOffCreateRequest は休暇・病欠の登録を受け取る。minutes 未指定は終日扱い。
+type TimeOffCreateRequest struct {
+	Date    string `json:"date"`
+	Kind    string `json:"kind"`
+	Minutes *int   `json:"minutes"`
+	Note    string `json:"note"`
+}
+
+// TimeOffCreateData はユースケースで使う正規化データ。
+type TimeOffCreateData struct {
+	Date        string
+	Kind        entity.TimeOffKind
+	DurationSec int64
+	Note        string
+}
+
+func (r TimeOffCreateRequest) Normalize() (TimeOffCreateData, error) {
+	date := strings.TrimSpace(r.Date)
+	if date == "" {
+		return TimeOffCreateData{}, ValidationError{Field: "date", Message: "is required"}
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return TimeOffCreateData{}, ValidationError{Field: "date", Message: "must be YYYY-MM-DD"}
+	}
+	kind := entity.TimeOffKind(strings.ToLower(strings.TrimSpace(r.Kind)))
+	if kind != entity.TimeOffVacation && kind != entity.TimeOffSick {
+		return TimeOffCreateData{}, ValidationError{Field: "kind", Message: "must be vacation or sick"}
+	}
+	var duration int64
+	if r.Minutes != nil {
+		if *r.Minutes <= 0 || *r.Minutes > 24*60 {
+			return TimeOffCreateData{}, ValidationError{Field: "minutes", Message: "must be between 1 and 1440"}
+		}
+		duration = int64(*r.Minutes) * 60
+	}
+	note := strings.TrimSpace(r.Note)
+	if len(note) > 255 {
+		return TimeOffCreateData{}, ValidationError{Field: "note", Message: "is too long"}
+	}
+	return TimeOffCreateData{Date: date, Kind: kind, DurationSec: duration, Note: note}, nil
+}
+
+// ParseDateRange は YYYY-MM-DD の from / to クエリを検証する。空文字は無制限。
+func ParseDateRange(from, to string) (string, string, error) {
+	from = strings.TrimSpace(from)
+	to = strings.TrimSpace(to)
+	if from != "" {
+		if _, err := time.Parse("2006-01-02", from); err != nil {
+			return "", "", ValidationError{Field: "from", Message: "must be YYYY-MM-DD"}
+		}
+	}
+	if to != "" {
+		if _, err := time.Parse("2006-01-02", to); err != nil {
+			return "", "", ValidationError{Field: "to", Message: "must be YYYY-MM-DD"}
+		}
+	}
+	if from != "" && to != "" && from > to {
+		return "", "", ValidationError{Field: "from", Message: "must not be after to"}
+	}
+	return from, to, nil
+}
@@ -0,0 +1,91 @@
+package dto
+
+import (
+	"strings"
+)
+
+const totpDigits = 6
+
+// TwoFactorEnableRequest は認証アプリに表示されたコードと現在のパスワードで TOTP の登録を確定する。
+type TwoFactorEnableRequest struct {
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+// Normalize は TOTP コードと現在のパスワードを返す。
+func (r TwoFactorEnableRequest) Normalize() (string, string, error) {
+	code, err := normalizeTOTPCode("code", r.Code)
+	if err != nil {
+		return "", "", err
+	}
+	password, err := TwoFactorPasswordRequest{Password: r.Password}.Normalize()
+	if err != nil {
+		return "", "", err
+	}
+	return code, password, nil
+}
+
+// TwoFactorPasswordRequest は二要素認証の設定・無効化とリカバリーコードの再発行で、現在のパスワードを受け取る。
+type TwoFactorPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+func (r TwoFactorPasswordRequest) Normalize() (string, error) {
+	if r.Password == "" {
+		return "", ValidationError{Field: "password", Message: "is required"}
+	}
+	return r.Password, nil
+}
+
+// TwoFactorLoginRequest はログインのチャレンジに TOTP コードかリカバリーコードのどちらかで応える。
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// TwoFactorLoginData は正規化済みの入力。Code と RecoveryCode はどちらか一方だけが入る。
+type TwoFactorLoginData struct {
+	ChallengeToken string
+	Code           string
+	RecoveryCode   string
+}
+
+func (r TwoFactorLoginRequest) Normalize() (TwoFactorLoginData, error) {
+	data := TwoFactorLoginData{ChallengeToken: strings.TrimSpace(r.ChallengeToken)}
+	if data.ChallengeToken == "" {
+		return TwoFactorLoginData{}, ValidationError{Field: "challenge_token", Message: "is required"}
+	}
+	code := strings.TrimSpace(r.Code)
+	recovery := NormalizeRecoveryCode(r.RecoveryCode)
+	switch {
+	case code != "" && recovery != "":
+		return TwoFactorLoginData{}, ValidationError{Message: "specify either code or recovery_code"}
+	case code != "":
+		normalized, err := normalizeTOTPCode("code", code)
+		if err != nil {
+			return TwoFactorLoginData{}, err
+		}
+		data.Code = normalized
+	case recovery != "":
+		data.RecoveryCode = recovery
+	default:
+		return TwoFactorLoginData{}, ValidationError{Message: "code or recovery_code is required"}
+	}
+	return data, nil
+}
+
+// NormalizeRecoveryCode は表示用の区切りと大文字小文字の違いを取り除く。保存するハッシュもこの形から作る。
+func NormalizeRecoveryCode(raw string) string {
+	replacer := strings.NewReplacer("-", "", " ", "")
+	return strings.ToUpper(replacer.Replace(strings.TrimSpace(raw)))
+}
+
+// normalizeTOTPCode は認証アプリが 3 桁ずつ空けて表示する場合に備えて空白を除き、6 桁の数字かを検証する。
+func normalizeTOTPCode(field, raw string) (string, error) {
+	code := strings.ReplaceAll(strings.TrimSpace(raw), " ", "")
+	if len(code) != totpDigits || strings.Trim(code, "0123456789") != "" {
+		return "", ValidationError{Field: field, Message: "must be a 6-digit code"}
+	}
+	return code, nil
+}
@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+// maxVerificationEmailsPerDay は 1 ユーザーに 24 時間で送る確認メールの上限。
+const maxVerificationEmailsPerDay = 5
+
+// ErrInvalidEmailVerificationToken は未知・期限切れ・使用済みの確認トークンを表す。
+var ErrInvalidEmailVerificationToken = errors.New("invalid or expired verification token")
+
+// EmailVerificationUsecase は確認メールの送信と、リンクからの確認を扱う。
+// サインアップ時の確認とメールアドレス変更の確認は同じトークンで扱い、確定時にトークンのアドレスを確認済みにする。
+type EmailVerificationUsecase struct {
+	users         repository.UserRepository
+	verifications repository.EmailVerificationRepository
+	mailer        provider.Mailer
+	cfg           provider.AppConfig
+	clock         provider.Clock
+}
+
+func NewEmailVerificationUsecase(users repository.UserRepository, verifications repository.EmailVerificationRepository, mailer provider.Mailer, cfg provider.AppConfig, clock provider.Clock) *EmailVerificationUsecase {
+	return &EmailVerificationUsecase{users: users, verifications: verifications, mailer: mailer, cfg: cfg, clock: clock}
+}
+
+// Send は現在のメールアドレスに確認リンクを送る。サインアップ直後に呼ぶ。
+func (u *EmailVerificationUsecase) Send(ctx context.Context, user *entity.User) error {
+	link, err := u.issue(ctx, user, user.Email)
+	if err != nil {
+		return err
+	}
+	return u.mailer.Send(ctx, provider.MailMessage{
+		To:      user.Email,
+		Subject: "ChronoMe メールアドレス確認のお願い",
+		Body: fmt.Sprintf("ChronoMe にご登録いただきありがとうございます。\n\n"+
+			"以下のリンクから %d 時間以内にメールアドレスを確認してください。\n%s\n\n"+
+			"このメールに心当たりがない場合は破棄してください。\n",
+			int(u.cfg.EmailVerificationTTL().Hours()), link),
+	})
+}
+
+// SendChange は変更先のメールアドレスに確認リンクを送る。アドレスはリンクを開くまで変わらない。
+func (u *EmailVerificationUsecase) SendChange(ctx context.Context, user *entity.User, email string) error {
+	link, err := u.issue(ctx, user, email)
+	if err != nil {
+		return err
+	}
+	return u.mailer.Send(ctx, provider.MailMessage{
+		To:      email,
+		Subject: "ChronoMe メールアドレス確認のお願い",
+		Body: fmt.Sprintf("ChronoMe のメールアドレスをこのアドレスに変更する依頼を受け付けました。\n\n"+
+			"以下のリンクから %d 時間以内に変更を確定してください。\n%s\n\n"+
+			"このメールに心当たりがない場合は破棄してください。メールアドレスは変更されません。\n",
+			int(u.cfg.EmailVerificationTTL().Hours()), link),
+	})
+}
+
+// Resend は未確認のユーザーに確認メールを送り直す。
+// 確認済みのアドレスや未登録のアドレスでは何もせず nil を返す。前回の送信から EmailVerificationResendInterval
+// 経っていない場合と、24 時間の上限に達した場合も、アカウントの状態を応答で明かさないよう送らずに nil を返す。
+func (u *EmailVerificationUsecase) Resend(ctx context.Context, input dto.EmailVerifyResendRequest) error {
+	email, err := input.Normalize()
+	if err != nil {
+		return err
+	}
+	user, err := u.users.GetByEmail(ctx, email)
+	if err != nil || user.EmailVerified() {
+		return nil
+	}
+	now := u.clock.Now()
+	recent, err := u.verifications.ListCreatedSince(ctx, user.ID, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if len(recent) >= maxVerificationEmailsPerDay {
+		return nil
+	}
+	if len(recent) > 0 && now.Before(recent[0].CreatedAt.Add(u.cfg.EmailVerificationResendInterval())) {
+		return nil
+	}
+	return u.Send(ctx, user)
+}
+
+// Confirm は確認トークンを使用済みにし、トークンのアドレスを確認済みとしてユーザーに設定する。
+func (u *EmailVerificationUsecase) Confirm(ctx context.Context, input dto.EmailVerifyRequest) (*entity.User, error) {
+	raw, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	token, err := u.verifications.GetByHash(ctx, hashToken(raw))
+	if err != nil {
+		return nil, ErrInvalidEmailVerificationToken
+	}
+	now := u.clock.Now()
+	if !token.Usable(now) {
+		return nil, ErrInvalidEmailVerificationToken
+	}
+	user, err := u.users.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, ErrInvalidEmailVerificationToken
+	}
+	// 依頼後に同じアドレスで別アカウントが作られている場合は確定しない。
+	if owner, err := u.users.GetByEmail(ctx, token.Email); err == nil && owner.ID != user.ID {
+		return nil, ErrEmailTaken
+	}
+	marked, err := u.verifications.MarkUsed(ctx, token.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, ErrInvalidEmailVerificationToken
+	}
+	user.Email = token.Email
+	user.EmailVerifiedAt = &now
+	if err := u.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// issue はユーザーの未使用トークンを無効にしてから新しいトークンを保存し、メールに載せるリンクを返す。
+func (u *EmailVerificationUsecase) issue(ctx context.Context, user *entity.User, email string) (string, error) {
+	now := u.clock.Now()
+	if err := u.verifications.InvalidateByUser(ctx, user.ID, now); err != nil {
+		return "", err
+	}
+	raw, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	token := &entity.EmailVerificationToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: hashToken(raw),
+		ExpiresAt: now.Add(u.cfg.EmailVerificationTTL()),
+		CreatedAt: now,
+	}
+	if err := u.verifications.Create(ctx, token); err != nil {
+		return "", err
+	}
+	return u.cfg.EmailVerificationURL() + "?token=" + url.QueryEscape(raw), nil
+}
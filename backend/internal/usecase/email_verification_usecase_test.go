@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+	"chronome/test/fakes"
+)
+
+// verificationConfig は stubConfig のメール確認モードだけを差し替える。
+type verificationConfig struct {
+	stubConfig
+	mode provider.EmailVerificationMode
+}
+
+func (c verificationConfig) EmailVerificationMode() provider.EmailVerificationMode {
+	return c.mode
+}
+
+func TestEmailVerificationUsecase_ConfirmMarksEmailVerified(t *testing.T) {
+	user := userWithPassword(t, "user@example.com", "password-1")
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	mailer := &fakes.RecordingMailer{}
+	uc := NewEmailVerificationUsecase(memoryUsers(user), memoryEmailVerifications(), mailer, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	require.NoError(t, uc.Send(context.Background(), user))
+	sent := mailer.Messages()
+	require.Len(t, sent, 1)
+	require.Equal(t, "user@example.com", sent[0].To)
+	require.Contains(t, sent[0].Body, "24 時間以内")
+	require.False(t, user.EmailVerified())
+
+	confirmed, err := uc.Confirm(context.Background(), dto.EmailVerifyRequest{Token: verifyTokenFromMail(t, sent[0].Body)})
+	require.NoError(t, err)
+	require.Equal(t, "user@example.com", confirmed.Email)
+	require.NotNil(t, user.EmailVerifiedAt)
+	require.True(t, user.EmailVerifiedAt.Equal(now))
+
+	_, err = uc.Confirm(context.Background(), dto.EmailVerifyRequest{Token: "bogus"})
+	require.ErrorIs(t, err, ErrInvalidEmailVerificationToken)
+}
+
+func TestEmailVerificationUsecase_ResendIsRateLimited(t *testing.T) {
+	user := userWithPassword(t, "user@example.com", "password-1")
+	verified := userWithPassword(t, "verified@example.com", "password-2")
+	verifiedAt := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	verified.EmailVerifiedAt = &verifiedAt
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }}
+	mailer := &fakes.RecordingMailer{}
+	uc := NewEmailVerificationUsecase(memoryUsers(user, verified), memoryEmailVerifications(), mailer, stubConfig{}, clock)
+
+	// 未登録と確認済みのアドレスでは何も送らず、同じく成功を返す。
+	require.NoError(t, uc.Resend(context.Background(), dto.EmailVerifyResendRequest{Email: "nobody@example.com"}))
+	require.NoError(t, uc.Resend(context.Background(), dto.EmailVerifyResendRequest{Email: "verified@example.com"}))
+	require.Empty(t, mailer.Messages())
+
+	require.NoError(t, uc.Resend(context.Background(), dto.EmailVerifyResendRequest{Email: "User@Example.com"}))
+	require.Len(t, mailer.Messages(), 1)
+	// 間隔内の再送は送らずに成功を返し、未登録のアドレスと区別できないようにする。
+	now = now.Add(20 * time.Second)
+	require.NoError(t, uc.Resend(context.Background(), dto.EmailVerifyResendRequest{Email: "user@example.com"}))
+	require.Len(t, mailer.Messages(), 1)
+
+	for i := 1; i < maxVerificationEmailsPerDay; i++ {
+		now = now.Add(time.Minute)
+		require.NoError(t, uc.Resend(context.Background(), dto.EmailVerifyResendRequest{Email: "user@example.com"}))
+	}
+	require.Len(t, mailer.Messages(), maxVerificationEmailsPerDay)
+
+	// 24 時間の上限に達したら、最も古い送信から 24 時間経つまで送らない。
+	now = now.Add(time.Hour)
+	require.NoError(t, uc.Resend(context.Background(), dto.EmailVerifyResendRequest{Email: "user@example.com"}))
+	require.Len(t, mailer.Messages(), maxVerificationEmailsPerDay)
+	now = now.Add(23 * time.Hour)
+	require.NoError(t, uc.Resend(context.Background(), dto.EmailVerifyResendRequest{Email: "user@example.com"}))
+	require.Len(t, mailer.Messages(), maxVerificationEmailsPerDay+1)
+
+	// 以前のリンクは再送で無効になり、最後に送ったリンクだけが使える。
+	sent := mailer.Messages()
+	_, err := uc.Confirm(context.Background(), dto.EmailVerifyRequest{Token: verifyTokenFromMail(t, sent[0].Body)})
+	require.ErrorIs(t, err, ErrInvalidEmailVerificationToken)
+	_, err = uc.Confirm(context.Background(), dto.EmailVerifyRequest{Token: verifyTokenFromMail(t, sent[len(sent)-1].Body)})
+	require.NoError(t, err)
+}
+
+func TestAuthUsecase_LoginRequiresVerifiedEmailWhenConfigured(t *testing.T) {
+	user := userWithPassword(t, "user@example.com", "password-1")
+	required := NewAuthUsecase(memoryUsers(user), verificationConfig{mode: provider.EmailVerificationRequired})
+	limited := NewAuthUsecase(memoryUsers(user), verificationConfig{mode: provider.EmailVerificationLimited})
+
+	_, err := required.Login(context.Background(), "user@example.com", "password-1")
+	require.ErrorIs(t, err, ErrEmailNotVerified)
+	// パスワードが違う場合は未確認かどうかを明かさない。
+	_, err = required.Login(context.Background(), "user@example.com", "wrong-password")
+	require.EqualError(t, err, "invalid credentials")
+	_, err = limited.Login(context.Background(), "user@example.com", "password-1")
+	require.NoError(t, err)
+
+	verifiedAt := time.Now()
+	user.EmailVerifiedAt = &verifiedAt
+	_, err = required.Login(context.Background(), "user@example.com", "password-1")
+	require.NoError(t, err)
+}
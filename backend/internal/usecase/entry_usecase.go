@@ -143,10 +143,15 @@ func (u *EntryUsecase) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUI
 }
 
 func (u *EntryUsecase) loadTags(ctx context.Context, userID uuid.UUID, tagIDs []uuid.UUID) ([]entity.Tag, error) {
+	return loadOwnedTags(ctx, u.tags, userID, tagIDs)
+}
+
+// loadOwnedTags はユーザー所有の既存タグだけを重複なしで読み込む。
+func loadOwnedTags(ctx context.Context, tags repository.TagRepository, userID uuid.UUID, tagIDs []uuid.UUID) ([]entity.Tag, error) {
 	if len(tagIDs) == 0 {
 		return nil, nil
 	}
-	if u.tags == nil {
+	if tags == nil {
 		return nil, errors.New("tag repository is not configured")
 	}
 	seen := make(map[uuid.UUID]struct{}, len(tagIDs))
@@ -159,7 +164,7 @@ func (u *EntryUsecase) loadTags(ctx context.Context, userID uuid.UUID, tagIDs []
 			continue
 		}
 		// GetByID に userID を渡し、存在確認と所有者確認を同時に行う。
-		tag, err := u.tags.GetByID(ctx, userID, id)
+		tag, err := tags.GetByID(ctx, userID, id)
 		if err != nil {
 			return nil, dto.ValidationError{Field: "tag_ids", Message: "contains unknown tag"}
 		}
@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+const (
+	defaultRecentEntryLimit = 50
+	maxRecentEntryLimit     = 200
+)
+
+// FavoriteUsecase はお気に入りの管理とお気に入りからのエントリ開始を扱う。
+type FavoriteUsecase struct {
+	favorites repository.FavoriteRepository
+	entries   repository.EntryRepository
+	tags      repository.TagRepository
+	clock     provider.Clock
+}
+
+func NewFavoriteUsecase(favorites repository.FavoriteRepository, entries repository.EntryRepository, tags repository.TagRepository, clock provider.Clock) *FavoriteUsecase {
+	return &FavoriteUsecase{favorites: favorites, entries: entries, tags: tags, clock: clock}
+}
+
+// RecentCombination は直近エントリから抽出した入力の組み合わせを表す。
+type RecentCombination struct {
+	Title         string       `json:"title"`
+	ProjectID     *uuid.UUID   `json:"project_id,omitempty"`
+	IsBreak       bool         `json:"is_break"`
+	Ratio         float64      `json:"ratio"`
+	Tags          []entity.Tag `json:"tags"`
+	LastStartedAt time.Time    `json:"last_started_at"`
+	Count         int          `json:"count"`
+}
+
+func (u *FavoriteUsecase) List(ctx context.Context, userID uuid.UUID) ([]entity.Favorite, error) {
+	return u.favorites.ListByUser(ctx, userID)
+}
+
+func (u *FavoriteUsecase) Create(ctx context.Context, userID uuid.UUID, input dto.FavoriteCreateRequest) (*entity.Favorite, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := loadOwnedTags(ctx, u.tags, userID, data.TagIDs)
+	if err != nil {
+		return nil, err
+	}
+	favorite := &entity.Favorite{
+		ID:        uuid.New(),
+		UserID:    userID,
+		ProjectID: data.ProjectID,
+		Title:     data.Title,
+		Notes:     data.Notes,
+		IsBreak:   data.IsBreak,
+		Ratio:     data.Ratio,
+		Tags:      tags,
+	}
+	if err := favorite.Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.favorites.Create(ctx, favorite); err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		if err := u.favorites.ReplaceTags(ctx, favorite, tagIDsFrom(tags)); err != nil {
+			return nil, err
+		}
+	}
+	return favorite, nil
+}
+
+func (u *FavoriteUsecase) Update(ctx context.Context, userID uuid.UUID, id uuid.UUID, input dto.FavoriteUpdateRequest) (*entity.Favorite, error) {
+	updates, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	favorite, err := u.favorites.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if updates.Title != nil {
+		favorite.Title = *updates.Title
+	}
+	if updates.Notes != nil {
+		favorite.Notes = *updates.Notes
+	}
+	if updates.ProjectIDSet {
+		favorite.ProjectID = updates.ProjectID
+	}
+	if updates.IsBreak != nil {
+		favorite.IsBreak = *updates.IsBreak
+	}
+	if updates.Ratio != nil {
+		favorite.Ratio = *updates.Ratio
+	}
+	var tags []entity.Tag
+	if updates.TagIDsSet {
+		tags, err = loadOwnedTags(ctx, u.tags, userID, updates.TagIDs)
+		if err != nil {
+			return nil, err
+		}
+		favorite.Tags = tags
+	}
+	if err := favorite.Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.favorites.Update(ctx, favorite); err != nil {
+		return nil, err
+	}
+	if updates.TagIDsSet {
+		if err := u.favorites.ReplaceTags(ctx, favorite, tagIDsFrom(tags)); err != nil {
+			return nil, err
+		}
+	}
+	return favorite, nil
+}
+
+func (u *FavoriteUsecase) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return errors.New("id is required")
+	}
+	return u.favorites.Delete(ctx, userID, id)
+}
+
+// Start はお気に入りの内容で現在時刻から実行中エントリを作成する。
+func (u *FavoriteUsecase) Start(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+	favorite, err := u.favorites.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	entry := favorite.NewEntry(u.clock.Now())
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.entries.Create(ctx, entry); err != nil {
+		return nil, err
+	}
+	if len(entry.Tags) > 0 {
+		if err := u.entries.ReplaceTags(ctx, entry, tagIDsFrom(entry.Tags)); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// Recent は直近 limit 件のエントリから、重複を除いた入力の組み合わせを新しい順に返す。
+func (u *FavoriteUsecase) Recent(ctx context.Context, userID uuid.UUID, limit int) ([]RecentCombination, error) {
+	if limit <= 0 {
+		limit = defaultRecentEntryLimit
+	}
+	if limit > maxRecentEntryLimit {
+		limit = maxRecentEntryLimit
+	}
+	entries, err := u.entries.ListByUser(ctx, userID, repository.EntryFilter{Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	// repository は started_at の降順で返すため、最初に現れた組み合わせが最新になる。
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedAt.After(entries[j].StartedAt)
+	})
+	index := make(map[string]int)
+	combinations := make([]RecentCombination, 0)
+	for _, entry := range entries {
+		key := combinationKey(entry)
+		if i, ok := index[key]; ok {
+			combinations[i].Count++
+			continue
+		}
+		index[key] = len(combinations)
+		tags := entry.Tags
+		if tags == nil {
+			tags = []entity.Tag{}
+		}
+		combinations = append(combinations, RecentCombination{
+			Title:         entry.Title,
+			ProjectID:     entry.ProjectID,
+			IsBreak:       entry.IsBreak,
+			Ratio:         entry.Ratio,
+			Tags:          tags,
+			LastStartedAt: entry.StartedAt,
+			Count:         1,
+		})
+	}
+	return combinations, nil
+}
+
+func combinationKey(entry entity.Entry) string {
+	// タグの付与順が違うだけのエントリは同じ組み合わせとして扱う。
+	tagIDs := make([]string, 0, len(entry.Tags))
+	for _, tag := range entry.Tags {
+		tagIDs = append(tagIDs, tag.ID.String())
+	}
+	sort.Strings(tagIDs)
+	project := ""
+	if entry.ProjectID != nil {
+		project = entry.ProjectID.String()
+	}
+	breakFlag := "0"
+	if entry.IsBreak {
+		breakFlag = "1"
+	}
+	return strings.Join([]string{entry.Title, project, breakFlag, strings.Join(tagIDs, ",")}, "|")
+}
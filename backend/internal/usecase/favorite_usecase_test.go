@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/test/fakes"
+)
+
+func TestFavoriteUsecase_StartCopiesFavoriteIntoRunningEntry(t *testing.T) {
+	userID := uuid.New()
+	projectID := uuid.New()
+	tag := entity.Tag{ID: uuid.New(), UserID: userID, Name: "Deep Work", Color: "#111111"}
+	favorite := &entity.Favorite{ID: uuid.New(), UserID: userID, ProjectID: &projectID, Title: "Review", Notes: "PRs", Ratio: 0.5, Tags: []entity.Tag{tag}}
+	favoriteRepo := &fakes.FakeFavoriteRepository{
+		GetByIDFn: func(_ context.Context, gotUser uuid.UUID, id uuid.UUID) (*entity.Favorite, error) {
+			require.Equal(t, userID, gotUser)
+			return favorite, nil
+		},
+	}
+	var created *entity.Entry
+	var replaced []uuid.UUID
+	entryRepo := &fakes.FakeEntryRepository{
+		CreateFn: func(_ context.Context, entry *entity.Entry) error {
+			created = entry
+			return nil
+		},
+		ReplaceTagsFn: func(_ context.Context, _ *entity.Entry, tagIDs []uuid.UUID) error {
+			replaced = tagIDs
+			return nil
+		},
+	}
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	uc := NewFavoriteUsecase(favoriteRepo, entryRepo, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	entry, err := uc.Start(context.Background(), userID, favorite.ID)
+	require.NoError(t, err)
+	require.Equal(t, created, entry)
+	require.Equal(t, now, entry.StartedAt)
+	require.Nil(t, entry.EndedAt)
+	require.Equal(t, "Review", entry.Title)
+	require.Equal(t, "PRs", entry.Notes)
+	require.Equal(t, &projectID, entry.ProjectID)
+	require.Equal(t, 0.5, entry.Ratio)
+	require.Equal(t, []uuid.UUID{tag.ID}, replaced)
+}
+
+func TestFavoriteUsecase_RecentGroupsCombinations(t *testing.T) {
+	projectID := uuid.New()
+	tagA := entity.Tag{ID: uuid.New(), Name: "A"}
+	tagB := entity.Tag{ID: uuid.New(), Name: "B"}
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	var capturedFilter repository.EntryFilter
+	entryRepo := &fakes.FakeEntryRepository{
+		ListFn: func(_ context.Context, _ uuid.UUID, filter repository.EntryFilter) ([]entity.Entry, error) {
+			capturedFilter = filter
+			return []entity.Entry{
+				{Title: "Coding", ProjectID: &projectID, StartedAt: base.Add(3 * time.Hour), Ratio: 1, Tags: []entity.Tag{tagB, tagA}},
+				{Title: "Meeting", StartedAt: base.Add(2 * time.Hour), Ratio: 1},
+				{Title: "Coding", ProjectID: &projectID, StartedAt: base.Add(time.Hour), Ratio: 1, Tags: []entity.Tag{tagA, tagB}},
+				{Title: "Coding", StartedAt: base, Ratio: 1},
+			}, nil
+		},
+	}
+	uc := NewFavoriteUsecase(&fakes.FakeFavoriteRepository{}, entryRepo, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+
+	combinations, err := uc.Recent(context.Background(), uuid.New(), 1000)
+	require.NoError(t, err)
+	require.Equal(t, maxRecentEntryLimit, capturedFilter.Limit)
+	require.Len(t, combinations, 3)
+	require.Equal(t, "Coding", combinations[0].Title)
+	require.Equal(t, 2, combinations[0].Count)
+	require.Equal(t, base.Add(3*time.Hour), combinations[0].LastStartedAt)
+	require.Equal(t, "Meeting", combinations[1].Title)
+	require.Nil(t, combinations[2].ProjectID)
+}
@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+// streak を遡る上限。これより古い期間は集計しない。
+const (
+	goalStreakLookbackDays   = 366
+	goalStreakLookbackWeeks  = 104
+	goalStreakLookbackMonths = 24
+)
+
+// GoalUsecase は作業時間目標の管理と達成状況の集計を扱う。
+type GoalUsecase struct {
+	goals    repository.GoalRepository
+	entries  repository.EntryRepository
+	projects repository.ProjectRepository
+	tags     repository.TagRepository
+	clock    provider.Clock
+}
+
+func NewGoalUsecase(goals repository.GoalRepository, entries repository.EntryRepository, projects repository.ProjectRepository, tags repository.TagRepository, clock provider.Clock) *GoalUsecase {
+	return &GoalUsecase{goals: goals, entries: entries, projects: projects, tags: tags, clock: clock}
+}
+
+// GoalProgress は指定日を含む期間の達成状況と連続達成数を返す。
+type GoalProgress struct {
+	Goal             entity.Goal `json:"goal"`
+	PeriodStart      string      `json:"period_start"`
+	PeriodEnd        string      `json:"period_end"`
+	TrackedSeconds   int64       `json:"tracked_seconds"`
+	TargetSeconds    int64       `json:"target_seconds"`
+	RemainingSeconds int64       `json:"remaining_seconds"`
+	Completion       float64     `json:"completion"`
+	Completed        bool        `json:"completed"`
+	IsWorkingDay     bool        `json:"is_working_day"`
+	CurrentStreak    int         `json:"current_streak"`
+}
+
+func (u *GoalUsecase) List(ctx context.Context, userID uuid.UUID) ([]entity.Goal, error) {
+	return u.goals.ListByUser(ctx, userID)
+}
+
+func (u *GoalUsecase) Create(ctx context.Context, userID uuid.UUID, input dto.GoalCreateRequest) (*entity.Goal, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	// 対象のプロジェクト・タグは所有者確認を兼ねて存在を検証する。
+	if data.ProjectID != nil {
+		if _, err := u.projects.GetByID(ctx, userID, *data.ProjectID); err != nil {
+			return nil, dto.ValidationError{Field: "project_id", Message: "refers to unknown project"}
+		}
+	}
+	if data.TagID != nil {
+		if _, err := u.tags.GetByID(ctx, userID, *data.TagID); err != nil {
+			return nil, dto.ValidationError{Field: "tag_id", Message: "refers to unknown tag"}
+		}
+	}
+	goal := &entity.Goal{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Name:        data.Name,
+		Scope:       data.Scope,
+		ProjectID:   data.ProjectID,
+		TagID:       data.TagID,
+		Period:      data.Period,
+		TargetSec:   data.TargetSec,
+		WorkingDays: data.WorkingDays,
+	}
+	if err := goal.Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.goals.Create(ctx, goal); err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+func (u *GoalUsecase) Update(ctx context.Context, userID uuid.UUID, id uuid.UUID, input dto.GoalUpdateRequest) (*entity.Goal, error) {
+	updates, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	goal, err := u.goals.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if updates.Name != nil {
+		goal.Name = *updates.Name
+	}
+	if updates.TargetSec != nil {
+		goal.TargetSec = *updates.TargetSec
+	}
+	if updates.WorkingDays != nil {
+		goal.WorkingDays = *updates.WorkingDays
+	}
+	if err := goal.Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.goals.Update(ctx, goal); err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+func (u *GoalUsecase) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return errors.New("id is required")
+	}
+	return u.goals.Delete(ctx, userID, id)
+}
+
+// Progress は at を含む期間の達成状況を返す。期間境界は at のロケーション (ユーザーのタイムゾーン) で決める。
+func (u *GoalUsecase) Progress(ctx context.Context, userID uuid.UUID, id uuid.UUID, at time.Time) (*GoalProgress, error) {
+	goal, err := u.goals.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	loc := at.Location()
+	current := goal.PeriodStart(at)
+	earliest := current
+	for i := 0; i < goalStreakLookback(goal.Period); i++ {
+		earliest = goal.PrevPeriod(earliest)
+	}
+	from := earliest.In(time.UTC)
+	to := goal.NextPeriod(current).In(time.UTC)
+	filter := repository.EntryFilter{From: &from, To: &to, ProjectID: goal.ProjectID, TagID: goal.TagID}
+	entries, err := u.entries.ListByUser(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := u.clock.Now()
+	totals := make(map[string]int64)
+	for _, entry := range entries {
+		if !goal.Matches(entry) {
+			continue
+		}
+		// 実行中エントリは保存済み duration が古いため現在時刻で補う。
+		if entry.IsRunning() {
+			entry.UpdateDuration(now)
+		}
+		key := goal.PeriodStart(entry.StartedAt.In(loc)).Format("2006-01-02")
+		totals[key] += entry.DurationSec
+	}
+
+	tracked := totals[current.Format("2006-01-02")]
+	progress := &GoalProgress{
+		Goal:           *goal,
+		PeriodStart:    current.Format("2006-01-02"),
+		PeriodEnd:      goal.NextPeriod(current).AddDate(0, 0, -1).Format("2006-01-02"),
+		TrackedSeconds: tracked,
+		TargetSeconds:  goal.TargetSec,
+		Completion:     float64(tracked) / float64(goal.TargetSec),
+		Completed:      tracked >= goal.TargetSec,
+		IsWorkingDay:   goal.WorkingDays.Contains(at.Weekday()),
+		CurrentStreak:  goalStreak(goal, current, totals),
+	}
+	if !progress.Completed {
+		progress.RemainingSeconds = goal.TargetSec - tracked
+	}
+	return progress, nil
+}
+
+// goalStreak は current から遡って連続で達成した期間数を数える。
+// 進行中の current は未達でも streak を途切れさせず、稼働日以外の日は数えも途切れさせもしない。
+func goalStreak(goal *entity.Goal, current time.Time, totals map[string]int64) int {
+	streak := 0
+	if goal.CountsForStreak(current) && totals[current.Format("2006-01-02")] >= goal.TargetSec {
+		streak++
+	}
+	start := current
+	for i := 0; i < goalStreakLookback(goal.Period); i++ {
+		start = goal.PrevPeriod(start)
+		if !goal.CountsForStreak(start) {
+			continue
+		}
+		if totals[start.Format("2006-01-02")] < goal.TargetSec {
+			break
+		}
+		streak++
+	}
+	return streak
+}
+
+func goalStreakLookback(period entity.GoalPeriod) int {
+	switch period {
+	case entity.GoalPeriodWeekly:
+		return goalStreakLookbackWeeks
+	case entity.GoalPeriodMonthly:
+		return goalStreakLookbackMonths
+	default:
+		return goalStreakLookbackDays
+	}
+}
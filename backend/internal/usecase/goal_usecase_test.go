@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+func TestGoalUsecase_ProgressStreakSkipsNonWorkingDays(t *testing.T) {
+	loc := time.FixedZone("JST", 9*3600)
+	// 2024-05-13 は月曜日。前週の月〜金に達成し、週末は記録なし。
+	today := time.Date(2024, 5, 13, 18, 0, 0, 0, loc)
+	tagID := uuid.New()
+	goal := &entity.Goal{
+		ID: uuid.New(), Name: "Deep work", Scope: entity.GoalScopeTag, TagID: &tagID,
+		Period: entity.GoalPeriodDaily, TargetSec: 4 * 3600, WorkingDays: entity.DefaultWorkingDays,
+	}
+	deepWork := []entity.Tag{{ID: tagID}}
+	var entries []entity.Entry
+	for _, day := range []int{8, 9, 10} {
+		entries = append(entries, entity.Entry{StartedAt: time.Date(2024, 5, day, 9, 0, 0, 0, loc).UTC(), DurationSec: 4 * 3600, Tags: deepWork, EndedAt: &today})
+	}
+	// 5/7 (火) は未達で streak が途切れる。
+	entries = append(entries, entity.Entry{StartedAt: time.Date(2024, 5, 7, 9, 0, 0, 0, loc).UTC(), DurationSec: 3600, Tags: deepWork, EndedAt: &today})
+	// 5/12 (日) の記録は稼働日外なので数えない。休憩は常に対象外。
+	entries = append(entries, entity.Entry{StartedAt: time.Date(2024, 5, 12, 9, 0, 0, 0, loc).UTC(), DurationSec: 5 * 3600, Tags: deepWork, EndedAt: &today})
+	entries = append(entries, entity.Entry{StartedAt: time.Date(2024, 5, 13, 9, 0, 0, 0, loc).UTC(), DurationSec: 3600, Tags: deepWork, IsBreak: true, EndedAt: &today})
+	// 5/11 (土) に 9:00 JST より前 (UTC では前日) 開始の記録はローカル日付で 5/11 に入る。
+	entries = append(entries, entity.Entry{StartedAt: time.Date(2024, 5, 11, 1, 0, 0, 0, loc).UTC(), DurationSec: 3600, Tags: deepWork, EndedAt: &today})
+	// 今日は実行中のエントリだけで 2 時間経過している。
+	entries = append(entries, entity.Entry{StartedAt: time.Date(2024, 5, 13, 16, 0, 0, 0, loc).UTC(), Tags: deepWork})
+
+	var captured repository.EntryFilter
+	entryRepo := &fakes.FakeEntryRepository{
+		ListFn: func(_ context.Context, _ uuid.UUID, filter repository.EntryFilter) ([]entity.Entry, error) {
+			captured = filter
+			return entries, nil
+		},
+	}
+	goalRepo := &fakes.FakeGoalRepository{
+		GetByIDFn: func(context.Context, uuid.UUID, uuid.UUID) (*entity.Goal, error) {
+			return goal, nil
+		},
+	}
+	uc := NewGoalUsecase(goalRepo, entryRepo, &fakes.FakeProjectRepository{}, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return today.UTC() }})
+
+	progress, err := uc.Progress(context.Background(), uuid.New(), goal.ID, today)
+	require.NoError(t, err)
+	require.Equal(t, "2024-05-13", progress.PeriodStart)
+	require.Equal(t, "2024-05-13", progress.PeriodEnd)
+	require.EqualValues(t, 2*3600, progress.TrackedSeconds)
+	require.EqualValues(t, 2*3600, progress.RemainingSeconds)
+	require.InDelta(t, 0.5, progress.Completion, 1e-9)
+	require.False(t, progress.Completed)
+	require.True(t, progress.IsWorkingDay)
+	require.Equal(t, 3, progress.CurrentStreak)
+	require.Equal(t, tagID, *captured.TagID)
+	require.Equal(t, time.Date(2024, 5, 13, 0, 0, 0, 0, loc).AddDate(0, 0, 1).UTC(), *captured.To)
+}
+
+func TestGoalUsecase_WeeklyProgressUsesMondayStart(t *testing.T) {
+	loc := time.UTC
+	at := time.Date(2024, 5, 15, 12, 0, 0, 0, loc)
+	projectID := uuid.New()
+	goal := &entity.Goal{
+		ID: uuid.New(), Name: "Client Y", Scope: entity.GoalScopeProject, ProjectID: &projectID,
+		Period: entity.GoalPeriodWeekly, TargetSec: 20 * 3600, WorkingDays: entity.DefaultWorkingDays,
+	}
+	ended := at
+	entryRepo := &fakes.FakeEntryRepository{
+		ListFn: func(context.Context, uuid.UUID, repository.EntryFilter) ([]entity.Entry, error) {
+			return []entity.Entry{
+				{StartedAt: time.Date(2024, 5, 13, 9, 0, 0, 0, loc), DurationSec: 12 * 3600, ProjectID: &projectID, EndedAt: &ended},
+				{StartedAt: time.Date(2024, 5, 14, 9, 0, 0, 0, loc), DurationSec: 9 * 3600, ProjectID: &projectID, EndedAt: &ended},
+				{StartedAt: time.Date(2024, 5, 6, 9, 0, 0, 0, loc), DurationSec: 21 * 3600, ProjectID: &projectID, EndedAt: &ended},
+				{StartedAt: time.Date(2024, 4, 29, 9, 0, 0, 0, loc), DurationSec: 10 * 3600, ProjectID: &projectID, EndedAt: &ended},
+			}, nil
+		},
+	}
+	goalRepo := &fakes.FakeGoalRepository{
+		GetByIDFn: func(context.Context, uuid.UUID, uuid.UUID) (*entity.Goal, error) {
+			return goal, nil
+		},
+	}
+	uc := NewGoalUsecase(goalRepo, entryRepo, &fakes.FakeProjectRepository{}, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return at }})
+
+	progress, err := uc.Progress(context.Background(), uuid.New(), goal.ID, at)
+	require.NoError(t, err)
+	require.Equal(t, "2024-05-13", progress.PeriodStart)
+	require.Equal(t, "2024-05-19", progress.PeriodEnd)
+	require.True(t, progress.Completed)
+	require.Zero(t, progress.RemainingSeconds)
+	require.Equal(t, 2, progress.CurrentStreak)
+}
+
+func TestGoalUsecase_CreateRejectsUnknownProject(t *testing.T) {
+	projectRepo := &fakes.FakeProjectRepository{
+		GetByIDFn: func(context.Context, uuid.UUID, uuid.UUID) (*entity.Project, error) {
+			return nil, errors.New("not found")
+		},
+	}
+	uc := NewGoalUsecase(&fakes.FakeGoalRepository{}, &fakes.FakeEntryRepository{}, projectRepo, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+	projectID := uuid.New().String()
+
+	_, err := uc.Create(context.Background(), uuid.New(), dto.GoalCreateRequest{
+		Name: "Client Y", Scope: "project", ProjectID: &projectID, Period: "weekly", TargetMinutes: 1200,
+	})
+	var valErr dto.ValidationError
+	require.True(t, errors.As(err, &valErr))
+	require.Equal(t, "project_id", valErr.Field)
+}
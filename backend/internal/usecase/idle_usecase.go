@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+const idleBreakTitle = "Break"
+
+// IdleUsecase は実行中エントリの heartbeat とアイドル区間の後処理を扱う。
+type IdleUsecase struct {
+	entries repository.EntryRepository
+	cfg     provider.AppConfig
+	clock   provider.Clock
+}
+
+func NewIdleUsecase(entries repository.EntryRepository, cfg provider.AppConfig, clock provider.Clock) *IdleUsecase {
+	return &IdleUsecase{entries: entries, cfg: cfg, clock: clock}
+}
+
+// Running はユーザーの実行中エントリをアイドル判定付きで返す。
+func (u *IdleUsecase) Running(ctx context.Context, userID uuid.UUID) ([]entity.Entry, error) {
+	entries, err := u.entries.ListByUser(ctx, userID, repository.EntryFilter{RunningOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	now := u.clock.Now()
+	for i := range entries {
+		entries[i].UpdateDuration(now)
+		entries[i].MarkIdle(now, u.cfg.IdleThreshold())
+	}
+	return entries, nil
+}
+
+// Heartbeat は実行中エントリの最終アクティビティを更新する。
+// すでにアイドルと判定される場合は区間を失わないよう更新せず、IdleSince を付けて返す。
+func (u *IdleUsecase) Heartbeat(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+	entry, err := u.runningEntry(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	now := u.clock.Now()
+	entry.MarkIdle(now, u.cfg.IdleThreshold())
+	if entry.IdleSince != nil {
+		entry.UpdateDuration(now)
+		return entry, nil
+	}
+	entry.LastActivityAt = &now
+	entry.UpdateDuration(now)
+	if err := u.entries.Update(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ResolveIdle はアイドル区間を keep / discard / break のいずれかで確定し、作成・更新したエントリを返す。
+func (u *IdleUsecase) ResolveIdle(ctx context.Context, userID uuid.UUID, id uuid.UUID, input dto.IdleResolveRequest) ([]entity.Entry, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := u.runningEntry(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	now := u.clock.Now()
+	entry.MarkIdle(now, u.cfg.IdleThreshold())
+	if entry.IdleSince == nil {
+		return nil, dto.ValidationError{Field: "entry", Message: "is not idle"}
+	}
+	idleStart := *entry.IdleSince
+
+	if data.Action == dto.IdleActionKeep {
+		// アイドル区間を作業として残し、最終アクティビティを現在時刻まで進める。
+		entry.LastActivityAt = &now
+		entry.IdleSince = nil
+		entry.UpdateDuration(now)
+		if err := u.entries.Update(ctx, entry); err != nil {
+			return nil, err
+		}
+		return []entity.Entry{*entry}, nil
+	}
+
+	// discard / break はどちらもアイドル開始時刻で元のエントリを打ち切る。
+	// 休憩と続きのエントリも同じトランザクションで作り、途中で失敗しても元のエントリだけが打ち切られないようにする。
+	entry.EndedAt = &idleStart
+	entry.IdleSince = nil
+	entry.UpdateDuration(now)
+	changes := repository.EntryChanges{Stop: []entity.Entry{*entry}}
+
+	if data.Action == dto.IdleActionBreak {
+		end := now
+		breakEntry := entity.Entry{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Title:     idleBreakTitle,
+			StartedAt: idleStart,
+			EndedAt:   &end,
+			IsBreak:   true,
+			Ratio:     1,
+		}
+		breakEntry.UpdateDuration(now)
+		changes.Create = append(changes.Create, breakEntry)
+	}
+
+	if data.Continue {
+		next := entry.ContinueAt(now)
+		next.LastActivityAt = &now
+		changes.Create = append(changes.Create, *next)
+	}
+	if err := u.entries.ApplyChanges(ctx, userID, changes); err != nil {
+		// 同時に確定・停止された場合は、休憩や続きのエントリを重ねて作らない。
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, dto.ValidationError{Field: "entry", Message: "is not running"}
+		}
+		return nil, err
+	}
+	return append([]entity.Entry{changes.Stop[0]}, changes.Create...), nil
+}
+
+// StopForgotten は AutoStopAfter を超えて heartbeat のない実行中エントリを最終アクティビティ時刻で停止する。
+// heartbeat を一度も受けていないエントリは、記録を開始時刻で打ち切らないよう止めない。
+func (u *IdleUsecase) StopForgotten(ctx context.Context) (int, error) {
+	after := u.cfg.AutoStopAfter()
+	if after <= 0 {
+		return 0, nil
+	}
+	now := u.clock.Now()
+	entries, err := u.entries.ListIdleRunning(ctx, now.Add(-after))
+	if err != nil {
+		return 0, err
+	}
+	stopped := 0
+	for i := range entries {
+		entry := &entries[i]
+		if entry.LastActivityAt == nil {
+			continue
+		}
+		end := entry.LastActiveAt()
+		entry.EndedAt = &end
+		entry.UpdateDuration(now)
+		if err := u.entries.Update(ctx, entry); err != nil {
+			return stopped, err
+		}
+		stopped++
+	}
+	return stopped, nil
+}
+
+func (u *IdleUsecase) runningEntry(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+	entry, err := u.entries.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsRunning() {
+		return nil, dto.ValidationError{Field: "entry", Message: "is not running"}
+	}
+	return entry, nil
+}
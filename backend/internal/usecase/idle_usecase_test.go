@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+func TestIdleUsecase_HeartbeatRecordsActivity(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	lastActive := now.Add(-5 * time.Minute)
+	var updated *entity.Entry
+	repo := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			return &entity.Entry{ID: id, UserID: userID, Title: "Focus", StartedAt: now.Add(-time.Hour), LastActivityAt: &lastActive, Ratio: 1}, nil
+		},
+		UpdateFn: func(_ context.Context, entry *entity.Entry) error {
+			updated = entry
+			return nil
+		},
+	}
+	uc := NewIdleUsecase(repo, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	entry, err := uc.Heartbeat(context.Background(), uuid.New(), uuid.New())
+	require.NoError(t, err)
+	require.Nil(t, entry.IdleSince)
+	require.NotNil(t, updated)
+	require.Equal(t, now, *updated.LastActivityAt)
+	require.EqualValues(t, 3600, updated.DurationSec)
+}
+
+func TestIdleUsecase_HeartbeatKeepsIdleGapUntilResolved(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	lastActive := now.Add(-time.Hour)
+	updated := false
+	repo := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			return &entity.Entry{ID: id, UserID: userID, Title: "Focus", StartedAt: now.Add(-2 * time.Hour), LastActivityAt: &lastActive, Ratio: 1}, nil
+		},
+		UpdateFn: func(context.Context, *entity.Entry) error {
+			updated = true
+			return nil
+		},
+	}
+	uc := NewIdleUsecase(repo, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	entry, err := uc.Heartbeat(context.Background(), uuid.New(), uuid.New())
+	require.NoError(t, err)
+	require.NotNil(t, entry.IdleSince)
+	require.Equal(t, lastActive, *entry.IdleSince)
+	require.False(t, updated)
+}
+
+func TestIdleUsecase_ResolveRejectsActiveEntry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			return &entity.Entry{ID: id, UserID: userID, Title: "Focus", StartedAt: now.Add(-time.Minute), Ratio: 1}, nil
+		},
+	}
+	uc := NewIdleUsecase(repo, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	_, err := uc.ResolveIdle(context.Background(), uuid.New(), uuid.New(), dto.IdleResolveRequest{Action: "discard"})
+	var valErr dto.ValidationError
+	require.True(t, errors.As(err, &valErr))
+	require.Contains(t, valErr.Error(), "is not idle")
+}
+
+func TestIdleUsecase_ResolveAppliesBreakAndContinuationTogether(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	lastActive := now.Add(-time.Hour)
+	tag := entity.Tag{ID: uuid.New(), Name: "Focus"}
+	var applied []repository.EntryChanges
+	applyErr := error(nil)
+	repo := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			return &entity.Entry{ID: id, UserID: userID, Title: "Focus", StartedAt: now.Add(-3 * time.Hour), LastActivityAt: &lastActive, Ratio: 1, Tags: []entity.Tag{tag}}, nil
+		},
+		ApplyChangesFn: func(_ context.Context, _ uuid.UUID, changes repository.EntryChanges) error {
+			applied = append(applied, changes)
+			return applyErr
+		},
+		UpdateFn: func(context.Context, *entity.Entry) error {
+			t.Fatal("entries must be changed through ApplyChanges")
+			return nil
+		},
+		CreateFn: func(context.Context, *entity.Entry) error {
+			t.Fatal("entries must be changed through ApplyChanges")
+			return nil
+		},
+	}
+	uc := NewIdleUsecase(repo, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+	userID := uuid.New()
+	entryID := uuid.New()
+	resume := true
+
+	result, err := uc.ResolveIdle(context.Background(), userID, entryID, dto.IdleResolveRequest{Action: "break", Continue: &resume})
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.Len(t, applied[0].Stop, 1)
+	require.Equal(t, entryID, applied[0].Stop[0].ID)
+	require.Equal(t, lastActive, *applied[0].Stop[0].EndedAt)
+	require.Len(t, applied[0].Create, 2)
+	require.True(t, applied[0].Create[0].IsBreak)
+	require.Equal(t, []entity.Tag{tag}, applied[0].Create[1].Tags)
+	require.Len(t, result, 3)
+
+	// 同時に確定・停止されていた場合は、休憩や続きを作らずに実行中でないと返す。
+	applyErr = repository.ErrNotFound
+	_, err = uc.ResolveIdle(context.Background(), userID, entryID, dto.IdleResolveRequest{Action: "break", Continue: &resume})
+	var valErr dto.ValidationError
+	require.True(t, errors.As(err, &valErr))
+	require.Contains(t, valErr.Error(), "is not running")
+
+	applyErr = errors.New("db down")
+	_, err = uc.ResolveIdle(context.Background(), userID, entryID, dto.IdleResolveRequest{Action: "discard"})
+	require.EqualError(t, err, "db down")
+}
+
+func TestIdleUsecase_StopForgottenEndsAtLastActivity(t *testing.T) {
+	now := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	started := now.Add(-20 * time.Hour)
+	lastActive := now.Add(-15 * time.Hour)
+	var cutoff time.Time
+	var updated []*entity.Entry
+	repo := &fakes.FakeEntryRepository{
+		ListIdleFn: func(_ context.Context, inactiveBefore time.Time) ([]entity.Entry, error) {
+			cutoff = inactiveBefore
+			return []entity.Entry{
+				{ID: uuid.New(), Title: "Forgotten", StartedAt: started, LastActivityAt: &lastActive, Ratio: 1},
+				{ID: uuid.New(), Title: "Never pinged", StartedAt: started, Ratio: 1},
+			}, nil
+		},
+		UpdateFn: func(_ context.Context, entry *entity.Entry) error {
+			updated = append(updated, entry)
+			return nil
+		},
+	}
+	uc := NewIdleUsecase(repo, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	stopped, err := uc.StopForgotten(context.Background())
+	require.NoError(t, err)
+	// heartbeat を一度も受けていないエントリは開始時刻で打ち切らない。
+	require.Equal(t, 1, stopped)
+	require.Equal(t, now.Add(-8*time.Hour), cutoff)
+	require.Len(t, updated, 1)
+	require.Equal(t, lastActive, *updated[0].EndedAt)
+	require.EqualValues(t, 5*3600, updated[0].DurationSec)
+}
+
+func TestIdleUsecase_RunningIgnoresEntriesWithoutHeartbeat(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	lastActive := now.Add(-time.Hour)
+	repo := &fakes.FakeEntryRepository{
+		ListFn: func(_ context.Context, userID uuid.UUID, _ repository.EntryFilter) ([]entity.Entry, error) {
+			return []entity.Entry{
+				{ID: uuid.New(), UserID: userID, Title: "Web timer", StartedAt: now.Add(-3 * time.Hour), Ratio: 1},
+				{ID: uuid.New(), UserID: userID, Title: "Desktop timer", StartedAt: now.Add(-3 * time.Hour), LastActivityAt: &lastActive, Ratio: 1},
+			}, nil
+		},
+	}
+	uc := NewIdleUsecase(repo, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	entries, err := uc.Running(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	// heartbeat を送らないクライアントのタイマーは、開始から閾値を過ぎてもアイドルにしない。
+	require.Nil(t, entries[0].IdleSince)
+	require.EqualValues(t, 3*3600, entries[0].DurationSec)
+	require.NotNil(t, entries[1].IdleSince)
+	require.Equal(t, lastActive, *entries[1].IdleSince)
+}
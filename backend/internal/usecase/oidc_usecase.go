@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/provider"
+)
+
+const (
+	// oidcLoginRequestTTL は ID プロバイダーへリダイレクトしてからコールバックを受けるまでに許す時間。
+	oidcLoginRequestTTL = 10 * time.Minute
+	// maxUserDisplayNameBytes は entity.User.Validate が受け入れる表示名の長さ。
+	maxUserDisplayNameBytes = 50
+)
+
+var (
+	// ErrInvalidOIDCLogin は未知・期限切れ・使用済みの state でコールバックを受けたことを表す。
+	ErrInvalidOIDCLogin = errors.New("invalid or expired oidc login")
+	// ErrIdentityRejected は ID プロバイダーとのトークン交換か、ID トークンの検証に失敗したことを表す。
+	ErrIdentityRejected = errors.New("identity provider login failed")
+	// ErrIdentityEmailNotVerified は ID プロバイダーがメールアドレスの所有を確認していないことを表す。
+	ErrIdentityEmailNotVerified = errors.New("identity provider has not verified the email address")
+	// ErrIdentitySignupDisabled は既存ユーザーに紐付かない ID で、ユーザーの作成も許可されていないことを表す。
+	ErrIdentitySignupDisabled = errors.New("no account is linked to this identity")
+)
+
+// OIDCUsecase は OpenID Connect でのログインと、ID プロバイダーのアカウントとユーザーの紐付けを扱う。
+type OIDCUsecase struct {
+	users      repository.UserRepository
+	identities repository.ExternalIdentityRepository
+	requests   repository.OIDCLoginRequestRepository
+	idp        provider.IdentityProvider
+	cfg        provider.AppConfig
+	clock      provider.Clock
+}
+
+func NewOIDCUsecase(users repository.UserRepository, identities repository.ExternalIdentityRepository, requests repository.OIDCLoginRequestRepository, idp provider.IdentityProvider, cfg provider.AppConfig, clock provider.Clock) *OIDCUsecase {
+	return &OIDCUsecase{users: users, identities: identities, requests: requests, idp: idp, cfg: cfg, clock: clock}
+}
+
+// OIDCLoginStart は ID プロバイダーへのリダイレクト先と、コールバックで照合する state の平文。
+type OIDCLoginStart struct {
+	AuthURL   string
+	State     string
+	ExpiresAt time.Time
+}
+
+// OIDCLoginResult はコールバックで確定したログインするユーザーと、ログイン後に戻すパス。
+type OIDCLoginResult struct {
+	User         *entity.User
+	RedirectPath string
+	// CredentialsReset は未確認のアカウントに紐付けたため、パスワードと二要素認証を無効にしたことを表す。
+	// 呼び出し側は既存の session とリフレッシュトークンを失効させる。
+	CredentialsReset bool
+}
+
+// Begin は state・nonce・PKCE の code_verifier を発行して保存し、ID プロバイダーへのリダイレクト先を返す。
+func (u *OIDCUsecase) Begin(ctx context.Context, redirectPath string) (*OIDCLoginStart, error) {
+	var secrets [3]string
+	for i := range secrets {
+		raw, err := generateOpaqueToken()
+		if err != nil {
+			return nil, err
+		}
+		secrets[i] = raw
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+	authURL, err := u.idp.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+	now := u.clock.Now()
+	request := &entity.OIDCLoginRequest{
+		ID:           uuid.New(),
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		RedirectPath: redirectPath,
+		ExpiresAt:    now.Add(oidcLoginRequestTTL),
+		CreatedAt:    now,
+	}
+	if err := u.requests.Create(ctx, request); err != nil {
+		return nil, err
+	}
+	return &OIDCLoginStart{AuthURL: authURL, State: state, ExpiresAt: request.ExpiresAt}, nil
+}
+
+// Complete はコールバックの state を確かめてから認可コードを ID トークンと交換し、ログインするユーザーを返す。
+// 紐付け済みの ID ならそのユーザー、未紐付けなら確認済みのメールアドレスが一致するユーザーに紐付け、
+// どちらもなければ設定で許可されている場合に限りユーザーを作成する。
+func (u *OIDCUsecase) Complete(ctx context.Context, state, code string) (*OIDCLoginResult, error) {
+	request, err := u.requests.GetByStateHash(ctx, hashToken(state))
+	if err != nil {
+		return nil, ErrInvalidOIDCLogin
+	}
+	now := u.clock.Now()
+	if !request.Usable(now) {
+		return nil, ErrInvalidOIDCLogin
+	}
+	// 交換の前に使用済みにして、同じコールバックの再送では 2 回ログインさせない。
+	marked, err := u.requests.MarkUsed(ctx, request.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, ErrInvalidOIDCLogin
+	}
+	claims, err := u.idp.Exchange(ctx, code, request.CodeVerifier, request.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIdentityRejected, err)
+	}
+	result, err := u.resolveUser(ctx, claims, now)
+	if err != nil {
+		return nil, err
+	}
+	result.RedirectPath = request.RedirectPath
+	return result, nil
+}
+
+func (u *OIDCUsecase) resolveUser(ctx context.Context, claims *provider.IdentityClaims, now time.Time) (*OIDCLoginResult, error) {
+	// 紐付け済みならメールアドレスが変わっていても同じユーザーとしてログインさせる。
+	identity, err := u.identities.GetBySubject(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		user, err := u.users.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := u.identities.TouchLastLogin(ctx, identity.ID, now); err != nil {
+			return nil, err
+		}
+		return &OIDCLoginResult{User: user}, nil
+	}
+
+	// 新しく紐付けるのは、ID プロバイダーがメールアドレスの所有を確認している場合に限る。
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" || !claims.EmailVerified {
+		return nil, ErrIdentityEmailNotVerified
+	}
+	result := &OIDCLoginResult{}
+	user, err := u.users.GetByEmail(ctx, email)
+	switch {
+	case err == nil && !user.EmailVerified():
+		// 未確認のアカウントはメールアドレスの持ち主が作ったとは限らない。登録した人がログインし続けられないよう、
+		// パスワードと二要素認証を無効にしてから紐付ける。パスワードは再設定すればまた使える。
+		hash, err := unusablePasswordHash()
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = hash
+		user.TOTPSecret = ""
+		user.TOTPEnabledAt = nil
+		user.TOTPLastStep = 0
+		user.EmailVerifiedAt = &now
+		if err := u.users.Update(ctx, user); err != nil {
+			return nil, err
+		}
+		result.CredentialsReset = true
+	case err == nil:
+		// 確認済みのアカウントにはそのまま紐付ける。
+	case !u.cfg.OIDCAllowSignup():
+		return nil, ErrIdentitySignupDisabled
+	default:
+		user, err = u.createUser(ctx, email, claims.Name, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	identity = &entity.ExternalIdentity{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		Issuer:      claims.Issuer,
+		Subject:     claims.Subject,
+		Email:       email,
+		CreatedAt:   now,
+		LastLoginAt: now,
+	}
+	if err := u.identities.Create(ctx, identity); err != nil {
+		return nil, err
+	}
+	result.User = user
+	return result, nil
+}
+
+// createUser は ID プロバイダーで確認済みのメールアドレスでユーザーを作る。確認メールは送らない。
+func (u *OIDCUsecase) createUser(ctx context.Context, email, name string, now time.Time) (*entity.User, error) {
+	hash, err := unusablePasswordHash()
+	if err != nil {
+		return nil, err
+	}
+	user := &entity.User{
+		ID:              uuid.New(),
+		Email:           email,
+		PasswordHash:    hash,
+		DisplayName:     truncateDisplayName(name),
+		EmailVerifiedAt: &now,
+	}
+	user.Normalize()
+	if err := user.Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// unusablePasswordHash は誰も知らない乱数のパスワードのハッシュを返す。
+// ID プロバイダーでだけログインするユーザーも、パスワードを再設定すればパスワードでログインできる。
+func unusablePasswordHash() (string, error) {
+	raw, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(raw), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// truncateDisplayName は ID プロバイダーの名前を、文字の途中で切らずに表示名の上限に収める。
+func truncateDisplayName(name string) string {
+	name = strings.TrimSpace(name)
+	for len(name) > maxUserDisplayNameBytes {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/usecase/provider"
+	"chronome/test/fakes"
+)
+
+// signupConfig は stubConfig の OIDC でのユーザー作成の可否だけを差し替える。
+type signupConfig struct {
+	stubConfig
+	allow bool
+}
+
+func (c signupConfig) OIDCAllowSignup() bool {
+	return c.allow
+}
+
+// stubIdentityProvider は Exchange で claims を返し、受け取った code_verifier と nonce を覚えておく。
+type stubIdentityProvider struct {
+	claims   provider.IdentityClaims
+	verifier string
+	nonce    string
+}
+
+func (p *stubIdentityProvider) AuthCodeURL(_ context.Context, state, nonce, codeVerifier string) (string, error) {
+	p.verifier, p.nonce = codeVerifier, nonce
+	return "https://idp.example/authorize?state=" + state, nil
+}
+
+func (p *stubIdentityProvider) Exchange(_ context.Context, code, codeVerifier, nonce string) (*provider.IdentityClaims, error) {
+	if code != "code" || codeVerifier != p.verifier || nonce != p.nonce {
+		return nil, errors.New("invalid_grant")
+	}
+	claims := p.claims
+	return &claims, nil
+}
+
+// memoryExternalIdentities は発行者と subject で引ける紐付けの保存先を fake に被せる。
+func memoryExternalIdentities() *fakes.FakeExternalIdentityRepository {
+	var stored []entity.ExternalIdentity
+	return &fakes.FakeExternalIdentityRepository{
+		CreateFn: func(_ context.Context, identity *entity.ExternalIdentity) error {
+			stored = append(stored, *identity)
+			return nil
+		},
+		GetBySubjectFn: func(_ context.Context, issuer, subject string) (*entity.ExternalIdentity, error) {
+			for _, identity := range stored {
+				if identity.Issuer == issuer && identity.Subject == subject {
+					copied := identity
+					return &copied, nil
+				}
+			}
+			return nil, errors.New("not found")
+		},
+	}
+}
+
+// memoryOIDCLoginRequests は state のハッシュで引けるログインの保存先を fake に被せる。
+func memoryOIDCLoginRequests() *fakes.FakeOIDCLoginRequestRepository {
+	stored := make(map[string]*entity.OIDCLoginRequest)
+	return &fakes.FakeOIDCLoginRequestRepository{
+		CreateFn: func(_ context.Context, request *entity.OIDCLoginRequest) error {
+			stored[request.StateHash] = request
+			return nil
+		},
+		GetByStateHashFn: func(_ context.Context, hash string) (*entity.OIDCLoginRequest, error) {
+			request, ok := stored[hash]
+			if !ok {
+				return nil, errors.New("not found")
+			}
+			copied := *request
+			return &copied, nil
+		},
+		MarkUsedFn: func(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+			for _, request := range stored {
+				if request.ID == id && request.UsedAt == nil {
+					request.UsedAt = &at
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	}
+}
+
+func newOIDCUsecaseForTest(users *fakes.FakeUserRepository, idp *stubIdentityProvider, cfg provider.AppConfig, now time.Time) *OIDCUsecase {
+	return NewOIDCUsecase(users, memoryExternalIdentities(), memoryOIDCLoginRequests(), idp, cfg, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+}
+
+// completeOIDCLogin は Begin で発行した state と正しい認可コードで Complete を呼ぶ。
+func completeOIDCLogin(t *testing.T, uc *OIDCUsecase, redirectPath string) (*OIDCLoginResult, error) {
+	t.Helper()
+	start, err := uc.Begin(context.Background(), redirectPath)
+	require.NoError(t, err)
+	return uc.Complete(context.Background(), start.State, "code")
+}
+
+func TestOIDCUsecase_LinksVerifiedEmailToExistingUser(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	user := userWithPassword(t, "alice@example.com", "password-1")
+	user.EmailVerifiedAt = &now
+	idp := &stubIdentityProvider{claims: provider.IdentityClaims{Issuer: "https://idp.example", Subject: "sub-1", Email: "Alice@Example.com", EmailVerified: true}}
+	uc := newOIDCUsecaseForTest(memoryUsers(user), idp, stubConfig{}, now)
+
+	result, err := completeOIDCLogin(t, uc, "/reports")
+	require.NoError(t, err)
+	require.Equal(t, user.ID, result.User.ID)
+	require.Equal(t, "/reports", result.RedirectPath)
+	require.False(t, result.CredentialsReset)
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("password-1")))
+
+	// 紐付けた後は ID プロバイダー側でメールアドレスが変わっても同じユーザーになる。
+	idp.claims.Email = "alice@new.example"
+	idp.claims.EmailVerified = false
+	result, err = completeOIDCLogin(t, uc, "/")
+	require.NoError(t, err)
+	require.Equal(t, user.ID, result.User.ID)
+}
+
+func TestOIDCUsecase_RejectsReusedOrExpiredState(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	user := userWithPassword(t, "alice@example.com", "password-1")
+	user.EmailVerifiedAt = &now
+	idp := &stubIdentityProvider{claims: provider.IdentityClaims{Issuer: "https://idp.example", Subject: "sub-1", Email: "alice@example.com", EmailVerified: true}}
+	uc := newOIDCUsecaseForTest(memoryUsers(user), idp, stubConfig{}, now)
+	ctx := context.Background()
+
+	start, err := uc.Begin(ctx, "/")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(start.AuthURL, "https://idp.example/authorize"))
+	_, err = uc.Complete(ctx, start.State, "wrong-code")
+	require.ErrorIs(t, err, ErrIdentityRejected)
+	// 交換に失敗した state もやり直しには使えない。
+	_, err = uc.Complete(ctx, start.State, "code")
+	require.ErrorIs(t, err, ErrInvalidOIDCLogin)
+	_, err = uc.Complete(ctx, "unknown-state", "code")
+	require.ErrorIs(t, err, ErrInvalidOIDCLogin)
+
+	start, err = uc.Begin(ctx, "/")
+	require.NoError(t, err)
+	uc.clock = fakes.FixedTimeProvider{NowFunc: func() time.Time { return now.Add(oidcLoginRequestTTL) }}
+	_, err = uc.Complete(ctx, start.State, "code")
+	require.ErrorIs(t, err, ErrInvalidOIDCLogin)
+}
+
+func TestOIDCUsecase_CreatesUserOnlyForVerifiedEmailWhenAllowed(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	var created []*entity.User
+	users := memoryUsers()
+	users.CreateFn = func(_ context.Context, user *entity.User) error {
+		created = append(created, user)
+		return nil
+	}
+	idp := &stubIdentityProvider{claims: provider.IdentityClaims{
+		Issuer:  "https://idp.example",
+		Subject: "sub-1",
+		Email:   "new@example.com",
+		Name:    strings.Repeat("名", 20),
+	}}
+
+	_, err := completeOIDCLogin(t, newOIDCUsecaseForTest(users, idp, stubConfig{}, now), "/")
+	require.ErrorIs(t, err, ErrIdentityEmailNotVerified)
+
+	idp.claims.EmailVerified = true
+	_, err = completeOIDCLogin(t, newOIDCUsecaseForTest(users, idp, signupConfig{allow: false}, now), "/")
+	require.ErrorIs(t, err, ErrIdentitySignupDisabled)
+	require.Empty(t, created)
+
+	result, err := completeOIDCLogin(t, newOIDCUsecaseForTest(users, idp, signupConfig{allow: true}, now), "/")
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	require.Equal(t, "new@example.com", result.User.Email)
+	require.True(t, result.User.EmailVerified())
+	require.Equal(t, strings.Repeat("名", 16), result.User.DisplayName)
+	require.Equal(t, "UTC", result.User.TimeZone)
+}
+
+func TestOIDCUsecase_ResetsCredentialsOfUnverifiedAccount(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	user := userWithPassword(t, "alice@example.com", "password-1")
+	user.TOTPSecret = "SECRET"
+	user.TOTPEnabledAt = &now
+	idp := &stubIdentityProvider{claims: provider.IdentityClaims{Issuer: "https://idp.example", Subject: "sub-1", Email: "alice@example.com", EmailVerified: true}}
+	uc := newOIDCUsecaseForTest(memoryUsers(user), idp, stubConfig{}, now)
+
+	result, err := completeOIDCLogin(t, uc, "/")
+	require.NoError(t, err)
+	require.True(t, result.CredentialsReset)
+	require.True(t, user.EmailVerified())
+	require.False(t, user.TwoFactorEnabled())
+	require.Error(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("password-1")))
+}
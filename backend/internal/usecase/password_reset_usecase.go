@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+// ErrInvalidPasswordResetToken は未知・期限切れ・使用済みの再設定トークンを表す。
+var ErrInvalidPasswordResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResetUsecase はメールによるパスワード再設定を扱う。
+type PasswordResetUsecase struct {
+	users  repository.UserRepository
+	resets repository.PasswordResetRepository
+	mailer provider.Mailer
+	cfg    provider.AppConfig
+	clock  provider.Clock
+}
+
+func NewPasswordResetUsecase(users repository.UserRepository, resets repository.PasswordResetRepository, mailer provider.Mailer, cfg provider.AppConfig, clock provider.Clock) *PasswordResetUsecase {
+	return &PasswordResetUsecase{users: users, resets: resets, mailer: mailer, cfg: cfg, clock: clock}
+}
+
+// Request は登録済みのメールアドレスにだけ再設定リンクを送る。
+// 未登録のアドレスでも同じく nil を返し、アドレスの有無を応答から推測させない。
+// 新しいトークンを発行すると、それまでの未使用トークンは無効になる。
+func (u *PasswordResetUsecase) Request(ctx context.Context, input dto.PasswordForgotRequest) error {
+	email, err := input.Normalize()
+	if err != nil {
+		return err
+	}
+	user, err := u.users.GetByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+	now := u.clock.Now()
+	if err := u.resets.InvalidateByUser(ctx, user.ID, now); err != nil {
+		return err
+	}
+	raw, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	token := &entity.PasswordResetToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: now.Add(u.cfg.PasswordResetTTL()),
+	}
+	if err := u.resets.Create(ctx, token); err != nil {
+		return err
+	}
+	link := u.cfg.PasswordResetURL() + "?token=" + url.QueryEscape(raw)
+	return u.mailer.Send(ctx, provider.MailMessage{
+		To:      user.Email,
+		Subject: "ChronoMe パスワード再設定のご案内",
+		Body: fmt.Sprintf("ChronoMe のパスワード再設定を受け付けました。\n\n"+
+			"以下のリンクから %d 分以内に新しいパスワードを設定してください。\n%s\n\n"+
+			"このメールに心当たりがない場合は破棄してください。パスワードは変更されません。\n",
+			int(u.cfg.PasswordResetTTL().Minutes()), link),
+	})
+}
+
+// Reset はトークンを使用済みにしてからパスワードを置き換え、対象のユーザー ID を返す。トークンは 1 回だけ使える。
+// セッションとトークンの失効は呼び出し側で行う。
+func (u *PasswordResetUsecase) Reset(ctx context.Context, input dto.PasswordResetRequest) (uuid.UUID, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	token, err := u.resets.GetByHash(ctx, hashToken(data.Token))
+	if err != nil {
+		return uuid.Nil, ErrInvalidPasswordResetToken
+	}
+	now := u.clock.Now()
+	if !token.Usable(now) {
+		return uuid.Nil, ErrInvalidPasswordResetToken
+	}
+	user, err := u.users.GetByID(ctx, token.UserID)
+	if err != nil {
+		return uuid.Nil, ErrInvalidPasswordResetToken
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(data.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	// 同じトークンでの同時リセットは、先に使用済みにできた方だけを通す。
+	marked, err := u.resets.MarkUsed(ctx, token.ID, now)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !marked {
+		return uuid.Nil, ErrInvalidPasswordResetToken
+	}
+	user.PasswordHash = string(hash)
+	// メールのリンクを開けたことはアドレスの所有の確認にもなる。
+	if !user.EmailVerified() {
+		user.EmailVerifiedAt = &now
+	}
+	if err := u.users.Update(ctx, user); err != nil {
+		return uuid.Nil, err
+	}
+	if err := u.resets.InvalidateByUser(ctx, user.ID, now); err != nil {
+		return uuid.Nil, err
+	}
+	return user.ID, nil
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+// memoryPasswordResets はハッシュで引ける最小限の再設定トークン保存先を fake に被せる。
+func memoryPasswordResets() *fakes.FakePasswordResetRepository {
+	stored := make(map[string]*entity.PasswordResetToken)
+	return &fakes.FakePasswordResetRepository{
+		CreateFn: func(_ context.Context, token *entity.PasswordResetToken) error {
+			stored[token.TokenHash] = token
+			return nil
+		},
+		GetByHashFn: func(_ context.Context, hash string) (*entity.PasswordResetToken, error) {
+			token, ok := stored[hash]
+			if !ok {
+				return nil, errors.New("not found")
+			}
+			copied := *token
+			return &copied, nil
+		},
+		MarkUsedFn: func(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+			for _, token := range stored {
+				if token.ID == id && token.UsedAt == nil {
+					token.UsedAt = &at
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		InvalidateByUserFn: func(_ context.Context, userID uuid.UUID, at time.Time) error {
+			for _, token := range stored {
+				if token.UserID == userID && token.UsedAt == nil {
+					token.UsedAt = &at
+				}
+			}
+			return nil
+		},
+	}
+}
+
+var resetLinkPattern = regexp.MustCompile(`https://chronome\.example/reset-password\?token=(\S+)`)
+
+func resetTokenFromMail(t *testing.T, body string) string {
+	t.Helper()
+	match := resetLinkPattern.FindStringSubmatch(body)
+	require.Len(t, match, 2, body)
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+	return token
+}
+
+func TestPasswordResetUsecase_RequestDoesNotRevealUnknownEmail(t *testing.T) {
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com"}
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(_ context.Context, email string) (*entity.User, error) {
+			if email == user.Email {
+				return user, nil
+			}
+			return nil, errors.New("not found")
+		},
+	}
+	mailer := &fakes.RecordingMailer{}
+	uc := NewPasswordResetUsecase(users, memoryPasswordResets(), mailer, stubConfig{}, fakes.FixedTimeProvider{})
+
+	require.NoError(t, uc.Request(context.Background(), dto.PasswordForgotRequest{Email: "nobody@example.com"}))
+	require.Empty(t, mailer.Messages())
+
+	require.NoError(t, uc.Request(context.Background(), dto.PasswordForgotRequest{Email: " User@Example.com "}))
+	sent := mailer.Messages()
+	require.Len(t, sent, 1)
+	require.Equal(t, "user@example.com", sent[0].To)
+	require.Contains(t, sent[0].Body, "60 分以内")
+	require.NotEmpty(t, resetTokenFromMail(t, sent[0].Body))
+
+	err := uc.Request(context.Background(), dto.PasswordForgotRequest{Email: "not-an-email"})
+	var valErr dto.ValidationError
+	require.ErrorAs(t, err, &valErr)
+}
+
+func TestPasswordResetUsecase_ResetIsSingleUseAndExpires(t *testing.T) {
+	oldHash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &entity.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: string(oldHash)}
+	users := &fakes.FakeUserRepository{
+		GetByEmailFn: func(context.Context, string) (*entity.User, error) { return user, nil },
+		GetByIDFn: func(context.Context, uuid.UUID) (*entity.User, error) {
+			copied := *user
+			return &copied, nil
+		},
+		UpdateFn: func(_ context.Context, updated *entity.User) error {
+			user = updated
+			return nil
+		},
+	}
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }}
+	mailer := &fakes.RecordingMailer{}
+	resets := memoryPasswordResets()
+	uc := NewPasswordResetUsecase(users, resets, mailer, stubConfig{}, clock)
+
+	require.NoError(t, uc.Request(context.Background(), dto.PasswordForgotRequest{Email: user.Email}))
+	first := resetTokenFromMail(t, mailer.Messages()[0].Body)
+	// 新しいリンクを送ると古いリンクは使えなくなる。
+	require.NoError(t, uc.Request(context.Background(), dto.PasswordForgotRequest{Email: user.Email}))
+	second := resetTokenFromMail(t, mailer.Messages()[1].Body)
+	_, err = uc.Reset(context.Background(), dto.PasswordResetRequest{Token: first, Password: "new-password"})
+	require.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+
+	_, err = uc.Reset(context.Background(), dto.PasswordResetRequest{Token: second, Password: "short"})
+	var valErr dto.ValidationError
+	require.ErrorAs(t, err, &valErr)
+
+	resetUser, err := uc.Reset(context.Background(), dto.PasswordResetRequest{Token: second, Password: "new-password"})
+	require.NoError(t, err)
+	require.Equal(t, user.ID, resetUser)
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("new-password")))
+	_, err = uc.Reset(context.Background(), dto.PasswordResetRequest{Token: second, Password: "another-password"})
+	require.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+
+	require.NoError(t, uc.Request(context.Background(), dto.PasswordForgotRequest{Email: user.Email}))
+	expired := resetTokenFromMail(t, mailer.Messages()[2].Body)
+	late := NewPasswordResetUsecase(users, resets, mailer, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now.Add(2 * time.Hour) }})
+	_, err = late.Reset(context.Background(), dto.PasswordResetRequest{Token: expired, Password: "new-password"})
+	require.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+var (
+	// ErrPersonalTokenNotFound はユーザー所有のパーソナルアクセストークンが見つからないことを表す。
+	ErrPersonalTokenNotFound = errors.New("personal token not found")
+	// ErrInvalidPersonalToken は未知・期限切れのパーソナルアクセストークンを表す。
+	ErrInvalidPersonalToken = errors.New("invalid personal token")
+)
+
+const (
+	// personalTokenPrefixLength は一覧で見分けられるように保存する平文先頭の長さ。
+	personalTokenPrefixLength = 12
+	// personalTokenTouchInterval より短い間隔の利用では last_used_at を更新せず、リクエストごとの書き込みを避ける。
+	personalTokenTouchInterval = time.Minute
+)
+
+// PersonalTokenUsecase はパーソナルアクセストークンの発行・一覧・失効と、リクエスト時の照合を扱う。
+type PersonalTokenUsecase struct {
+	tokens repository.PersonalTokenRepository
+	clock  provider.Clock
+}
+
+func NewPersonalTokenUsecase(tokens repository.PersonalTokenRepository, clock provider.Clock) *PersonalTokenUsecase {
+	return &PersonalTokenUsecase{tokens: tokens, clock: clock}
+}
+
+// PersonalTokenView は API で返すトークン情報。ハッシュは含めない。
+type PersonalTokenView struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// PersonalTokenCreated は作成したトークンと、この時だけ取得できる平文を返す。
+type PersonalTokenCreated struct {
+	Token  PersonalTokenView `json:"token"`
+	Secret string            `json:"secret"`
+}
+
+func (u *PersonalTokenUsecase) List(ctx context.Context, userID uuid.UUID) ([]PersonalTokenView, error) {
+	tokens, err := u.tokens.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]PersonalTokenView, 0, len(tokens))
+	for _, token := range tokens {
+		views = append(views, newPersonalTokenView(token))
+	}
+	return views, nil
+}
+
+func (u *PersonalTokenUsecase) Create(ctx context.Context, userID uuid.UUID, input dto.PersonalTokenCreateRequest) (*PersonalTokenCreated, error) {
+	data, err := input.Normalize(u.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	random, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	secret := entity.PersonalTokenPrefix + random
+	token := &entity.PersonalAccessToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      data.Name,
+		TokenHash: hashToken(secret),
+		Prefix:    secret[:personalTokenPrefixLength],
+		Scopes:    strings.Join(data.Scopes, " "),
+		ExpiresAt: data.ExpiresAt,
+	}
+	if err := token.Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.tokens.Create(ctx, token); err != nil {
+		return nil, err
+	}
+	return &PersonalTokenCreated{Token: newPersonalTokenView(*token), Secret: secret}, nil
+}
+
+// Delete はトークンを削除して即座に失効させる。
+func (u *PersonalTokenUsecase) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	deleted, err := u.tokens.Delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPersonalTokenNotFound
+	}
+	return nil
+}
+
+// Authenticate は Bearer ヘッダーの平文からトークンを引き当て、利用日時を記録する。
+func (u *PersonalTokenUsecase) Authenticate(ctx context.Context, raw string) (*entity.PersonalAccessToken, error) {
+	if !strings.HasPrefix(raw, entity.PersonalTokenPrefix) {
+		return nil, ErrInvalidPersonalToken
+	}
+	token, err := u.tokens.GetByHash(ctx, hashToken(raw))
+	if err != nil {
+		return nil, ErrInvalidPersonalToken
+	}
+	now := u.clock.Now()
+	if token.Expired(now) {
+		return nil, ErrInvalidPersonalToken
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= personalTokenTouchInterval {
+		// 利用日時は目安なので、記録に失敗してもリクエスト自体は通す。
+		if err := u.tokens.TouchLastUsed(ctx, token.ID, now); err == nil {
+			token.LastUsedAt = &now
+		}
+	}
+	return token, nil
+}
+
+func newPersonalTokenView(token entity.PersonalAccessToken) PersonalTokenView {
+	return PersonalTokenView{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.ScopeList(),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
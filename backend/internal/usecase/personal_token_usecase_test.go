@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+func TestPersonalTokenUsecase_CreateStoresHashAndNormalizesScopes(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	var saved *entity.PersonalAccessToken
+	repo := &fakes.FakePersonalTokenRepository{
+		CreateFn: func(_ context.Context, token *entity.PersonalAccessToken) error {
+			saved = token
+			return nil
+		},
+	}
+	uc := NewPersonalTokenUsecase(repo, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+	days := 30
+
+	created, err := uc.Create(context.Background(), uuid.New(), dto.PersonalTokenCreateRequest{
+		Name:          " nightly export ",
+		Scopes:        []string{"reports:read", "Entries:Read", "reports:read"},
+		ExpiresInDays: &days,
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(created.Secret, entity.PersonalTokenPrefix))
+	require.Equal(t, hashToken(created.Secret), saved.TokenHash)
+	require.NotContains(t, saved.TokenHash, created.Secret)
+	require.Equal(t, created.Secret[:12], created.Token.Prefix)
+	require.Equal(t, "nightly export", created.Token.Name)
+	require.Equal(t, []string{entity.ScopeEntriesRead, entity.ScopeReportsRead}, created.Token.Scopes)
+	require.Equal(t, now.AddDate(0, 0, 30), *created.Token.ExpiresAt)
+
+	_, err = uc.Create(context.Background(), uuid.New(), dto.PersonalTokenCreateRequest{Name: "x", Scopes: []string{"admin"}})
+	var valErr dto.ValidationError
+	require.ErrorAs(t, err, &valErr)
+	require.Equal(t, "scopes", valErr.Field)
+}
+
+func TestPersonalTokenUsecase_AuthenticateTouchesLastUsedAndRejectsExpired(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+	secret := entity.PersonalTokenPrefix + "abc"
+	stored := &entity.PersonalAccessToken{ID: uuid.New(), UserID: uuid.New(), TokenHash: hashToken(secret), Scopes: "entries:read", ExpiresAt: &expiresAt}
+	touches := 0
+	repo := &fakes.FakePersonalTokenRepository{
+		GetByHashFn: func(_ context.Context, hash string) (*entity.PersonalAccessToken, error) {
+			if hash != stored.TokenHash {
+				return nil, errors.New("not found")
+			}
+			copied := *stored
+			return &copied, nil
+		},
+		TouchLastUsedFn: func(_ context.Context, _ uuid.UUID, at time.Time) error {
+			touches++
+			stored.LastUsedAt = &at
+			return nil
+		},
+	}
+	clockNow := now
+	uc := NewPersonalTokenUsecase(repo, fakes.FixedTimeProvider{NowFunc: func() time.Time { return clockNow }})
+
+	token, err := uc.Authenticate(context.Background(), secret)
+	require.NoError(t, err)
+	require.Equal(t, stored.UserID, token.UserID)
+	require.Equal(t, now, *token.LastUsedAt)
+
+	// 間隔の短い利用では書き込まない。
+	clockNow = now.Add(10 * time.Second)
+	_, err = uc.Authenticate(context.Background(), secret)
+	require.NoError(t, err)
+	require.Equal(t, 1, touches)
+	clockNow = now.Add(2 * time.Minute)
+	_, err = uc.Authenticate(context.Background(), secret)
+	require.NoError(t, err)
+	require.Equal(t, 2, touches)
+
+	clockNow = expiresAt
+	_, err = uc.Authenticate(context.Background(), secret)
+	require.ErrorIs(t, err, ErrInvalidPersonalToken)
+	_, err = uc.Authenticate(context.Background(), "abc")
+	require.ErrorIs(t, err, ErrInvalidPersonalToken)
+}
+
+func TestPersonalTokenUsecase_DeleteReportsMissingToken(t *testing.T) {
+	repo := &fakes.FakePersonalTokenRepository{
+		DeleteFn: func(context.Context, uuid.UUID, uuid.UUID) (bool, error) { return false, nil },
+	}
+	uc := NewPersonalTokenUsecase(repo, fakes.FixedTimeProvider{})
+	err := uc.Delete(context.Background(), uuid.New(), uuid.New())
+	require.ErrorIs(t, err, ErrPersonalTokenNotFound)
+}
@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+// maxPomodoroAttempts は同時に進められたセッションを読み直して処理をやり直す回数の上限。
+const maxPomodoroAttempts = 3
+
+// PomodoroUsecase は作業と休憩のサイクルをエントリとして記録する。
+// フェーズ境界はサーバー時刻で計算し、参照・停止のたびと AdvanceAll の定期実行で経過済みの境界までエントリを確定させる。
+type PomodoroUsecase struct {
+	pomodoros repository.PomodoroRepository
+	entries   repository.EntryRepository
+	clock     provider.Clock
+}
+
+func NewPomodoroUsecase(pomodoros repository.PomodoroRepository, entries repository.EntryRepository, clock provider.Clock) *PomodoroUsecase {
+	return &PomodoroUsecase{pomodoros: pomodoros, entries: entries, clock: clock}
+}
+
+// PomodoroStatus はセッションと現在フェーズの状態を返す。
+type PomodoroStatus struct {
+	Session      entity.PomodoroSession `json:"session"`
+	Phase        entity.PomodoroPhase   `json:"phase"`
+	PhaseEndsAt  *time.Time             `json:"phase_ends_at,omitempty"`
+	RemainingSec int64                  `json:"remaining_sec"`
+	CurrentEntry *entity.Entry          `json:"current_entry,omitempty"`
+}
+
+// Start は新しいセッションを開始し、最初の work エントリを作成する。
+func (u *PomodoroUsecase) Start(ctx context.Context, userID uuid.UUID, input dto.PomodoroStartRequest) (*PomodoroStatus, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	now := u.clock.Now()
+	if active, err := u.pomodoros.GetActive(ctx, userID); err == nil {
+		// 前回のセッションが放置されたまま自然終了している場合は、先に確定させてから判定する。
+		if _, err := u.advance(ctx, active, now); err != nil {
+			return nil, err
+		}
+		if active.EndedAt == nil {
+			return nil, dto.ValidationError{Field: "pomodoro", Message: "session is already running"}
+		}
+	}
+	session := &entity.PomodoroSession{
+		ID:             uuid.New(),
+		UserID:         userID,
+		ProjectID:      data.ProjectID,
+		Title:          data.Title,
+		WorkSec:        data.WorkSec,
+		ShortBreakSec:  data.ShortBreakSec,
+		LongBreakSec:   data.LongBreakSec,
+		Cycles:         data.Cycles,
+		PhaseStartedAt: now,
+		StartedAt:      now,
+	}
+	if err := session.Validate(); err != nil {
+		return nil, err
+	}
+	entry := session.NewPhaseEntry()
+	session.CurrentEntryID = &entry.ID
+	if err := u.pomodoros.Create(ctx, session, entry); err != nil {
+		return nil, err
+	}
+	return buildPomodoroStatus(session, entry, now), nil
+}
+
+// Current は実行中セッションを現在時刻まで進めて返す。
+func (u *PomodoroUsecase) Current(ctx context.Context, userID uuid.UUID) (*PomodoroStatus, error) {
+	session, err := u.pomodoros.GetActive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := u.clock.Now()
+	current, err := u.advance(ctx, session, now)
+	if err != nil {
+		return nil, err
+	}
+	return buildPomodoroStatus(session, current, now), nil
+}
+
+// Stop はセッションを終了し、進行中のエントリを現在時刻で打ち切る。途中の work はポモドーロに数えない。
+func (u *PomodoroUsecase) Stop(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*PomodoroStatus, error) {
+	session, err := u.pomodoros.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	now := u.clock.Now()
+	for attempt := 1; ; attempt++ {
+		current, err := u.advance(ctx, session, now)
+		if err != nil {
+			return nil, err
+		}
+		if session.EndedAt != nil {
+			return buildPomodoroStatus(session, nil, now), nil
+		}
+		var changes repository.EntryChanges
+		if current != nil && current.IsRunning() {
+			end := now
+			current.EndedAt = &end
+			current.UpdateDuration(now)
+			changes.Update = []entity.Entry{*current}
+		}
+		end := now
+		session.EndedAt = &end
+		session.CurrentEntryID = nil
+		err = u.pomodoros.Advance(ctx, session, session.PhaseIndex, changes)
+		if err == nil {
+			return buildPomodoroStatus(session, nil, now), nil
+		}
+		if !errors.Is(err, repository.ErrConflict) || attempt >= maxPomodoroAttempts {
+			return nil, err
+		}
+		if err := u.reload(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// AdvanceAll は全ユーザーの進行中セッションを現在時刻まで進め、フェーズが進んだセッションの数を返す。
+// 誰も参照しないセッションでも、work のエントリが境界を過ぎて伸び続けないようにする。
+func (u *PomodoroUsecase) AdvanceAll(ctx context.Context) (int, error) {
+	sessions, err := u.pomodoros.ListActive(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := u.clock.Now()
+	advanced := 0
+	for i := range sessions {
+		session := &sessions[i]
+		from := session.PhaseIndex
+		if _, err := u.advance(ctx, session, now); err != nil {
+			return advanced, err
+		}
+		if session.PhaseIndex != from || session.EndedAt != nil {
+			advanced++
+		}
+	}
+	return advanced, nil
+}
+
+// advance は now までに到達したフェーズ境界ごとにエントリを確定し、次フェーズのエントリを作成する。
+// 同じセッションを別のリクエストが先に進めていれば、保存済みの状態を読み直してその続きから進める。
+func (u *PomodoroUsecase) advance(ctx context.Context, session *entity.PomodoroSession, now time.Time) (*entity.Entry, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := u.advanceOnce(ctx, session, now)
+		if !errors.Is(err, repository.ErrConflict) || attempt >= maxPomodoroAttempts {
+			return current, err
+		}
+		if err := u.reload(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// advanceOnce は経過済みの境界で確定・作成するエントリとセッションの位置を、Advance でまとめて保存する。
+func (u *PomodoroUsecase) advanceOnce(ctx context.Context, session *entity.PomodoroSession, now time.Time) (*entity.Entry, error) {
+	var current *entity.Entry
+	if session.CurrentEntryID != nil {
+		// 利用者がエントリを削除していてもセッション自体は進められるようにする。
+		if entry, err := u.entries.GetByID(ctx, session.UserID, *session.CurrentEntryID); err == nil {
+			current = entry
+		}
+	}
+	from := session.PhaseIndex
+	var changes repository.EntryChanges
+	var created []*entity.Entry
+	for session.EndedAt == nil {
+		phase := session.Phase()
+		boundary := session.PhaseEndsAt()
+		if boundary.After(now) {
+			break
+		}
+		if current != nil && current.IsRunning() {
+			end := boundary
+			current.EndedAt = &end
+			current.UpdateDuration(boundary)
+			current.PomodoroCompleted = phase == entity.PomodoroPhaseWork
+			if len(created) == 0 {
+				changes.Update = []entity.Entry{*current}
+			}
+		}
+		if phase == entity.PomodoroPhaseWork {
+			session.CompletedPomodoros++
+		}
+		session.PhaseIndex++
+		session.PhaseStartedAt = boundary
+		if session.Phase() == entity.PomodoroPhaseFinished {
+			end := boundary
+			session.EndedAt = &end
+			session.CurrentEntryID = nil
+			current = nil
+			break
+		}
+		current = session.NewPhaseEntry()
+		created = append(created, current)
+		session.CurrentEntryID = &current.ID
+	}
+	if session.PhaseIndex != from {
+		for _, entry := range created {
+			changes.Create = append(changes.Create, *entry)
+		}
+		if err := u.pomodoros.Advance(ctx, session, from, changes); err != nil {
+			return nil, err
+		}
+	}
+	if current != nil {
+		current.UpdateDuration(now)
+	}
+	return current, nil
+}
+
+// reload は別のリクエストが保存したセッションの状態で session を置き換える。
+func (u *PomodoroUsecase) reload(ctx context.Context, session *entity.PomodoroSession) error {
+	latest, err := u.pomodoros.GetByID(ctx, session.UserID, session.ID)
+	if err != nil {
+		return err
+	}
+	*session = *latest
+	return nil
+}
+
+func buildPomodoroStatus(session *entity.PomodoroSession, current *entity.Entry, now time.Time) *PomodoroStatus {
+	status := &PomodoroStatus{
+		Session:      *session,
+		Phase:        session.Phase(),
+		CurrentEntry: current,
+	}
+	if status.Phase != entity.PomodoroPhaseFinished {
+		endsAt := session.PhaseEndsAt()
+		status.PhaseEndsAt = &endsAt
+		if remaining := endsAt.Sub(now); remaining > 0 {
+			status.RemainingSec = int64(remaining.Seconds())
+		}
+	}
+	return status
+}
@@ -0,0 +1,270 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+func TestPomodoroUsecase_CurrentCreatesEntriesAtBoundaries(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	now := start.Add(60 * time.Minute)
+	userID := uuid.New()
+	firstEntry := uuid.New()
+	session := &entity.PomodoroSession{
+		ID: uuid.New(), UserID: userID, Title: "Write report",
+		WorkSec: 25 * 60, ShortBreakSec: 5 * 60, LongBreakSec: 15 * 60, Cycles: 2,
+		PhaseStartedAt: start, StartedAt: start, CurrentEntryID: &firstEntry,
+	}
+	entryRepo := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, uid uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			return &entity.Entry{ID: id, UserID: uid, Title: "Write report", StartedAt: start, Ratio: 1, PomodoroSessionID: &session.ID}, nil
+		},
+	}
+	var fromIndex []int
+	var applied []repository.EntryChanges
+	pomodoroRepo := &fakes.FakePomodoroRepository{
+		GetActiveFn: func(context.Context, uuid.UUID) (*entity.PomodoroSession, error) {
+			return session, nil
+		},
+		AdvanceFn: func(_ context.Context, _ *entity.PomodoroSession, from int, changes repository.EntryChanges) error {
+			fromIndex = append(fromIndex, from)
+			applied = append(applied, changes)
+			return nil
+		},
+	}
+	uc := NewPomodoroUsecase(pomodoroRepo, entryRepo, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	status, err := uc.Current(context.Background(), userID)
+	require.NoError(t, err)
+	// work(25) → short(5) → work(25) を経て、55 分時点から long break に入っている。
+	require.Equal(t, entity.PomodoroPhaseLongBreak, status.Phase)
+	require.Equal(t, 2, status.Session.CompletedPomodoros)
+	require.Equal(t, start.Add(70*time.Minute), *status.PhaseEndsAt)
+	require.EqualValues(t, 10*60, status.RemainingSec)
+
+	// 経過した境界の分はまとめて、読み込んだフェーズから進める 1 回の Advance で保存する。
+	require.Equal(t, []int{0}, fromIndex)
+	require.Len(t, applied[0].Update, 1)
+	require.Equal(t, firstEntry, applied[0].Update[0].ID)
+	require.True(t, applied[0].Update[0].PomodoroCompleted)
+	require.Equal(t, start.Add(25*time.Minute), *applied[0].Update[0].EndedAt)
+
+	created := applied[0].Create
+	require.Len(t, created, 3)
+	require.True(t, created[0].IsBreak)
+	require.Equal(t, start.Add(25*time.Minute), created[0].StartedAt)
+	require.Equal(t, start.Add(30*time.Minute), *created[0].EndedAt)
+	require.False(t, created[0].PomodoroCompleted)
+	require.False(t, created[1].IsBreak)
+	require.Equal(t, start.Add(30*time.Minute), created[1].StartedAt)
+	require.True(t, created[1].PomodoroCompleted)
+	require.Equal(t, "Long break", created[2].Title)
+	require.Equal(t, start.Add(55*time.Minute), created[2].StartedAt)
+	require.Nil(t, created[2].EndedAt)
+	require.Equal(t, created[2].ID, *status.Session.CurrentEntryID)
+}
+
+func TestPomodoroUsecase_CurrentContinuesFromConcurrentAdvance(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	now := start.Add(26 * time.Minute)
+	userID := uuid.New()
+	workEntry := uuid.New()
+	breakEntry := uuid.New()
+	session := &entity.PomodoroSession{
+		ID: uuid.New(), UserID: userID, Title: "Focus",
+		WorkSec: 25 * 60, ShortBreakSec: 5 * 60, LongBreakSec: 15 * 60, Cycles: 4,
+		PhaseStartedAt: start, StartedAt: start, CurrentEntryID: &workEntry,
+	}
+	// 別の端末が先に short break へ進めた後の状態。
+	advanced := *session
+	advanced.PhaseIndex = 1
+	advanced.PhaseStartedAt = start.Add(25 * time.Minute)
+	advanced.CompletedPomodoros = 1
+	advanced.CurrentEntryID = &breakEntry
+	entryRepo := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, uid uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			if id == breakEntry {
+				return &entity.Entry{ID: id, UserID: uid, Title: "Short break", StartedAt: advanced.PhaseStartedAt, IsBreak: true, Ratio: 1}, nil
+			}
+			return &entity.Entry{ID: id, UserID: uid, Title: "Focus", StartedAt: start, Ratio: 1}, nil
+		},
+	}
+	advances := 0
+	pomodoroRepo := &fakes.FakePomodoroRepository{
+		GetActiveFn: func(context.Context, uuid.UUID) (*entity.PomodoroSession, error) {
+			copied := *session
+			return &copied, nil
+		},
+		GetByIDFn: func(context.Context, uuid.UUID, uuid.UUID) (*entity.PomodoroSession, error) {
+			copied := advanced
+			return &copied, nil
+		},
+		AdvanceFn: func(context.Context, *entity.PomodoroSession, int, repository.EntryChanges) error {
+			advances++
+			return repository.ErrConflict
+		},
+	}
+	uc := NewPomodoroUsecase(pomodoroRepo, entryRepo, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	status, err := uc.Current(context.Background(), userID)
+	require.NoError(t, err)
+	// 競合したら保存済みの状態を読み直し、次のフェーズのエントリを重ねて作らない。
+	require.Equal(t, 1, advances)
+	require.Equal(t, entity.PomodoroPhaseShortBreak, status.Phase)
+	require.Equal(t, breakEntry, status.CurrentEntry.ID)
+	require.EqualValues(t, 60, status.CurrentEntry.DurationSec)
+}
+
+func TestPomodoroUsecase_StartSavesSessionWithFirstEntry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	var saved *entity.PomodoroSession
+	var first *entity.Entry
+	pomodoroRepo := &fakes.FakePomodoroRepository{
+		CreateFn: func(_ context.Context, session *entity.PomodoroSession, entry *entity.Entry) error {
+			saved = session
+			first = entry
+			return nil
+		},
+	}
+	entryRepo := &fakes.FakeEntryRepository{
+		CreateFn: func(context.Context, *entity.Entry) error {
+			t.Fatal("the first entry must be saved with the session")
+			return nil
+		},
+	}
+	uc := NewPomodoroUsecase(pomodoroRepo, entryRepo, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	status, err := uc.Start(context.Background(), uuid.New(), dto.PomodoroStartRequest{Title: "Focus"})
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	require.Equal(t, first.ID, *saved.CurrentEntryID)
+	require.Equal(t, saved.ID, *first.PomodoroSessionID)
+	require.Equal(t, first.ID, status.CurrentEntry.ID)
+}
+
+func TestPomodoroUsecase_AdvanceAllMovesUnpolledSessions(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	now := start.Add(27 * time.Minute)
+	due := entity.PomodoroSession{
+		ID: uuid.New(), UserID: uuid.New(), Title: "Due",
+		WorkSec: 25 * 60, ShortBreakSec: 5 * 60, LongBreakSec: 15 * 60, Cycles: 4,
+		PhaseStartedAt: start, StartedAt: start,
+	}
+	running := due
+	running.ID = uuid.New()
+	running.Title = "Running"
+	running.PhaseStartedAt = start.Add(10 * time.Minute)
+	var advanced []uuid.UUID
+	var applied repository.EntryChanges
+	pomodoroRepo := &fakes.FakePomodoroRepository{
+		ListActiveFn: func(context.Context) ([]entity.PomodoroSession, error) {
+			return []entity.PomodoroSession{due, running}, nil
+		},
+		AdvanceFn: func(_ context.Context, session *entity.PomodoroSession, _ int, changes repository.EntryChanges) error {
+			advanced = append(advanced, session.ID)
+			applied = changes
+			return nil
+		},
+	}
+	uc := NewPomodoroUsecase(pomodoroRepo, &fakes.FakeEntryRepository{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	count, err := uc.AdvanceAll(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, []uuid.UUID{due.ID}, advanced)
+	require.Len(t, applied.Create, 1)
+	require.True(t, applied.Create[0].IsBreak)
+	require.Equal(t, start.Add(25*time.Minute), applied.Create[0].StartedAt)
+}
+
+func TestPomodoroUsecase_StopDoesNotCountPartialWork(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	now := start.Add(10 * time.Minute)
+	entryID := uuid.New()
+	session := &entity.PomodoroSession{
+		ID: uuid.New(), UserID: uuid.New(), Title: "Focus",
+		WorkSec: 25 * 60, ShortBreakSec: 5 * 60, LongBreakSec: 15 * 60, Cycles: 4,
+		PhaseStartedAt: start, StartedAt: start, CurrentEntryID: &entryID,
+	}
+	entryRepo := &fakes.FakeEntryRepository{
+		GetByIDFn: func(_ context.Context, uid uuid.UUID, id uuid.UUID) (*entity.Entry, error) {
+			return &entity.Entry{ID: id, UserID: uid, Title: "Focus", StartedAt: start, Ratio: 1}, nil
+		},
+	}
+	var stopped *entity.PomodoroSession
+	var applied repository.EntryChanges
+	pomodoroRepo := &fakes.FakePomodoroRepository{
+		GetByIDFn: func(context.Context, uuid.UUID, uuid.UUID) (*entity.PomodoroSession, error) {
+			return session, nil
+		},
+		AdvanceFn: func(_ context.Context, session *entity.PomodoroSession, from int, changes repository.EntryChanges) error {
+			require.Equal(t, 0, from)
+			stopped = session
+			applied = changes
+			return nil
+		},
+	}
+	uc := NewPomodoroUsecase(pomodoroRepo, entryRepo, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	status, err := uc.Stop(context.Background(), session.UserID, session.ID)
+	require.NoError(t, err)
+	require.Equal(t, entity.PomodoroPhaseFinished, status.Phase)
+	require.Equal(t, 0, status.Session.CompletedPomodoros)
+	require.NotNil(t, stopped.EndedAt)
+	require.Len(t, applied.Update, 1)
+	updated := applied.Update[0]
+	require.Equal(t, now, *updated.EndedAt)
+	require.False(t, updated.PomodoroCompleted)
+	require.EqualValues(t, 600, updated.DurationSec)
+}
+
+func TestPomodoroUsecase_StartRejectsSecondSession(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 10, 0, 0, time.UTC)
+	pomodoroRepo := &fakes.FakePomodoroRepository{
+		GetActiveFn: func(_ context.Context, userID uuid.UUID) (*entity.PomodoroSession, error) {
+			return &entity.PomodoroSession{
+				ID: uuid.New(), UserID: userID, Title: "Focus",
+				WorkSec: 25 * 60, ShortBreakSec: 5 * 60, LongBreakSec: 15 * 60, Cycles: 4,
+				PhaseStartedAt: now.Add(-10 * time.Minute), StartedAt: now.Add(-10 * time.Minute),
+			}, nil
+		},
+	}
+	uc := NewPomodoroUsecase(pomodoroRepo, &fakes.FakeEntryRepository{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	_, err := uc.Start(context.Background(), uuid.New(), dto.PomodoroStartRequest{Title: "Another"})
+	var valErr dto.ValidationError
+	require.True(t, errors.As(err, &valErr))
+	require.Equal(t, "pomodoro", valErr.Field)
+}
+
+func TestReportUsecase_DailyCountsPomodoros(t *testing.T) {
+	sessionID := uuid.New()
+	repo := &fakes.FakeEntryRepository{
+		ListFn: func(context.Context, uuid.UUID, repository.EntryFilter) ([]entity.Entry, error) {
+			return []entity.Entry{
+				{DurationSec: 1500, PomodoroSessionID: &sessionID, PomodoroCompleted: true},
+				{DurationSec: 300, PomodoroSessionID: &sessionID, IsBreak: true},
+				{DurationSec: 1500, PomodoroSessionID: &sessionID, PomodoroCompleted: true},
+				{DurationSec: 600, PomodoroSessionID: &sessionID},
+				{DurationSec: 900},
+			}, nil
+		},
+	}
+	uc := NewReportUsecase(repo, &fakes.FakeProjectRepository{}, &fakes.FakeScheduleRepository{})
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	report, err := uc.Daily(context.Background(), uuid.New(), ReportRange{Start: start, End: start.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	require.Equal(t, 2, report.PomodoroCount)
+	require.EqualValues(t, 4800, report.TotalSeconds)
+}
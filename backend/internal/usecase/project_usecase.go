@@ -33,6 +33,7 @@ func (u *ProjectUsecase) Create(ctx context.Context, userID uuid.UUID, input dto
 		Name:        data.Name,
 		Color:       data.Color,
 		Description: data.Description,
+		Rounding:    data.Rounding,
 	}
 	if err := project.Validate(); err != nil {
 		return nil, err
@@ -68,6 +69,9 @@ func (u *ProjectUsecase) Update(ctx context.Context, userID uuid.UUID, id uuid.U
 	if data.IsArchived != nil {
 		project.IsArchived = *data.IsArchived
 	}
+	if data.Rounding != nil {
+		project.Rounding = *data.Rounding
+	}
 	if err := project.Validate(); err != nil {
 		return nil, err
 	}
@@ -6,4 +6,35 @@ import "time"
 type AppConfig interface {
 	DefaultProjectColor() string
 	SessionTTL() time.Duration
+	IdleThreshold() time.Duration
+	AutoStopAfter() time.Duration
+	RefreshTokenTTL() time.Duration
+	PasswordResetTTL() time.Duration
+	PasswordResetURL() string
+	EmailVerificationTTL() time.Duration
+	EmailVerificationURL() string
+	EmailVerificationMode() EmailVerificationMode
+	EmailVerificationResendInterval() time.Duration
+	OIDCAllowSignup() bool
+}
+
+// EmailVerificationMode はメールアドレスが未確認のユーザーをどう扱うかを表す。
+type EmailVerificationMode string
+
+const (
+	// EmailVerificationOptional は確認メールを送るだけで、未確認でも制限しない。
+	EmailVerificationOptional EmailVerificationMode = "optional"
+	// EmailVerificationLimited は未確認でもログインできるが、データの変更はできない。
+	EmailVerificationLimited EmailVerificationMode = "limited"
+	// EmailVerificationRequired は確認が済むまでログインさせない。
+	EmailVerificationRequired EmailVerificationMode = "required"
+)
+
+// Valid は既知のモードかを返す。
+func (m EmailVerificationMode) Valid() bool {
+	switch m {
+	case EmailVerificationOptional, EmailVerificationLimited, EmailVerificationRequired:
+		return true
+	}
+	return false
 }
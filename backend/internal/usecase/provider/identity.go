@@ -0,0 +1,20 @@
+package provider
+
+import "context"
+
+// IdentityClaims は検証済みの ID トークンから取り出した、外部の ID プロバイダーのユーザー情報。
+type IdentityClaims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// IdentityProvider は OpenID Connect の認可コードフロー（PKCE 付き）を抽象化する。
+type IdentityProvider interface {
+	// AuthCodeURL は認可エンドポイントへのリダイレクト先を返す。code_challenge は codeVerifier から S256 で作る。
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	// Exchange は認可コードを ID トークンと交換し、署名・発行者・audience・有効期限・nonce を検証してから claims を返す。
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*IdentityClaims, error)
+}
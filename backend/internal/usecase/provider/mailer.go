@@ -0,0 +1,15 @@
+package provider
+
+import "context"
+
+// MailMessage はユースケースから送るテキストメール 1 通を表す。
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer はメール送信を抽象化する。送信元アドレスや経路は実装側の設定で決める。
+type Mailer interface {
+	Send(ctx context.Context, msg MailMessage) error
+}
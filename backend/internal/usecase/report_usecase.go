@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"sort"
 	"time"
 
@@ -15,15 +16,18 @@ import (
 const unassignedProjectKey = "unassigned"
 
 type ReportUsecase struct {
-	entries  repository.EntryRepository
-	projects repository.ProjectRepository
+	entries   repository.EntryRepository
+	projects  repository.ProjectRepository
+	schedules repository.ScheduleRepository
 }
 
 // ReportRange はユーザーのローカル時間で期間を保持する。
+// Rounding はプロジェクトに丸めルールがないエントリへ適用するユーザー既定のルール。
 type ReportRange struct {
 	Start    time.Time
 	End      time.Time
 	Location *time.Location
+	Rounding entity.RoundingRule
 }
 
 func (r ReportRange) utcBounds() (time.Time, time.Time) {
@@ -46,38 +50,100 @@ func (r ReportRange) dayCount() int {
 	return count
 }
 
+// WorkBalance は所定労働時間と休憩を除いた実績時間の差分 (残業・不足) を表す。
+type WorkBalance struct {
+	ExpectedSeconds int64 `json:"expected_seconds"`
+	ActualSeconds   int64 `json:"actual_seconds"`
+	OvertimeSeconds int64 `json:"overtime_seconds"`
+}
+
+func (b *WorkBalance) add(expected, actual int64) {
+	b.ExpectedSeconds += expected
+	b.ActualSeconds += actual
+	b.OvertimeSeconds = b.ActualSeconds - b.ExpectedSeconds
+}
+
+// RoundedTotal は丸めルールを適用した合計と、丸めで増減した秒数 (残差) を表す。
+// TotalSeconds は常に丸め前の値のまま返す。
+type RoundedTotal struct {
+	RoundedSeconds         int64 `json:"rounded_seconds"`
+	RoundingResidueSeconds int64 `json:"rounding_residue_seconds"`
+}
+
+func (t *RoundedTotal) addRounded(raw, rounded int64) {
+	t.RoundedSeconds += rounded
+	t.RoundingResidueSeconds += rounded - raw
+}
+
+// entryRounder はエントリごとに適用する丸めルールを解決する。プロジェクトのルールがユーザー既定より優先される。
+type entryRounder struct {
+	user     entity.RoundingRule
+	projects map[uuid.UUID]entity.Project
+}
+
+func (r entryRounder) round(entry entity.Entry) int64 {
+	if entry.ProjectID != nil {
+		if project, ok := r.projects[*entry.ProjectID]; ok && project.Rounding.IsSet() {
+			return project.Rounding.Apply(entry.DurationSec)
+		}
+	}
+	return r.user.Apply(entry.DurationSec)
+}
+
 type DailyReport struct {
-	Date         string         `json:"date"`
-	TotalSeconds int64          `json:"total_seconds"`
-	Entries      []entity.Entry `json:"entries"`
+	Date          string `json:"date"`
+	TotalSeconds  int64  `json:"total_seconds"`
+	PomodoroCount int    `json:"pomodoro_count"`
+	RoundedTotal
+	WorkBalance
+	Holiday string             `json:"holiday,omitempty"`
+	TimeOff entity.TimeOffKind `json:"time_off,omitempty"`
+	Entries []entity.Entry     `json:"entries"`
 }
 
 type ReportDay struct {
 	Date         string `json:"date"`
 	TotalSeconds int64  `json:"total_seconds"`
+	RoundedTotal
+	WorkBalance
+	Holiday string             `json:"holiday,omitempty"`
+	TimeOff entity.TimeOffKind `json:"time_off,omitempty"`
 }
 
 type WeeklyReport struct {
-	WeekStart    string             `json:"week_start"`
-	TotalSeconds int64              `json:"total_seconds"`
-	Days         []ReportDay        `json:"days"`
-	Projects     []ProjectBreakdown `json:"projects"`
-	Tags         []TagBreakdown     `json:"tags"`
+	WeekStart    string `json:"week_start"`
+	TotalSeconds int64  `json:"total_seconds"`
+	RoundedTotal
+	WorkBalance
+	Days     []ReportDay        `json:"days"`
+	Projects []ProjectBreakdown `json:"projects"`
+	Tags     []TagBreakdown     `json:"tags"`
 }
 
 type MonthlyReport struct {
-	Month        string             `json:"month"`
-	TotalSeconds int64              `json:"total_seconds"`
-	Days         []ReportDay        `json:"days"`
-	Weeks        []ReportWeek       `json:"weeks"`
-	Projects     []ProjectBreakdown `json:"projects"`
-	Tags         []TagBreakdown     `json:"tags"`
-	DaysInMonth  int                `json:"days_in_month"`
+	Month        string `json:"month"`
+	TotalSeconds int64  `json:"total_seconds"`
+	RoundedTotal
+	WorkBalance
+	Days        []ReportDay        `json:"days"`
+	Weeks       []ReportWeek       `json:"weeks"`
+	Projects    []ProjectBreakdown `json:"projects"`
+	Tags        []TagBreakdown     `json:"tags"`
+	DaysInMonth int                `json:"days_in_month"`
 }
 
 type ReportWeek struct {
 	WeekStart    string `json:"week_start"`
 	TotalSeconds int64  `json:"total_seconds"`
+	RoundedTotal
+	WorkBalance
+}
+
+// dayPlan は 1 日分の所定労働時間と、その根拠になった祝日・休暇を保持する。
+type dayPlan struct {
+	Expected int64
+	Holiday  string
+	TimeOff  entity.TimeOffKind
 }
 
 type ProjectBreakdown struct {
@@ -85,6 +151,7 @@ type ProjectBreakdown struct {
 	Name         string     `json:"name"`
 	Color        string     `json:"color"`
 	TotalSeconds int64      `json:"total_seconds"`
+	RoundedTotal
 }
 
 type TagBreakdown struct {
@@ -92,10 +159,26 @@ type TagBreakdown struct {
 	Name         string    `json:"name"`
 	Color        string    `json:"color"`
 	TotalSeconds int64     `json:"total_seconds"`
+	RoundedTotal
+}
+
+// secondsTotal は丸め前後の秒数を並べて集計する。
+type secondsTotal struct {
+	raw     int64
+	rounded int64
+}
+
+func (t *secondsTotal) add(raw, rounded int64) {
+	t.raw += raw
+	t.rounded += rounded
 }
 
-func NewReportUsecase(entries repository.EntryRepository, projects repository.ProjectRepository) *ReportUsecase {
-	return &ReportUsecase{entries: entries, projects: projects}
+func (t secondsTotal) roundedTotal() RoundedTotal {
+	return RoundedTotal{RoundedSeconds: t.rounded, RoundingResidueSeconds: t.rounded - t.raw}
+}
+
+func NewReportUsecase(entries repository.EntryRepository, projects repository.ProjectRepository, schedules repository.ScheduleRepository) *ReportUsecase {
+	return &ReportUsecase{entries: entries, projects: projects, schedules: schedules}
 }
 
 func (u *ReportUsecase) Daily(ctx context.Context, userID uuid.UUID, rr ReportRange) (DailyReport, error) {
@@ -104,15 +187,37 @@ func (u *ReportUsecase) Daily(ctx context.Context, userID uuid.UUID, rr ReportRa
 	if err != nil {
 		return DailyReport{}, err
 	}
-	var total int64
+	plan, err := u.workPlan(ctx, userID, rr)
+	if err != nil {
+		return DailyReport{}, err
+	}
+	rounder := u.rounder(ctx, userID, rr)
+	var total, actual, rounded int64
+	pomodoros := 0
 	for _, entry := range entries {
 		total += entry.DurationSec
+		rounded += rounder.round(entry)
+		if !entry.IsBreak {
+			actual += entry.DurationSec
+		}
+		// 最後まで完了した work 区間だけをポモドーロとして数える。
+		if entry.PomodoroCompleted {
+			pomodoros++
+		}
 	}
-	return DailyReport{
-		Date:         rr.Start.Format("2006-01-02"),
-		TotalSeconds: total,
-		Entries:      entries,
-	}, nil
+	date := rr.Start.Format("2006-01-02")
+	day := plan[date]
+	report := DailyReport{
+		Date:          date,
+		TotalSeconds:  total,
+		PomodoroCount: pomodoros,
+		Holiday:       day.Holiday,
+		TimeOff:       day.TimeOff,
+		Entries:       entries,
+	}
+	report.addRounded(total, rounded)
+	report.add(day.Expected, actual)
+	return report, nil
 }
 
 func (u *ReportUsecase) Weekly(ctx context.Context, userID uuid.UUID, rr ReportRange) (WeeklyReport, error) {
@@ -121,12 +226,20 @@ func (u *ReportUsecase) Weekly(ctx context.Context, userID uuid.UUID, rr ReportR
 	if err != nil {
 		return WeeklyReport{}, err
 	}
+	plan, err := u.workPlan(ctx, userID, rr)
+	if err != nil {
+		return WeeklyReport{}, err
+	}
+	rounder := u.rounder(ctx, userID, rr)
 	total := int64(0)
+	var roundedTotal secondsTotal
 	dayTotals := map[string]int64{}
+	dayRounded := map[string]secondsTotal{}
+	dayActuals := map[string]int64{}
 	loc := rr.location()
-	projectTotals := make(map[uuid.UUID]int64)
-	var unassignedTotal int64
-	tagTotals := make(map[uuid.UUID]int64)
+	projectTotals := make(map[uuid.UUID]secondsTotal)
+	var unassignedTotal secondsTotal
+	tagTotals := make(map[uuid.UUID]secondsTotal)
 	tagMeta := make(map[uuid.UUID]entity.Tag)
 	for _, entry := range entries {
 		// 集計の所属日は保存時刻ではなく、ユーザーのローカル日付で決める。
@@ -135,32 +248,50 @@ func (u *ReportUsecase) Weekly(ctx context.Context, userID uuid.UUID, rr ReportR
 			continue
 		}
 		dayKey := localStarted.Format("2006-01-02")
+		rounded := rounder.round(entry)
 		dayTotals[dayKey] += entry.DurationSec
+		dayRound := dayRounded[dayKey]
+		dayRound.add(entry.DurationSec, rounded)
+		dayRounded[dayKey] = dayRound
+		if !entry.IsBreak {
+			dayActuals[dayKey] += entry.DurationSec
+		}
 		total += entry.DurationSec
+		roundedTotal.add(entry.DurationSec, rounded)
 		if entry.ProjectID == nil {
-			unassignedTotal += entry.DurationSec
+			unassignedTotal.add(entry.DurationSec, rounded)
 		} else {
-			projectTotals[*entry.ProjectID] += entry.DurationSec
+			projectTotal := projectTotals[*entry.ProjectID]
+			projectTotal.add(entry.DurationSec, rounded)
+			projectTotals[*entry.ProjectID] = projectTotal
 		}
 		for _, tag := range entry.Tags {
-			tagTotals[tag.ID] += entry.DurationSec
+			tagTotal := tagTotals[tag.ID]
+			tagTotal.add(entry.DurationSec, rounded)
+			tagTotals[tag.ID] = tagTotal
 			if _, ok := tagMeta[tag.ID]; !ok {
 				tagMeta[tag.ID] = tag
 			}
 		}
 	}
 	days := make([]ReportDay, 7)
+	var balance WorkBalance
 	for i := 0; i < 7; i++ {
 		// エントリがない日も 0 秒として返し、フロント側の欠損補完を不要にする。
 		day := rr.Start.AddDate(0, 0, i)
 		key := day.Format("2006-01-02")
-		days[i] = ReportDay{Date: key, TotalSeconds: dayTotals[key]}
+		p := plan[key]
+		days[i] = ReportDay{Date: key, TotalSeconds: dayTotals[key], RoundedTotal: dayRounded[key].roundedTotal(), Holiday: p.Holiday, TimeOff: p.TimeOff}
+		days[i].add(p.Expected, dayActuals[key])
+		balance.add(p.Expected, dayActuals[key])
 	}
-	projectBreakdown := u.buildProjectBreakdown(ctx, userID, projectTotals, unassignedTotal)
+	projectBreakdown := buildProjectBreakdown(rounder.projects, projectTotals, unassignedTotal)
 	tagBreakdown := buildTagBreakdown(tagTotals, tagMeta)
 	return WeeklyReport{
 		WeekStart:    rr.Start.Format("2006-01-02"),
 		TotalSeconds: total,
+		RoundedTotal: roundedTotal.roundedTotal(),
+		WorkBalance:  balance,
 		Days:         days,
 		Projects:     projectBreakdown,
 		Tags:         tagBreakdown,
@@ -173,13 +304,22 @@ func (u *ReportUsecase) Monthly(ctx context.Context, userID uuid.UUID, rr Report
 	if err != nil {
 		return MonthlyReport{}, err
 	}
+	plan, err := u.workPlan(ctx, userID, rr)
+	if err != nil {
+		return MonthlyReport{}, err
+	}
+	rounder := u.rounder(ctx, userID, rr)
 	daysInMonth := rr.dayCount()
 	dayTotals := make([]int64, daysInMonth)
+	dayRounded := make([]secondsTotal, daysInMonth)
+	dayActuals := make([]int64, daysInMonth)
 	weekTotals := map[string]int64{}
-	projectTotals := make(map[uuid.UUID]int64)
-	var unassignedTotal int64
+	weekRounded := map[string]secondsTotal{}
+	projectTotals := make(map[uuid.UUID]secondsTotal)
+	var unassignedTotal secondsTotal
 	total := int64(0)
-	tagTotals := make(map[uuid.UUID]int64)
+	var roundedTotal secondsTotal
+	tagTotals := make(map[uuid.UUID]secondsTotal)
 	tagMeta := make(map[uuid.UUID]entity.Tag)
 	loc := rr.location()
 	for _, entry := range entries {
@@ -188,39 +328,70 @@ func (u *ReportUsecase) Monthly(ctx context.Context, userID uuid.UUID, rr Report
 		if localDate.Before(rr.Start) || !localDate.Before(rr.End) {
 			continue
 		}
+		rounded := rounder.round(entry)
 		dayIndex := int(localDate.Sub(rr.Start).Hours() / 24)
 		if dayIndex >= 0 && dayIndex < len(dayTotals) {
 			dayTotals[dayIndex] += entry.DurationSec
+			dayRounded[dayIndex].add(entry.DurationSec, rounded)
+			if !entry.IsBreak {
+				dayActuals[dayIndex] += entry.DurationSec
+			}
 		}
 		total += entry.DurationSec
+		roundedTotal.add(entry.DurationSec, rounded)
 		weekStart := startOfWeek(localDate)
 		key := weekStart.Format("2006-01-02")
 		weekTotals[key] += entry.DurationSec
+		weekRound := weekRounded[key]
+		weekRound.add(entry.DurationSec, rounded)
+		weekRounded[key] = weekRound
 		if entry.ProjectID == nil {
-			unassignedTotal += entry.DurationSec
+			unassignedTotal.add(entry.DurationSec, rounded)
 		} else {
-			projectTotals[*entry.ProjectID] += entry.DurationSec
+			projectTotal := projectTotals[*entry.ProjectID]
+			projectTotal.add(entry.DurationSec, rounded)
+			projectTotals[*entry.ProjectID] = projectTotal
 		}
 		for _, tag := range entry.Tags {
-			tagTotals[tag.ID] += entry.DurationSec
+			tagTotal := tagTotals[tag.ID]
+			tagTotal.add(entry.DurationSec, rounded)
+			tagTotals[tag.ID] = tagTotal
 			if _, ok := tagMeta[tag.ID]; !ok {
 				tagMeta[tag.ID] = tag
 			}
 		}
 	}
 	days := make([]ReportDay, daysInMonth)
+	weekBalances := map[string]WorkBalance{}
+	var balance WorkBalance
 	for i := 0; i < daysInMonth; i++ {
 		day := rr.Start.AddDate(0, 0, i)
+		key := day.Format("2006-01-02")
+		p := plan[key]
 		days[i] = ReportDay{
-			Date:         day.Format("2006-01-02"),
+			Date:         key,
 			TotalSeconds: dayTotals[i],
+			RoundedTotal: dayRounded[i].roundedTotal(),
+			Holiday:      p.Holiday,
+			TimeOff:      p.TimeOff,
 		}
+		days[i].add(p.Expected, dayActuals[i])
+		balance.add(p.Expected, dayActuals[i])
+		// 週の過不足は月内に含まれる日だけで計算する。
+		weekKey := startOfWeek(day).Format("2006-01-02")
+		weekBalance := weekBalances[weekKey]
+		weekBalance.add(p.Expected, dayActuals[i])
+		weekBalances[weekKey] = weekBalance
 	}
 	var weeks []ReportWeek
-	for key, value := range weekTotals {
-		weeks = append(weeks, ReportWeek{WeekStart: key, TotalSeconds: value})
+	for key, value := range weekBalances {
+		// エントリも所定労働時間もない週は従来どおり返さない。
+		if weekTotals[key] == 0 && value.ExpectedSeconds == 0 {
+			continue
+		}
+		weeks = append(weeks, ReportWeek{WeekStart: key, TotalSeconds: weekTotals[key], RoundedTotal: weekRounded[key].roundedTotal(), WorkBalance: value})
 	}
-	projectBreakdown := u.buildProjectBreakdown(ctx, userID, projectTotals, unassignedTotal)
+	projectBreakdown := buildProjectBreakdown(rounder.projects, projectTotals, unassignedTotal)
 	tagBreakdown := buildTagBreakdown(tagTotals, tagMeta)
 	sort.Slice(weeks, func(i, j int) bool {
 		return weeks[i].WeekStart < weeks[j].WeekStart
@@ -228,6 +399,8 @@ func (u *ReportUsecase) Monthly(ctx context.Context, userID uuid.UUID, rr Report
 	return MonthlyReport{
 		Month:        rr.Start.Format("2006-01"),
 		TotalSeconds: total,
+		RoundedTotal: roundedTotal.roundedTotal(),
+		WorkBalance:  balance,
 		Days:         days,
 		Weeks:        weeks,
 		Projects:     projectBreakdown,
@@ -236,6 +409,53 @@ func (u *ReportUsecase) Monthly(ctx context.Context, userID uuid.UUID, rr Report
 	}, nil
 }
 
+// workPlan は期間内の各日の所定労働時間を、祝日と休暇を反映して日付キーで返す。
+// スケジュール未設定のユーザーは所定 0 時間として扱い、実績がそのまま残業になる。
+func (u *ReportUsecase) workPlan(ctx context.Context, userID uuid.UUID, rr ReportRange) (map[string]dayPlan, error) {
+	plan := make(map[string]dayPlan)
+	if u.schedules == nil {
+		return plan, nil
+	}
+	schedule, err := u.schedules.GetSchedule(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		schedule = nil
+	} else if err != nil {
+		return nil, err
+	}
+	from := rr.Start.Format("2006-01-02")
+	to := rr.End.AddDate(0, 0, -1).Format("2006-01-02")
+	holidays, err := u.schedules.ListHolidays(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	timeOffs, err := u.schedules.ListTimeOff(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	holidayByDate := make(map[string]*entity.Holiday, len(holidays))
+	for i := range holidays {
+		holidayByDate[holidays[i].Date] = &holidays[i]
+	}
+	timeOffByDate := make(map[string]*entity.TimeOff, len(timeOffs))
+	for i := range timeOffs {
+		timeOffByDate[timeOffs[i].Date] = &timeOffs[i]
+	}
+	for d := rr.Start; d.Before(rr.End); d = d.AddDate(0, 0, 1) {
+		key := d.Format("2006-01-02")
+		holiday := holidayByDate[key]
+		timeOff := timeOffByDate[key]
+		day := dayPlan{Expected: entity.ExpectedSeconds(schedule, d.Weekday(), holiday, timeOff)}
+		if holiday != nil {
+			day.Holiday = holiday.Name
+		}
+		if timeOff != nil {
+			day.TimeOff = timeOff.Kind
+		}
+		plan[key] = day
+	}
+	return plan, nil
+}
+
 func startOfWeek(t time.Time) time.Time {
 	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 	weekday := int(t.Weekday())
@@ -245,14 +465,20 @@ func startOfWeek(t time.Time) time.Time {
 	return t.AddDate(0, 0, -(weekday - 1))
 }
 
-func (u *ReportUsecase) buildProjectBreakdown(ctx context.Context, userID uuid.UUID, totals map[uuid.UUID]int64, unassigned int64) []ProjectBreakdown {
+// rounder はユーザー既定とプロジェクトごとの丸めルールを読み込む。
+// プロジェクト一覧は breakdown の表示名解決にも使う。
+func (u *ReportUsecase) rounder(ctx context.Context, userID uuid.UUID, rr ReportRange) entryRounder {
 	meta := make(map[uuid.UUID]entity.Project)
-	// 集計対象エントリに紐づく project 表示名を後から解決する。
 	if projects, err := u.projects.ListByUser(ctx, userID); err == nil {
 		for _, project := range projects {
 			meta[project.ID] = project
 		}
 	}
+	return entryRounder{user: rr.Rounding, projects: meta}
+}
+
+func buildProjectBreakdown(meta map[uuid.UUID]entity.Project, totals map[uuid.UUID]secondsTotal, unassigned secondsTotal) []ProjectBreakdown {
+	// 集計対象エントリに紐づく project 表示名を後から解決する。
 	var breakdown []ProjectBreakdown
 	for id, total := range totals {
 		proj := meta[id]
@@ -266,14 +492,16 @@ func (u *ReportUsecase) buildProjectBreakdown(ctx context.Context, userID uuid.U
 			ProjectID:    &idCopy,
 			Name:         name,
 			Color:        proj.Color,
-			TotalSeconds: total,
+			TotalSeconds: total.raw,
+			RoundedTotal: total.roundedTotal(),
 		})
 	}
-	if unassigned > 0 {
+	if unassigned.raw > 0 {
 		breakdown = append(breakdown, ProjectBreakdown{
 			Name:         "Unassigned",
 			Color:        "",
-			TotalSeconds: unassigned,
+			TotalSeconds: unassigned.raw,
+			RoundedTotal: unassigned.roundedTotal(),
 		})
 	}
 	sort.Slice(breakdown, func(i, j int) bool {
@@ -282,7 +510,7 @@ func (u *ReportUsecase) buildProjectBreakdown(ctx context.Context, userID uuid.U
 	return breakdown
 }
 
-func buildTagBreakdown(totals map[uuid.UUID]int64, meta map[uuid.UUID]entity.Tag) []TagBreakdown {
+func buildTagBreakdown(totals map[uuid.UUID]secondsTotal, meta map[uuid.UUID]entity.Tag) []TagBreakdown {
 	var breakdown []TagBreakdown
 	for id, total := range totals {
 		tag := meta[id]
@@ -290,7 +518,8 @@ func buildTagBreakdown(totals map[uuid.UUID]int64, meta map[uuid.UUID]entity.Tag
 			TagID:        id,
 			Name:         tag.Name,
 			Color:        tag.Color,
-			TotalSeconds: total,
+			TotalSeconds: total.raw,
+			RoundedTotal: total.roundedTotal(),
 		})
 	}
 	sort.Slice(breakdown, func(i, j int) bool {
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+func TestRoundingRule_Apply(t *testing.T) {
+	cases := []struct {
+		rule    entity.RoundingRule
+		seconds int64
+		want    int64
+	}{
+		{entity.RoundingRule{}, 61, 61},
+		{entity.RoundingRule{Mode: entity.RoundingUp, IncrementMinutes: 15}, 1, 900},
+		{entity.RoundingRule{Mode: entity.RoundingUp, IncrementMinutes: 15}, 900, 900},
+		{entity.RoundingRule{Mode: entity.RoundingDown, IncrementMinutes: 6}, 719, 360},
+		{entity.RoundingRule{Mode: entity.RoundingNearest, IncrementMinutes: 30}, 899, 0},
+		{entity.RoundingRule{Mode: entity.RoundingNearest, IncrementMinutes: 30}, 900, 1800},
+		{entity.RoundingRule{Mode: entity.RoundingUp, IncrementMinutes: 15}, 0, 0},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.want, tc.rule.Apply(tc.seconds), "%+v %d", tc.rule, tc.seconds)
+	}
+}
+
+func TestReportUsecase_WeeklyRoundsPerEntryWithoutMutatingDurations(t *testing.T) {
+	userID := uuid.New()
+	billed := uuid.New()
+	internal := uuid.New()
+	entries := []entity.Entry{
+		{DurationSec: 610, ProjectID: &billed},
+		{DurationSec: 1000, ProjectID: &billed},
+		{DurationSec: 200, ProjectID: &internal},
+		{DurationSec: 400},
+	}
+	repo := &fakes.FakeEntryRepository{
+		ListFn: func(_ context.Context, _ uuid.UUID, filter repository.EntryFilter) ([]entity.Entry, error) {
+			for i := range entries {
+				entries[i].StartedAt = filter.From.Add(time.Hour)
+			}
+			return entries, nil
+		},
+	}
+	projectRepo := &fakes.FakeProjectRepository{
+		ListFn: func(context.Context, uuid.UUID) ([]entity.Project, error) {
+			return []entity.Project{
+				{ID: billed, UserID: userID, Name: "Billed", Rounding: entity.RoundingRule{Mode: entity.RoundingUp, IncrementMinutes: 15}},
+				{ID: internal, UserID: userID, Name: "Internal"},
+			}, nil
+		},
+	}
+	uc := NewReportUsecase(repo, projectRepo, &fakes.FakeScheduleRepository{})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	report, err := uc.Weekly(context.Background(), userID, ReportRange{
+		Start:    start,
+		End:      start.AddDate(0, 0, 7),
+		Location: time.UTC,
+		Rounding: entity.RoundingRule{Mode: entity.RoundingNearest, IncrementMinutes: 6},
+	})
+	require.NoError(t, err)
+
+	// Billed は 15 分単位の切り上げ、それ以外はユーザー既定の 6 分単位の四捨五入。
+	require.Equal(t, int64(2210), report.TotalSeconds)
+	require.Equal(t, int64(900+1800+360+360), report.RoundedSeconds)
+	require.Equal(t, report.RoundedSeconds-report.TotalSeconds, report.RoundingResidueSeconds)
+	require.Equal(t, report.RoundedSeconds, report.Days[0].RoundedSeconds)
+	require.Equal(t, "Billed", report.Projects[0].Name)
+	require.Equal(t, int64(1610), report.Projects[0].TotalSeconds)
+	require.Equal(t, int64(2700), report.Projects[0].RoundedSeconds)
+	require.Equal(t, int64(1090), report.Projects[0].RoundingResidueSeconds)
+	require.Equal(t, int64(610), entries[0].DurationSec)
+}
+
+func TestAuthUsecase_UpdateRoundingValidates(t *testing.T) {
+	var saved *entity.User
+	users := &fakes.FakeUserRepository{
+		GetByIDFn: func(_ context.Context, id uuid.UUID) (*entity.User, error) {
+			return &entity.User{ID: id, Email: "user@example.com"}, nil
+		},
+		UpdateFn: func(_ context.Context, user *entity.User) error {
+			saved = user
+			return nil
+		},
+	}
+	uc := NewAuthUsecase(users, stubConfig{})
+
+	_, err := uc.UpdateRounding(context.Background(), uuid.New(), dto.RoundingRuleRequest{Mode: "up"})
+	var valErr dto.ValidationError
+	require.ErrorAs(t, err, &valErr)
+	require.Nil(t, saved)
+
+	user, err := uc.UpdateRounding(context.Background(), uuid.New(), dto.RoundingRuleRequest{Mode: "Nearest", IncrementMinutes: 6})
+	require.NoError(t, err)
+	require.Equal(t, entity.RoundingRule{Mode: entity.RoundingNearest, IncrementMinutes: 6}, user.Rounding)
+	require.Same(t, user, saved)
+}
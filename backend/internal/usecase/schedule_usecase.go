@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+)
+
+// maxHolidayNameRunes は祝日名として保存する最大文字数。
+const maxHolidayNameRunes = 120
+
+// ScheduleUsecase は所定労働時間・祝日・休暇の管理を扱う。
+type ScheduleUsecase struct {
+	schedules repository.ScheduleRepository
+}
+
+func NewScheduleUsecase(schedules repository.ScheduleRepository) *ScheduleUsecase {
+	return &ScheduleUsecase{schedules: schedules}
+}
+
+// GetSchedule は保存済みのスケジュールを返す。未設定の場合はすべて 0 時間のスケジュールになる。
+func (u *ScheduleUsecase) GetSchedule(ctx context.Context, userID uuid.UUID) (*entity.WorkSchedule, error) {
+	schedule, err := u.schedules.GetSchedule(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &entity.WorkSchedule{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (u *ScheduleUsecase) UpdateSchedule(ctx context.Context, userID uuid.UUID, input dto.ScheduleUpdateRequest) (*entity.WorkSchedule, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := u.GetSchedule(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for day, sec := range data.Seconds {
+		schedule.SetSecondsFor(day, sec)
+	}
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.schedules.SaveSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (u *ScheduleUsecase) ListHolidays(ctx context.Context, userID uuid.UUID, from, to string) ([]entity.Holiday, error) {
+	return u.schedules.ListHolidays(ctx, userID, from, to)
+}
+
+// ImportHolidays は取り込んだ祝日を日付単位で保存し、保存後の行を返す。同じ日付が複数ある場合は先に現れた名前を使う。
+// 既に同じ日付の祝日があれば名前と UID だけを更新するため、返す ID は既存の行のものになる。
+func (u *ScheduleUsecase) ImportHolidays(ctx context.Context, userID uuid.UUID, items []dto.HolidayImportItem) ([]entity.Holiday, error) {
+	seen := make(map[string]struct{}, len(items))
+	holidays := make([]entity.Holiday, 0, len(items))
+	from, to := "", ""
+	for _, item := range items {
+		if _, ok := seen[item.Date]; ok {
+			continue
+		}
+		seen[item.Date] = struct{}{}
+		if from == "" || item.Date < from {
+			from = item.Date
+		}
+		if item.Date > to {
+			to = item.Date
+		}
+		name := strings.TrimSpace(item.Name)
+		if name == "" {
+			name = "Holiday"
+		}
+		holidays = append(holidays, entity.Holiday{
+			ID:     uuid.New(),
+			UserID: userID,
+			Date:   item.Date,
+			Name:   truncateRunes(name, maxHolidayNameRunes),
+			UID:    item.UID,
+		})
+	}
+	if len(holidays) == 0 {
+		return holidays, nil
+	}
+	if err := u.schedules.UpsertHolidays(ctx, holidays); err != nil {
+		return nil, err
+	}
+	stored, err := u.schedules.ListHolidays(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	imported := make([]entity.Holiday, 0, len(holidays))
+	for _, holiday := range stored {
+		if _, ok := seen[holiday.Date]; ok {
+			imported = append(imported, holiday)
+		}
+	}
+	return imported, nil
+}
+
+// truncateRunes は s を文字の途中で切らずに max 文字までに収める。
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+func (u *ScheduleUsecase) DeleteHoliday(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return errors.New("id is required")
+	}
+	return u.schedules.DeleteHoliday(ctx, userID, id)
+}
+
+func (u *ScheduleUsecase) ListTimeOff(ctx context.Context, userID uuid.UUID, from, to string) ([]entity.TimeOff, error) {
+	return u.schedules.ListTimeOff(ctx, userID, from, to)
+}
+
+func (u *ScheduleUsecase) CreateTimeOff(ctx context.Context, userID uuid.UUID, input dto.TimeOffCreateRequest) (*entity.TimeOff, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	existing, err := u.schedules.ListTimeOff(ctx, userID, data.Date, data.Date)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, dto.ValidationError{Field: "date", Message: "already has time off"}
+	}
+	record := &entity.TimeOff{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Date:        data.Date,
+		Kind:        data.Kind,
+		DurationSec: data.DurationSec,
+		Note:        data.Note,
+	}
+	if err := record.Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.schedules.CreateTimeOff(ctx, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (u *ScheduleUsecase) DeleteTimeOff(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return errors.New("id is required")
+	}
+	return u.schedules.DeleteTimeOff(ctx, userID, id)
+}
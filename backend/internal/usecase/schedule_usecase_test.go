@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+func TestReportUsecase_WeeklyOvertimeHonoursHolidaysAndTimeOff(t *testing.T) {
+	loc := time.FixedZone("JST", 9*3600)
+	weekStart := time.Date(2024, 4, 29, 0, 0, 0, 0, loc)
+	schedules := &fakes.FakeScheduleRepository{
+		GetScheduleFn: func(_ context.Context, userID uuid.UUID) (*entity.WorkSchedule, error) {
+			return &entity.WorkSchedule{UserID: userID, MondaySec: 8 * 3600, TuesdaySec: 8 * 3600, WednesdaySec: 8 * 3600, ThursdaySec: 8 * 3600, FridaySec: 4 * 3600}, nil
+		},
+		ListHolidaysFn: func(_ context.Context, _ uuid.UUID, from, to string) ([]entity.Holiday, error) {
+			require.Equal(t, "2024-04-29", from)
+			require.Equal(t, "2024-05-05", to)
+			return []entity.Holiday{{Date: "2024-04-29", Name: "Showa Day"}}, nil
+		},
+		ListTimeOffFn: func(context.Context, uuid.UUID, string, string) ([]entity.TimeOff, error) {
+			return []entity.TimeOff{
+				{Date: "2024-05-01", Kind: entity.TimeOffVacation},
+				{Date: "2024-05-02", Kind: entity.TimeOffSick, DurationSec: 2 * 3600},
+			}, nil
+		},
+	}
+	entries := &fakes.FakeEntryRepository{
+		ListFn: func(context.Context, uuid.UUID, repository.EntryFilter) ([]entity.Entry, error) {
+			return []entity.Entry{
+				{StartedAt: time.Date(2024, 4, 30, 9, 0, 0, 0, loc), DurationSec: 9 * 3600},
+				{StartedAt: time.Date(2024, 4, 30, 12, 0, 0, 0, loc), DurationSec: 3600, IsBreak: true},
+				{StartedAt: time.Date(2024, 5, 2, 9, 0, 0, 0, loc), DurationSec: 5 * 3600},
+				{StartedAt: time.Date(2024, 5, 4, 10, 0, 0, 0, loc), DurationSec: 2 * 3600},
+			}, nil
+		},
+	}
+	uc := NewReportUsecase(entries, &fakes.FakeProjectRepository{}, schedules)
+
+	report, err := uc.Weekly(context.Background(), uuid.New(), ReportRange{Start: weekStart, End: weekStart.AddDate(0, 0, 7), Location: loc})
+	require.NoError(t, err)
+	// 所定: 月 0 (祝日) + 火 8 + 水 0 (休暇) + 木 6 (2h 病欠) + 金 4 = 18h
+	require.EqualValues(t, 18*3600, report.ExpectedSeconds)
+	require.EqualValues(t, 16*3600, report.ActualSeconds)
+	require.EqualValues(t, -2*3600, report.OvertimeSeconds)
+	require.EqualValues(t, 17*3600, report.TotalSeconds)
+
+	require.Equal(t, "Showa Day", report.Days[0].Holiday)
+	require.Zero(t, report.Days[0].ExpectedSeconds)
+	require.EqualValues(t, 3600, report.Days[1].OvertimeSeconds)
+	require.Equal(t, entity.TimeOffVacation, report.Days[2].TimeOff)
+	require.EqualValues(t, 6*3600, report.Days[3].ExpectedSeconds)
+	require.EqualValues(t, -3600, report.Days[3].OvertimeSeconds)
+	require.EqualValues(t, -4*3600, report.Days[4].OvertimeSeconds)
+	require.EqualValues(t, 2*3600, report.Days[5].OvertimeSeconds)
+}
+
+func TestScheduleUsecase_CreateTimeOffRejectsDuplicateDate(t *testing.T) {
+	repo := &fakes.FakeScheduleRepository{
+		ListTimeOffFn: func(_ context.Context, _ uuid.UUID, from, to string) ([]entity.TimeOff, error) {
+			require.Equal(t, "2024-05-01", from)
+			require.Equal(t, "2024-05-01", to)
+			return []entity.TimeOff{{Date: "2024-05-01", Kind: entity.TimeOffSick}}, nil
+		},
+	}
+	uc := NewScheduleUsecase(repo)
+
+	_, err := uc.CreateTimeOff(context.Background(), uuid.New(), dto.TimeOffCreateRequest{Date: "2024-05-01", Kind: "vacation"})
+	var valErr dto.ValidationError
+	require.True(t, errors.As(err, &valErr))
+	require.Equal(t, "date", valErr.Field)
+}
+
+func TestScheduleUsecase_UpdateScheduleKeepsUnsetDays(t *testing.T) {
+	var saved *entity.WorkSchedule
+	repo := &fakes.FakeScheduleRepository{
+		GetScheduleFn: func(_ context.Context, userID uuid.UUID) (*entity.WorkSchedule, error) {
+			return &entity.WorkSchedule{UserID: userID, MondaySec: 8 * 3600, FridaySec: 8 * 3600}, nil
+		},
+		SaveScheduleFn: func(_ context.Context, schedule *entity.WorkSchedule) error {
+			saved = schedule
+			return nil
+		},
+	}
+	uc := NewScheduleUsecase(repo)
+	friday := 240
+
+	schedule, err := uc.UpdateSchedule(context.Background(), uuid.New(), dto.ScheduleUpdateRequest{FridayMinutes: &friday})
+	require.NoError(t, err)
+	require.Same(t, schedule, saved)
+	require.EqualValues(t, 8*3600, schedule.MondaySec)
+	require.EqualValues(t, 4*3600, schedule.FridaySec)
+}
+
+func TestScheduleUsecase_ImportHolidaysReturnsStoredRows(t *testing.T) {
+	userID := uuid.New()
+	existing := entity.Holiday{ID: uuid.New(), UserID: userID, Date: "2024-05-03", Name: "Old name"}
+	stored := map[string]entity.Holiday{existing.Date: existing}
+	repo := &fakes.FakeScheduleRepository{
+		// 同じ日付の行は ID を保ったまま名前だけを更新する。
+		UpsertHolidaysFn: func(_ context.Context, holidays []entity.Holiday) error {
+			for _, holiday := range holidays {
+				if current, ok := stored[holiday.Date]; ok {
+					holiday.ID = current.ID
+				}
+				stored[holiday.Date] = holiday
+			}
+			return nil
+		},
+		ListHolidaysFn: func(_ context.Context, _ uuid.UUID, from, to string) ([]entity.Holiday, error) {
+			require.Equal(t, "2024-05-03", from)
+			require.Equal(t, "2024-05-05", to)
+			return []entity.Holiday{stored["2024-05-03"], stored["2024-05-05"]}, nil
+		},
+	}
+	uc := NewScheduleUsecase(repo)
+	longName := strings.Repeat("憲", 130)
+
+	holidays, err := uc.ImportHolidays(context.Background(), userID, []dto.HolidayImportItem{
+		{Date: "2024-05-05", Name: "Kodomo no Hi"},
+		{Date: "2024-05-03", Name: longName},
+	})
+	require.NoError(t, err)
+	require.Len(t, holidays, 2)
+	require.Equal(t, existing.ID, holidays[0].ID)
+	require.Equal(t, strings.Repeat("憲", 120), holidays[0].Name)
+	require.Equal(t, stored["2024-05-05"].ID, holidays[1].ID)
+}
+
+func TestScheduleUsecase_GetScheduleDistinguishesMissingFromFailure(t *testing.T) {
+	failure := errors.New("connection refused")
+	repo := &fakes.FakeScheduleRepository{}
+	uc := NewScheduleUsecase(repo)
+
+	schedule, err := uc.GetSchedule(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Zero(t, schedule.SecondsFor(time.Monday))
+
+	repo.GetScheduleFn = func(context.Context, uuid.UUID) (*entity.WorkSchedule, error) {
+		return nil, failure
+	}
+	_, err = uc.GetSchedule(context.Background(), uuid.New())
+	require.ErrorIs(t, err, failure)
+
+	// 集計でも所定労働時間を 0 とみなさずにエラーを返す。
+	reports := NewReportUsecase(&fakes.FakeEntryRepository{}, &fakes.FakeProjectRepository{}, repo)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	_, err = reports.Weekly(context.Background(), uuid.New(), ReportRange{Start: day, End: day.AddDate(0, 0, 7), Location: time.UTC})
+	require.ErrorIs(t, err, failure)
+}
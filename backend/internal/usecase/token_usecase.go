@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/provider"
+)
+
+// ErrInvalidRefreshToken は未知・期限切れ・失効済み・再利用されたリフレッシュトークンを表す。
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// TokenUsecase は Bearer 認証クライアント向けのリフレッシュトークンを発行・ローテーションする。
+// アクセストークンは状態を持たない署名付きトークンなので HTTP 層で発行し、ユーザー単位の失効だけをここで確かめる。
+type TokenUsecase struct {
+	tokens repository.RefreshTokenRepository
+	users  repository.UserRepository
+	cfg    provider.AppConfig
+	clock  provider.Clock
+}
+
+func NewTokenUsecase(tokens repository.RefreshTokenRepository, users repository.UserRepository, cfg provider.AppConfig, clock provider.Clock) *TokenUsecase {
+	return &TokenUsecase{tokens: tokens, users: users, cfg: cfg, clock: clock}
+}
+
+// RefreshTokenGrant は発行したリフレッシュトークンの平文と持ち主を返す。平文はこの時だけ取得できる。
+type RefreshTokenGrant struct {
+	UserID    uuid.UUID
+	Token     string
+	ExpiresAt time.Time
+}
+
+// Issue はログイン直後に新しいファミリーのリフレッシュトークンを発行する。
+func (u *TokenUsecase) Issue(ctx context.Context, userID uuid.UUID) (*RefreshTokenGrant, error) {
+	return u.issue(ctx, userID, uuid.New())
+}
+
+// Rotate はリフレッシュトークンを使用済みにし、同じファミリーの新しいトークンを返す。
+// 使用済みトークンが再提示された場合は漏えいとみなし、ファミリー全体を失効させる。
+func (u *TokenUsecase) Rotate(ctx context.Context, raw string) (*RefreshTokenGrant, error) {
+	if raw == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	token, err := u.tokens.GetByHash(ctx, hashToken(raw))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	now := u.clock.Now()
+	if token.UsedAt != nil {
+		if err := u.tokens.RevokeFamily(ctx, token.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if !token.Active(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+	marked, err := u.tokens.MarkUsed(ctx, token.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		// 同時に別のリクエストが同じトークンを使った。どちらが正規か判別できないので両方止める。
+		if err := u.tokens.RevokeFamily(ctx, token.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	return u.issue(ctx, token.UserID, token.FamilyID)
+}
+
+// Revoke はリフレッシュトークンのファミリーを失効させる。未知のトークンは何もせず成功扱いにする。
+func (u *TokenUsecase) Revoke(ctx context.Context, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	token, err := u.tokens.GetByHash(ctx, hashToken(raw))
+	if err != nil {
+		return nil
+	}
+	return u.tokens.RevokeFamily(ctx, token.FamilyID, u.clock.Now())
+}
+
+// RevokeAll はユーザーのリフレッシュトークンと発行済みのアクセストークンをすべて失効させる。
+// パスワード変更時に他の端末を締め出すために使う。
+func (u *TokenUsecase) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	now := u.clock.Now()
+	if err := u.tokens.RevokeByUser(ctx, userID, now); err != nil {
+		return err
+	}
+	return u.users.RevokeAccessTokens(ctx, userID, now)
+}
+
+// AccessTokenValid は issuedAt に発行したアクセストークンをまだ受け付けるかを返す。
+// 削除済みのユーザーと、RevokeAll より前に発行したトークンは拒否する。
+func (u *TokenUsecase) AccessTokenValid(ctx context.Context, userID uuid.UUID, issuedAt time.Time) bool {
+	user, err := u.users.GetByID(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return user.TokensNotBefore == nil || !issuedAt.Before(*user.TokensNotBefore)
+}
+
+func (u *TokenUsecase) issue(ctx context.Context, userID, familyID uuid.UUID) (*RefreshTokenGrant, error) {
+	raw, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	token := &entity.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: u.clock.Now().Add(u.cfg.RefreshTokenTTL()),
+	}
+	if err := u.tokens.Create(ctx, token); err != nil {
+		return nil, err
+	}
+	return &RefreshTokenGrant{UserID: userID, Token: raw, ExpiresAt: token.ExpiresAt}, nil
+}
+
+// generateOpaqueToken は URL セーフな 256 bit の乱数トークンを返す。
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken は保存・検索用にトークンの SHA-256 を hex で返す。
+// 乱数トークンはエントロピーが十分なので、パスワードと違い低速ハッシュは使わない。
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/test/fakes"
+)
+
+// memoryRefreshTokens はハッシュで引けるだけの最小限のリフレッシュトークン保存先を fake に被せる。
+func memoryRefreshTokens() (*fakes.FakeRefreshTokenRepository, map[string]*entity.RefreshToken) {
+	stored := make(map[string]*entity.RefreshToken)
+	repo := &fakes.FakeRefreshTokenRepository{
+		CreateFn: func(_ context.Context, token *entity.RefreshToken) error {
+			stored[token.TokenHash] = token
+			return nil
+		},
+		GetByHashFn: func(_ context.Context, hash string) (*entity.RefreshToken, error) {
+			token, ok := stored[hash]
+			if !ok {
+				return nil, errors.New("not found")
+			}
+			copied := *token
+			return &copied, nil
+		},
+		MarkUsedFn: func(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+			for _, token := range stored {
+				if token.ID == id && token.UsedAt == nil && token.RevokedAt == nil {
+					token.UsedAt = &at
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		RevokeFamilyFn: func(_ context.Context, familyID uuid.UUID, at time.Time) error {
+			for _, token := range stored {
+				if token.FamilyID == familyID && token.RevokedAt == nil {
+					token.RevokedAt = &at
+				}
+			}
+			return nil
+		},
+	}
+	return repo, stored
+}
+
+func TestTokenUsecase_RotateReplacesTokenAndStoresOnlyHash(t *testing.T) {
+	repo, stored := memoryRefreshTokens()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	uc := NewTokenUsecase(repo, &fakes.FakeUserRepository{}, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+	userID := uuid.New()
+
+	first, err := uc.Issue(context.Background(), userID)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(30*24*time.Hour), first.ExpiresAt)
+	for hash := range stored {
+		require.NotEqual(t, first.Token, hash)
+	}
+
+	second, err := uc.Rotate(context.Background(), first.Token)
+	require.NoError(t, err)
+	require.Equal(t, userID, second.UserID)
+	require.NotEqual(t, first.Token, second.Token)
+	require.Equal(t, stored[hashToken(first.Token)].FamilyID, stored[hashToken(second.Token)].FamilyID)
+
+	// 使用済みトークンの再提示はファミリーごと失効させ、正規の新しいトークンも使えなくなる。
+	_, err = uc.Rotate(context.Background(), first.Token)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = uc.Rotate(context.Background(), second.Token)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestTokenUsecase_RejectsExpiredUnknownAndRevokedTokens(t *testing.T) {
+	repo, _ := memoryRefreshTokens()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }}
+	uc := NewTokenUsecase(repo, &fakes.FakeUserRepository{}, stubConfig{}, clock)
+
+	_, err := uc.Rotate(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	revoked, err := uc.Issue(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.NoError(t, uc.Revoke(context.Background(), revoked.Token))
+	_, err = uc.Rotate(context.Background(), revoked.Token)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	require.NoError(t, uc.Revoke(context.Background(), "unknown"))
+
+	expired, err := uc.Issue(context.Background(), uuid.New())
+	require.NoError(t, err)
+	later := NewTokenUsecase(repo, &fakes.FakeUserRepository{}, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now.Add(31 * 24 * time.Hour) }})
+	_, err = later.Rotate(context.Background(), expired.Token)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestTokenUsecase_RevokeAllRejectsEarlierAccessTokens(t *testing.T) {
+	repo, _ := memoryRefreshTokens()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	user := &entity.User{ID: uuid.New()}
+	users := &fakes.FakeUserRepository{
+		GetByIDFn: func(_ context.Context, id uuid.UUID) (*entity.User, error) {
+			if id != user.ID {
+				return nil, repository.ErrNotFound
+			}
+			return user, nil
+		},
+		RevokeAccessTokensFn: func(_ context.Context, _ uuid.UUID, at time.Time) error {
+			user.TokensNotBefore = &at
+			return nil
+		},
+	}
+	uc := NewTokenUsecase(repo, users, stubConfig{}, fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }})
+
+	require.True(t, uc.AccessTokenValid(context.Background(), user.ID, now.Add(-time.Minute)))
+	require.True(t, uc.AccessTokenValid(context.Background(), user.ID, time.Time{}))
+	require.NoError(t, uc.RevokeAll(context.Background(), user.ID))
+	require.False(t, uc.AccessTokenValid(context.Background(), user.ID, now.Add(-time.Minute)))
+	// 発行時刻を持たない旧形式のトークンも失効後は拒否する。
+	require.False(t, uc.AccessTokenValid(context.Background(), user.ID, time.Time{}))
+	require.True(t, uc.AccessTokenValid(context.Background(), user.ID, now))
+
+	// 削除済みのユーザーのトークンは受け付けない。
+	require.False(t, uc.AccessTokenValid(context.Background(), uuid.New(), now))
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// RFC 6238 付録 B の SHA1 の値。6 桁にするので下 6 桁を比べる。
+	key := []byte("12345678901234567890")
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range cases {
+		require.Equal(t, want, totpCode(key, totpStep(time.Unix(unix, 0))), unix)
+	}
+}
+
+func TestVerifyTOTP_AllowsSkewAndRejectsReuse(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	at := time.Unix(1111111111, 0)
+	previous := totpCode([]byte("12345678901234567890"), totpStep(at)-1)
+
+	step, ok := verifyTOTP(secret, previous, at, 0)
+	require.True(t, ok)
+	require.Equal(t, totpStep(at)-1, step)
+	_, ok = verifyTOTP(secret, previous, at, step)
+	require.False(t, ok)
+	_, ok = verifyTOTP(secret, previous, at.Add(2*time.Minute), 0)
+	require.False(t, ok)
+}
+
+func TestTOTPURI_EncodesIssuerAndAccount(t *testing.T) {
+	uri, err := url.Parse(totpURI("JBSWY3DPEHPK3PXP", "user+1@example.com"))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/ChronoMe:user+1@example.com", uri.Path)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	require.Equal(t, "ChronoMe", uri.Query().Get("issuer"))
+	require.Equal(t, "6", uri.Query().Get("digits"))
+}
@@ -0,0 +1,291 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/domain/repository"
+	"chronome/internal/usecase/dto"
+	"chronome/internal/usecase/provider"
+)
+
+const (
+	// loginChallengeTTL はパスワード確認後、二要素目を入力するまでに許す時間。
+	loginChallengeTTL = 5 * time.Minute
+	// maxLoginChallengeAttempts を超えてコードを間違えたチャレンジは使えなくなり、パスワードからやり直しになる。
+	maxLoginChallengeAttempts = 5
+	recoveryCodeCount         = 10
+	recoveryCodeLength        = 10
+	// recoveryCodeAlphabet は読み間違えやすい 0/O と 1/I を除いた 32 文字。
+	recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+var (
+	// ErrTwoFactorAlreadyEnabled は二要素認証を有効にしたまま登録をやり直そうとしたことを表す。
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotSetUp は共有鍵を発行する前に有効化しようとしたことを表す。
+	ErrTwoFactorNotSetUp = errors.New("two-factor setup has not been started")
+	// ErrTwoFactorNotEnabled は二要素認証が無効なユーザーに無効化や再発行を求めたことを表す。
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrInvalidTwoFactorCode は TOTP コードまたはリカバリーコードが一致しないことを表す。
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrInvalidLoginChallenge は未知・期限切れ・使用済み・試行回数超過のチャレンジを表す。
+	ErrInvalidLoginChallenge = errors.New("invalid or expired login challenge")
+)
+
+// TwoFactorUsecase は TOTP による二要素認証の登録と、二段階ログインのチャレンジを扱う。
+type TwoFactorUsecase struct {
+	users         repository.UserRepository
+	recoveryCodes repository.RecoveryCodeRepository
+	challenges    repository.LoginChallengeRepository
+	clock         provider.Clock
+}
+
+func NewTwoFactorUsecase(users repository.UserRepository, recoveryCodes repository.RecoveryCodeRepository, challenges repository.LoginChallengeRepository, clock provider.Clock) *TwoFactorUsecase {
+	return &TwoFactorUsecase{users: users, recoveryCodes: recoveryCodes, challenges: challenges, clock: clock}
+}
+
+// TOTPProvisioning は認証アプリに登録する共有鍵と otpauth URI。
+type TOTPProvisioning struct {
+	Secret string
+	URI    string
+}
+
+// TwoFactorStatus は設定画面に表示する二要素認証の状態。
+type TwoFactorStatus struct {
+	Enabled                bool
+	RecoveryCodesRemaining int64
+}
+
+// LoginChallengeGrant は発行したチャレンジの平文トークン。平文はこの時だけ取得できる。
+type LoginChallengeGrant struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+func (u *TwoFactorUsecase) Status(ctx context.Context, userID uuid.UUID) (*TwoFactorStatus, error) {
+	user, err := u.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{Enabled: user.TwoFactorEnabled()}
+	if status.Enabled {
+		remaining, err := u.recoveryCodes.CountUnused(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		status.RecoveryCodesRemaining = remaining
+	}
+	return status, nil
+}
+
+// Setup は現在のパスワードを確認してから新しい共有鍵を発行する。Enable でコードを確認するまでログインには影響しない。
+// 途中でやり直した場合は以前の鍵を捨てる。
+func (u *TwoFactorUsecase) Setup(ctx context.Context, userID uuid.UUID, input dto.TwoFactorPasswordRequest) (*TOTPProvisioning, error) {
+	password, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	user, err := reauthenticate(ctx, u.users, userID, password)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := u.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return &TOTPProvisioning{Secret: secret, URI: totpURI(secret, user.Email)}, nil
+}
+
+// Enable は現在のパスワードと認証アプリのコードで共有鍵の登録を確かめてから二要素認証を有効にし、リカバリーコードを返す。
+// 盗まれたセッションだけで他人の鍵を登録され、本人が締め出されないようにパスワードを求める。
+func (u *TwoFactorUsecase) Enable(ctx context.Context, userID uuid.UUID, input dto.TwoFactorEnableRequest) ([]string, error) {
+	code, password, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	user, err := reauthenticate(ctx, u.users, userID, password)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	now := u.clock.Now()
+	step, ok := verifyTOTP(user.TOTPSecret, code, now, user.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	codes, err := u.replaceRecoveryCodes(ctx, user.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPEnabledAt = &now
+	user.TOTPLastStep = step
+	if err := u.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable は現在のパスワードを確認してから二要素認証を無効にし、共有鍵とリカバリーコードを破棄する。
+func (u *TwoFactorUsecase) Disable(ctx context.Context, userID uuid.UUID, input dto.TwoFactorPasswordRequest) error {
+	password, err := input.Normalize()
+	if err != nil {
+		return err
+	}
+	user, err := reauthenticate(ctx, u.users, userID, password)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := u.recoveryCodes.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	return u.users.Update(ctx, user)
+}
+
+// RegenerateRecoveryCodes は現在のパスワードを確認してからリカバリーコードを作り直す。以前のコードは使えなくなる。
+func (u *TwoFactorUsecase) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, input dto.TwoFactorPasswordRequest) ([]string, error) {
+	password, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	user, err := reauthenticate(ctx, u.users, userID, password)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return u.replaceRecoveryCodes(ctx, userID, u.clock.Now())
+}
+
+// Challenge はパスワード確認を済ませたユーザーに、二要素目を待つチャレンジを発行する。
+func (u *TwoFactorUsecase) Challenge(ctx context.Context, user *entity.User) (*LoginChallengeGrant, error) {
+	raw, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	now := u.clock.Now()
+	challenge := &entity.LoginChallenge{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: now.Add(loginChallengeTTL),
+		CreatedAt: now,
+	}
+	if err := u.challenges.Create(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return &LoginChallengeGrant{Token: raw, ExpiresAt: challenge.ExpiresAt}, nil
+}
+
+// CompleteLogin はチャレンジに TOTP コードかリカバリーコードで応え、成功したらログインするユーザーを返す。
+// コードを間違えるたびに試行回数を数え、上限に達したチャレンジは使えなくする。
+func (u *TwoFactorUsecase) CompleteLogin(ctx context.Context, input dto.TwoFactorLoginRequest) (*entity.User, error) {
+	data, err := input.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := u.challenges.GetByHash(ctx, hashToken(data.ChallengeToken))
+	if err != nil {
+		return nil, ErrInvalidLoginChallenge
+	}
+	now := u.clock.Now()
+	if !challenge.Usable(now, maxLoginChallengeAttempts) {
+		return nil, ErrInvalidLoginChallenge
+	}
+	user, err := u.users.GetByID(ctx, challenge.UserID)
+	if err != nil || !user.TwoFactorEnabled() {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	step, ok := int64(0), false
+	if data.Code != "" {
+		step, ok = verifyTOTP(user.TOTPSecret, data.Code, now, user.TOTPLastStep)
+	} else {
+		ok, err = u.recoveryCodes.Use(ctx, user.ID, hashToken(data.RecoveryCode), now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		if err := u.challenges.RecordFailure(ctx, challenge.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	marked, err := u.challenges.MarkUsed(ctx, challenge.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, ErrInvalidLoginChallenge
+	}
+	if step > 0 {
+		user.TOTPLastStep = step
+		if err := u.users.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// replaceRecoveryCodes は新しいリカバリーコードを保存し、表示用に区切りを入れた平文を返す。
+func (u *TwoFactorUsecase) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID, now time.Time) ([]string, error) {
+	plain := make([]string, 0, recoveryCodeCount)
+	codes := make([]entity.RecoveryCode, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		raw, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		plain = append(plain, raw[:recoveryCodeLength/2]+"-"+raw[recoveryCodeLength/2:])
+		codes = append(codes, entity.RecoveryCode{
+			ID:        uuid.New(),
+			UserID:    userID,
+			CodeHash:  hashToken(raw),
+			CreatedAt: now,
+		})
+	}
+	if err := u.recoveryCodes.Replace(ctx, userID, codes); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+// generateRecoveryCode は区切りなしの大文字のコードを返す。ハッシュは dto.NormalizeRecoveryCode と同じ形から作る。
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	// 256 は 32 で割り切れるので、剰余を取っても文字の出現率に偏りは出ない。
+	for i, b := range buf {
+		buf[i] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+	}
+	return string(buf), nil
+}
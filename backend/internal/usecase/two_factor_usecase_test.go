@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"chronome/internal/domain/entity"
+	"chronome/internal/usecase/dto"
+	"chronome/test/fakes"
+)
+
+// memoryRecoveryCodes はハッシュで照合できる最小限のリカバリーコード保存先を fake に被せる。
+func memoryRecoveryCodes() *fakes.FakeRecoveryCodeRepository {
+	var stored []entity.RecoveryCode
+	return &fakes.FakeRecoveryCodeRepository{
+		ReplaceFn: func(_ context.Context, userID uuid.UUID, codes []entity.RecoveryCode) error {
+			kept := stored[:0]
+			for _, code := range stored {
+				if code.UserID != userID {
+					kept = append(kept, code)
+				}
+			}
+			stored = append(kept, codes...)
+			return nil
+		},
+		UseFn: func(_ context.Context, userID uuid.UUID, hash string, at time.Time) (bool, error) {
+			for i := range stored {
+				if stored[i].UserID == userID && stored[i].CodeHash == hash && stored[i].UsedAt == nil {
+					stored[i].UsedAt = &at
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		CountUnusedFn: func(_ context.Context, userID uuid.UUID) (int64, error) {
+			var count int64
+			for _, code := range stored {
+				if code.UserID == userID && code.UsedAt == nil {
+					count++
+				}
+			}
+			return count, nil
+		},
+	}
+}
+
+// memoryLoginChallenges はハッシュで引けるチャレンジの保存先を fake に被せる。
+func memoryLoginChallenges() *fakes.FakeLoginChallengeRepository {
+	stored := make(map[string]*entity.LoginChallenge)
+	find := func(id uuid.UUID) *entity.LoginChallenge {
+		for _, challenge := range stored {
+			if challenge.ID == id {
+				return challenge
+			}
+		}
+		return nil
+	}
+	return &fakes.FakeLoginChallengeRepository{
+		CreateFn: func(_ context.Context, challenge *entity.LoginChallenge) error {
+			stored[challenge.TokenHash] = challenge
+			return nil
+		},
+		GetByHashFn: func(_ context.Context, hash string) (*entity.LoginChallenge, error) {
+			challenge, ok := stored[hash]
+			if !ok {
+				return nil, errors.New("not found")
+			}
+			copied := *challenge
+			return &copied, nil
+		},
+		RecordFailureFn: func(_ context.Context, id uuid.UUID) error {
+			find(id).Attempts++
+			return nil
+		},
+		MarkUsedFn: func(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+			challenge := find(id)
+			if challenge == nil || challenge.UsedAt != nil {
+				return false, nil
+			}
+			challenge.UsedAt = &at
+			return true, nil
+		},
+	}
+}
+
+// currentTOTP は認証アプリの代わりに at のコードを計算する。
+func currentTOTP(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	return totpCode(key, totpStep(at))
+}
+
+func enableTwoFactorForTest(t *testing.T, uc *TwoFactorUsecase, user *entity.User, at time.Time) []string {
+	t.Helper()
+	provisioning, err := uc.Setup(context.Background(), user.ID, dto.TwoFactorPasswordRequest{Password: "password-1"})
+	require.NoError(t, err)
+	codes, err := uc.Enable(context.Background(), user.ID, dto.TwoFactorEnableRequest{Code: currentTOTP(t, provisioning.Secret, at), Password: "password-1"})
+	require.NoError(t, err)
+	return codes
+}
+
+func TestTwoFactorUsecase_EnableRequiresValidCode(t *testing.T) {
+	user := userWithPassword(t, "user@example.com", "password-1")
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }}
+	recovery := memoryRecoveryCodes()
+	uc := NewTwoFactorUsecase(memoryUsers(user), recovery, memoryLoginChallenges(), clock)
+
+	_, err := uc.Enable(context.Background(), user.ID, dto.TwoFactorEnableRequest{Code: "123456", Password: "password-1"})
+	require.ErrorIs(t, err, ErrTwoFactorNotSetUp)
+
+	// セッションを盗んだだけでは鍵を発行・登録できないよう、現在のパスワードを求める。
+	_, err = uc.Setup(context.Background(), user.ID, dto.TwoFactorPasswordRequest{Password: "wrong-password"})
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+	require.Empty(t, user.TOTPSecret)
+
+	provisioning, err := uc.Setup(context.Background(), user.ID, dto.TwoFactorPasswordRequest{Password: "password-1"})
+	require.NoError(t, err)
+	require.Contains(t, provisioning.URI, "secret="+provisioning.Secret)
+	require.False(t, user.TwoFactorEnabled())
+
+	_, err = uc.Enable(context.Background(), user.ID, dto.TwoFactorEnableRequest{Code: currentTOTP(t, provisioning.Secret, now), Password: "wrong-password"})
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+	_, err = uc.Enable(context.Background(), user.ID, dto.TwoFactorEnableRequest{Code: currentTOTP(t, provisioning.Secret, now)})
+	var valErr dto.ValidationError
+	require.True(t, errors.As(err, &valErr))
+	require.False(t, user.TwoFactorEnabled())
+
+	wrong := currentTOTP(t, provisioning.Secret, now.Add(time.Hour))
+	_, err = uc.Enable(context.Background(), user.ID, dto.TwoFactorEnableRequest{Code: wrong, Password: "password-1"})
+	require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	require.False(t, user.TwoFactorEnabled())
+
+	codes, err := uc.Enable(context.Background(), user.ID, dto.TwoFactorEnableRequest{Code: currentTOTP(t, provisioning.Secret, now), Password: "password-1"})
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+	require.Regexp(t, `^[A-Z2-9]{5}-[A-Z2-9]{5}$`, codes[0])
+	require.True(t, user.TwoFactorEnabled())
+
+	_, err = uc.Setup(context.Background(), user.ID, dto.TwoFactorPasswordRequest{Password: "password-1"})
+	require.ErrorIs(t, err, ErrTwoFactorAlreadyEnabled)
+	status, err := uc.Status(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(recoveryCodeCount), status.RecoveryCodesRemaining)
+
+	err = uc.Disable(context.Background(), user.ID, dto.TwoFactorPasswordRequest{Password: "wrong-password"})
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+	require.NoError(t, uc.Disable(context.Background(), user.ID, dto.TwoFactorPasswordRequest{Password: "password-1"}))
+	require.False(t, user.TwoFactorEnabled())
+	require.Empty(t, user.TOTPSecret)
+}
+
+func TestTwoFactorUsecase_CompleteLoginRejectsReplayAndLimitsAttempts(t *testing.T) {
+	user := userWithPassword(t, "user@example.com", "password-1")
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	clock := fakes.FixedTimeProvider{NowFunc: func() time.Time { return now }}
+	uc := NewTwoFactorUsecase(memoryUsers(user), memoryRecoveryCodes(), memoryLoginChallenges(), clock)
+	recoveryCodes := enableTwoFactorForTest(t, uc, user, now)
+
+	// 有効化に使ったコードはログインには使えないので、次のステップまで進める。
+	now = now.Add(totpPeriod * time.Second)
+	challenge, err := uc.Challenge(context.Background(), user)
+	require.NoError(t, err)
+	code := currentTOTP(t, user.TOTPSecret, now)
+	loggedIn, err := uc.CompleteLogin(context.Background(), dto.TwoFactorLoginRequest{ChallengeToken: challenge.Token, Code: code})
+	require.NoError(t, err)
+	require.Equal(t, user.ID, loggedIn.ID)
+	_, err = uc.CompleteLogin(context.Background(), dto.TwoFactorLoginRequest{ChallengeToken: challenge.Token, Code: code})
+	require.ErrorIs(t, err, ErrInvalidLoginChallenge)
+
+	// 同じコードは新しいチャレンジでも使えない。
+	again, err := uc.Challenge(context.Background(), user)
+	require.NoError(t, err)
+	_, err = uc.CompleteLogin(context.Background(), dto.TwoFactorLoginRequest{ChallengeToken: again.Token, Code: code})
+	require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	// リカバリーコードは区切りや大文字小文字を問わず 1 回だけ使える。
+	loggedIn, err = uc.CompleteLogin(context.Background(), dto.TwoFactorLoginRequest{ChallengeToken: again.Token, RecoveryCode: " " + recoveryCodes[0][:5] + recoveryCodes[0][6:] + " "})
+	require.NoError(t, err)
+	require.Equal(t, user.ID, loggedIn.ID)
+	third, err := uc.Challenge(context.Background(), user)
+	require.NoError(t, err)
+	_, err = uc.CompleteLogin(context.Background(), dto.TwoFactorLoginRequest{ChallengeToken: third.Token, RecoveryCode: recoveryCodes[0]})
+	require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	// 上限まで間違えたチャレンジは正しいコードでも通らない。
+	for i := 1; i < maxLoginChallengeAttempts; i++ {
+		_, err = uc.CompleteLogin(context.Background(), dto.TwoFactorLoginRequest{ChallengeToken: third.Token, Code: "000000"})
+		require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	}
+	now = now.Add(totpPeriod * time.Second)
+	_, err = uc.CompleteLogin(context.Background(), dto.TwoFactorLoginRequest{ChallengeToken: third.Token, Code: currentTOTP(t, user.TOTPSecret, now)})
+	require.ErrorIs(t, err, ErrInvalidLoginChallenge)
+}
@@ -142,7 +142,7 @@ func TestAuthUsecase_SignupRejectsExistingUser(t *testing.T) {
 			return &entity.User{ID: uuid.New()}, nil
 		},
 	}
-	uc := NewAuthUsecase(repo)
+	uc := NewAuthUsecase(repo, stubConfig{})
 
 	_, err := uc.Signup(context.Background(), SignupParams{Email: "taken@example.com", Password: "secret"})
 	require.Error(t, err)
@@ -157,7 +157,7 @@ func TestAuthUsecase_LoginFailsForInvalidPassword(t *testing.T) {
 			return &entity.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: string(hash)}, nil
 		},
 	}
-	uc := NewAuthUsecase(repo)
+	uc := NewAuthUsecase(repo, stubConfig{})
 
 	_, err = uc.Login(context.Background(), "user@example.com", "wrong")
 	require.EqualError(t, err, "invalid credentials")
@@ -175,7 +175,7 @@ func TestReportUsecase_DailyAggregatesEntries(t *testing.T) {
 		},
 	}
 	projectRepo := &fakes.FakeProjectRepository{}
-	uc := NewReportUsecase(repo, projectRepo)
+	uc := NewReportUsecase(repo, projectRepo, &fakes.FakeScheduleRepository{})
 
 	loc := time.FixedZone("JST", 9*3600)
 	start := time.Date(2024, 1, 5, 0, 0, 0, 0, loc)
@@ -197,7 +197,7 @@ func TestAuthUsecase_SignupValidatesRequiredFields(t *testing.T) {
 		GetByEmailFn: func(context.Context, string) (*entity.User, error) {
 			return nil, errors.New("not found")
 		},
-	})
+	}, stubConfig{})
 	_, err := uc.Signup(context.Background(), SignupParams{Email: "", Password: ""})
 	require.EqualError(t, err, "email and password are required")
 }
@@ -249,7 +249,7 @@ func TestReportUsecase_WeeklyAggregates(t *testing.T) {
 			return []entity.Project{{ID: projectID, UserID: userID, Name: "Backend", Color: "#111111"}}, nil
 		},
 	}
-	uc := NewReportUsecase(repo, projectRepo)
+	uc := NewReportUsecase(repo, projectRepo, &fakes.FakeScheduleRepository{})
 	loc := time.FixedZone("UTC+1", 3600)
 	start := time.Date(2024, 1, 1, 0, 0, 0, 0, loc)
 	report, err := uc.Weekly(context.Background(), userID, ReportRange{
@@ -285,7 +285,7 @@ func TestReportUsecase_MonthlyBreakdown(t *testing.T) {
 			return []entity.Project{{ID: projectID, Name: "Backend", Color: "#111"}}, nil
 		},
 	}
-	uc := NewReportUsecase(repo, projectRepo)
+	uc := NewReportUsecase(repo, projectRepo, &fakes.FakeScheduleRepository{})
 	loc := time.UTC
 	start := time.Date(2024, 2, 1, 0, 0, 0, 0, loc)
 	report, err := uc.Monthly(context.Background(), uuid.New(), ReportRange{
@@ -300,6 +300,31 @@ func TestReportUsecase_MonthlyBreakdown(t *testing.T) {
 	require.Equal(t, "Backend", report.Projects[0].Name)
 }
 
+func TestAllocationUsecase_ListClampsPaging(t *testing.T) {
+	var gotLimit, gotOffset int
+	repo := &fakes.FakeAllocationRepository{
+		ListFn: func(_ context.Context, _ uuid.UUID, limit, offset int) ([]entity.AllocationRequest, int64, error) {
+			gotLimit, gotOffset = limit, offset
+			return nil, 250, nil
+		},
+	}
+	uc := NewAllocationUsecase(repo, &fakes.FakeEntryRepository{}, &fakes.FakeProjectRepository{}, &fakes.FakeTagRepository{}, fakes.FixedTimeProvider{})
+
+	page, err := uc.List(context.Background(), uuid.New(), 3, 500)
+	require.NoError(t, err)
+	require.Equal(t, 100, gotLimit)
+	require.Equal(t, 200, gotOffset)
+	require.Equal(t, 100, page.PerPage)
+	require.Equal(t, int64(250), page.Total)
+	require.NotNil(t, page.Allocations)
+
+	page, err = uc.List(context.Background(), uuid.New(), 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, page.Page)
+	require.Equal(t, 20, gotLimit)
+	require.Equal(t, 0, gotOffset)
+}
+
 func TestDistributeAllocations_MinSumExceedsTotal(t *testing.T) {
 	_, err := distributeAllocations(dto.AllocationRequestData{
 		TotalMinutes: 30,
@@ -362,6 +387,46 @@ func (stubConfig) SessionTTL() time.Duration {
 	return time.Hour
 }
 
+func (stubConfig) IdleThreshold() time.Duration {
+	return 15 * time.Minute
+}
+
+func (stubConfig) AutoStopAfter() time.Duration {
+	return 8 * time.Hour
+}
+
+func (stubConfig) RefreshTokenTTL() time.Duration {
+	return 30 * 24 * time.Hour
+}
+
+func (stubConfig) PasswordResetTTL() time.Duration {
+	return time.Hour
+}
+
+func (stubConfig) PasswordResetURL() string {
+	return "https://chronome.example/reset-password"
+}
+
+func (stubConfig) EmailVerificationTTL() time.Duration {
+	return 24 * time.Hour
+}
+
+func (stubConfig) EmailVerificationURL() string {
+	return "https://chronome.example/verify-email"
+}
+
+func (stubConfig) EmailVerificationMode() provider.EmailVerificationMode {
+	return provider.EmailVerificationOptional
+}
+
+func (stubConfig) EmailVerificationResendInterval() time.Duration {
+	return time.Minute
+}
+
+func (stubConfig) OIDCAllowSignup() bool {
+	return true
+}
+
 var _ provider.AppConfig = stubConfig{}
 
 func intPtr(value int) *int {
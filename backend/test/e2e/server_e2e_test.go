@@ -20,6 +20,7 @@ import (
 	"chronome/internal/adapter/http/middleware"
 	"chronome/internal/adapter/infra/config"
 	"chronome/internal/adapter/infra/database"
+	"chronome/internal/adapter/infra/ratelimit"
 	sess "chronome/internal/adapter/infra/session"
 	infTime "chronome/internal/adapter/infra/time"
 	"chronome/internal/domain/entity"
@@ -156,6 +157,8 @@ func newFixture(t *testing.T) *fixture {
 		AllowedOrigin:          "http://localhost",
 		Environment:            "test",
 		DefaultProjectColorHex: "#3B82F6",
+		AccessTokenTTLValue:    15 * time.Minute,
+		RefreshTokenTTLValue:   24 * time.Hour,
 	}
 
 	db, err := database.Open(cfg)
@@ -168,19 +171,57 @@ func newFixture(t *testing.T) *fixture {
 	require.NoError(t, database.Automigrate(db))
 
 	sessionStore := sess.NewMemoryStore()
+	tokenSigner, err := sess.NewHMACTokenSigner(cfg.SessionSecret)
+	require.NoError(t, err)
 	userRepo := gormrepo.NewUserRepository(db)
 	projectRepo := gormrepo.NewProjectRepository(db)
 	entryRepo := gormrepo.NewEntryRepository(db)
 	tagRepo := gormrepo.NewTagRepository(db)
-
-	authUC := usecase.NewAuthUsecase(userRepo)
+	scheduleRepo := gormrepo.NewScheduleRepository(db)
+
+	authUC := usecase.NewAuthUsecase(userRepo, cfg)
+	tokenUC := usecase.NewTokenUsecase(gormrepo.NewRefreshTokenRepository(db), userRepo, cfg, infTime.SystemClock{})
+	personalTokenUC := usecase.NewPersonalTokenUsecase(gormrepo.NewPersonalTokenRepository(db), infTime.SystemClock{})
+	passwordResetUC := usecase.NewPasswordResetUsecase(userRepo, gormrepo.NewPasswordResetRepository(db), &fakes.RecordingMailer{}, cfg, infTime.SystemClock{})
+	emailVerificationUC := usecase.NewEmailVerificationUsecase(userRepo, gormrepo.NewEmailVerificationRepository(db), &fakes.RecordingMailer{}, cfg, infTime.SystemClock{})
+	accountUC := usecase.NewAccountUsecase(userRepo, emailVerificationUC, &fakes.RecordingMailer{})
+	twoFactorUC := usecase.NewTwoFactorUsecase(userRepo, gormrepo.NewRecoveryCodeRepository(db), gormrepo.NewLoginChallengeRepository(db), infTime.SystemClock{})
 	projectUC := usecase.NewProjectUsecase(projectRepo, cfg)
 	tagUC := usecase.NewTagUsecase(tagRepo, cfg)
 	entryUC := usecase.NewEntryUsecase(entryRepo, tagRepo, infTime.SystemClock{})
-	reportUC := usecase.NewReportUsecase(entryRepo, projectRepo)
-
-	allocationUC := usecase.NewAllocationUsecase(&fakes.FakeAllocationRepository{}, fakes.FixedTimeProvider{})
-	apiHandler := handler.NewAPIHandler(cfg, sessionStore, authUC, projectUC, tagUC, entryUC, reportUC, allocationUC)
+	reportUC := usecase.NewReportUsecase(entryRepo, projectRepo, scheduleRepo)
+
+	allocationUC := usecase.NewAllocationUsecase(&fakes.FakeAllocationRepository{}, entryRepo, projectRepo, tagRepo, fakes.FixedTimeProvider{})
+	allocationTemplateUC := usecase.NewAllocationTemplateUsecase(&fakes.FakeAllocationTemplateRepository{}, allocationUC, entryRepo, projectRepo, tagRepo, fakes.FixedTimeProvider{})
+	favoriteUC := usecase.NewFavoriteUsecase(gormrepo.NewFavoriteRepository(db), entryRepo, tagRepo, infTime.SystemClock{})
+	idleUC := usecase.NewIdleUsecase(entryRepo, cfg, infTime.SystemClock{})
+	pomodoroUC := usecase.NewPomodoroUsecase(gormrepo.NewPomodoroRepository(db), entryRepo, infTime.SystemClock{})
+	goalUC := usecase.NewGoalUsecase(gormrepo.NewGoalRepository(db), entryRepo, projectRepo, tagRepo, infTime.SystemClock{})
+	scheduleUC := usecase.NewScheduleUsecase(scheduleRepo)
+	apiHandler := handler.NewAPIHandler(handler.APIHandlerDeps{
+		Config:    cfg,
+		Sessions:  sessionStore,
+		Signer:    tokenSigner,
+		Limits:    ratelimit.NewMemoryStore(),
+		Auth:      authUC,
+		Tokens:    tokenUC,
+		Personal:  personalTokenUC,
+		Resets:    passwordResetUC,
+		Accounts:  accountUC,
+		Verifier:  emailVerificationUC,
+		TwoFactor: twoFactorUC,
+		Projects:  projectUC,
+		Tags:      tagUC,
+		Entries:   entryUC,
+		Reports:   reportUC,
+		Allocs:    allocationUC,
+		Templates: allocationTemplateUC,
+		Favorites: favoriteUC,
+		Idle:      idleUC,
+		Pomodoros: pomodoroUC,
+		Goals:     goalUC,
+		Schedules: scheduleUC,
+	})
 	server := httptest.NewServer(apiHandler.Router())
 
 	jar, err := cookiejar.New(nil)
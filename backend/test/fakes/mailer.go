@@ -0,0 +1,35 @@
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"chronome/internal/usecase/provider"
+)
+
+// RecordingMailer は送信したメールを保持し、テストで本文を確認できるようにする。
+type RecordingMailer struct {
+	mu   sync.Mutex
+	Sent []provider.MailMessage
+	// Err を設定すると送信を記録せずにそのエラーを返す。
+	Err error
+}
+
+func (m *RecordingMailer) Send(_ context.Context, msg provider.MailMessage) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Sent = append(m.Sent, msg)
+	return nil
+}
+
+// Messages は送信済みメールのコピーを返す。
+func (m *RecordingMailer) Messages() []provider.MailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]provider.MailMessage(nil), m.Sent...)
+}
+
+var _ provider.Mailer = (*RecordingMailer)(nil)
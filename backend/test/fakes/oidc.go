@@ -0,0 +1,299 @@
+package fakes
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// OIDCIdentity は FakeOIDCProvider でログインさせる ID プロバイダー側のユーザー。
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// FakeOIDCProvider はテスト用のプロセス内 OpenID Connect プロバイダー。
+// ディスカバリー、JWKS、認可、トークンの各エンドポイントを提供し、PKCE (S256) を確かめてから RS256 で署名した ID トークンを返す。
+type FakeOIDCProvider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu       sync.Mutex
+	identity *OIDCIdentity
+	key      *rsa.PrivateKey
+	keyID    string
+	forged   *rsa.PrivateKey
+	grants   map[string]oidcGrant
+	modify   func(claims map[string]any)
+}
+
+// oidcGrant は発行した認可コードに結び付けた認可リクエストの内容。
+type oidcGrant struct {
+	identity      OIDCIdentity
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// NewFakeOIDCProvider はプロバイダーを起動し、テストの終了時に止める。
+func NewFakeOIDCProvider(t testing.TB) *FakeOIDCProvider {
+	t.Helper()
+	p := &FakeOIDCProvider{
+		ClientID:     "chronome-test",
+		ClientSecret: "client-secret",
+		grants:       make(map[string]oidcGrant),
+	}
+	p.RotateKey(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	mux.HandleFunc("GET /authorize", p.authorize)
+	mux.HandleFunc("POST /token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Server.Close)
+	return p
+}
+
+// Issuer は ID トークンの iss とディスカバリー文書の issuer に使う URL を返す。
+func (p *FakeOIDCProvider) Issuer() string {
+	return p.Server.URL
+}
+
+// SignIn は以降の認可リクエストで identity をログインさせる。nil にするとユーザーが拒否したものとして error=access_denied を返す。
+func (p *FakeOIDCProvider) SignIn(identity *OIDCIdentity) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.identity = identity
+}
+
+// ModifyClaims は以降に発行する ID トークンの claims を署名前に書き換える。不正なトークンのテストに使う。
+func (p *FakeOIDCProvider) ModifyClaims(modify func(claims map[string]any)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.modify = modify
+}
+
+// ForgeSignatures を true にすると、JWKS に載せていない鍵で（kid はそのままに）署名する。
+func (p *FakeOIDCProvider) ForgeSignatures(t testing.TB, forge bool) {
+	t.Helper()
+	var key *rsa.PrivateKey
+	if forge {
+		key = generateRSAKey(t)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.forged = key
+}
+
+// RotateKey は署名鍵と kid を新しくする。JWKS には新しい鍵だけを載せる。
+func (p *FakeOIDCProvider) RotateKey(t testing.TB) {
+	t.Helper()
+	key := generateRSAKey(t)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.key = key
+	p.keyID = randomString(8)
+}
+
+// Authorize はブラウザの代わりに認可 URL を開き、リダイレクト先（クライアントのコールバック URL）を返す。
+func (p *FakeOIDCProvider) Authorize(authURL string) (*url.URL, error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return nil, errors.New("authorize returned " + resp.Status)
+	}
+	return resp.Location()
+}
+
+func (p *FakeOIDCProvider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeOIDCJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+func (p *FakeOIDCProvider) jwks(w http.ResponseWriter, _ *http.Request) {
+	p.mu.Lock()
+	key, kid := p.key.PublicKey, p.keyID
+	p.mu.Unlock()
+	writeOIDCJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"use": "sig",
+		"alg": "RS256",
+		"kid": kid,
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+}
+
+func (p *FakeOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" || query.Get("client_id") != p.ClientID ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" ||
+		!strings.Contains(" "+query.Get("scope")+" ", " openid ") {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	callback := redirectURI.Query()
+	callback.Set("state", query.Get("state"))
+	p.mu.Lock()
+	identity := p.identity
+	if identity == nil {
+		callback.Set("error", "access_denied")
+	} else {
+		code := randomString(16)
+		p.grants[code] = oidcGrant{
+			identity:      *identity,
+			redirectURI:   redirectURI.String(),
+			nonce:         query.Get("nonce"),
+			codeChallenge: query.Get("code_challenge"),
+		}
+		callback.Set("code", code)
+	}
+	p.mu.Unlock()
+	redirectURI.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *FakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOIDCError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || subtle.ConstantTimeCompare([]byte(secret), []byte(p.ClientSecret)) != 1 {
+		writeOIDCError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeOIDCError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// 認可コードは 1 回だけ使える。
+	code := r.PostForm.Get("code")
+	grant, ok := p.grants[code]
+	delete(p.grants, code)
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || grant.redirectURI != r.PostForm.Get("redirect_uri") || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.codeChallenge {
+		writeOIDCError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":            p.Issuer(),
+		"sub":            grant.identity.Subject,
+		"aud":            p.ClientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          grant.nonce,
+		"email":          grant.identity.Email,
+		"email_verified": grant.identity.EmailVerified,
+		"name":           grant.identity.Name,
+	}
+	if p.modify != nil {
+		p.modify(claims)
+	}
+	key := p.key
+	if p.forged != nil {
+		key = p.forged
+	}
+	idToken, err := signRS256(key, p.keyID, claims)
+	if err != nil {
+		writeOIDCError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	writeOIDCJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(16),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func signRS256(key *rsa.PrivateKey, kid string, claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func generateRSAKey(t testing.TB) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	return key
+}
+
+func randomString(size int) string {
+	buf := make([]byte, size)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func writeOIDCJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func writeOIDCError(w http.ResponseWriter, status int, code string) {
+	writeOIDCJSON(w, status, map[string]string{"error": code})
+}
@@ -3,6 +3,7 @@ package fakes
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -12,9 +13,12 @@ import (
 
 // FakeUserRepository はテスト用に repository.UserRepository を実装する。
 type FakeUserRepository struct {
-	CreateFn     func(context.Context, *entity.User) error
-	GetByEmailFn func(context.Context, string) (*entity.User, error)
-	GetByIDFn    func(context.Context, uuid.UUID) (*entity.User, error)
+	CreateFn             func(context.Context, *entity.User) error
+	GetByEmailFn         func(context.Context, string) (*entity.User, error)
+	GetByIDFn            func(context.Context, uuid.UUID) (*entity.User, error)
+	UpdateFn             func(context.Context, *entity.User) error
+	DeleteFn             func(context.Context, uuid.UUID) error
+	RevokeAccessTokensFn func(context.Context, uuid.UUID, time.Time) error
 }
 
 func (f *FakeUserRepository) Create(ctx context.Context, user *entity.User) error {
@@ -38,6 +42,27 @@ func (f *FakeUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity
 	return nil, errors.New("GetByID not implemented")
 }
 
+func (f *FakeUserRepository) Update(ctx context.Context, user *entity.User) error {
+	if f.UpdateFn != nil {
+		return f.UpdateFn(ctx, user)
+	}
+	return nil
+}
+
+func (f *FakeUserRepository) RevokeAccessTokens(ctx context.Context, id uuid.UUID, at time.Time) error {
+	if f.RevokeAccessTokensFn != nil {
+		return f.RevokeAccessTokensFn(ctx, id, at)
+	}
+	return nil
+}
+
+func (f *FakeUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	if f.DeleteFn != nil {
+		return f.DeleteFn(ctx, id)
+	}
+	return nil
+}
+
 // FakeProjectRepository はテスト用に repository.ProjectRepository を実装する。
 type FakeProjectRepository struct {
 	CreateFn  func(context.Context, *entity.Project) error
@@ -90,6 +115,9 @@ type FakeEntryRepository struct {
 	UpdateFn      func(context.Context, *entity.Entry) error
 	DeleteFn      func(context.Context, uuid.UUID, uuid.UUID) error
 	ReplaceTagsFn func(context.Context, *entity.Entry, []uuid.UUID) error
+	ListIdleFn    func(context.Context, time.Time) ([]entity.Entry, error)
+	// ApplyChangesFn を省略すると、DeleteFn・UpdateFn・CreateFn と ReplaceTagsFn を変更の順に呼ぶ。Stop は UpdateFn で扱う。
+	ApplyChangesFn func(context.Context, uuid.UUID, repository.EntryChanges) error
 }
 
 func (f *FakeEntryRepository) Create(ctx context.Context, entry *entity.Entry) error {
@@ -110,7 +138,7 @@ func (f *FakeEntryRepository) GetByID(ctx context.Context, userID uuid.UUID, id
 	if f.GetByIDFn != nil {
 		return f.GetByIDFn(ctx, userID, id)
 	}
-	return nil, errors.New("GetByID not implemented")
+	return nil, repository.ErrNotFound
 }
 
 func (f *FakeEntryRepository) Update(ctx context.Context, entry *entity.Entry) error {
@@ -134,6 +162,52 @@ func (f *FakeEntryRepository) ReplaceTags(ctx context.Context, entry *entity.Ent
 	return nil
 }
 
+func (f *FakeEntryRepository) ApplyChanges(ctx context.Context, userID uuid.UUID, changes repository.EntryChanges) error {
+	if f.ApplyChangesFn != nil {
+		return f.ApplyChangesFn(ctx, userID, changes)
+	}
+	for _, id := range changes.Delete {
+		if err := f.Delete(ctx, userID, id); err != nil {
+			return err
+		}
+	}
+	updates := append(append([]entity.Entry{}, changes.Stop...), changes.Update...)
+	for i := range updates {
+		if err := f.Update(ctx, &updates[i]); err != nil {
+			return err
+		}
+		if err := f.ReplaceTags(ctx, &updates[i], tagIDs(updates[i].Tags)); err != nil {
+			return err
+		}
+	}
+	for i := range changes.Create {
+		if err := f.Create(ctx, &changes.Create[i]); err != nil {
+			return err
+		}
+		if len(changes.Create[i].Tags) > 0 {
+			if err := f.ReplaceTags(ctx, &changes.Create[i], tagIDs(changes.Create[i].Tags)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func tagIDs(tags []entity.Tag) []uuid.UUID {
+	ids := make([]uuid.UUID, len(tags))
+	for i, tag := range tags {
+		ids[i] = tag.ID
+	}
+	return ids
+}
+
+func (f *FakeEntryRepository) ListIdleRunning(ctx context.Context, inactiveBefore time.Time) ([]entity.Entry, error) {
+	if f.ListIdleFn != nil {
+		return f.ListIdleFn(ctx, inactiveBefore)
+	}
+	return nil, nil
+}
+
 // FakeTagRepository はテスト用に repository.TagRepository を実装する。
 type FakeTagRepository struct {
 	CreateFn  func(context.Context, *entity.Tag) error
@@ -181,6 +255,14 @@ func (f *FakeTagRepository) Delete(ctx context.Context, userID uuid.UUID, id uui
 // FakeAllocationRepository は分配履歴保存のテスト用実装。
 type FakeAllocationRepository struct {
 	CreateFn func(context.Context, *entity.AllocationRequest, []entity.TaskAllocation) error
+	ListFn   func(context.Context, uuid.UUID, int, int) ([]entity.AllocationRequest, int64, error)
+	// CreateWithEntriesFn が未設定の場合は CreateFn だけを呼び、エントリの変更は捨てる。
+	CreateWithEntriesFn func(context.Context, *entity.AllocationRequest, []entity.TaskAllocation, repository.EntryChanges) error
+	GetByIDFn           func(context.Context, uuid.UUID, uuid.UUID) (*entity.AllocationRequest, []entity.TaskAllocation, error)
+	DeleteFn            func(context.Context, uuid.UUID, uuid.UUID) error
+	// CreateBatchFn / GetBatchFn はバッチ分配用。
+	CreateBatchFn func(context.Context, *entity.AllocationBatch, []entity.AllocationRequest, [][]entity.TaskAllocation) error
+	GetBatchFn    func(context.Context, uuid.UUID, uuid.UUID) (*entity.AllocationBatch, []entity.AllocationRequest, []entity.TaskAllocation, error)
 }
 
 func (f *FakeAllocationRepository) Create(ctx context.Context, request *entity.AllocationRequest, allocations []entity.TaskAllocation) error {
@@ -189,3 +271,593 @@ func (f *FakeAllocationRepository) Create(ctx context.Context, request *entity.A
 	}
 	return nil
 }
+
+func (f *FakeAllocationRepository) CreateWithEntries(ctx context.Context, request *entity.AllocationRequest, allocations []entity.TaskAllocation, changes repository.EntryChanges) error {
+	if f.CreateWithEntriesFn != nil {
+		return f.CreateWithEntriesFn(ctx, request, allocations, changes)
+	}
+	return f.Create(ctx, request, allocations)
+}
+
+func (f *FakeAllocationRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]entity.AllocationRequest, int64, error) {
+	if f.ListFn != nil {
+		return f.ListFn(ctx, userID, limit, offset)
+	}
+	return nil, 0, nil
+}
+
+func (f *FakeAllocationRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationRequest, []entity.TaskAllocation, error) {
+	if f.GetByIDFn != nil {
+		return f.GetByIDFn(ctx, userID, id)
+	}
+	return nil, nil, repository.ErrNotFound
+}
+
+func (f *FakeAllocationRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if f.DeleteFn != nil {
+		return f.DeleteFn(ctx, userID, id)
+	}
+	return nil
+}
+
+func (f *FakeAllocationRepository) CreateBatch(ctx context.Context, batch *entity.AllocationBatch, requests []entity.AllocationRequest, allocations [][]entity.TaskAllocation) error {
+	if f.CreateBatchFn != nil {
+		return f.CreateBatchFn(ctx, batch, requests, allocations)
+	}
+	return nil
+}
+
+func (f *FakeAllocationRepository) GetBatch(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationBatch, []entity.AllocationRequest, []entity.TaskAllocation, error) {
+	if f.GetBatchFn != nil {
+		return f.GetBatchFn(ctx, userID, id)
+	}
+	return nil, nil, nil, repository.ErrNotFound
+}
+
+// FakeFavoriteRepository はテスト用に repository.FavoriteRepository を実装する。
+type FakeFavoriteRepository struct {
+	CreateFn      func(context.Context, *entity.Favorite) error
+	ListFn        func(context.Context, uuid.UUID) ([]entity.Favorite, error)
+	GetByIDFn     func(context.Context, uuid.UUID, uuid.UUID) (*entity.Favorite, error)
+	UpdateFn      func(context.Context, *entity.Favorite) error
+	DeleteFn      func(context.Context, uuid.UUID, uuid.UUID) error
+	ReplaceTagsFn func(context.Context, *entity.Favorite, []uuid.UUID) error
+}
+
+func (f *FakeFavoriteRepository) Create(ctx context.Context, favorite *entity.Favorite) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, favorite)
+	}
+	return nil
+}
+
+func (f *FakeFavoriteRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.Favorite, error) {
+	if f.ListFn != nil {
+		return f.ListFn(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (f *FakeFavoriteRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Favorite, error) {
+	if f.GetByIDFn != nil {
+		return f.GetByIDFn(ctx, userID, id)
+	}
+	return nil, errors.New("GetByID not implemented")
+}
+
+func (f *FakeFavoriteRepository) Update(ctx context.Context, favorite *entity.Favorite) error {
+	if f.UpdateFn != nil {
+		return f.UpdateFn(ctx, favorite)
+	}
+	return nil
+}
+
+func (f *FakeFavoriteRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if f.DeleteFn != nil {
+		return f.DeleteFn(ctx, userID, id)
+	}
+	return nil
+}
+
+func (f *FakeFavoriteRepository) ReplaceTags(ctx context.Context, favorite *entity.Favorite, tagIDs []uuid.UUID) error {
+	if f.ReplaceTagsFn != nil {
+		return f.ReplaceTagsFn(ctx, favorite, tagIDs)
+	}
+	return nil
+}
+
+// FakePomodoroRepository はテスト用に repository.PomodoroRepository を実装する。
+type FakePomodoroRepository struct {
+	CreateFn     func(context.Context, *entity.PomodoroSession, *entity.Entry) error
+	GetByIDFn    func(context.Context, uuid.UUID, uuid.UUID) (*entity.PomodoroSession, error)
+	GetActiveFn  func(context.Context, uuid.UUID) (*entity.PomodoroSession, error)
+	ListActiveFn func(context.Context) ([]entity.PomodoroSession, error)
+	AdvanceFn    func(context.Context, *entity.PomodoroSession, int, repository.EntryChanges) error
+}
+
+func (f *FakePomodoroRepository) Create(ctx context.Context, session *entity.PomodoroSession, entry *entity.Entry) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, session, entry)
+	}
+	return nil
+}
+
+func (f *FakePomodoroRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.PomodoroSession, error) {
+	if f.GetByIDFn != nil {
+		return f.GetByIDFn(ctx, userID, id)
+	}
+	return nil, errors.New("GetByID not implemented")
+}
+
+func (f *FakePomodoroRepository) GetActive(ctx context.Context, userID uuid.UUID) (*entity.PomodoroSession, error) {
+	if f.GetActiveFn != nil {
+		return f.GetActiveFn(ctx, userID)
+	}
+	return nil, errors.New("no active session")
+}
+
+func (f *FakePomodoroRepository) ListActive(ctx context.Context) ([]entity.PomodoroSession, error) {
+	if f.ListActiveFn != nil {
+		return f.ListActiveFn(ctx)
+	}
+	return nil, nil
+}
+
+func (f *FakePomodoroRepository) Advance(ctx context.Context, session *entity.PomodoroSession, fromIndex int, changes repository.EntryChanges) error {
+	if f.AdvanceFn != nil {
+		return f.AdvanceFn(ctx, session, fromIndex, changes)
+	}
+	return nil
+}
+
+// FakeGoalRepository はテスト用に repository.GoalRepository を実装する。
+type FakeGoalRepository struct {
+	CreateFn  func(context.Context, *entity.Goal) error
+	ListFn    func(context.Context, uuid.UUID) ([]entity.Goal, error)
+	GetByIDFn func(context.Context, uuid.UUID, uuid.UUID) (*entity.Goal, error)
+	UpdateFn  func(context.Context, *entity.Goal) error
+	DeleteFn  func(context.Context, uuid.UUID, uuid.UUID) error
+}
+
+func (f *FakeGoalRepository) Create(ctx context.Context, goal *entity.Goal) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, goal)
+	}
+	return nil
+}
+
+func (f *FakeGoalRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.Goal, error) {
+	if f.ListFn != nil {
+		return f.ListFn(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (f *FakeGoalRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.Goal, error) {
+	if f.GetByIDFn != nil {
+		return f.GetByIDFn(ctx, userID, id)
+	}
+	return nil, errors.New("GetByID not implemented")
+}
+
+func (f *FakeGoalRepository) Update(ctx context.Context, goal *entity.Goal) error {
+	if f.UpdateFn != nil {
+		return f.UpdateFn(ctx, goal)
+	}
+	return nil
+}
+
+func (f *FakeGoalRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if f.DeleteFn != nil {
+		return f.DeleteFn(ctx, userID, id)
+	}
+	return nil
+}
+
+// FakeAllocationTemplateRepository はテスト用に repository.AllocationTemplateRepository を実装する。
+type FakeAllocationTemplateRepository struct {
+	CreateFn  func(context.Context, *entity.AllocationTemplate) error
+	ListFn    func(context.Context, uuid.UUID) ([]entity.AllocationTemplate, error)
+	GetByIDFn func(context.Context, uuid.UUID, uuid.UUID) (*entity.AllocationTemplate, error)
+	UpdateFn  func(context.Context, *entity.AllocationTemplate) error
+	DeleteFn  func(context.Context, uuid.UUID, uuid.UUID) error
+}
+
+func (f *FakeAllocationTemplateRepository) Create(ctx context.Context, template *entity.AllocationTemplate) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, template)
+	}
+	return nil
+}
+
+func (f *FakeAllocationTemplateRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.AllocationTemplate, error) {
+	if f.ListFn != nil {
+		return f.ListFn(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (f *FakeAllocationTemplateRepository) GetByID(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*entity.AllocationTemplate, error) {
+	if f.GetByIDFn != nil {
+		return f.GetByIDFn(ctx, userID, id)
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *FakeAllocationTemplateRepository) Update(ctx context.Context, template *entity.AllocationTemplate) error {
+	if f.UpdateFn != nil {
+		return f.UpdateFn(ctx, template)
+	}
+	return nil
+}
+
+func (f *FakeAllocationTemplateRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if f.DeleteFn != nil {
+		return f.DeleteFn(ctx, userID, id)
+	}
+	return nil
+}
+
+// FakeScheduleRepository はテスト用に repository.ScheduleRepository を実装する。
+type FakeScheduleRepository struct {
+	GetScheduleFn    func(context.Context, uuid.UUID) (*entity.WorkSchedule, error)
+	SaveScheduleFn   func(context.Context, *entity.WorkSchedule) error
+	ListHolidaysFn   func(context.Context, uuid.UUID, string, string) ([]entity.Holiday, error)
+	UpsertHolidaysFn func(context.Context, []entity.Holiday) error
+	DeleteHolidayFn  func(context.Context, uuid.UUID, uuid.UUID) error
+	ListTimeOffFn    func(context.Context, uuid.UUID, string, string) ([]entity.TimeOff, error)
+	CreateTimeOffFn  func(context.Context, *entity.TimeOff) error
+	DeleteTimeOffFn  func(context.Context, uuid.UUID, uuid.UUID) error
+}
+
+func (f *FakeScheduleRepository) GetSchedule(ctx context.Context, userID uuid.UUID) (*entity.WorkSchedule, error) {
+	if f.GetScheduleFn != nil {
+		return f.GetScheduleFn(ctx, userID)
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *FakeScheduleRepository) SaveSchedule(ctx context.Context, schedule *entity.WorkSchedule) error {
+	if f.SaveScheduleFn != nil {
+		return f.SaveScheduleFn(ctx, schedule)
+	}
+	return nil
+}
+
+func (f *FakeScheduleRepository) ListHolidays(ctx context.Context, userID uuid.UUID, from, to string) ([]entity.Holiday, error) {
+	if f.ListHolidaysFn != nil {
+		return f.ListHolidaysFn(ctx, userID, from, to)
+	}
+	return nil, nil
+}
+
+func (f *FakeScheduleRepository) UpsertHolidays(ctx context.Context, holidays []entity.Holiday) error {
+	if f.UpsertHolidaysFn != nil {
+		return f.UpsertHolidaysFn(ctx, holidays)
+	}
+	return nil
+}
+
+func (f *FakeScheduleRepository) DeleteHoliday(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if f.DeleteHolidayFn != nil {
+		return f.DeleteHolidayFn(ctx, userID, id)
+	}
+	return nil
+}
+
+func (f *FakeScheduleRepository) ListTimeOff(ctx context.Context, userID uuid.UUID, from, to string) ([]entity.TimeOff, error) {
+	if f.ListTimeOffFn != nil {
+		return f.ListTimeOffFn(ctx, userID, from, to)
+	}
+	return nil, nil
+}
+
+func (f *FakeScheduleRepository) CreateTimeOff(ctx context.Context, timeOff *entity.TimeOff) error {
+	if f.CreateTimeOffFn != nil {
+		return f.CreateTimeOffFn(ctx, timeOff)
+	}
+	return nil
+}
+
+func (f *FakeScheduleRepository) DeleteTimeOff(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	if f.DeleteTimeOffFn != nil {
+		return f.DeleteTimeOffFn(ctx, userID, id)
+	}
+	return nil
+}
+
+// FakeRefreshTokenRepository はテスト用に repository.RefreshTokenRepository を実装する。
+type FakeRefreshTokenRepository struct {
+	CreateFn       func(context.Context, *entity.RefreshToken) error
+	GetByHashFn    func(context.Context, string) (*entity.RefreshToken, error)
+	MarkUsedFn     func(context.Context, uuid.UUID, time.Time) (bool, error)
+	RevokeFamilyFn func(context.Context, uuid.UUID, time.Time) error
+	RevokeByUserFn func(context.Context, uuid.UUID, time.Time) error
+}
+
+func (f *FakeRefreshTokenRepository) Create(ctx context.Context, token *entity.RefreshToken) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, token)
+	}
+	return nil
+}
+
+func (f *FakeRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	if f.GetByHashFn != nil {
+		return f.GetByHashFn(ctx, tokenHash)
+	}
+	return nil, errors.New("GetByHash not implemented")
+}
+
+func (f *FakeRefreshTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	if f.MarkUsedFn != nil {
+		return f.MarkUsedFn(ctx, id, at)
+	}
+	return true, nil
+}
+
+func (f *FakeRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error {
+	if f.RevokeFamilyFn != nil {
+		return f.RevokeFamilyFn(ctx, familyID, at)
+	}
+	return nil
+}
+
+func (f *FakeRefreshTokenRepository) RevokeByUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	if f.RevokeByUserFn != nil {
+		return f.RevokeByUserFn(ctx, userID, at)
+	}
+	return nil
+}
+
+// FakePersonalTokenRepository はテスト用に repository.PersonalTokenRepository を実装する。
+type FakePersonalTokenRepository struct {
+	CreateFn        func(context.Context, *entity.PersonalAccessToken) error
+	ListFn          func(context.Context, uuid.UUID) ([]entity.PersonalAccessToken, error)
+	GetByHashFn     func(context.Context, string) (*entity.PersonalAccessToken, error)
+	DeleteFn        func(context.Context, uuid.UUID, uuid.UUID) (bool, error)
+	TouchLastUsedFn func(context.Context, uuid.UUID, time.Time) error
+}
+
+func (f *FakePersonalTokenRepository) Create(ctx context.Context, token *entity.PersonalAccessToken) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, token)
+	}
+	return nil
+}
+
+func (f *FakePersonalTokenRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.PersonalAccessToken, error) {
+	if f.ListFn != nil {
+		return f.ListFn(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (f *FakePersonalTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.PersonalAccessToken, error) {
+	if f.GetByHashFn != nil {
+		return f.GetByHashFn(ctx, tokenHash)
+	}
+	return nil, errors.New("GetByHash not implemented")
+}
+
+func (f *FakePersonalTokenRepository) Delete(ctx context.Context, userID uuid.UUID, id uuid.UUID) (bool, error) {
+	if f.DeleteFn != nil {
+		return f.DeleteFn(ctx, userID, id)
+	}
+	return true, nil
+}
+
+func (f *FakePersonalTokenRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	if f.TouchLastUsedFn != nil {
+		return f.TouchLastUsedFn(ctx, id, at)
+	}
+	return nil
+}
+
+// FakePasswordResetRepository はテスト用に repository.PasswordResetRepository を実装する。
+type FakePasswordResetRepository struct {
+	CreateFn           func(context.Context, *entity.PasswordResetToken) error
+	GetByHashFn        func(context.Context, string) (*entity.PasswordResetToken, error)
+	MarkUsedFn         func(context.Context, uuid.UUID, time.Time) (bool, error)
+	InvalidateByUserFn func(context.Context, uuid.UUID, time.Time) error
+}
+
+func (f *FakePasswordResetRepository) Create(ctx context.Context, token *entity.PasswordResetToken) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, token)
+	}
+	return nil
+}
+
+func (f *FakePasswordResetRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.PasswordResetToken, error) {
+	if f.GetByHashFn != nil {
+		return f.GetByHashFn(ctx, tokenHash)
+	}
+	return nil, errors.New("GetByHash not implemented")
+}
+
+func (f *FakePasswordResetRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	if f.MarkUsedFn != nil {
+		return f.MarkUsedFn(ctx, id, at)
+	}
+	return true, nil
+}
+
+func (f *FakePasswordResetRepository) InvalidateByUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	if f.InvalidateByUserFn != nil {
+		return f.InvalidateByUserFn(ctx, userID, at)
+	}
+	return nil
+}
+
+// FakeEmailVerificationRepository はテスト用に repository.EmailVerificationRepository を実装する。
+type FakeEmailVerificationRepository struct {
+	CreateFn           func(context.Context, *entity.EmailVerificationToken) error
+	GetByHashFn        func(context.Context, string) (*entity.EmailVerificationToken, error)
+	MarkUsedFn         func(context.Context, uuid.UUID, time.Time) (bool, error)
+	InvalidateByUserFn func(context.Context, uuid.UUID, time.Time) error
+	ListCreatedSinceFn func(context.Context, uuid.UUID, time.Time) ([]entity.EmailVerificationToken, error)
+}
+
+func (f *FakeEmailVerificationRepository) Create(ctx context.Context, token *entity.EmailVerificationToken) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, token)
+	}
+	return nil
+}
+
+func (f *FakeEmailVerificationRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.EmailVerificationToken, error) {
+	if f.GetByHashFn != nil {
+		return f.GetByHashFn(ctx, tokenHash)
+	}
+	return nil, errors.New("GetByHash not implemented")
+}
+
+func (f *FakeEmailVerificationRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	if f.MarkUsedFn != nil {
+		return f.MarkUsedFn(ctx, id, at)
+	}
+	return true, nil
+}
+
+func (f *FakeEmailVerificationRepository) InvalidateByUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	if f.InvalidateByUserFn != nil {
+		return f.InvalidateByUserFn(ctx, userID, at)
+	}
+	return nil
+}
+
+func (f *FakeEmailVerificationRepository) ListCreatedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]entity.EmailVerificationToken, error) {
+	if f.ListCreatedSinceFn != nil {
+		return f.ListCreatedSinceFn(ctx, userID, since)
+	}
+	return nil, nil
+}
+
+// FakeRecoveryCodeRepository はテスト用に repository.RecoveryCodeRepository を実装する。
+type FakeRecoveryCodeRepository struct {
+	ReplaceFn      func(context.Context, uuid.UUID, []entity.RecoveryCode) error
+	DeleteByUserFn func(context.Context, uuid.UUID) error
+	UseFn          func(context.Context, uuid.UUID, string, time.Time) (bool, error)
+	CountUnusedFn  func(context.Context, uuid.UUID) (int64, error)
+}
+
+func (f *FakeRecoveryCodeRepository) Replace(ctx context.Context, userID uuid.UUID, codes []entity.RecoveryCode) error {
+	if f.ReplaceFn != nil {
+		return f.ReplaceFn(ctx, userID, codes)
+	}
+	return nil
+}
+
+func (f *FakeRecoveryCodeRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	if f.DeleteByUserFn != nil {
+		return f.DeleteByUserFn(ctx, userID)
+	}
+	return nil
+}
+
+func (f *FakeRecoveryCodeRepository) Use(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) (bool, error) {
+	if f.UseFn != nil {
+		return f.UseFn(ctx, userID, codeHash, at)
+	}
+	return false, nil
+}
+
+func (f *FakeRecoveryCodeRepository) CountUnused(ctx context.Context, userID uuid.UUID) (int64, error) {
+	if f.CountUnusedFn != nil {
+		return f.CountUnusedFn(ctx, userID)
+	}
+	return 0, nil
+}
+
+// FakeLoginChallengeRepository はテスト用に repository.LoginChallengeRepository を実装する。
+type FakeLoginChallengeRepository struct {
+	CreateFn        func(context.Context, *entity.LoginChallenge) error
+	GetByHashFn     func(context.Context, string) (*entity.LoginChallenge, error)
+	RecordFailureFn func(context.Context, uuid.UUID) error
+	MarkUsedFn      func(context.Context, uuid.UUID, time.Time) (bool, error)
+}
+
+func (f *FakeLoginChallengeRepository) Create(ctx context.Context, challenge *entity.LoginChallenge) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, challenge)
+	}
+	return nil
+}
+
+func (f *FakeLoginChallengeRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.LoginChallenge, error) {
+	if f.GetByHashFn != nil {
+		return f.GetByHashFn(ctx, tokenHash)
+	}
+	return nil, errors.New("GetByHash not implemented")
+}
+
+func (f *FakeLoginChallengeRepository) RecordFailure(ctx context.Context, id uuid.UUID) error {
+	if f.RecordFailureFn != nil {
+		return f.RecordFailureFn(ctx, id)
+	}
+	return nil
+}
+
+func (f *FakeLoginChallengeRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	if f.MarkUsedFn != nil {
+		return f.MarkUsedFn(ctx, id, at)
+	}
+	return true, nil
+}
+
+// FakeExternalIdentityRepository はテスト用に repository.ExternalIdentityRepository を実装する。
+type FakeExternalIdentityRepository struct {
+	CreateFn         func(context.Context, *entity.ExternalIdentity) error
+	GetBySubjectFn   func(context.Context, string, string) (*entity.ExternalIdentity, error)
+	TouchLastLoginFn func(context.Context, uuid.UUID, time.Time) error
+}
+
+func (f *FakeExternalIdentityRepository) Create(ctx context.Context, identity *entity.ExternalIdentity) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, identity)
+	}
+	return nil
+}
+
+func (f *FakeExternalIdentityRepository) GetBySubject(ctx context.Context, issuer, subject string) (*entity.ExternalIdentity, error) {
+	if f.GetBySubjectFn != nil {
+		return f.GetBySubjectFn(ctx, issuer, subject)
+	}
+	return nil, errors.New("GetBySubject not implemented")
+}
+
+func (f *FakeExternalIdentityRepository) TouchLastLogin(ctx context.Context, id uuid.UUID, at time.Time) error {
+	if f.TouchLastLoginFn != nil {
+		return f.TouchLastLoginFn(ctx, id, at)
+	}
+	return nil
+}
+
+// FakeOIDCLoginRequestRepository はテスト用に repository.OIDCLoginRequestRepository を実装する。
+type FakeOIDCLoginRequestRepository struct {
+	CreateFn         func(context.Context, *entity.OIDCLoginRequest) error
+	GetByStateHashFn func(context.Context, string) (*entity.OIDCLoginRequest, error)
+	MarkUsedFn       func(context.Context, uuid.UUID, time.Time) (bool, error)
+}
+
+func (f *FakeOIDCLoginRequestRepository) Create(ctx context.Context, request *entity.OIDCLoginRequest) error {
+	if f.CreateFn != nil {
+		return f.CreateFn(ctx, request)
+	}
+	return nil
+}
+
+func (f *FakeOIDCLoginRequestRepository) GetByStateHash(ctx context.Context, stateHash string) (*entity.OIDCLoginRequest, error) {
+	if f.GetByStateHashFn != nil {
+		return f.GetByStateHashFn(ctx, stateHash)
+	}
+	return nil, errors.New("GetByStateHash not implemented")
+}
+
+func (f *FakeOIDCLoginRequestRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	if f.MarkUsedFn != nil {
+		return f.MarkUsedFn(ctx, id, at)
+	}
+	return true, nil
+}